@@ -0,0 +1,245 @@
+package dtls
+
+import (
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pion/dtls/v2/pkg/protocol"
+	"github.com/pion/dtls/v2/pkg/protocol/alert"
+	"github.com/pion/dtls/v2/pkg/protocol/recordlayer"
+)
+
+const (
+	defaultIPv4SubnetPrefix = 24
+	defaultIPv6SubnetPrefix = 64
+
+	// At most this many address and subnet buckets are kept each, the least
+	// recently used full ones are forgotten first
+	handshakeRateLimiterMaxBuckets = 4096
+)
+
+// AdmissionDecision is the decision of Config.AdmitHandshake on the
+// handshake of a new client
+type AdmissionDecision int
+
+// AdmissionDecision enums
+const (
+	// AdmissionAccept lets the handshake proceed
+	AdmissionAccept AdmissionDecision = iota
+	// AdmissionDrop silently drops the ClientHello
+	AdmissionDrop
+	// AdmissionReject answers the ClientHello with a fatal access_denied
+	// alert
+	AdmissionReject
+)
+
+func (d AdmissionDecision) String() string {
+	switch d {
+	case AdmissionAccept:
+		return "AdmissionAccept"
+	case AdmissionDrop:
+		return "AdmissionDrop"
+	case AdmissionReject:
+		return "AdmissionReject"
+	default:
+		return "Invalid AdmissionDecision"
+	}
+}
+
+// HandshakeRateLimiter limits the handshakes a listener admits per IP
+// address and per subnet with token buckets. Handshakes over a limit are
+// dropped silently. Addresses which aren't IP addresses are limited by
+// their string representation, and belong to no subnet. At most 4096
+// address and subnet buckets are kept each. Only buckets which have refilled
+// are forgotten, the least recently used first, so while every bucket kept
+// is still limiting, handshakes from new addresses and subnets are dropped.
+//
+// A HandshakeRateLimiter is safe for concurrent use, and may be shared by
+// the Configs of several listeners.
+type HandshakeRateLimiter struct {
+	// AddressRate is the number of handshakes per second admitted from an
+	// IP address, unlimited if zero. AddressBurst is the number of
+	// handshakes admitted at once, 1 if zero.
+	AddressRate  float64
+	AddressBurst int
+
+	// SubnetRate and SubnetBurst limit the handshakes from a subnet alike
+	SubnetRate  float64
+	SubnetBurst int
+
+	// IPv4SubnetPrefix and IPv6SubnetPrefix are the prefix lengths of the
+	// subnets, 24 and 64 if zero
+	IPv4SubnetPrefix int
+	IPv6SubnetPrefix int
+
+	mu        sync.Mutex
+	addresses *lruMap
+	subnets   *lruMap
+	now       func() time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// allow reports whether a handshake from the address is admitted, and
+// takes a token from its buckets if it is
+func (r *HandshakeRateLimiter) allow(remoteAddr net.Addr) bool {
+	addressKey, subnetKey := addressKeys(remoteAddr, r.IPv4SubnetPrefix, r.IPv6SubnetPrefix)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.timeNow()
+	if r.addresses == nil {
+		r.addresses = newLRUMap(handshakeRateLimiterMaxBuckets)
+		r.subnets = newLRUMap(handshakeRateLimiterMaxBuckets)
+	}
+
+	var address, subnet *tokenBucket
+	if r.AddressRate > 0 {
+		address = refill(r.addresses, addressKey, r.AddressRate, r.AddressBurst, now)
+		if address == nil || address.tokens < 1 {
+			return false
+		}
+	}
+	if r.SubnetRate > 0 && subnetKey != "" {
+		subnet = refill(r.subnets, subnetKey, r.SubnetRate, r.SubnetBurst, now)
+		if subnet == nil || subnet.tokens < 1 {
+			return false
+		}
+	}
+
+	// Only take tokens once every limit admits the handshake
+	for _, b := range []*tokenBucket{address, subnet} {
+		if b != nil {
+			b.tokens--
+		}
+	}
+	return true
+}
+
+// refill returns the bucket of the key, refilled for the time elapsed. A
+// new bucket only evicts a full one, nil is returned if there is none.
+func refill(buckets *lruMap, key string, rate float64, burst int, now time.Time) *tokenBucket {
+	size := bucketSize(burst)
+	v, ok := buckets.get(key)
+	if !ok {
+		v = &tokenBucket{tokens: size, last: now}
+		full := func(v interface{}) bool {
+			b := v.(*tokenBucket) //nolint:forcetypeassert
+			return b.tokens+now.Sub(b.last).Seconds()*rate >= size
+		}
+		if !buckets.put(key, v, full) {
+			return nil
+		}
+	}
+	b := v.(*tokenBucket) //nolint:forcetypeassert
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * rate
+		if b.tokens > size {
+			b.tokens = size
+		}
+	}
+	b.last = now
+	return b
+}
+
+// addressKeys returns the keys of a remote address and of its subnet, with
+// the given prefix lengths or else the defaults. The subnet key is empty if
+// it isn't an IP address.
+func addressKeys(remoteAddr net.Addr, ipv4Prefix, ipv6Prefix int) (string, string) {
+	var ip net.IP
+	switch addr := remoteAddr.(type) {
+	case nil:
+		return "", ""
+	case *net.UDPAddr:
+		ip = addr.IP
+	default:
+		if host, _, err := net.SplitHostPort(addr.String()); err == nil {
+			ip = net.ParseIP(host)
+		}
+	}
+	if ip == nil {
+		return remoteAddr.String(), ""
+	}
+
+	prefix, bits := ipv6Prefix, 8*net.IPv6len
+	if prefix <= 0 {
+		prefix = defaultIPv6SubnetPrefix
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+		prefix, bits = ipv4Prefix, 8*net.IPv4len
+		if prefix <= 0 {
+			prefix = defaultIPv4SubnetPrefix
+		}
+	}
+	if prefix > bits {
+		prefix = bits
+	}
+	subnet := ip.Mask(net.CIDRMask(prefix, bits))
+	return ip.String(), subnet.String() + "/" + strconv.Itoa(prefix)
+}
+
+func (r *HandshakeRateLimiter) timeNow() time.Time {
+	if r.now != nil {
+		return r.now()
+	}
+	return time.Now()
+}
+
+func bucketSize(burst int) float64 {
+	if burst <= 0 {
+		return 1
+	}
+	return float64(burst)
+}
+
+// includesAdmission reports whether the Config controls the admission of
+// handshakes by a listener
+func (c *Config) includesAdmission() bool {
+	return c.AdmitHandshake != nil || c.HandshakeRateLimiter != nil
+}
+
+// admitHandshake decides on the handshake of a new client of a listener.
+// Handshakes are rate limited once admitted by AdmitHandshake, so that
+// rejected clients don't use up the limits.
+func (c *Config) admitHandshake(clientHelloInfo *ClientHelloInfo) AdmissionDecision {
+	if c.AdmitHandshake != nil {
+		if decision := c.AdmitHandshake(clientHelloInfo); decision != AdmissionAccept {
+			return decision
+		}
+	}
+	if c.HandshakeRateLimiter != nil && !c.HandshakeRateLimiter.allow(clientHelloInfo.RemoteAddr) {
+		return AdmissionDrop
+	}
+	return AdmissionAccept
+}
+
+// admissionError returns the alert and the error failing a handshake which
+// is not admitted
+func admissionError(decision AdmissionDecision) (*alert.Alert, error) {
+	switch decision {
+	case AdmissionReject:
+		return &alert.Alert{Level: alert.Fatal, Description: alert.AccessDenied}, errHandshakeRejected
+	default:
+		return nil, errHandshakeDropped
+	}
+}
+
+// rejectAlert builds an access_denied alert datagram answering the given
+// ClientHello statelessly, with the record sequence number of the
+// ClientHello like a HelloVerifyRequest
+func rejectAlert(h *recordlayer.Header) ([]byte, error) {
+	return (&recordlayer.RecordLayer{
+		Header: recordlayer.Header{
+			Version:        protocol.Version1_2,
+			SequenceNumber: h.SequenceNumber,
+		},
+		Content: &alert.Alert{Level: alert.Fatal, Description: alert.AccessDenied},
+	}).Marshal()
+}
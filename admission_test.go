@@ -0,0 +1,101 @@
+package dtls
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestHandshakeRateLimiter(t *testing.T) {
+	now := time.Unix(1000, 0)
+	limiter := &HandshakeRateLimiter{
+		AddressRate:  1,
+		AddressBurst: 2,
+		SubnetRate:   1,
+		SubnetBurst:  3,
+		now:          func() time.Time { return now },
+	}
+
+	addr := func(ip string, port int) net.Addr {
+		return &net.UDPAddr{IP: net.ParseIP(ip), Port: port}
+	}
+
+	for i, test := range []struct {
+		elapsed time.Duration
+		addr    net.Addr
+		allowed bool
+	}{
+		// The burst of the address, whatever the port
+		{0, addr("192.0.2.1", 1), true},
+		{0, addr("192.0.2.1", 2), true},
+		{0, addr("192.0.2.1", 1), false},
+		// The burst of the subnet, the handshake over the limit of the
+		// address took no token from it
+		{0, addr("192.0.2.2", 1), true},
+		{0, addr("192.0.2.3", 1), false},
+		// Other subnets are independent
+		{0, addr("198.51.100.1", 1), true},
+		{0, addr("2001:db8:0:1::1", 1), true},
+		{0, addr("2001:db8:0:1::2", 1), true},
+		{0, addr("2001:db8:0:1::3", 1), true},
+		{0, addr("2001:db8:0:1::4", 1), false},
+		{0, addr("2001:db8:0:2::1", 1), true},
+		// Buckets refill over time
+		{2 * time.Second, addr("192.0.2.1", 1), true},
+		{0, addr("192.0.2.1", 1), true},
+		{0, addr("192.0.2.1", 1), false},
+		// Addresses which aren't IP addresses belong to no subnet
+		{0, &net.UnixAddr{Name: "a", Net: "unixgram"}, true},
+		{0, &net.UnixAddr{Name: "a", Net: "unixgram"}, true},
+		{0, &net.UnixAddr{Name: "a", Net: "unixgram"}, false},
+	} {
+		now = now.Add(test.elapsed)
+		if allowed := limiter.allow(test.addr); allowed != test.allowed {
+			t.Errorf("%d: allow(%s) expected(%v) actual(%v)", i, test.addr, test.allowed, allowed)
+		}
+	}
+
+	// Buckets which are still limiting are never forgotten
+	for i := 0; i < 2*handshakeRateLimiterMaxBuckets; i++ {
+		limiter.allow(addr(net.IPv4(10, byte(i>>16), byte(i>>8), byte(i)).String(), 1))
+	}
+	if n := limiter.addresses.len(); n != handshakeRateLimiterMaxBuckets {
+		t.Errorf("kept %d address buckets, expected %d", n, handshakeRateLimiterMaxBuckets)
+	}
+	if n := limiter.subnets.len(); n > handshakeRateLimiterMaxBuckets {
+		t.Errorf("kept %d subnet buckets, expected at most %d", n, handshakeRateLimiterMaxBuckets)
+	}
+	if limiter.allow(addr("192.0.2.1", 1)) {
+		t.Error("limited address allowed after a flood")
+	}
+}
+
+func TestHandshakeRateLimiterFull(t *testing.T) {
+	// While every bucket kept is limiting, new addresses are dropped
+	now := time.Unix(1000, 0)
+	limiter := &HandshakeRateLimiter{
+		AddressRate: 1,
+		now:         func() time.Time { return now },
+	}
+	addr := func(i int) net.Addr {
+		return &net.UDPAddr{IP: net.IPv4(10, 0, byte(i>>8), byte(i)), Port: 1}
+	}
+
+	for i := 0; i < handshakeRateLimiterMaxBuckets; i++ {
+		if !limiter.allow(addr(i)) {
+			t.Fatalf("%s not allowed", addr(i))
+		}
+	}
+	if limiter.allow(addr(handshakeRateLimiterMaxBuckets)) {
+		t.Error("new address allowed while every bucket is limiting")
+	}
+
+	// Once refilled, the least recently used bucket is forgotten
+	now = now.Add(time.Second)
+	if !limiter.allow(addr(handshakeRateLimiterMaxBuckets)) {
+		t.Error("new address not allowed once buckets refilled")
+	}
+	if _, ok := limiter.addresses.peek(addr(0).(*net.UDPAddr).IP.String()); ok {
+		t.Error("least recently used bucket not forgotten")
+	}
+}
@@ -1,17 +1,311 @@
 package dtls
 
 import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
+	"net"
 	"strings"
+
+	"github.com/pion/dtls/v2/pkg/crypto/clientcertificate"
+	"github.com/pion/dtls/v2/pkg/crypto/elliptic"
+	"github.com/pion/dtls/v2/pkg/crypto/hash"
+	"github.com/pion/dtls/v2/pkg/crypto/signature"
+	"github.com/pion/dtls/v2/pkg/crypto/signaturehash"
+	"github.com/pion/dtls/v2/pkg/protocol/alert"
+	"github.com/pion/dtls/v2/pkg/protocol/extension"
+	"github.com/pion/dtls/v2/pkg/protocol/handshake"
 )
 
-func (c *handshakeConfig) getCertificate(serverName string) (*tls.Certificate, error) {
+// ClientHelloInfo contains information from a ClientHello message in order to
+// guide application logic in the GetCertificate, GetConfigForClient and
+// AdmitHandshake callbacks.
+type ClientHelloInfo struct {
+	// ServerName is the value of the server_name extension, if any
+	ServerName string
+
+	// CipherSuites lists the CipherSuites supported by the client
+	CipherSuites []CipherSuiteID
+
+	// SupportedCurves lists the elliptic curves supported by the client,
+	// empty if it did not send the supported_elliptic_curves extension
+	SupportedCurves []elliptic.Curve
+
+	// SignatureSchemes lists the signature and hash schemes the client is
+	// willing to verify, empty if it did not send the signature_algorithms
+	// extension
+	SignatureSchemes []tls.SignatureScheme
+
+	// SupportedProtos lists the application protocols supported by the
+	// client (ALPN)
+	SupportedProtos []string
+
+	// RemoteAddr is the address of the client
+	RemoteAddr net.Addr
+
+	config *handshakeConfig
+}
+
+func newClientHelloInfo(remoteAddr net.Addr, cfg *handshakeConfig, clientHello *handshake.MessageClientHello) *ClientHelloInfo {
+	info := &ClientHelloInfo{
+		RemoteAddr: remoteAddr,
+		config:     cfg,
+	}
+	for _, id := range clientHello.CipherSuiteIDs {
+		info.CipherSuites = append(info.CipherSuites, CipherSuiteID(id))
+	}
+	for _, val := range clientHello.Extensions {
+		switch e := val.(type) {
+		case *extension.ServerName:
+			info.ServerName = e.ServerName
+		case *extension.SupportedEllipticCurves:
+			info.SupportedCurves = e.EllipticCurves
+		case *extension.SupportedSignatureAlgorithms:
+			info.SignatureSchemes = signatureSchemes(e.SignatureHashAlgorithms)
+		case *extension.ALPN:
+			info.SupportedProtos = e.ProtocolNameList
+		}
+	}
+	return info
+}
+
+// SupportsCertificate returns nil if the certificate is supported by the
+// client that sent the ClientHello: a cipher suite offered by the client,
+// and supported by the server, authenticates with the type of its key, the
+// client supports the curve of an ECDSA key, and the client can verify one
+// of its signature schemes.
+func (chi *ClientHelloInfo) SupportsCertificate(c *tls.Certificate) error {
+	certType, ok := certificateSignType(c)
+	if !ok {
+		return errInvalidPrivateKey
+	}
+
+	var customCipherSuites func() []CipherSuite
+	localSignatureSchemes := signaturehash.Algorithms()
+	if chi.config != nil {
+		customCipherSuites = chi.config.customCipherSuites
+		localSignatureSchemes = chi.config.localSignatureSchemes
+	}
+
+	var supported bool
+	for _, id := range chi.CipherSuites {
+		cipherSuite := cipherSuiteForID(id, customCipherSuites)
+		if cipherSuite == nil || cipherSuite.AuthenticationType() != CipherSuiteAuthenticationTypeCertificate || cipherSuite.CertificateType() != certType {
+			continue
+		}
+		if chi.config != nil {
+			if _, ok := findMatchingCipherSuite([]CipherSuite{cipherSuite}, chi.config.localCipherSuites); !ok {
+				continue
+			}
+		}
+		supported = true
+		break
+	}
+	if !supported {
+		return errCipherSuiteNoIntersection
+	}
+
+	// The curve of an ECDSA key must be one the client supports, RFC 8422
+	// Section 5.1
+	if signer, ok := c.PrivateKey.(crypto.Signer); ok && len(chi.SupportedCurves) > 0 {
+		if publicKey, ok := signer.Public().(*ecdsa.PublicKey); ok && !curveSupported(chi.SupportedCurves, publicKey) {
+			return errCertificateCurveNotSupported
+		}
+	}
+
+	_, err := selectSignatureScheme(localSignatureSchemes, signatureHashAlgorithms(chi.SignatureSchemes), c.Certificate, c.PrivateKey)
+	return err
+}
+
+// curveSupported returns true if the curve of the ECDSA key is one of curves
+func curveSupported(curves []elliptic.Curve, publicKey *ecdsa.PublicKey) bool {
+	for _, c := range curves {
+		if curve := c.EllipticCurve(); curve != nil && curve.Params().Name == publicKey.Curve.Params().Name {
+			return true
+		}
+	}
+	return false
+}
+
+// CertificateRequestInfo contains information from a server's
+// CertificateRequest message, in order to guide application logic in the
+// GetClientCertificate callback.
+type CertificateRequestInfo struct {
+	// AcceptableCAs contains the DER-encoded distinguished names of the
+	// certificate authorities the server accepts, empty if any
+	AcceptableCAs [][]byte
+
+	// CertificateTypes lists the types of key the server accepts
+	CertificateTypes []clientcertificate.Type
+
+	// SignatureSchemes lists the signature and hash schemes the server is
+	// willing to verify
+	SignatureSchemes []tls.SignatureScheme
+
+	config *handshakeConfig
+}
+
+func newCertificateRequestInfo(state *State, cfg *handshakeConfig) *CertificateRequestInfo {
+	return &CertificateRequestInfo{
+		AcceptableCAs:    state.remoteCertificateAuthorities,
+		CertificateTypes: state.remoteCertificateTypes,
+		SignatureSchemes: signatureSchemes(state.remoteSignatureSchemes),
+		config:           cfg,
+	}
+}
+
+// SupportsCertificate returns nil if the certificate is supported by the
+// server that sent the CertificateRequest: it accepts the type of its key,
+// can verify one of its signature schemes, and, if it lists certificate
+// authorities, one of them issued a certificate of the chain.
+func (cri *CertificateRequestInfo) SupportsCertificate(c *tls.Certificate) error {
+	certType, ok := certificateSignType(c)
+	if !ok {
+		return errInvalidPrivateKey
+	}
+
+	if len(cri.CertificateTypes) > 0 {
+		var accepted bool
+		for _, t := range cri.CertificateTypes {
+			if t == certType {
+				accepted = true
+				break
+			}
+		}
+		if !accepted {
+			return errCertificateTypeNotAccepted
+		}
+	}
+
+	localSignatureSchemes := signaturehash.Algorithms()
+	if cri.config != nil {
+		localSignatureSchemes = cri.config.localSignatureSchemes
+	}
+	if _, err := selectSignatureScheme(localSignatureSchemes, signatureHashAlgorithms(cri.SignatureSchemes), c.Certificate, c.PrivateKey); err != nil {
+		return err
+	}
+
+	if len(cri.AcceptableCAs) == 0 {
+		return nil
+	}
+	for _, rawCertificate := range c.Certificate {
+		x509Cert, err := x509.ParseCertificate(rawCertificate)
+		if err != nil {
+			continue
+		}
+		for _, ca := range cri.AcceptableCAs {
+			if bytes.Equal(x509Cert.RawIssuer, ca) {
+				return nil
+			}
+		}
+	}
+	return errCertificateAuthorityNotAccepted
+}
+
+// getClientCertificate returns the certificate for the CertificateRequest,
+// from the GetClientCertificate callback if set. Otherwise, the first
+// certificate the server supports is returned, or else the first of them.
+// A certificate without a chain sends no certificate.
+func (c *handshakeConfig) getClientCertificate(certificateRequestInfo *CertificateRequestInfo) (*tls.Certificate, error) {
+	if c.localGetClientCertificate != nil {
+		certificate, err := c.localGetClientCertificate(certificateRequestInfo)
+		if err == nil && certificate == nil {
+			return &tls.Certificate{}, nil
+		}
+		return certificate, err
+	}
+
+	if len(c.localCertificates) == 0 {
+		return &tls.Certificate{}, nil
+	}
+	for i := range c.localCertificates {
+		if certificateRequestInfo.SupportsCertificate(&c.localCertificates[i]) == nil {
+			return &c.localCertificates[i], nil
+		}
+	}
+	return &c.localCertificates[0], nil
+}
+
+// signatureSchemes converts signature and hash algorithms to the
+// tls.SignatureScheme of the same code point
+func signatureSchemes(algorithms []signaturehash.Algorithm) []tls.SignatureScheme {
+	var schemes []tls.SignatureScheme
+	for _, ss := range algorithms {
+		schemes = append(schemes, tls.SignatureScheme(uint16(ss.Hash)<<8|uint16(ss.Signature)))
+	}
+	return schemes
+}
+
+// signatureHashAlgorithms converts tls.SignatureScheme to the signature and
+// hash algorithms of the same code point
+func signatureHashAlgorithms(schemes []tls.SignatureScheme) []signaturehash.Algorithm {
+	algorithms := []signaturehash.Algorithm{}
+	for _, ss := range schemes {
+		algorithms = append(algorithms, signaturehash.Algorithm{
+			Hash:      hash.Algorithm(ss >> 8),
+			Signature: signature.Algorithm(ss & 0xFF),
+		})
+	}
+	return algorithms
+}
+
+// selectServerCertificate selects the certificate of a server for the
+// ClientHello, and returns the local CipherSuites which authenticate with it.
+// RFC 5246 Section 7.4.3. Certificate cipher suites are left out if no
+// certificate is configured.
+func selectServerCertificate(state *State, cfg *handshakeConfig, clientHelloInfo *ClientHelloInfo) ([]CipherSuite, *alert.Alert, error) {
+	state.localCertificate = nil
+
+	var certificateSuites bool
+	for _, c := range cfg.localCipherSuites {
+		if c.AuthenticationType() == CipherSuiteAuthenticationTypeCertificate {
+			certificateSuites = true
+			break
+		}
+	}
+	if !certificateSuites {
+		return cfg.localCipherSuites, nil, nil
+	}
+
+	certificate, err := cfg.getCertificate(clientHelloInfo)
+	switch {
+	case errors.Is(err, errNoCertificates):
+		cipherSuites := []CipherSuite{}
+		for _, c := range cfg.localCipherSuites {
+			if c.AuthenticationType() != CipherSuiteAuthenticationTypeCertificate {
+				cipherSuites = append(cipherSuites, c)
+			}
+		}
+		return cipherSuites, nil, nil
+	case err != nil:
+		return nil, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
+	}
+
+	state.localCertificate = certificate
+	return filterCipherSuitesForCertificate(certificate, cfg.localCipherSuites), nil, nil
+}
+
+// getCertificate returns the certificate for the ClientHello, from the
+// GetCertificate callback if set. Otherwise, the certificates matching the
+// server name are preferred, or all of them if none does, and the first the
+// client supports is returned, or else the first of them.
+func (c *handshakeConfig) getCertificate(clientHelloInfo *ClientHelloInfo) (*tls.Certificate, error) {
+	if c.localGetCertificate != nil {
+		certificate, err := c.localGetCertificate(clientHelloInfo)
+		if err == nil && certificate == nil {
+			return nil, errNoCertificates
+		}
+		return certificate, err
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	if c.nameToCertificate == nil {
-		nameToCertificate := make(map[string]*tls.Certificate)
+		nameToCertificate := make(map[string][]*tls.Certificate)
 		for i := range c.localCertificates {
 			cert := &c.localCertificates[i]
 			x509Cert := cert.Leaf
@@ -23,10 +317,12 @@ func (c *handshakeConfig) getCertificate(serverName string) (*tls.Certificate, e
 				}
 			}
 			if len(x509Cert.Subject.CommonName) > 0 {
-				nameToCertificate[strings.ToLower(x509Cert.Subject.CommonName)] = cert
+				name := strings.ToLower(x509Cert.Subject.CommonName)
+				nameToCertificate[name] = append(nameToCertificate[name], cert)
 			}
 			for _, san := range x509Cert.DNSNames {
-				nameToCertificate[strings.ToLower(san)] = cert
+				name := strings.ToLower(san)
+				nameToCertificate[name] = append(nameToCertificate[name], cert)
 			}
 		}
 		c.nameToCertificate = nameToCertificate
@@ -41,27 +337,40 @@ func (c *handshakeConfig) getCertificate(serverName string) (*tls.Certificate, e
 		return &c.localCertificates[0], nil
 	}
 
-	if len(serverName) == 0 {
-		return &c.localCertificates[0], nil
+	candidates := c.certificatesForName(clientHelloInfo.ServerName)
+	for _, cert := range candidates {
+		if clientHelloInfo.SupportsCertificate(cert) == nil {
+			return cert, nil
+		}
 	}
+	return candidates[0], nil
+}
 
-	name := strings.TrimRight(strings.ToLower(serverName), ".")
+// certificatesForName returns the certificates matching the server name, or
+// all of them if none does
+func (c *handshakeConfig) certificatesForName(serverName string) []*tls.Certificate {
+	if len(serverName) > 0 {
+		name := strings.TrimRight(strings.ToLower(serverName), ".")
 
-	if cert, ok := c.nameToCertificate[name]; ok {
-		return cert, nil
-	}
+		if certs, ok := c.nameToCertificate[name]; ok {
+			return certs
+		}
 
-	// try replacing labels in the name with wildcards until we get a
-	// match.
-	labels := strings.Split(name, ".")
-	for i := range labels {
-		labels[i] = "*"
-		candidate := strings.Join(labels, ".")
-		if cert, ok := c.nameToCertificate[candidate]; ok {
-			return cert, nil
+		// try replacing labels in the name with wildcards until we get a
+		// match.
+		labels := strings.Split(name, ".")
+		for i := range labels {
+			labels[i] = "*"
+			candidate := strings.Join(labels, ".")
+			if certs, ok := c.nameToCertificate[candidate]; ok {
+				return certs
+			}
 		}
 	}
 
-	// If nothing matches, return the first certificate.
-	return &c.localCertificates[0], nil
+	certs := make([]*tls.Certificate, len(c.localCertificates))
+	for i := range c.localCertificates {
+		certs[i] = &c.localCertificates[i]
+	}
+	return certs
 }
@@ -1,11 +1,16 @@
 package dtls
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
 	"crypto/tls"
+	"errors"
 	"reflect"
 	"testing"
 
+	"github.com/pion/dtls/v2/pkg/crypto/elliptic"
 	"github.com/pion/dtls/v2/pkg/crypto/selfsign"
+	"github.com/pion/dtls/v2/pkg/crypto/signaturehash"
 )
 
 func TestGetCertificate(t *testing.T) {
@@ -64,7 +69,7 @@ func TestGetCertificate(t *testing.T) {
 		test := test
 
 		t.Run(test.desc, func(t *testing.T) {
-			cert, err := cfg.getCertificate(test.serverName)
+			cert, err := cfg.getCertificate(&ClientHelloInfo{ServerName: test.serverName})
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -75,3 +80,129 @@ func TestGetCertificate(t *testing.T) {
 		})
 	}
 }
+
+func TestGetCertificateSupported(t *testing.T) {
+	certificateECDSA, err := selfsign.GenerateSelfSignedWithDNS("test.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificateRSA, err := selfsign.WithDNS(rsaKey, "test.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &handshakeConfig{
+		localCipherSuites: []CipherSuite{
+			cipherSuiteForID(TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, nil),
+			cipherSuiteForID(TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, nil),
+		},
+		localSignatureSchemes: signaturehash.Algorithms(),
+		localCertificates: []tls.Certificate{
+			certificateECDSA,
+			certificateRSA,
+		},
+	}
+
+	testCases := []struct {
+		desc                string
+		clientHelloInfo     *ClientHelloInfo
+		expectedCertificate tls.Certificate
+	}{
+		{
+			desc: "ECDSA cipher suite",
+			clientHelloInfo: &ClientHelloInfo{
+				ServerName:   "test.test",
+				CipherSuites: []CipherSuiteID{TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+			},
+			expectedCertificate: certificateECDSA,
+		},
+		{
+			desc: "RSA cipher suite",
+			clientHelloInfo: &ClientHelloInfo{
+				ServerName:   "test.test",
+				CipherSuites: []CipherSuiteID{TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+			},
+			expectedCertificate: certificateRSA,
+		},
+		{
+			desc: "RSA signature scheme",
+			clientHelloInfo: &ClientHelloInfo{
+				CipherSuites:     []CipherSuiteID{TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+				SignatureSchemes: []tls.SignatureScheme{tls.PKCS1WithSHA256},
+			},
+			expectedCertificate: certificateRSA,
+		},
+		{
+			desc: "Supported ECDSA curve",
+			clientHelloInfo: &ClientHelloInfo{
+				CipherSuites:    []CipherSuiteID{TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+				SupportedCurves: []elliptic.Curve{elliptic.X25519, elliptic.P256},
+			},
+			expectedCertificate: certificateECDSA,
+		},
+		{
+			desc: "Unsupported ECDSA curve",
+			clientHelloInfo: &ClientHelloInfo{
+				CipherSuites:    []CipherSuiteID{TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+				SupportedCurves: []elliptic.Curve{elliptic.X25519, elliptic.P384},
+			},
+			expectedCertificate: certificateRSA,
+		},
+		{
+			desc: "Unsupported return first",
+			clientHelloInfo: &ClientHelloInfo{
+				CipherSuites: []CipherSuiteID{TLS_PSK_WITH_AES_128_CCM_8},
+			},
+			expectedCertificate: certificateECDSA,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+
+		t.Run(test.desc, func(t *testing.T) {
+			test.clientHelloInfo.config = cfg
+			cert, err := cfg.getCertificate(test.clientHelloInfo)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(cert.Certificate, test.expectedCertificate.Certificate) {
+				t.Fatal("Certificate does not match")
+			}
+		})
+	}
+}
+
+func TestGetCertificateCallback(t *testing.T) {
+	certificate, err := selfsign.GenerateSelfSigned()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &handshakeConfig{
+		localGetCertificate: func(info *ClientHelloInfo) (*tls.Certificate, error) {
+			if info.ServerName != "test.test" {
+				return nil, nil
+			}
+			return &certificate, nil
+		},
+	}
+
+	cert, err := cfg.getCertificate(&ClientHelloInfo{ServerName: "test.test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cert.Certificate, certificate.Certificate) {
+		t.Fatal("Certificate does not match")
+	}
+
+	if _, err := cfg.getCertificate(&ClientHelloInfo{ServerName: "foo.bar"}); !errors.Is(err, errNoCertificates) {
+		t.Fatalf("Expected error(%v), got(%v)", errNoCertificates, err)
+	}
+}
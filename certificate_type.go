@@ -0,0 +1,139 @@
+package dtls
+
+import (
+	"crypto/x509"
+
+	"github.com/pion/dtls/v2/pkg/protocol/alert"
+	"github.com/pion/dtls/v2/pkg/protocol/extension"
+	"github.com/pion/dtls/v2/pkg/protocol/handshake"
+)
+
+// CertificateType is a type of certificate a peer authenticates with
+// https://tools.ietf.org/html/rfc7250#section-3
+type CertificateType = extension.CertificateType
+
+// CertificateType enums
+const (
+	CertificateTypeX509         CertificateType = extension.CertificateTypeX509
+	CertificateTypeRawPublicKey CertificateType = extension.CertificateTypeRawPublicKey
+)
+
+// An empty list of certificate types means X.509 only
+func defaultCertificateTypes(certificateTypes []CertificateType) []CertificateType {
+	if len(certificateTypes) == 0 {
+		return []CertificateType{CertificateTypeX509}
+	}
+	return certificateTypes
+}
+
+func isX509Only(certificateTypes []CertificateType) bool {
+	certificateTypes = defaultCertificateTypes(certificateTypes)
+	return len(certificateTypes) == 1 && certificateTypes[0] == CertificateTypeX509
+}
+
+func containsCertificateType(certificateTypes []CertificateType, certificateType CertificateType) bool {
+	for _, t := range defaultCertificateTypes(certificateTypes) {
+		if t == certificateType {
+			return true
+		}
+	}
+	return false
+}
+
+// selectCertificateType returns the most preferred local type the peer offered
+func selectCertificateType(local, offered []CertificateType) (CertificateType, bool) {
+	for _, t := range defaultCertificateTypes(local) {
+		if containsCertificateType(offered, t) {
+			return t, true
+		}
+	}
+	return 0, false
+}
+
+// rawPublicKey returns the DER encoded SubjectPublicKeyInfo of the leaf of a
+// certificate chain. The leaf is either an X.509 certificate or a
+// SubjectPublicKeyInfo itself.
+func rawPublicKey(certificate [][]byte) ([]byte, error) {
+	if len(certificate) == 0 {
+		return nil, errInvalidCertificate
+	}
+	if leaf, err := x509.ParseCertificate(certificate[0]); err == nil {
+		return leaf.RawSubjectPublicKeyInfo, nil
+	}
+	if _, err := x509.ParsePKIXPublicKey(certificate[0]); err != nil {
+		return nil, err
+	}
+	return certificate[0], nil
+}
+
+// certificateMessage builds the Certificate message sending a certificate
+// chain as the negotiated type of certificate
+func certificateMessage(certificate [][]byte, certificateType CertificateType) (*handshake.MessageCertificate, error) {
+	if certificateType != CertificateTypeRawPublicKey {
+		return &handshake.MessageCertificate{Certificate: certificate}, nil
+	}
+
+	spki, err := rawPublicKey(certificate)
+	if err != nil {
+		return nil, err
+	}
+	return &handshake.MessageCertificate{
+		Certificate:     [][]byte{spki},
+		CertificateType: CertificateTypeRawPublicKey,
+	}, nil
+}
+
+// clientCertificateTypeExtensions returns the extensions a client offers its
+// certificate types with. Peers assume X.509 without them, so they are only
+// sent when other types are configured. RFC 7250 Section 4.1
+func clientCertificateTypeExtensions(cfg *handshakeConfig) []extension.Extension {
+	extensions := []extension.Extension{}
+	if len(cfg.localCertificates) > 0 && !isX509Only(cfg.localCertificateTypes) {
+		extensions = append(extensions, &extension.ClientCertificateType{
+			CertificateTypes: cfg.localCertificateTypes,
+		})
+	}
+	if !isX509Only(cfg.peerCertificateTypes) {
+		extensions = append(extensions, &extension.ServerCertificateType{
+			CertificateTypes: cfg.peerCertificateTypes,
+		})
+	}
+	return extensions
+}
+
+// negotiateCertificateTypes selects the types of certificate the server and
+// client authenticate with from the types offered in the ClientHello, nil
+// if the extension was absent. RFC 7250 Section 4.2
+func negotiateCertificateTypes(state *State, cfg *handshakeConfig, clientCertificateTypes, serverCertificateTypes []CertificateType) (*alert.Alert, error) {
+	state.localCertificateType = CertificateTypeX509
+	state.remoteCertificateType = CertificateTypeX509
+	state.certificateTypeExtensions = nil
+
+	if serverCertificateTypes != nil {
+		certificateType, ok := selectCertificateType(cfg.localCertificateTypes, serverCertificateTypes)
+		if !ok {
+			return &alert.Alert{Level: alert.Fatal, Description: alert.UnsupportedCertificate}, errUnsupportedCertificateType
+		}
+		state.localCertificateType = certificateType
+		state.certificateTypeExtensions = append(state.certificateTypeExtensions, &extension.ServerCertificateType{
+			CertificateTypes: []CertificateType{certificateType},
+			Selected:         true,
+		})
+	} else if !containsCertificateType(cfg.localCertificateTypes, CertificateTypeX509) {
+		return &alert.Alert{Level: alert.Fatal, Description: alert.UnsupportedCertificate}, errUnsupportedCertificateType
+	}
+
+	// The client certificate type only matters if a certificate is requested.
+	// Without a common type the extension is omitted, and the client falls
+	// back to X.509.
+	if clientCertificateTypes != nil && cfg.clientAuth > NoClientCert {
+		if certificateType, ok := selectCertificateType(cfg.peerCertificateTypes, clientCertificateTypes); ok {
+			state.remoteCertificateType = certificateType
+			state.certificateTypeExtensions = append(state.certificateTypeExtensions, &extension.ClientCertificateType{
+				CertificateTypes: []CertificateType{certificateType},
+				Selected:         true,
+			})
+		}
+	}
+	return nil, nil //nolint:nilnil
+}
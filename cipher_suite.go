@@ -1,6 +1,7 @@
 package dtls
 
 import (
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/ed25519"
 	"crypto/rsa"
@@ -22,6 +23,10 @@ const (
 	TLS_ECDHE_ECDSA_WITH_AES_128_CCM   CipherSuiteID = ciphersuite.TLS_ECDHE_ECDSA_WITH_AES_128_CCM   //nolint:revive,stylecheck
 	TLS_ECDHE_ECDSA_WITH_AES_128_CCM_8 CipherSuiteID = ciphersuite.TLS_ECDHE_ECDSA_WITH_AES_128_CCM_8 //nolint:revive,stylecheck
 
+	// AES-256-CCM
+	TLS_ECDHE_ECDSA_WITH_AES_256_CCM   CipherSuiteID = ciphersuite.TLS_ECDHE_ECDSA_WITH_AES_256_CCM   //nolint:revive,stylecheck
+	TLS_ECDHE_ECDSA_WITH_AES_256_CCM_8 CipherSuiteID = ciphersuite.TLS_ECDHE_ECDSA_WITH_AES_256_CCM_8 //nolint:revive,stylecheck
+
 	// AES-128-GCM-SHA256
 	TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 CipherSuiteID = ciphersuite.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 //nolint:revive,stylecheck
 	TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256   CipherSuiteID = ciphersuite.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256   //nolint:revive,stylecheck
@@ -33,13 +38,34 @@ const (
 	TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA CipherSuiteID = ciphersuite.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA //nolint:revive,stylecheck
 	TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA   CipherSuiteID = ciphersuite.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA   //nolint:revive,stylecheck
 
+	// AES-CBC-SHA256/384
+	TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256 CipherSuiteID = ciphersuite.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256 //nolint:revive,stylecheck
+	TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA384 CipherSuiteID = ciphersuite.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA384 //nolint:revive,stylecheck
+	TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256   CipherSuiteID = ciphersuite.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256   //nolint:revive,stylecheck
+	TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA384   CipherSuiteID = ciphersuite.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA384   //nolint:revive,stylecheck
+
 	TLS_PSK_WITH_AES_128_CCM        CipherSuiteID = ciphersuite.TLS_PSK_WITH_AES_128_CCM        //nolint:revive,stylecheck
 	TLS_PSK_WITH_AES_128_CCM_8      CipherSuiteID = ciphersuite.TLS_PSK_WITH_AES_128_CCM_8      //nolint:revive,stylecheck
 	TLS_PSK_WITH_AES_256_CCM_8      CipherSuiteID = ciphersuite.TLS_PSK_WITH_AES_256_CCM_8      //nolint:revive,stylecheck
 	TLS_PSK_WITH_AES_128_GCM_SHA256 CipherSuiteID = ciphersuite.TLS_PSK_WITH_AES_128_GCM_SHA256 //nolint:revive,stylecheck
 	TLS_PSK_WITH_AES_128_CBC_SHA256 CipherSuiteID = ciphersuite.TLS_PSK_WITH_AES_128_CBC_SHA256 //nolint:revive,stylecheck
+	TLS_PSK_WITH_AES_256_CCM        CipherSuiteID = ciphersuite.TLS_PSK_WITH_AES_256_CCM        //nolint:revive,stylecheck
+	TLS_PSK_WITH_AES_256_GCM_SHA384 CipherSuiteID = ciphersuite.TLS_PSK_WITH_AES_256_GCM_SHA384 //nolint:revive,stylecheck
+	TLS_PSK_WITH_AES_256_CBC_SHA384 CipherSuiteID = ciphersuite.TLS_PSK_WITH_AES_256_CBC_SHA384 //nolint:revive,stylecheck
 
 	TLS_ECDHE_PSK_WITH_AES_128_CBC_SHA256 CipherSuiteID = ciphersuite.TLS_ECDHE_PSK_WITH_AES_128_CBC_SHA256 //nolint:revive,stylecheck
+	TLS_ECDHE_PSK_WITH_AES_256_CBC_SHA384 CipherSuiteID = ciphersuite.TLS_ECDHE_PSK_WITH_AES_256_CBC_SHA384 //nolint:revive,stylecheck
+	TLS_ECDHE_PSK_WITH_AES_128_GCM_SHA256 CipherSuiteID = ciphersuite.TLS_ECDHE_PSK_WITH_AES_128_GCM_SHA256 //nolint:revive,stylecheck
+	TLS_ECDHE_PSK_WITH_AES_128_CCM_SHA256 CipherSuiteID = ciphersuite.TLS_ECDHE_PSK_WITH_AES_128_CCM_SHA256 //nolint:revive,stylecheck
+
+	// CHACHA20-POLY1305-SHA256
+	TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256 CipherSuiteID = ciphersuite.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256 //nolint:revive,stylecheck
+	TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256   CipherSuiteID = ciphersuite.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256   //nolint:revive,stylecheck
+	TLS_PSK_WITH_CHACHA20_POLY1305_SHA256         CipherSuiteID = ciphersuite.TLS_PSK_WITH_CHACHA20_POLY1305_SHA256         //nolint:revive,stylecheck
+	TLS_ECDHE_PSK_WITH_CHACHA20_POLY1305_SHA256   CipherSuiteID = ciphersuite.TLS_ECDHE_PSK_WITH_CHACHA20_POLY1305_SHA256   //nolint:revive,stylecheck
+
+	// EC J-PAKE
+	TLS_ECJPAKE_WITH_AES_128_CCM_8 CipherSuiteID = ciphersuite.TLS_ECJPAKE_WITH_AES_128_CCM_8 //nolint:revive,stylecheck
 )
 
 // CipherSuiteAuthenticationType controls what authentication method is using during the handshake for a CipherSuite
@@ -50,6 +76,7 @@ const (
 	CipherSuiteAuthenticationTypeCertificate  CipherSuiteAuthenticationType = ciphersuite.AuthenticationTypeCertificate
 	CipherSuiteAuthenticationTypePreSharedKey CipherSuiteAuthenticationType = ciphersuite.AuthenticationTypePreSharedKey
 	CipherSuiteAuthenticationTypeAnonymous    CipherSuiteAuthenticationType = ciphersuite.AuthenticationTypeAnonymous
+	CipherSuiteAuthenticationTypePassword     CipherSuiteAuthenticationType = ciphersuite.AuthenticationTypePassword
 )
 
 // CipherSuiteKeyExchangeAlgorithm controls what exchange algorithm is using during the handshake for a CipherSuite
@@ -57,9 +84,11 @@ type CipherSuiteKeyExchangeAlgorithm = ciphersuite.KeyExchangeAlgorithm
 
 // CipherSuiteKeyExchangeAlgorithm Bitmask
 const (
-	CipherSuiteKeyExchangeAlgorithmNone  CipherSuiteKeyExchangeAlgorithm = ciphersuite.KeyExchangeAlgorithmNone
-	CipherSuiteKeyExchangeAlgorithmPsk   CipherSuiteKeyExchangeAlgorithm = ciphersuite.KeyExchangeAlgorithmPsk
-	CipherSuiteKeyExchangeAlgorithmEcdhe CipherSuiteKeyExchangeAlgorithm = ciphersuite.KeyExchangeAlgorithmEcdhe
+	CipherSuiteKeyExchangeAlgorithmNone    CipherSuiteKeyExchangeAlgorithm = ciphersuite.KeyExchangeAlgorithmNone
+	CipherSuiteKeyExchangeAlgorithmPsk     CipherSuiteKeyExchangeAlgorithm = ciphersuite.KeyExchangeAlgorithmPsk
+	CipherSuiteKeyExchangeAlgorithmEcdhe   CipherSuiteKeyExchangeAlgorithm = ciphersuite.KeyExchangeAlgorithmEcdhe
+	CipherSuiteKeyExchangeAlgorithmEcjpake CipherSuiteKeyExchangeAlgorithm = ciphersuite.KeyExchangeAlgorithmEcjpake
+	CipherSuiteKeyExchangeAlgorithmCustom  CipherSuiteKeyExchangeAlgorithm = ciphersuite.KeyExchangeAlgorithmCustom
 )
 
 var _ = allCipherSuites() // Necessary until this function isn't only used by Go 1.14
@@ -95,6 +124,34 @@ type CipherSuite interface {
 	Decrypt(in []byte) ([]byte, error)
 }
 
+// connectionIDCipherSuite is implemented by cipher suites which can protect
+// records with a connection ID (RFC 9146). The connection ID of a record
+// can't be parsed from the record alone, so these records are decrypted
+// with the header parsed by the Conn. Connection IDs are only negotiated
+// for cipher suites implementing it.
+type connectionIDCipherSuite interface {
+	CipherSuite
+	DecryptWithHeader(h recordlayer.Header, in []byte) ([]byte, error)
+}
+
+// encryptThenMACCipherSuite is implemented by CBC cipher suites, which can
+// calculate the MAC over the ciphertext when negotiated with the
+// encrypt_then_mac extension.
+// https://tools.ietf.org/html/rfc7366
+type encryptThenMACCipherSuite interface {
+	CipherSuite
+	SetEncryptThenMAC(bool)
+}
+
+func encryptThenMACSupported(cipherSuites []CipherSuite) bool {
+	for _, c := range cipherSuites {
+		if _, ok := c.(encryptThenMACCipherSuite); ok {
+			return true
+		}
+	}
+	return false
+}
+
 // CipherSuiteName provides the same functionality as tls.CipherSuiteName
 // that appeared first in Go 1.14.
 //
@@ -141,6 +198,40 @@ func cipherSuiteForID(id CipherSuiteID, customCiphers func() []CipherSuite) Ciph
 		return &ciphersuite.TLSEcdheRsaWithAes256GcmSha384{}
 	case TLS_ECDHE_PSK_WITH_AES_128_CBC_SHA256:
 		return ciphersuite.NewTLSEcdhePskWithAes128CbcSha256()
+	case TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256:
+		return &ciphersuite.TLSEcdheEcdsaWithChacha20Poly1305Sha256{}
+	case TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256:
+		return &ciphersuite.TLSEcdheRsaWithChacha20Poly1305Sha256{}
+	case TLS_PSK_WITH_CHACHA20_POLY1305_SHA256:
+		return &ciphersuite.TLSPskWithChacha20Poly1305Sha256{}
+	case TLS_ECDHE_PSK_WITH_CHACHA20_POLY1305_SHA256:
+		return &ciphersuite.TLSEcdhePskWithChacha20Poly1305Sha256{}
+	case TLS_ECDHE_ECDSA_WITH_AES_256_CCM:
+		return ciphersuite.NewTLSEcdheEcdsaWithAes256Ccm()
+	case TLS_ECDHE_ECDSA_WITH_AES_256_CCM_8:
+		return ciphersuite.NewTLSEcdheEcdsaWithAes256Ccm8()
+	case TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256:
+		return ciphersuite.NewTLSEcdheEcdsaWithAes128CbcSha256()
+	case TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA384:
+		return ciphersuite.NewTLSEcdheEcdsaWithAes256CbcSha384()
+	case TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256:
+		return ciphersuite.NewTLSEcdheRsaWithAes128CbcSha256()
+	case TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA384:
+		return ciphersuite.NewTLSEcdheRsaWithAes256CbcSha384()
+	case TLS_PSK_WITH_AES_256_CCM:
+		return ciphersuite.NewTLSPskWithAes256Ccm()
+	case TLS_PSK_WITH_AES_256_GCM_SHA384:
+		return &ciphersuite.TLSPskWithAes256GcmSha384{}
+	case TLS_PSK_WITH_AES_256_CBC_SHA384:
+		return ciphersuite.NewTLSPskWithAes256CbcSha384()
+	case TLS_ECDHE_PSK_WITH_AES_128_GCM_SHA256:
+		return &ciphersuite.TLSEcdhePskWithAes128GcmSha256{}
+	case TLS_ECDHE_PSK_WITH_AES_128_CCM_SHA256:
+		return ciphersuite.NewTLSEcdhePskWithAes128CcmSha256()
+	case TLS_ECDHE_PSK_WITH_AES_256_CBC_SHA384:
+		return ciphersuite.NewTLSEcdhePskWithAes256CbcSha384()
+	case TLS_ECJPAKE_WITH_AES_128_CCM_8:
+		return ciphersuite.NewTLSEcjpakeWithAes128Ccm8()
 	}
 
 	if customCiphers != nil {
@@ -163,6 +254,8 @@ func defaultCipherSuites() []CipherSuite {
 		&ciphersuite.TLSEcdheRsaWithAes256CbcSha{},
 		&ciphersuite.TLSEcdheEcdsaWithAes256GcmSha384{},
 		&ciphersuite.TLSEcdheRsaWithAes256GcmSha384{},
+		&ciphersuite.TLSEcdheEcdsaWithChacha20Poly1305Sha256{},
+		&ciphersuite.TLSEcdheRsaWithChacha20Poly1305Sha256{},
 	}
 }
 
@@ -180,6 +273,23 @@ func allCipherSuites() []CipherSuite {
 		&ciphersuite.TLSPskWithAes128GcmSha256{},
 		&ciphersuite.TLSEcdheEcdsaWithAes256GcmSha384{},
 		&ciphersuite.TLSEcdheRsaWithAes256GcmSha384{},
+		&ciphersuite.TLSEcdheEcdsaWithChacha20Poly1305Sha256{},
+		&ciphersuite.TLSEcdheRsaWithChacha20Poly1305Sha256{},
+		&ciphersuite.TLSPskWithChacha20Poly1305Sha256{},
+		&ciphersuite.TLSEcdhePskWithChacha20Poly1305Sha256{},
+		ciphersuite.NewTLSEcdheEcdsaWithAes256Ccm(),
+		ciphersuite.NewTLSEcdheEcdsaWithAes256Ccm8(),
+		ciphersuite.NewTLSEcdheEcdsaWithAes128CbcSha256(),
+		ciphersuite.NewTLSEcdheEcdsaWithAes256CbcSha384(),
+		ciphersuite.NewTLSEcdheRsaWithAes128CbcSha256(),
+		ciphersuite.NewTLSEcdheRsaWithAes256CbcSha384(),
+		ciphersuite.NewTLSPskWithAes256Ccm(),
+		&ciphersuite.TLSPskWithAes256GcmSha384{},
+		ciphersuite.NewTLSPskWithAes256CbcSha384(),
+		&ciphersuite.TLSEcdhePskWithAes128GcmSha256{},
+		ciphersuite.NewTLSEcdhePskWithAes128CcmSha256(),
+		ciphersuite.NewTLSEcdhePskWithAes256CbcSha384(),
+		ciphersuite.NewTLSEcjpakeWithAes128Ccm8(),
 	}
 }
 
@@ -191,7 +301,7 @@ func cipherSuiteIDs(cipherSuites []CipherSuite) []uint16 {
 	return rtrn
 }
 
-func parseCipherSuites(userSelectedSuites []CipherSuiteID, customCipherSuites func() []CipherSuite, includeCertificateSuites, includePSKSuites bool) ([]CipherSuite, error) {
+func parseCipherSuites(userSelectedSuites []CipherSuiteID, customCipherSuites func() []CipherSuite, includeCertificateSuites, includePSKSuites, includePasswordSuites bool) ([]CipherSuite, error) {
 	cipherSuitesForIDs := func(ids []CipherSuiteID) ([]CipherSuite, error) {
 		cipherSuites := []CipherSuite{}
 		for _, id := range ids {
@@ -223,13 +333,15 @@ func parseCipherSuites(userSelectedSuites []CipherSuiteID, customCipherSuites fu
 		cipherSuites = append(customCipherSuites(), cipherSuites...)
 	}
 
-	var foundCertificateSuite, foundPSKSuite, foundAnonymousSuite bool
+	var foundCertificateSuite, foundPSKSuite, foundPasswordSuite, foundAnonymousSuite bool
 	for _, c := range cipherSuites {
 		switch {
 		case includeCertificateSuites && c.AuthenticationType() == CipherSuiteAuthenticationTypeCertificate:
 			foundCertificateSuite = true
 		case includePSKSuites && c.AuthenticationType() == CipherSuiteAuthenticationTypePreSharedKey:
 			foundPSKSuite = true
+		case includePasswordSuites && c.AuthenticationType() == CipherSuiteAuthenticationTypePassword:
+			foundPasswordSuite = true
 		case c.AuthenticationType() == CipherSuiteAuthenticationTypeAnonymous:
 			foundAnonymousSuite = true
 		default:
@@ -244,6 +356,8 @@ func parseCipherSuites(userSelectedSuites []CipherSuiteID, customCipherSuites fu
 		return nil, errNoAvailableCertificateCipherSuite
 	case includePSKSuites && !foundPSKSuite:
 		return nil, errNoAvailablePSKCipherSuite
+	case includePasswordSuites && !foundPasswordSuite:
+		return nil, errNoAvailablePasswordCipherSuite
 	case i == 0:
 		return nil, errNoAvailableCipherSuites
 	}
@@ -255,13 +369,10 @@ func filterCipherSuitesForCertificate(cert *tls.Certificate, cipherSuites []Ciph
 	if cert == nil || cert.PrivateKey == nil {
 		return cipherSuites
 	}
-	var certType clientcertificate.Type
-	switch cert.PrivateKey.(type) {
-	case ed25519.PrivateKey, *ecdsa.PrivateKey:
-		certType = clientcertificate.ECDSASign
-	case *rsa.PrivateKey:
-		certType = clientcertificate.RSASign
+	if _, ok := cert.PrivateKey.(crypto.Signer); !ok {
+		return cipherSuites
 	}
+	certType, _ := certificateSignType(cert)
 
 	filtered := []CipherSuite{}
 	for _, c := range cipherSuites {
@@ -271,3 +382,20 @@ func filterCipherSuitesForCertificate(cert *tls.Certificate, cipherSuites []Ciph
 	}
 	return filtered
 }
+
+// certificateSignType returns the type of certificate the key of cert signs
+// with, false if it is not a supported crypto.Signer
+func certificateSignType(cert *tls.Certificate) (clientcertificate.Type, bool) {
+	signer, ok := cert.PrivateKey.(crypto.Signer)
+	if !ok {
+		return 0, false
+	}
+	switch signer.Public().(type) {
+	case ed25519.PublicKey, *ecdsa.PublicKey:
+		return clientcertificate.ECDSASign, true
+	case *rsa.PublicKey:
+		return clientcertificate.RSASign, true
+	default:
+		return 0, false
+	}
+}
@@ -1,7 +1,10 @@
 package dtls
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
 	"testing"
 	"time"
 
@@ -106,3 +109,163 @@ func TestCustomCipherSuite(t *testing.T) {
 		})
 	})
 }
+
+// testKeyExchangeCipherSuite brings a KeyExchange which derives the premaster
+// secret from a secret shared in advance, and a nonce of each side
+type testKeyExchangeCipherSuite struct {
+	testCustomCipherSuite
+	secret                []byte
+	omitServerKeyExchange bool
+}
+
+func (t *testKeyExchangeCipherSuite) NewKeyExchange(info *KeyExchangeInfo) (KeyExchange, error) {
+	return &testKeyExchange{
+		info:                  info,
+		secret:                t.secret,
+		omitServerKeyExchange: t.omitServerKeyExchange,
+	}, nil
+}
+
+type testKeyExchange struct {
+	info                  *KeyExchangeInfo
+	secret                []byte
+	omitServerKeyExchange bool
+	serverNonce           []byte
+}
+
+func (k *testKeyExchange) nonce() ([]byte, error) {
+	nonce := make([]byte, 16)
+	_, err := rand.Read(nonce)
+	return nonce, err
+}
+
+func (k *testKeyExchange) preMasterSecret(clientNonce []byte) []byte {
+	secret := sha256.Sum256(append(append(append([]byte{}, k.secret...), k.serverNonce...), clientNonce...))
+	return secret[:]
+}
+
+func (k *testKeyExchange) ServerKeyExchange() (payload []byte, err error) {
+	if k.omitServerKeyExchange {
+		return nil, nil
+	}
+	k.serverNonce, err = k.nonce()
+	return k.serverNonce, err
+}
+
+func (k *testKeyExchange) ProcessServerKeyExchange(payload []byte) error {
+	if (payload == nil) != k.omitServerKeyExchange {
+		return errUnexpectedServerNonce
+	}
+	k.serverNonce = payload
+	return nil
+}
+
+func (k *testKeyExchange) ClientKeyExchange() (payload, preMasterSecret []byte, err error) {
+	if payload, err = k.nonce(); err != nil {
+		return nil, nil, err
+	}
+	return payload, k.preMasterSecret(payload), nil
+}
+
+func (k *testKeyExchange) ProcessClientKeyExchange(payload []byte) ([]byte, error) {
+	return k.preMasterSecret(payload), nil
+}
+
+// Assert that a CipherSuite can bring its own key exchange
+func TestKeyExchangeCipherSuite(t *testing.T) {
+	type result struct {
+		c   *Conn
+		err error
+	}
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	for name, omitServerKeyExchange := range map[string]bool{
+		"ServerKeyExchange":        false,
+		"OmittedServerKeyExchange": true,
+	} {
+		omitServerKeyExchange := omitServerKeyExchange
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			cipherFactory := func() []CipherSuite {
+				return []CipherSuite{&testKeyExchangeCipherSuite{
+					testCustomCipherSuite: testCustomCipherSuite{authenticationType: CipherSuiteAuthenticationTypeAnonymous},
+					secret:                []byte("shared secret"),
+					omitServerKeyExchange: omitServerKeyExchange,
+				}}
+			}
+
+			ca, cb := dpipe.Pipe()
+			c := make(chan result)
+
+			go func() {
+				client, err := testClient(ctx, ca, &Config{
+					CipherSuites:       []CipherSuiteID{},
+					CustomCipherSuites: cipherFactory,
+					ServerName:         "example.com",
+					PSKIdentityHint:    []byte("client"),
+				}, false)
+				c <- result{client, err}
+			}()
+
+			server, err := testServer(ctx, cb, &Config{
+				CipherSuites:       []CipherSuiteID{},
+				CustomCipherSuites: cipherFactory,
+				PSKIdentityHint:    []byte("server"),
+			}, false)
+
+			clientResult := <-c
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				_ = server.Close()
+			}()
+			if clientResult.err != nil {
+				t.Fatal(clientResult.err)
+			}
+			defer func() {
+				_ = clientResult.c.Close()
+			}()
+
+			if server.state.keyExchange == nil || clientResult.c.state.keyExchange == nil {
+				t.Fatal("Key exchange of the CipherSuite was not used")
+			}
+			if !bytes.Equal(server.state.masterSecret, clientResult.c.state.masterSecret) {
+				t.Error("Master secrets differ")
+			}
+
+			for _, tt := range []struct {
+				conn     *Conn
+				isClient bool
+				identity string
+			}{
+				{clientResult.c, true, "client"},
+				{server, false, "server"},
+			} {
+				info := tt.conn.state.keyExchange.(*testKeyExchange).info //nolint:forcetypeassert
+				clientRandom := tt.conn.state.localRandom.MarshalFixed()
+				if !tt.isClient {
+					clientRandom = tt.conn.state.remoteRandom.MarshalFixed()
+				}
+				switch {
+				case info.IsClient != tt.isClient:
+					t.Errorf("IsClient %v, expected %v", info.IsClient, tt.isClient)
+				case !bytes.Equal(info.ClientRandom, clientRandom[:]):
+					t.Error("ClientRandom is not the random of the client")
+				case info.ServerName != "example.com":
+					t.Errorf("ServerName %q, expected %q", info.ServerName, "example.com")
+				case string(info.Identity) != tt.identity:
+					t.Errorf("Identity %q, expected %q", info.Identity, tt.identity)
+				case info.RemoteAddr == nil || info.Context() == nil:
+					t.Error("RemoteAddr and Context must be set")
+				}
+			}
+		})
+	}
+}
@@ -2,14 +2,17 @@ package dtls
 
 import (
 	"context"
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/ed25519"
 	"crypto/rsa"
 	"crypto/tls"
 	"crypto/x509"
 	"io"
+	"net"
 	"time"
 
+	"github.com/pion/dtls/v2/pkg/crypto/elliptic"
 	"github.com/pion/logging"
 )
 
@@ -21,8 +24,44 @@ type Config struct {
 	// Certificates contains certificate chain to present to the other side of the connection.
 	// Server MUST set this if PSK is non-nil
 	// client SHOULD sets this so CertificateRequests can be handled if PSK is non-nil
+	// The PrivateKey may be any crypto.Signer of an Ed25519, ECDSA or RSA key,
+	// and a ContextSigner if signing may be slow.
 	Certificates []tls.Certificate
 
+	// GetCertificate returns the certificate of a server for the ClientHello
+	// of a client, for instance by its server name, instead of Certificates.
+	// The certificate should suit the client, which
+	// ClientHelloInfo.SupportsCertificate reports. Certificate cipher suites
+	// are left out if it returns nil and no error. Only used by servers.
+	GetCertificate func(*ClientHelloInfo) (*tls.Certificate, error)
+
+	// GetClientCertificate returns the certificate of a client for the
+	// CertificateRequest of a server, instead of Certificates. It must not
+	// return a nil certificate; a certificate without a chain sends no
+	// certificate. The certificate should suit the server, which
+	// CertificateRequestInfo.SupportsCertificate reports. Only used by
+	// clients.
+	GetClientCertificate func(*CertificateRequestInfo) (*tls.Certificate, error)
+
+	// GetConfigForClient returns the Config of a server for the ClientHello
+	// of a client, or nil to keep this one. It is called once per
+	// handshake: the ClientHello a client repeats after a HelloVerifyRequest
+	// keeps the Config returned for the first one. The returned Config
+	// applies to the rest of the handshake, except for the settings of the
+	// connection itself and of the listener, which are kept from this
+	// Config:
+	//   - LoggerFactory, MTU, ReplayProtectionWindow and
+	//     PaddingLengthGenerator
+	//   - ConnectionIDGenerator, since the listener routes datagrams by
+	//     connection IDs of the length it generates
+	//   - SessionTicketKeys and CookieSecrets
+	//   - SkipHelloVerify, AdmitHandshake, HandshakeRateLimiter,
+	//     MaxConcurrentHandshakes and OnHandshakeError
+	//   - GetConfigForClient
+	//
+	// Only used by servers.
+	GetConfigForClient func(*ClientHelloInfo) (*Config, error)
+
 	// CipherSuites is a list of supported cipher suites.
 	// If CipherSuites is nil, a default list is used
 	CipherSuites []CipherSuiteID
@@ -30,11 +69,31 @@ type Config struct {
 	// CustomCipherSuites is a list of CipherSuites that can be
 	// provided by the user. This allow users to user Ciphers that are reserved
 	// for private usage.
+	// A CipherSuite implementing KeyExchangeCipherSuite also brings its own
+	// key exchange, which authenticates without certificates and only gets
+	// the randoms of the handshake, see KeyExchangeCipherSuite.
 	CustomCipherSuites func() []CipherSuite
 
 	// SignatureSchemes contains the signature and hash schemes that the peer requests to verify.
 	SignatureSchemes []tls.SignatureScheme
 
+	// CurvePreferences contains the elliptic curves used for ECDHE key
+	// exchanges, in order of preference. Clients offer them in the
+	// supported_elliptic_curves extension, and servers only accept them.
+	// If empty, X25519, P-256 and P-384 are used. P-521, X448 and the
+	// Brainpool curves of RFC 7027 are only used when listed here, as is
+	// the X25519MLKEM768Experimental hybrid group, which needs Go 1.24.
+	// No specification defines X25519MLKEM768 for DTLS 1.2: the
+	// experimental group uses a private use codepoint and a wire format of
+	// pion's own, with a two byte length instead of the one byte length of
+	// an ECPoint. Only list it for peers known to run pion/dtls.
+	CurvePreferences []elliptic.Curve
+
+	// PreferServerCipherSuites makes a server select the cipher suite and
+	// elliptic curve by its own order of preference in CipherSuites and
+	// CurvePreferences, instead of the order of the client.
+	PreferServerCipherSuites bool
+
 	// SRTPProtectionProfiles are the supported protection profiles
 	// Clients will send this via use_srtp and assert that the server properly responds
 	// Servers will assert that clients send one of these profiles and will respond as needed
@@ -48,15 +107,50 @@ type Config struct {
 	// should be disabled, requested, or required (default requested).
 	ExtendedMasterSecret ExtendedMasterSecretType
 
+	// EncryptThenMAC determines if the "Encrypt-then-MAC" extension should be
+	// disabled, requested, or required (default requested). It only applies
+	// to CBC cipher suites, so requiring it rejects CBC cipher suites
+	// without it.
+	EncryptThenMAC EncryptThenMACType
+
 	// FlightInterval controls how often we send outbound handshake messages
 	// defaults to time.Second
 	FlightInterval time.Duration
 
 	// PSK sets the pre-shared key used by this DTLS connection
 	// If PSK is non-nil only PSK CipherSuites will be used
-	PSK             PSKCallback
+	PSK PSKCallback
+
+	// PSKIdentityHint is the identity of a client, or the identity hint of
+	// a server. The KeyExchange of a custom CipherSuite gets it as
+	// KeyExchangeInfo.Identity, so it may also be set without a PSK when
+	// CustomCipherSuites is set.
 	PSKIdentityHint []byte
 
+	// GetPSK returns the pre-shared key of a server for the identity of a
+	// client, instead of PSK. The ClientHelloInfo carries the remote
+	// address and server name of the client, and ctx is the context of the
+	// handshake. An error wrapping ErrUnknownPSKIdentity sends the
+	// unknown_psk_identity alert. Only used by servers.
+	GetPSK func(ctx context.Context, identity []byte, clientHelloInfo *ClientHelloInfo) ([]byte, error)
+
+	// GetClientPSK returns the identity of a client and its pre-shared
+	// key, for the identity hint of the server, nil if it sent none. It
+	// replaces PSK and PSKIdentityHint, and lets every connection choose
+	// its identity. Only used by clients.
+	GetClientPSK func(ctx context.Context, identityHint []byte) (identity, psk []byte, err error)
+
+	// PSKAuthTracker counts the failed PSK authentications of a server, and
+	// locks out identities and subnets which fail repeatedly. Disabled if
+	// nil. Only used by servers.
+	PSKAuthTracker *PSKAuthTracker
+
+	// ECJPAKEPassphrase sets the passphrase shared by both sides for the
+	// EC J-PAKE key exchange, as used by Thread commissioning. If it is
+	// non-nil the TLS_ECJPAKE_WITH_AES_128_CCM_8 CipherSuite can be used,
+	// which authenticates the connection by the passphrase alone.
+	ECJPAKEPassphrase []byte
+
 	// InsecureSkipVerify controls whether a client verifies the
 	// server's certificate chain and host name.
 	// If InsecureSkipVerify is true, TLS accepts any certificate
@@ -82,6 +176,44 @@ type Config struct {
 	// be considered but the verifiedChains will always be nil.
 	VerifyPeerCertificate func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error
 
+	// CertificateTypes lists the types of certificate this endpoint can
+	// authenticate with, in order of preference. With
+	// CertificateTypeRawPublicKey only the SubjectPublicKeyInfo of the
+	// leaf of Certificates is sent (RFC 7250), and the leaf may be a DER
+	// encoded SubjectPublicKeyInfo instead of a certificate.
+	// If empty, only X.509 certificates are used.
+	CertificateTypes []CertificateType
+
+	// PeerCertificateTypes lists the types of certificate accepted from
+	// the peer, in order of preference. If empty, only X.509 certificates
+	// are accepted.
+	PeerCertificateTypes []CertificateType
+
+	// VerifyPeerRawPublicKey is called with the DER encoded
+	// SubjectPublicKeyInfo of a peer authenticating with a raw public key,
+	// which is also available in the PeerCertificates of the connection
+	// state. Raw public keys can't be verified against a certificate
+	// authority, so it must be set to accept them. If it returns a non-nil
+	// error, the handshake is aborted and that error results.
+	VerifyPeerRawPublicKey func(spki []byte) error
+
+	// RevocationChecker, if not nil, is consulted after normal certificate
+	// verification by either a client or server, with the chains the peer
+	// certificate was verified with. If it returns a non-nil error, the
+	// handshake is aborted with a certificate_revoked alert.
+	// CRLRevocationChecker checks against locally loaded CRLs.
+	RevocationChecker RevocationChecker
+
+	// VerifyOCSPResponse, if not nil, makes a client request the server to
+	// staple an OCSP response for its certificate (RFC 6066 Section 8). It
+	// is called after normal certificate verification with the DER encoded
+	// response, nil if the server stapled none, and the verified chains.
+	// If it returns a non-nil error, the handshake is aborted with a
+	// bad_certificate_status_response alert. VerifyOCSPStaple is a policy
+	// failing closed on a bad or missing response. It is not called when
+	// InsecureSkipVerify is set.
+	VerifyOCSPResponse func(ocspResponse []byte, verifiedChains [][]*x509.Certificate) error
+
 	// RootCAs defines the set of root certificate authorities
 	// that one peer uses when verifying the other peer's certificates.
 	// If RootCAs is nil, TLS uses the host's root CA set.
@@ -111,6 +243,21 @@ type Config struct {
 	// fit within the maximum transmission unit (default is 1200 bytes)
 	MTU int
 
+	// RecordSizeLimit is the largest record plaintext, between 64 and 16384
+	// bytes, this endpoint is willing to receive. It is advertised with the
+	// record_size_limit extension, and protected records that exceed it are
+	// rejected with a record_overflow alert. Zero disables the extension,
+	// though a server still honors the limit of a client.
+	// https://tools.ietf.org/html/rfc8449
+	RecordSizeLimit int
+
+	// MaxFragmentLength is requested by a client with the max_fragment_length
+	// extension, limiting the record plaintext in both directions. Servers
+	// that support record_size_limit ignore it when both are offered. Zero
+	// disables the extension. Servers always honor the extension.
+	// https://tools.ietf.org/html/rfc6066#section-4
+	MaxFragmentLength FragmentLength
+
 	// ReplayProtectionWindow is the size of the replay attack protection window.
 	// Duplication of the sequence number is checked in this window size.
 	// Packet with sequence number older than this value compared to the latest
@@ -126,12 +273,98 @@ type Config struct {
 	KeyLogWriter io.Writer
 
 	// SessionStore is the container to store session for resumption.
+	// Clients also keep the session tickets issued by servers in it.
 	SessionStore SessionStore
 
+	// SessionTicketKeys enables stateless session resumption with session
+	// tickets (RFC 5077) on a server. Session state is encrypted into the
+	// ticket, so servers sharing the same keys can resume each other's
+	// sessions without a SessionStore. Clients request tickets whenever
+	// SessionStore is set.
+	SessionTicketKeys *SessionTicketKeys
+
+	// SessionTicketLifetime is how long a session can be resumed with
+	// session tickets after its full handshake, 24 hours if zero. Tickets
+	// issued on resumption don't extend it. The PSK identity of the client
+	// and the server name are sealed into the ticket: a session is only
+	// resumed for the same server name, and only while GetPSK or
+	// PSKCallback still knows the identity and the PSKAuthTracker doesn't
+	// lock it out.
+	SessionTicketLifetime time.Duration
+
+	// CookieSecrets are the secrets used by the listener returned by Listen
+	// to verify HelloVerifyRequest cookies statelessly. The first ClientHello
+	// of a client is answered without keeping any state, and a connection is
+	// only created once the client returns a valid cookie, proving that it
+	// can receive packets at its address. Sharing the secrets lets servers
+	// of a cluster verify each other's cookies. Cookies expire after one to
+	// two minutes whatever the secret. If nil, Listen uses a random secret.
+	CookieSecrets *CookieSecrets
+
+	// SkipHelloVerify, if not nil, is called by a server with the address of
+	// a new client. Returning true skips the HelloVerifyRequest cookie
+	// exchange for the client, saving a round trip. This gives up the
+	// protection against spoofed addresses and amplification the cookie
+	// exchange provides, so it should only be used for trusted peers. The
+	// listener returned by Listen calls it in its own goroutine for every
+	// new client, so it may block without delaying other connections, but
+	// Close waits for it.
+	SkipHelloVerify func(net.Addr) bool
+
+	// AdmitHandshake, if not nil, is called by a listener with the first
+	// ClientHello of a new client, before any expensive work is done for
+	// the handshake. The listener returned by Listen calls it once the
+	// client returned a valid cookie, unless the cookie exchange is
+	// skipped, and drops or rejects the ClientHello without keeping any
+	// state. Otherwise it is called by the Conn of the client, which fails
+	// the handshake if it is not admitted. It may block without delaying
+	// other connections, the listener returned by Listen calls it in the
+	// goroutine of SkipHelloVerify.
+	AdmitHandshake func(*ClientHelloInfo) AdmissionDecision
+
+	// HandshakeRateLimiter, if not nil, limits the handshakes admitted by a
+	// listener per address and per subnet, after AdmitHandshake.
+	HandshakeRateLimiter *HandshakeRateLimiter
+
+	// MaxConcurrentHandshakes limits the connections a listener handshakes
+	// in the background or holds for Accept, 128 if zero. Further clients
+	// wait in the parent listener until a handshake finishes.
+	MaxConcurrentHandshakes int
+
+	// OnHandshakeError, if not nil, is called by a listener with the
+	// address and the error of every client whose handshake failed, except
+	// for the handshakes aborted by closing the listener. It is called from
+	// the goroutine of the handshake once the handshake no longer counts
+	// against MaxConcurrentHandshakes, and may close the listener. Close
+	// doesn't wait for callbacks in progress to return.
+	OnHandshakeError func(remoteAddr net.Addr, err error)
+
 	// List of application protocols the peer supports, for ALPN
 	SupportedProtocols []string
+
+	// ConnectionIDGenerator generates connection identifiers that should be
+	// sent by the remote party if it supports the DTLS Connection Identifier
+	// extension, as determined during the handshake. Generated connection
+	// identifiers must always have the same length. Returning a zero-length
+	// connection identifier indicates that the local party supports sending
+	// connection identifiers but does not require the remote party to send
+	// them. A nil ConnectionIDGenerator indicates that connection identifiers
+	// are not supported.
+	// https://datatracker.ietf.org/doc/html/rfc9146
+	ConnectionIDGenerator func() []byte
+
+	// PaddingLengthGenerator generates the number of padding bytes used to
+	// inflate ciphertext size in order to obscure content size from observers.
+	// The length of the content is passed to the generator such that both
+	// deterministic and random padding schemes can be applied while not
+	// exceeding maximum record size.
+	// If no PaddingLengthGenerator is specified, padding will not be applied.
+	// https://datatracker.ietf.org/doc/html/rfc9146#section-4
+	PaddingLengthGenerator func(uint) uint
 }
 
+const defaultMaxConcurrentHandshakes = 128
+
 func defaultConnectContextMaker() (context.Context, func()) {
 	return context.WithTimeout(context.Background(), 30*time.Second)
 }
@@ -145,6 +378,11 @@ func (c *Config) connectContextMaker() (context.Context, func()) {
 
 const defaultMTU = 1200 // bytes
 
+// includesPSK reports whether the Config enables PSK cipher suites
+func (c *Config) includesPSK() bool {
+	return c.PSK != nil || c.GetPSK != nil || c.GetClientPSK != nil
+}
+
 // PSKCallback is called once we have the remote's PSKIdentityHint.
 // If the remote provided none it will be nil
 type PSKCallback func([]byte) ([]byte, error)
@@ -173,29 +411,72 @@ const (
 	DisableExtendedMasterSecret
 )
 
+// EncryptThenMACType declares the policy the client and server
+// will follow for the Encrypt-then-MAC extension
+type EncryptThenMACType int
+
+// EncryptThenMACType enums
+const (
+	RequestEncryptThenMAC EncryptThenMACType = iota
+	RequireEncryptThenMAC
+	DisableEncryptThenMAC
+)
+
 func validateConfig(config *Config) error {
 	switch {
 	case config == nil:
 		return errNoConfigProvided
-	case config.PSKIdentityHint != nil && config.PSK == nil:
+	case config.PSKIdentityHint != nil && !config.includesPSK() && config.CustomCipherSuites == nil:
 		return errIdentityNoPSK
 	}
 
+	for _, certificateType := range append(append([]CertificateType{}, config.CertificateTypes...), config.PeerCertificateTypes...) {
+		if certificateType != CertificateTypeX509 && certificateType != CertificateTypeRawPublicKey {
+			return errInvalidCertificateType
+		}
+	}
+	if containsCertificateType(config.PeerCertificateTypes, CertificateTypeRawPublicKey) && config.VerifyPeerRawPublicKey == nil {
+		return errNoRawPublicKeyVerifier
+	}
+
+	supportedCurves := elliptic.Curves()
+	for _, curve := range config.CurvePreferences {
+		if !supportedCurves[curve] {
+			return errInvalidEllipticCurve
+		}
+	}
+
+	if config.RecordSizeLimit != 0 && (config.RecordSizeLimit < minRecordSizeLimit || config.RecordSizeLimit > maxRecordSizeLimit) {
+		return errInvalidRecordSizeLimit
+	}
+	if config.MaxFragmentLength != 0 && config.MaxFragmentLength.Size() == 0 {
+		return errInvalidMaxFragmentLength
+	}
+
 	for _, cert := range config.Certificates {
 		if cert.Certificate == nil {
 			return errInvalidCertificate
 		}
+		if containsCertificateType(config.CertificateTypes, CertificateTypeRawPublicKey) {
+			if _, err := rawPublicKey(cert.Certificate); err != nil {
+				return err
+			}
+		}
 		if cert.PrivateKey != nil {
-			switch cert.PrivateKey.(type) {
-			case ed25519.PrivateKey:
-			case *ecdsa.PrivateKey:
-			case *rsa.PrivateKey:
+			signer, ok := cert.PrivateKey.(crypto.Signer)
+			if !ok {
+				return errInvalidPrivateKey
+			}
+			switch signer.Public().(type) {
+			case ed25519.PublicKey:
+			case *ecdsa.PublicKey:
+			case *rsa.PublicKey:
 			default:
 				return errInvalidPrivateKey
 			}
 		}
 	}
 
-	_, err := parseCipherSuites(config.CipherSuites, config.CustomCipherSuites, config.PSK == nil || len(config.Certificates) > 0, config.PSK != nil)
+	_, err := parseCipherSuites(config.CipherSuites, config.CustomCipherSuites, (!config.includesPSK() && config.ECJPAKEPassphrase == nil) || len(config.Certificates) > 0 || config.GetCertificate != nil || config.GetClientCertificate != nil, config.includesPSK(), config.ECJPAKEPassphrase != nil)
 	return err
 }
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/pion/dtls/v2/pkg/crypto/elliptic"
 	"github.com/pion/dtls/v2/pkg/crypto/selfsign"
 )
 
@@ -103,6 +104,12 @@ func TestValidateConfig(t *testing.T) {
 		t.Fatal("TestValidateConfig: Client error expected with invalid CipherSuiteID")
 	}
 
+	// Invalid elliptic curve
+	config = &Config{CurvePreferences: []elliptic.Curve{elliptic.X25519, 0x0000}}
+	if err = validateConfig(config); !errors.Is(err, errInvalidEllipticCurve) {
+		t.Fatalf("TestValidateConfig: Client error exp(%v) failed(%v)", errInvalidEllipticCurve, err)
+	}
+
 	// Valid config
 	rsaPrivateKey, err := rsa.GenerateKey(rand.Reader, 2048)
 	if err != nil {
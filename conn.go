@@ -1,6 +1,7 @@
 package dtls
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -12,7 +13,6 @@ import (
 
 	"github.com/pion/dtls/v2/internal/closer"
 	"github.com/pion/dtls/v2/pkg/crypto/elliptic"
-	"github.com/pion/dtls/v2/pkg/crypto/signaturehash"
 	"github.com/pion/dtls/v2/pkg/protocol"
 	"github.com/pion/dtls/v2/pkg/protocol/alert"
 	"github.com/pion/dtls/v2/pkg/protocol/handshake"
@@ -76,9 +76,22 @@ type Conn struct {
 	fsm *handshakeFSM
 
 	replayProtectionWindow uint
+
+	paddingLengthGenerator func(uint) uint
+	// highest epoch and sequence number of an authenticated tls12_cid record,
+	// used to decide whether the remote address may be updated
+	connectionIDSequence uint64
+}
+
+// remoteAddrUpdater is implemented by connections which can follow the
+// remote peer to a new address, such as those accepted by a listener that
+// routes datagrams by Connection ID.
+type remoteAddrUpdater interface {
+	SourceAddr() net.Addr
+	SetRemoteAddr(net.Addr)
 }
 
-func createConn(ctx context.Context, nextConn net.Conn, config *Config, isClient bool, initialState *State) (*Conn, error) {
+func createConn(ctx context.Context, nextConn net.Conn, config *Config, isClient bool, initialState *State, acceptedHello *acceptedClientHello) (*Conn, error) {
 	err := validateConfig(config)
 	if err != nil {
 		return nil, err
@@ -88,21 +101,6 @@ func createConn(ctx context.Context, nextConn net.Conn, config *Config, isClient
 		return nil, errNilNextConn
 	}
 
-	cipherSuites, err := parseCipherSuites(config.CipherSuites, config.CustomCipherSuites, config.PSK == nil || len(config.Certificates) > 0, config.PSK != nil)
-	if err != nil {
-		return nil, err
-	}
-
-	signatureSchemes, err := signaturehash.ParseSignatureSchemes(config.SignatureSchemes, config.InsecureHashes)
-	if err != nil {
-		return nil, err
-	}
-
-	workerInterval := initialTickerInterval
-	if config.FlightInterval != 0 {
-		workerInterval = config.FlightInterval
-	}
-
 	loggerFactory := config.LoggerFactory
 	if loggerFactory == nil {
 		loggerFactory = logging.NewDefaultLoggerFactory()
@@ -138,6 +136,7 @@ func createConn(ctx context.Context, nextConn net.Conn, config *Config, isClient
 		cancelHandshaker: func() {},
 
 		replayProtectionWindow: uint(replayProtectionWindow),
+		paddingLengthGenerator: config.PaddingLengthGenerator,
 
 		state: State{
 			isClient: isClient,
@@ -147,45 +146,39 @@ func createConn(ctx context.Context, nextConn net.Conn, config *Config, isClient
 	c.setRemoteEpoch(0)
 	c.setLocalEpoch(0)
 
-	serverName := config.ServerName
-	// Do not allow the use of an IP address literal as an SNI value.
-	// See RFC 6066, Section 3.
-	if net.ParseIP(serverName) != nil {
-		serverName = ""
+	hsCfg := &handshakeConfig{
+		getConfigForClient:    config.GetConfigForClient,
+		connectionIDGenerator: config.ConnectionIDGenerator,
+		sessionTicketKeys:     config.SessionTicketKeys,
+		log:                   logger,
+		initialEpoch:          0,
+	}
+	if err := hsCfg.setConfig(config); err != nil {
+		return nil, err
 	}
 
-	hsCfg := &handshakeConfig{
-		localPSKCallback:            config.PSK,
-		localPSKIdentityHint:        config.PSKIdentityHint,
-		localCipherSuites:           cipherSuites,
-		localSignatureSchemes:       signatureSchemes,
-		extendedMasterSecret:        config.ExtendedMasterSecret,
-		localSRTPProtectionProfiles: config.SRTPProtectionProfiles,
-		serverName:                  serverName,
-		supportedProtocols:          config.SupportedProtocols,
-		clientAuth:                  config.ClientAuth,
-		localCertificates:           config.Certificates,
-		insecureSkipVerify:          config.InsecureSkipVerify,
-		verifyPeerCertificate:       config.VerifyPeerCertificate,
-		rootCAs:                     config.RootCAs,
-		clientCAs:                   config.ClientCAs,
-		customCipherSuites:          config.CustomCipherSuites,
-		retransmitInterval:          workerInterval,
-		log:                         logger,
-		initialEpoch:                0,
-		keyLogWriter:                config.KeyLogWriter,
-		sessionStore:                config.SessionStore,
-	}
-
-	// rfc5246#section-7.4.3
-	// In addition, the hash and signature algorithms MUST be compatible
-	// with the key in the server's end-entity certificate.
 	if !isClient {
-		cert, err := hsCfg.getCertificate("")
-		if err != nil && !errors.Is(err, errNoCertificates) {
-			return nil, err
+		if acceptedHello != nil {
+			// The listener may already have answered ClientHellos statelessly,
+			// continue the sequence numbers of the ClientHello the Conn was
+			// created for. RFC 6347 Section 4.2.1
+			hsCfg.clientHelloSequence = int(acceptedHello.messageSequence)
+			c.state.handshakeRecvSequence = int(acceptedHello.messageSequence)
+			c.state.handshakeSendSequence = int(acceptedHello.messageSequence)
+			c.fragmentBuffer.currentMessageSequenceNumber = acceptedHello.messageSequence
+			c.state.localSequenceNumber = []uint64{acceptedHello.recordSequence}
+		}
+
+		if acceptedHello != nil && !acceptedHello.admitted && config.includesAdmission() {
+			hsCfg.admitHandshake = config.admitHandshake
+		}
+
+		switch {
+		case acceptedHello != nil && acceptedHello.cookieVerified:
+			hsCfg.skipHelloVerify = true
+		case config.SkipHelloVerify != nil:
+			hsCfg.skipHelloVerify = config.SkipHelloVerify(nextConn.RemoteAddr())
 		}
-		hsCfg.localCipherSuites = filterCipherSuitesForCertificate(cert, cipherSuites)
 	}
 
 	var initialFlight flightVal
@@ -266,7 +259,7 @@ func ClientWithContext(ctx context.Context, conn net.Conn, config *Config) (*Con
 		return nil, errPSKAndIdentityMustBeSetForClient
 	}
 
-	return createConn(ctx, conn, config, true, nil)
+	return createConn(ctx, conn, config, true, nil, nil)
 }
 
 // ServerWithContext listens for incoming DTLS connections.
@@ -275,7 +268,7 @@ func ServerWithContext(ctx context.Context, conn net.Conn, config *Config) (*Con
 		return nil, errNoConfigProvided
 	}
 
-	return createConn(ctx, conn, config, false, nil)
+	return createConn(ctx, conn, config, false, nil, nil)
 }
 
 // Read reads data from the connection.
@@ -328,6 +321,11 @@ func (c *Conn) Write(p []byte) (int, error) {
 		return 0, errHandshakeInProgress
 	}
 
+	// Datagrams are not split across records
+	if limit := c.remoteRecordContentLimit(len(c.state.remoteConnectionID) > 0); limit > 0 && len(p) > limit {
+		return 0, errRecordSizeLimitExceeded
+	}
+
 	return len(p), c.writePackets(c.writeDeadline, []*packet{
 		{
 			record: &recordlayer.RecordLayer{
@@ -447,9 +445,21 @@ func (c *Conn) processPacket(p *packet) ([]byte, error) {
 	}
 	p.record.Header.SequenceNumber = seq
 
-	rawPacket, err := p.record.Marshal()
-	if err != nil {
-		return nil, err
+	var rawPacket []byte
+	if p.shouldEncrypt && len(c.state.remoteConnectionID) > 0 {
+		content, err := p.record.Content.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		p.record.Header.ContentType = p.record.Content.ContentType()
+		if rawPacket, err = c.wrapConnectionID(&p.record.Header, content); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		if rawPacket, err = p.record.Marshal(); err != nil {
+			return nil, err
+		}
 	}
 
 	if p.shouldEncrypt {
@@ -466,7 +476,7 @@ func (c *Conn) processPacket(p *packet) ([]byte, error) {
 func (c *Conn) processHandshakePacket(p *packet, h *handshake.Handshake) ([][]byte, error) {
 	rawPackets := make([][]byte, 0)
 
-	handshakeFragments, err := c.fragmentHandshake(h)
+	handshakeFragments, err := c.fragmentHandshake(h, p.shouldEncrypt && len(c.state.remoteConnectionID) > 0)
 	if err != nil {
 		return nil, err
 	}
@@ -489,14 +499,20 @@ func (c *Conn) processHandshakePacket(p *packet, h *handshake.Handshake) ([][]by
 			SequenceNumber: seq,
 		}
 
-		rawPacket, err := recordlayerHeader.Marshal()
-		if err != nil {
-			return nil, err
+		var rawPacket []byte
+		if p.shouldEncrypt && len(c.state.remoteConnectionID) > 0 {
+			if rawPacket, err = c.wrapConnectionID(recordlayerHeader, handshakeFragment); err != nil {
+				return nil, err
+			}
+		} else {
+			if rawPacket, err = recordlayerHeader.Marshal(); err != nil {
+				return nil, err
+			}
+			rawPacket = append(rawPacket, handshakeFragment...)
 		}
 
 		p.record.Header = *recordlayerHeader
 
-		rawPacket = append(rawPacket, handshakeFragment...)
 		if p.shouldEncrypt {
 			var err error
 			rawPacket, err = c.state.cipherSuite.Encrypt(p.record, rawPacket)
@@ -511,7 +527,107 @@ func (c *Conn) processHandshakePacket(p *packet, h *handshake.Handshake) ([][]by
 	return rawPackets, nil
 }
 
-func (c *Conn) fragmentHandshake(h *handshake.Handshake) ([][]byte, error) {
+// wrapConnectionID turns a record into a tls12_cid record carrying the remote
+// connection ID, with the content moved into a DTLSInnerPlaintext.
+// https://datatracker.ietf.org/doc/html/rfc9146#section-4
+func (c *Conn) wrapConnectionID(h *recordlayer.Header, content []byte) ([]byte, error) {
+	inner := &recordlayer.InnerPlaintext{
+		Content:  content,
+		RealType: h.ContentType,
+	}
+	if c.paddingLengthGenerator != nil {
+		inner.Zeros = c.paddingLengthGenerator(uint(len(content)))
+	}
+	// The padding counts towards the record size limit of the peer
+	if limit := c.remoteRecordContentLimit(true); limit > 0 && int(inner.Zeros) > limit-len(content) {
+		inner.Zeros = 0
+		if limit > len(content) {
+			inner.Zeros = uint(limit - len(content))
+		}
+	}
+	rawInner, err := inner.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	h.ContentType = protocol.ContentTypeConnectionID
+	h.ConnectionID = c.state.remoteConnectionID
+	h.ContentLen = uint16(len(rawInner))
+
+	rawHeader, err := h.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return append(rawHeader, rawInner...), nil
+}
+
+// unwrapConnectionID turns a decrypted tls12_cid record back into a record of
+// its real content type.
+func unwrapConnectionID(h *recordlayer.Header, buf []byte) ([]byte, error) {
+	inner := &recordlayer.InnerPlaintext{}
+	if err := inner.Unmarshal(buf[h.Size():]); err != nil {
+		return nil, err
+	}
+
+	h.ContentType = inner.RealType
+	h.ContentLen = uint16(len(inner.Content))
+	h.ConnectionID = nil
+
+	rawHeader, err := h.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return append(rawHeader, inner.Content...), nil
+}
+
+// updateRemoteAddr follows the remote peer to the address an authenticated
+// tls12_cid record was received from. Only records newer than any seen
+// before may move the peer.
+// https://datatracker.ietf.org/doc/html/rfc9146#section-6
+func (c *Conn) updateRemoteAddr(h *recordlayer.Header, rAddr net.Addr) {
+	u, ok := c.nextConn.Conn().(remoteAddrUpdater)
+	if !ok || rAddr == nil {
+		return
+	}
+
+	seq := uint64(h.Epoch)<<48 | h.SequenceNumber
+	if seq <= c.connectionIDSequence {
+		return
+	}
+	c.connectionIDSequence = seq
+	u.SetRemoteAddr(rAddr)
+}
+
+// remoteRecordContentLimit returns the largest record content the peer
+// accepts, or 0 if it is not limited. The record size limit applies to the
+// plaintext of a record, which for a tls12_cid record is the
+// DTLSInnerPlaintext including the real content type and the padding.
+// https://datatracker.ietf.org/doc/html/rfc8449#section-4
+func (c *Conn) remoteRecordContentLimit(connectionID bool) int {
+	limit := c.state.remoteRecordSizeLimit
+	if limit > 0 && connectionID {
+		limit--
+	}
+	return limit
+}
+
+// exceedsLocalRecordSizeLimit reports whether the plaintext of a record is
+// larger than the record size limit we announced
+// https://datatracker.ietf.org/doc/html/rfc8449#section-4
+func (c *Conn) exceedsLocalRecordSizeLimit(h *recordlayer.Header, buf []byte) bool {
+	limit := c.state.getLocalRecordSizeLimit()
+	return limit > 0 && len(buf)-h.Size() > limit
+}
+
+// isClientHelloRecord reports whether a plaintext record carries a
+// ClientHello
+func isClientHelloRecord(h *recordlayer.Header, buf []byte) bool {
+	return h.ContentType == protocol.ContentTypeHandshake &&
+		len(buf) > recordlayer.HeaderSize &&
+		handshake.Type(buf[recordlayer.HeaderSize]) == handshake.TypeClientHello
+}
+
+func (c *Conn) fragmentHandshake(h *handshake.Handshake, connectionID bool) ([][]byte, error) {
 	content, err := h.Message.Marshal()
 	if err != nil {
 		return nil, err
@@ -519,7 +635,12 @@ func (c *Conn) fragmentHandshake(h *handshake.Handshake) ([][]byte, error) {
 
 	fragmentedHandshakes := make([][]byte, 0)
 
-	contentFragments := splitBytes(content, c.maximumTransmissionUnit)
+	fragmentLength := c.maximumTransmissionUnit
+	if limit := c.remoteRecordContentLimit(connectionID); limit > 0 && limit-handshake.HeaderLength < fragmentLength {
+		fragmentLength = limit - handshake.HeaderLength
+	}
+
+	contentFragments := splitBytes(content, fragmentLength)
 	if len(contentFragments) == 0 {
 		contentFragments = [][]byte{
 			{},
@@ -572,14 +693,19 @@ func (c *Conn) readAndBuffer(ctx context.Context) error {
 		return netError(err)
 	}
 
-	pkts, err := recordlayer.UnpackDatagram(b[:i])
+	var rAddr net.Addr
+	if u, ok := c.nextConn.Conn().(remoteAddrUpdater); ok {
+		rAddr = u.SourceAddr()
+	}
+
+	pkts, err := recordlayer.ContentAwareUnpackDatagram(b[:i], len(c.state.getLocalConnectionID()))
 	if err != nil {
 		return err
 	}
 
 	var hasHandshake bool
 	for _, p := range pkts {
-		hs, alert, err := c.handleIncomingPacket(ctx, p, true)
+		hs, alert, err := c.handleIncomingPacket(ctx, p, rAddr, true)
 		if alert != nil {
 			if alertErr := c.notify(ctx, alert.Level, alert.Description); alertErr != nil {
 				if err == nil {
@@ -597,7 +723,7 @@ func (c *Conn) readAndBuffer(ctx context.Context) error {
 				return e
 			}
 		} else if err != nil {
-			return e
+			return err
 		}
 	}
 	if hasHandshake {
@@ -618,7 +744,7 @@ func (c *Conn) handleQueuedPackets(ctx context.Context) error {
 	c.encryptedPackets = nil
 
 	for _, p := range pkts {
-		_, alert, err := c.handleIncomingPacket(ctx, p, false) // don't re-enqueue
+		_, alert, err := c.handleIncomingPacket(ctx, p, nil, false) // don't re-enqueue
 		if alert != nil {
 			if alertErr := c.notify(ctx, alert.Level, alert.Description); alertErr != nil {
 				if err == nil {
@@ -632,14 +758,20 @@ func (c *Conn) handleQueuedPackets(ctx context.Context) error {
 				return e
 			}
 		} else if err != nil {
-			return e
+			return err
 		}
 	}
 	return nil
 }
 
-func (c *Conn) handleIncomingPacket(ctx context.Context, buf []byte, enqueue bool) (bool, *alert.Alert, error) { //nolint:gocognit
+func (c *Conn) handleIncomingPacket(ctx context.Context, buf []byte, rAddr net.Addr, enqueue bool) (bool, *alert.Alert, error) { //nolint:gocognit
 	h := &recordlayer.Header{}
+	// Set the expected connection ID length so that tls12_cid records
+	// can be parsed.
+	localConnectionID := c.state.getLocalConnectionID()
+	if len(localConnectionID) > 0 {
+		h.ConnectionID = make([]byte, len(localConnectionID))
+	}
 	if err := h.Unmarshal(buf); err != nil {
 		// Decode error must be silently discarded
 		// [RFC6347 Section-4.1.2.7]
@@ -663,6 +795,19 @@ func (c *Conn) handleIncomingPacket(ctx context.Context, buf []byte, enqueue boo
 		return false, nil, nil
 	}
 
+	// Once negotiated, every protected record must carry our connection ID
+	// https://datatracker.ietf.org/doc/html/rfc9146#section-3
+	if h.ContentType == protocol.ContentTypeConnectionID || (h.Epoch != 0 && len(localConnectionID) > 0) {
+		if h.ContentType != protocol.ContentTypeConnectionID {
+			c.log.Debug("discarded packet missing connection ID after value negotiated")
+			return false, nil, nil
+		}
+		if h.Epoch == 0 || !bytes.Equal(localConnectionID, h.ConnectionID) {
+			c.log.Debug("discarded packet with unexpected connection ID")
+			return false, nil, nil
+		}
+	}
+
 	// Anti-replay protection
 	for len(c.state.replayDetector) <= int(h.Epoch) {
 		c.state.replayDetector = append(c.state.replayDetector,
@@ -688,11 +833,34 @@ func (c *Conn) handleIncomingPacket(ctx context.Context, buf []byte, enqueue boo
 		}
 
 		var err error
-		buf, err = c.state.cipherSuite.Decrypt(buf)
+		if cipherSuite, ok := c.state.cipherSuite.(connectionIDCipherSuite); ok {
+			buf, err = cipherSuite.DecryptWithHeader(*h, buf)
+		} else {
+			buf, err = c.state.cipherSuite.Decrypt(buf)
+		}
 		if err != nil {
 			c.log.Debugf("%s: decrypt failed: %s", srvCliStr(c.state.isClient), err)
+			if h.ContentType == protocol.ContentTypeHandshake || h.ContentType == protocol.ContentTypeConnectionID {
+				atomic.StoreInt32(&c.state.handshakeDecryptFailed, 1)
+			}
 			return false, nil, nil
 		}
+
+		if c.exceedsLocalRecordSizeLimit(h, buf) {
+			return false, &alert.Alert{Level: alert.Fatal, Description: alert.RecordOverflow}, errRecordOverflow
+		}
+
+		if h.ContentType == protocol.ContentTypeConnectionID {
+			if buf, err = unwrapConnectionID(h, buf); err != nil {
+				c.log.Debugf("%s: unwrapping connection ID failed: %s", srvCliStr(c.state.isClient), err)
+				return false, nil, nil
+			}
+			c.updateRemoteAddr(h, rAddr)
+		}
+	} else if c.exceedsLocalRecordSizeLimit(h, buf) && !isClientHelloRecord(h, buf) {
+		// A client sends its ClientHello before it learns the limit, and
+		// may retransmit it afterwards
+		return false, &alert.Alert{Level: alert.Fatal, Description: alert.RecordOverflow}, errRecordOverflow
 	}
 
 	isHandshake, err := c.fragmentBuffer.push(append([]byte{}, buf...))
@@ -5,6 +5,7 @@ import (
 	"context"
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	cryptoElliptic "crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
@@ -15,6 +16,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"reflect"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -36,11 +38,14 @@ import (
 )
 
 var (
-	errTestPSKInvalidIdentity = errors.New("TestPSK: Server got invalid identity")
-	errPSKRejected            = errors.New("PSK Rejected")
-	errNotExpectedChain       = errors.New("not expected chain")
-	errExpecedChain           = errors.New("expected chain")
-	errWrongCert              = errors.New("wrong cert")
+	errTestPSKInvalidIdentity       = errors.New("TestPSK: Server got invalid identity")
+	errPSKRejected                  = errors.New("PSK Rejected")
+	errNotExpectedChain             = errors.New("not expected chain")
+	errExpecedChain                 = errors.New("expected chain")
+	errWrongCert                    = errors.New("wrong cert")
+	errUnexpectedServerNonce        = errors.New("unexpected server nonce")
+	errUnexpectedClientHello        = errors.New("unexpected ClientHello")
+	errUnexpectedCertificateRequest = errors.New("unexpected CertificateRequest")
 )
 
 func TestStressDuplex(t *testing.T) {
@@ -111,6 +116,46 @@ func TestRoutineLeakOnClose(t *testing.T) {
 	// inboundLoop routine should not be leaked.
 }
 
+func TestApplicationDataEpochZero(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(5 * time.Second)
+	defer lim.Stop()
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	ca, cb := dpipe.Pipe()
+	client, server, err := pipeConn(ca, cb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = client.Close()
+		_ = server.Close()
+	}()
+
+	packet, err := (&recordlayer.RecordLayer{
+		Header: recordlayer.Header{
+			Version:        protocol.Version1_2,
+			Epoch:          0,
+			SequenceNumber: 100, // above the replay window of the handshake
+		},
+		Content: &protocol.ApplicationData{Data: []byte("plaintext")},
+	}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ca.Write(packet); err != nil {
+		t.Fatal(err)
+	}
+
+	// The error of the record, which is not an alert, is passed to Read
+	if _, err = server.Read(make([]byte, 1024)); !errors.Is(err, errApplicationDataEpochZero) {
+		t.Errorf("Expected error: %v, got: %v", errApplicationDataEpochZero, err)
+	}
+}
+
 func TestReadWriteDeadline(t *testing.T) {
 	// Limit runtime in case of deadlocks
 	lim := test.TimeOut(5 * time.Second)
@@ -366,8 +411,8 @@ func TestHandshakeWithAlert(t *testing.T) {
 				CipherSuites:     []CipherSuiteID{TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
 				SignatureSchemes: []tls.SignatureScheme{tls.ECDSAWithP521AndSHA512},
 			},
-			errServer: &alertError{&alert.Alert{Level: alert.Fatal, Description: alert.InsufficientSecurity}},
-			errClient: errNoAvailableSignatureSchemes,
+			errServer: errNoAvailableSignatureSchemes,
+			errClient: &alertError{&alert.Alert{Level: alert.Fatal, Description: alert.InsufficientSecurity}},
 		},
 	}
 
@@ -490,6 +535,21 @@ func TestPSK(t *testing.T) {
 			ServerIdentity: nil,
 			CipherSuites:   []CipherSuiteID{TLS_ECDHE_PSK_WITH_AES_128_CBC_SHA256},
 		},
+		{
+			Name:           "TLS_ECDHE_PSK_WITH_AES_128_GCM_SHA256",
+			ServerIdentity: nil,
+			CipherSuites:   []CipherSuiteID{TLS_ECDHE_PSK_WITH_AES_128_GCM_SHA256},
+		},
+		{
+			Name:           "TLS_ECDHE_PSK_WITH_AES_128_CCM_SHA256",
+			ServerIdentity: nil,
+			CipherSuites:   []CipherSuiteID{TLS_ECDHE_PSK_WITH_AES_128_CCM_SHA256},
+		},
+		{
+			Name:           "TLS_ECDHE_PSK_WITH_AES_256_CBC_SHA384",
+			ServerIdentity: nil,
+			CipherSuites:   []CipherSuiteID{TLS_ECDHE_PSK_WITH_AES_256_CBC_SHA384},
+		},
 	} {
 		test := test
 		t.Run(test.Name, func(t *testing.T) {
@@ -1236,6 +1296,14 @@ func TestCipherSuiteConfiguration(t *testing.T) {
 			WantServerError:         nil,
 			WantSelectedCipherSuite: TLS_ECDHE_ECDSA_WITH_AES_128_CCM_8,
 		},
+		{
+			Name:                    "Valid CipherSuites ChaCha20-Poly1305 specified",
+			ClientCipherSuites:      []CipherSuiteID{TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256},
+			ServerCipherSuites:      []CipherSuiteID{TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256},
+			WantClientError:         nil,
+			WantServerError:         nil,
+			WantSelectedCipherSuite: TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+		},
 		{
 			Name:                    "Server supports subset of client suites",
 			ClientCipherSuites:      []CipherSuiteID{TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA},
@@ -2530,6 +2598,113 @@ func TestSessionResume(t *testing.T) {
 	})
 }
 
+func TestSessionTicket(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	var key1, key2, key3 [SessionTicketKeyLength]byte
+	for i, key := range []*[SessionTicketKeyLength]byte{&key1, &key2, &key3} {
+		if _, err := rand.Read(key[:]); err != nil {
+			t.Fatalf("TestSessionTicket: key %d: %v", i, err)
+		}
+	}
+
+	clientStore := &memSessStore{}
+	sessionKey := []byte(":1_example.com")
+
+	handshakeWith := func(t *testing.T, keys *SessionTicketKeys) *Conn {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		type result struct {
+			c   *Conn
+			err error
+		}
+		clientRes := make(chan result, 1)
+
+		ca, cb := dpipe.Pipe()
+		go func() {
+			config := &Config{
+				CipherSuites: []CipherSuiteID{TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+				ServerName:   "example.com",
+				SessionStore: clientStore,
+			}
+			c, err := testClient(ctx, ca, config, false)
+			clientRes <- result{c, err}
+		}()
+
+		config := &Config{
+			CipherSuites:      []CipherSuiteID{TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+			SessionTicketKeys: keys,
+		}
+		server, err := testServer(ctx, cb, config, true)
+		if err != nil {
+			t.Fatalf("TestSessionTicket: Server failed(%v)", err)
+		}
+
+		res := <-clientRes
+		if res.err != nil {
+			t.Fatal(res.err)
+		}
+		_ = res.c.Close()
+		return server
+	}
+
+	keys, err := NewSessionTicketKeys(key1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A full handshake issues a ticket to the client
+	server := handshakeWith(t, keys)
+	issued, _ := clientStore.Get(sessionKey)
+	if len(issued.Ticket) == 0 || len(issued.ID) == 0 {
+		t.Fatalf("TestSessionTicket: no ticket saved by the client: %#v", issued)
+	}
+	if !bytes.Equal(issued.Secret, server.ConnectionState().masterSecret) {
+		t.Errorf("TestSessionTicket: masterSecret Mismatch: expected(%v) actual(%v)", server.ConnectionState().masterSecret, issued.Secret)
+	}
+	_ = server.Close()
+
+	// Another server knowing the key, now rotated out, resumes the session
+	// statelessly and issues a ticket with its current key
+	rotated, err := NewSessionTicketKeys(key1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = rotated.Rotate(key2); err != nil {
+		t.Fatal(err)
+	}
+	server = handshakeWith(t, rotated)
+	if actual := server.ConnectionState().SessionID; !bytes.Equal(actual, issued.ID) {
+		t.Errorf("TestSessionTicket: SessionID Mismatch: expected(%v) actual(%v)", issued.ID, actual)
+	}
+	if actual := server.ConnectionState().masterSecret; !bytes.Equal(actual, issued.Secret) {
+		t.Errorf("TestSessionTicket: masterSecret Mismatch: expected(%v) actual(%v)", issued.Secret, actual)
+	}
+	reissued, _ := clientStore.Get(sessionKey)
+	if bytes.Equal(reissued.Ticket, issued.Ticket) {
+		t.Error("TestSessionTicket: ticket was not replaced on resumption")
+	}
+	_ = server.Close()
+
+	// A server without the key falls back to a full handshake
+	unknown, err := NewSessionTicketKeys(key3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server = handshakeWith(t, unknown)
+	if actual := server.ConnectionState().masterSecret; bytes.Equal(actual, issued.Secret) {
+		t.Error("TestSessionTicket: session resumed with an unknown ticket key")
+	}
+	_ = server.Close()
+}
+
 type memSessStore struct {
 	sync.Map
 }
@@ -2592,6 +2767,12 @@ func TestCipherSuiteMatchesCertificateType(t *testing.T) {
 			expectedCipher: TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
 			generateRSA:    true,
 		},
+		{
+			Name:           "RSA Certificate with CBC-SHA384 CipherSuite",
+			cipherList:     []CipherSuiteID{TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA384, TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA384},
+			expectedCipher: TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA384,
+			generateRSA:    true,
+		},
 	} {
 		test := test
 		t.Run(test.Name, func(t *testing.T) {
@@ -2645,6 +2826,92 @@ func TestCipherSuiteMatchesCertificateType(t *testing.T) {
 	}
 }
 
+func TestRSAPSSSignatureSchemes(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaCert, err := selfsign.SelfSign(rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, test := range map[string]struct {
+		clientSchemes []tls.SignatureScheme
+		serverSchemes []tls.SignatureScheme
+		wantErr       bool
+	}{
+		"PeerAdvertisesOnlyPSS": {
+			clientSchemes: []tls.SignatureScheme{tls.PSSWithSHA256},
+		},
+		"PeerAdvertisesOnlyPKCS1": {
+			clientSchemes: []tls.SignatureScheme{tls.PKCS1WithSHA384},
+		},
+		"PSSOnBothSides": {
+			clientSchemes: []tls.SignatureScheme{tls.PSSWithSHA512},
+			serverSchemes: []tls.SignatureScheme{tls.PSSWithSHA384, tls.PSSWithSHA512},
+		},
+		"NoCommonRSAScheme": {
+			clientSchemes: []tls.SignatureScheme{tls.PSSWithSHA256},
+			serverSchemes: []tls.SignatureScheme{tls.PKCS1WithSHA256},
+			wantErr:       true,
+		},
+	} {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			ca, cb := dpipe.Pipe()
+			type result struct {
+				c   *Conn
+				err error
+			}
+			c := make(chan result)
+
+			go func() {
+				client, err := testClient(context.TODO(), ca, &Config{
+					CipherSuites:     []CipherSuiteID{TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+					SignatureSchemes: test.clientSchemes,
+					Certificates:     []tls.Certificate{rsaCert},
+				}, false)
+				c <- result{client, err}
+			}()
+
+			server, err := testServer(context.TODO(), cb, &Config{
+				CipherSuites:     []CipherSuiteID{TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+				SignatureSchemes: test.serverSchemes,
+				Certificates:     []tls.Certificate{rsaCert},
+				ClientAuth:       RequireAnyClientCert,
+			}, false)
+			res := <-c
+			if test.wantErr {
+				if err == nil && res.err == nil {
+					t.Error("Expected handshake to fail")
+				}
+			} else {
+				if err != nil {
+					t.Errorf("Server failed(%v)", err)
+				}
+				if res.err != nil {
+					t.Errorf("Client failed(%v)", res.err)
+				}
+			}
+			if err == nil {
+				_ = server.Close()
+			}
+			if res.err == nil {
+				_ = res.c.Close()
+			}
+		})
+	}
+}
+
 // Test that we return the proper certificate if we are serving multiple ServerNames on a single Server
 func TestMultipleServerCertificates(t *testing.T) {
 	fooCert, err := selfsign.GenerateSelfSignedWithDNS("foo")
@@ -2724,3 +2991,1633 @@ func TestMultipleServerCertificates(t *testing.T) {
 		})
 	}
 }
+
+// testLegacyCipherSuite only implements CipherSuite, like the
+// CustomCipherSuites written before connection IDs were supported
+type testLegacyCipherSuite struct {
+	CipherSuite
+}
+
+func TestConnectionID(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	clientCID := []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
+	serverCID := []byte{0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f}
+	psk := func([]byte) ([]byte, error) {
+		return []byte{0xAB, 0xC1, 0x23}, nil
+	}
+	customCipherSuites := func() []CipherSuite {
+		return []CipherSuite{&testCustomCipherSuite{authenticationType: CipherSuiteAuthenticationTypeCertificate}}
+	}
+	legacyCipherSuites := func() []CipherSuite {
+		return []CipherSuite{&testLegacyCipherSuite{customCipherSuites()[0]}}
+	}
+
+	for name, test := range map[string]struct {
+		clientCfg          *Config
+		serverCfg          *Config
+		clientConnectionID []byte
+		serverConnectionID []byte
+	}{
+		"BidirectionalConnectionIDs": {
+			clientCfg: &Config{
+				ConnectionIDGenerator: func() []byte { return clientCID },
+			},
+			serverCfg: &Config{
+				ConnectionIDGenerator: func() []byte { return serverCID },
+			},
+			clientConnectionID: clientCID,
+			serverConnectionID: serverCID,
+		},
+		"BidirectionalConnectionIDsWithPadding": {
+			clientCfg: &Config{
+				ConnectionIDGenerator:  func() []byte { return clientCID },
+				PaddingLengthGenerator: func(uint) uint { return 16 },
+			},
+			serverCfg: &Config{
+				ConnectionIDGenerator:  func() []byte { return serverCID },
+				PaddingLengthGenerator: func(uint) uint { return 3 },
+			},
+			clientConnectionID: clientCID,
+			serverConnectionID: serverCID,
+		},
+		"BidirectionalConnectionIDsCBC": {
+			clientCfg: &Config{
+				ConnectionIDGenerator: func() []byte { return clientCID },
+				PSK:                   psk,
+				PSKIdentityHint:       []byte("Client Identity"),
+				CipherSuites:          []CipherSuiteID{TLS_PSK_WITH_AES_128_CBC_SHA256},
+			},
+			serverCfg: &Config{
+				ConnectionIDGenerator: func() []byte { return serverCID },
+				PSK:                   psk,
+				CipherSuites:          []CipherSuiteID{TLS_PSK_WITH_AES_128_CBC_SHA256},
+			},
+			clientConnectionID: clientCID,
+			serverConnectionID: serverCID,
+		},
+		"OnlyServerSendsConnectionIDs": {
+			clientCfg: &Config{
+				ConnectionIDGenerator: func() []byte { return clientCID },
+			},
+			serverCfg: &Config{
+				ConnectionIDGenerator: OnlySendCIDGenerator(),
+			},
+			clientConnectionID: clientCID,
+		},
+		"OnlyClientSendsConnectionIDs": {
+			clientCfg: &Config{
+				ConnectionIDGenerator: OnlySendCIDGenerator(),
+			},
+			serverCfg: &Config{
+				ConnectionIDGenerator: func() []byte { return serverCID },
+			},
+			serverConnectionID: serverCID,
+		},
+		"ServerDoesNotSupportConnectionIDs": {
+			clientCfg: &Config{
+				ConnectionIDGenerator: func() []byte { return clientCID },
+			},
+			serverCfg: &Config{},
+		},
+		"ClientDoesNotSupportConnectionIDs": {
+			clientCfg: &Config{},
+			serverCfg: &Config{
+				ConnectionIDGenerator: func() []byte { return serverCID },
+			},
+		},
+		"ClientCipherSuiteDoesNotSupportConnectionIDs": {
+			clientCfg: &Config{
+				ConnectionIDGenerator: func() []byte { return clientCID },
+				CipherSuites:          []CipherSuiteID{},
+				CustomCipherSuites:    legacyCipherSuites,
+			},
+			serverCfg: &Config{
+				ConnectionIDGenerator: func() []byte { return serverCID },
+				CipherSuites:          []CipherSuiteID{},
+				CustomCipherSuites:    customCipherSuites,
+			},
+		},
+		"ServerCipherSuiteDoesNotSupportConnectionIDs": {
+			clientCfg: &Config{
+				ConnectionIDGenerator: func() []byte { return clientCID },
+				CipherSuites:          []CipherSuiteID{},
+				CustomCipherSuites:    customCipherSuites,
+			},
+			serverCfg: &Config{
+				ConnectionIDGenerator: func() []byte { return serverCID },
+				CipherSuites:          []CipherSuiteID{},
+				CustomCipherSuites:    legacyCipherSuites,
+			},
+		},
+	} {
+		name, test := name, test
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			ca, cb := dpipe.Pipe()
+			type result struct {
+				c   *Conn
+				err error
+			}
+			clientRes := make(chan result, 1)
+
+			go func() {
+				client, err := testClient(ctx, ca, test.clientCfg, test.clientCfg.PSK == nil)
+				clientRes <- result{client, err}
+			}()
+
+			server, err := testServer(ctx, cb, test.serverCfg, test.serverCfg.PSK == nil)
+			if err != nil {
+				t.Fatalf("Server failed: %v", err)
+			}
+			defer func() {
+				_ = server.Close()
+			}()
+
+			res := <-clientRes
+			if res.err != nil {
+				t.Fatalf("Client failed: %v", res.err)
+			}
+			defer func() {
+				_ = res.c.Close()
+			}()
+
+			if got := res.c.state.getLocalConnectionID(); !bytes.Equal(got, test.clientConnectionID) {
+				t.Errorf("Unexpected client local connection ID: got %x, want %x", got, test.clientConnectionID)
+			}
+			if got := server.state.remoteConnectionID; !bytes.Equal(got, test.clientConnectionID) {
+				t.Errorf("Unexpected server remote connection ID: got %x, want %x", got, test.clientConnectionID)
+			}
+			if got := server.state.getLocalConnectionID(); !bytes.Equal(got, test.serverConnectionID) {
+				t.Errorf("Unexpected server local connection ID: got %x, want %x", got, test.serverConnectionID)
+			}
+			if got := res.c.state.remoteConnectionID; !bytes.Equal(got, test.serverConnectionID) {
+				t.Errorf("Unexpected client remote connection ID: got %x, want %x", got, test.serverConnectionID)
+			}
+
+			for _, pair := range [][2]*Conn{{res.c, server}, {server, res.c}} {
+				msg := []byte("connection id")
+				if _, err := pair[0].Write(msg); err != nil {
+					t.Fatal(err)
+				}
+				buf := make([]byte, 64)
+				n, err := pair[1].Read(buf)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(buf[:n], msg) {
+					t.Errorf("Unexpected message: got %q, want %q", buf[:n], msg)
+				}
+			}
+		})
+	}
+}
+
+func TestRawPublicKey(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	srvCert, err := selfsign.GenerateSelfSigned()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srvCertificate, err := x509.ParseCertificate(srvCert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	srvSPKI := srvCertificate.RawSubjectPublicKeyInfo
+
+	// The client only has a key pair, without any certificate
+	clientPub, clientPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientSPKI, err := x509.MarshalPKIXPublicKey(clientPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCert := tls.Certificate{Certificate: [][]byte{clientSPKI}, PrivateKey: clientPriv}
+
+	trust := func(trusted []byte) func([]byte) error {
+		return func(spki []byte) error {
+			if !bytes.Equal(spki, trusted) {
+				return errWrongCert
+			}
+			return nil
+		}
+	}
+	rawPublicKeyOnly := []CertificateType{CertificateTypeRawPublicKey}
+
+	for name, tt := range map[string]struct {
+		clientCfg *Config
+		serverCfg *Config
+		wantErr   bool
+	}{
+		"Server": {
+			clientCfg: &Config{
+				PeerCertificateTypes:   rawPublicKeyOnly,
+				VerifyPeerRawPublicKey: trust(srvSPKI),
+			},
+			serverCfg: &Config{
+				Certificates:     []tls.Certificate{srvCert},
+				CertificateTypes: []CertificateType{CertificateTypeRawPublicKey, CertificateTypeX509},
+			},
+		},
+		"Mutual": {
+			clientCfg: &Config{
+				Certificates:           []tls.Certificate{clientCert},
+				CertificateTypes:       rawPublicKeyOnly,
+				PeerCertificateTypes:   rawPublicKeyOnly,
+				VerifyPeerRawPublicKey: trust(srvSPKI),
+			},
+			serverCfg: &Config{
+				Certificates:           []tls.Certificate{srvCert},
+				CertificateTypes:       rawPublicKeyOnly,
+				PeerCertificateTypes:   rawPublicKeyOnly,
+				VerifyPeerRawPublicKey: trust(clientSPKI),
+				ClientAuth:             RequireAndVerifyClientCert,
+			},
+		},
+		"UntrustedKey": {
+			clientCfg: &Config{
+				PeerCertificateTypes:   rawPublicKeyOnly,
+				VerifyPeerRawPublicKey: trust(clientSPKI),
+			},
+			serverCfg: &Config{
+				Certificates:     []tls.Certificate{srvCert},
+				CertificateTypes: rawPublicKeyOnly,
+			},
+			wantErr: true,
+		},
+		"NoCommonType": {
+			clientCfg: &Config{InsecureSkipVerify: true},
+			serverCfg: &Config{
+				Certificates:     []tls.Certificate{srvCert},
+				CertificateTypes: rawPublicKeyOnly,
+			},
+			wantErr: true,
+		},
+	} {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			ca, cb := dpipe.Pipe()
+			type result struct {
+				c   *Conn
+				err error
+			}
+			c := make(chan result)
+
+			go func() {
+				client, err := Client(ca, tt.clientCfg)
+				c <- result{client, err}
+			}()
+
+			server, err := Server(cb, tt.serverCfg)
+			res := <-c
+			defer func() {
+				if err == nil {
+					_ = server.Close()
+				}
+				if res.err == nil {
+					_ = res.c.Close()
+				}
+			}()
+
+			if tt.wantErr {
+				if err == nil || res.err == nil {
+					t.Errorf("Error expected, server(%v) client(%v)", err, res.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Server failed(%v)", err)
+			}
+			if res.err != nil {
+				t.Fatalf("Client failed(%v)", res.err)
+			}
+
+			if actual := res.c.ConnectionState().PeerCertificates; len(actual) != 1 || !bytes.Equal(actual[0], srvSPKI) {
+				t.Errorf("Server raw public key was not communicated correctly")
+			}
+			if tt.serverCfg.ClientAuth != NoClientCert {
+				if actual := server.ConnectionState().PeerCertificates; len(actual) != 1 || !bytes.Equal(actual[0], clientSPKI) {
+					t.Errorf("Client raw public key was not communicated correctly")
+				}
+			}
+		})
+	}
+
+	if _, err := Client(nil, &Config{PeerCertificateTypes: rawPublicKeyOnly}); !errors.Is(err, errNoRawPublicKeyVerifier) {
+		t.Errorf("expected error: %v, got: %v", errNoRawPublicKeyVerifier, err)
+	}
+}
+
+func TestRecordSizeLimit(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	for name, tt := range map[string]struct {
+		clientCfg                *Config
+		serverCfg                *Config
+		clientLimit, serverLimit int // Largest record plaintext accepted by client/server
+		connectionID             bool
+		wantErr                  bool
+	}{
+		"None": {
+			clientCfg: &Config{},
+			serverCfg: &Config{},
+		},
+		"RecordSizeLimit": {
+			clientCfg:   &Config{RecordSizeLimit: 64},
+			serverCfg:   &Config{},
+			clientLimit: 64,
+			serverLimit: 16384,
+		},
+		"BothRecordSizeLimits": {
+			clientCfg:   &Config{RecordSizeLimit: 256},
+			serverCfg:   &Config{RecordSizeLimit: 512},
+			clientLimit: 256,
+			serverLimit: 512,
+		},
+		"MaxFragmentLength": {
+			clientCfg:   &Config{MaxFragmentLength: FragmentLength512},
+			serverCfg:   &Config{RecordSizeLimit: 1024},
+			clientLimit: 512,
+			serverLimit: 512,
+		},
+		"RecordSizeLimitPreferred": {
+			clientCfg:   &Config{RecordSizeLimit: 1024, MaxFragmentLength: FragmentLength512},
+			serverCfg:   &Config{},
+			clientLimit: 1024,
+			serverLimit: 16384,
+		},
+		"RecordSizeLimitConnectionID": {
+			clientCfg: &Config{RecordSizeLimit: 64},
+			// Padding must not push records over the limit
+			serverCfg:    &Config{PaddingLengthGenerator: func(uint) uint { return 16 }},
+			clientLimit:  64,
+			serverLimit:  16384,
+			connectionID: true,
+		},
+	} {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			tt.clientCfg.InsecureSkipVerify = true
+			if tt.connectionID {
+				tt.clientCfg.ConnectionIDGenerator = RandomCIDGenerator(8)
+				tt.serverCfg.ConnectionIDGenerator = RandomCIDGenerator(8)
+			}
+			ca, cb := dpipe.Pipe()
+			type result struct {
+				c   *Conn
+				err error
+			}
+			c := make(chan result)
+
+			go func() {
+				client, err := testClient(context.Background(), ca, tt.clientCfg, false)
+				c <- result{client, err}
+			}()
+
+			server, err := testServer(context.Background(), cb, tt.serverCfg, true)
+			res := <-c
+			if err != nil {
+				t.Fatalf("Server failed(%v)", err)
+			}
+			if res.err != nil {
+				t.Fatalf("Client failed(%v)", res.err)
+			}
+			client := res.c
+			defer func() {
+				_ = server.Close()
+				_ = client.Close()
+			}()
+
+			if limit := client.state.remoteRecordSizeLimit; limit != tt.serverLimit {
+				t.Errorf("Client sends records up to %d bytes, expected %d", limit, tt.serverLimit)
+			}
+			if limit := server.state.remoteRecordSizeLimit; limit != tt.clientLimit {
+				t.Errorf("Server sends records up to %d bytes, expected %d", limit, tt.clientLimit)
+			}
+			if tt.clientLimit == 0 {
+				return
+			}
+
+			// The DTLSInnerPlaintext of a tls12_cid record also holds the
+			// real content type
+			contentLimit := tt.clientLimit
+			if tt.connectionID {
+				contentLimit--
+			}
+			if _, err := server.Write(make([]byte, contentLimit+1)); !errors.Is(err, errRecordSizeLimitExceeded) {
+				t.Errorf("expected error: %v, got: %v", errRecordSizeLimitExceeded, err)
+			}
+			if _, err := server.Write(make([]byte, contentLimit)); err != nil {
+				t.Fatal(err)
+			}
+			buf := make([]byte, tt.clientLimit+1)
+			if n, err := client.Read(buf); err != nil || n != contentLimit {
+				t.Fatalf("Read %d bytes (%v), expected %d", n, err, contentLimit)
+			}
+
+			// A record exceeding the limit is rejected by the client
+			server.state.remoteRecordSizeLimit = 0
+			if _, err := server.Write(make([]byte, contentLimit+1)); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := server.Read(buf); err == nil {
+				t.Error("Expected the server to be closed by a record_overflow alert")
+			}
+		})
+	}
+
+	if _, err := Client(nil, &Config{RecordSizeLimit: 63}); !errors.Is(err, errInvalidRecordSizeLimit) {
+		t.Errorf("expected error: %v, got: %v", errInvalidRecordSizeLimit, err)
+	}
+	if _, err := Client(nil, &Config{MaxFragmentLength: 5}); !errors.Is(err, errInvalidMaxFragmentLength) {
+		t.Errorf("expected error: %v, got: %v", errInvalidMaxFragmentLength, err)
+	}
+}
+
+func TestEncryptThenMAC(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	psk := func(hint []byte) ([]byte, error) {
+		return []byte{0xAB, 0xC1, 0x23}, nil
+	}
+	cbc := []CipherSuiteID{TLS_PSK_WITH_AES_128_CBC_SHA256}
+
+	for name, tt := range map[string]struct {
+		clientCfg          *Config
+		serverCfg          *Config
+		wantEncryptThenMAC bool
+		wantErr            bool
+	}{
+		"Requested": {
+			clientCfg:          &Config{CipherSuites: cbc},
+			serverCfg:          &Config{CipherSuites: cbc},
+			wantEncryptThenMAC: true,
+		},
+		"Required": {
+			clientCfg:          &Config{CipherSuites: cbc, EncryptThenMAC: RequireEncryptThenMAC},
+			serverCfg:          &Config{CipherSuites: cbc, EncryptThenMAC: RequireEncryptThenMAC},
+			wantEncryptThenMAC: true,
+		},
+		"ClientDisabled": {
+			clientCfg: &Config{CipherSuites: cbc, EncryptThenMAC: DisableEncryptThenMAC},
+			serverCfg: &Config{CipherSuites: cbc},
+		},
+		"ServerDisabled": {
+			clientCfg: &Config{CipherSuites: cbc},
+			serverCfg: &Config{CipherSuites: cbc, EncryptThenMAC: DisableEncryptThenMAC},
+		},
+		"AEAD": {
+			clientCfg: &Config{CipherSuites: []CipherSuiteID{TLS_PSK_WITH_AES_128_GCM_SHA256}, EncryptThenMAC: RequireEncryptThenMAC},
+			serverCfg: &Config{CipherSuites: []CipherSuiteID{TLS_PSK_WITH_AES_128_GCM_SHA256}, EncryptThenMAC: RequireEncryptThenMAC},
+		},
+		"ClientRequiredServerDisabled": {
+			clientCfg: &Config{CipherSuites: cbc, EncryptThenMAC: RequireEncryptThenMAC},
+			serverCfg: &Config{CipherSuites: cbc, EncryptThenMAC: DisableEncryptThenMAC},
+			wantErr:   true,
+		},
+		"ServerRequiredClientDisabled": {
+			clientCfg: &Config{CipherSuites: cbc, EncryptThenMAC: DisableEncryptThenMAC},
+			serverCfg: &Config{CipherSuites: cbc, EncryptThenMAC: RequireEncryptThenMAC},
+			wantErr:   true,
+		},
+	} {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			tt.clientCfg.PSK, tt.clientCfg.PSKIdentityHint = psk, []byte{}
+			tt.serverCfg.PSK = psk
+
+			ca, cb := dpipe.Pipe()
+			type result struct {
+				c   *Conn
+				err error
+			}
+			c := make(chan result)
+
+			go func() {
+				client, err := testClient(context.Background(), ca, tt.clientCfg, false)
+				c <- result{client, err}
+			}()
+
+			server, err := testServer(context.Background(), cb, tt.serverCfg, false)
+			res := <-c
+			defer func() {
+				if err == nil {
+					_ = server.Close()
+				}
+				if res.err == nil {
+					_ = res.c.Close()
+				}
+			}()
+
+			if tt.wantErr {
+				if err == nil || res.err == nil {
+					t.Errorf("Error expected, server(%v) client(%v)", err, res.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Server failed(%v)", err)
+			}
+			if res.err != nil {
+				t.Fatalf("Client failed(%v)", res.err)
+			}
+
+			if res.c.state.encryptThenMAC != tt.wantEncryptThenMAC || server.state.encryptThenMAC != tt.wantEncryptThenMAC {
+				t.Errorf("Encrypt-then-MAC client(%t) server(%t), expected %t",
+					res.c.state.encryptThenMAC, server.state.encryptThenMAC, tt.wantEncryptThenMAC)
+			}
+
+			data := []byte("encrypt-then-mac")
+			if _, err := res.c.Write(data); err != nil {
+				t.Fatal(err)
+			}
+			buf := make([]byte, len(data))
+			if n, err := server.Read(buf); err != nil || !bytes.Equal(buf[:n], data) {
+				t.Errorf("Read %q (%v), expected %q", buf[:n], err, data)
+			}
+		})
+	}
+}
+
+func TestServerPreference(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	clientCipherSuites := []CipherSuiteID{TLS_ECDHE_ECDSA_WITH_AES_128_CCM_8, TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}
+	serverCipherSuites := []CipherSuiteID{TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, TLS_ECDHE_ECDSA_WITH_AES_128_CCM_8}
+
+	for name, tt := range map[string]struct {
+		clientCfg       *Config
+		serverCfg       *Config
+		wantCipherSuite CipherSuiteID
+		wantCurve       elliptic.Curve
+		wantErr         bool
+	}{
+		"Default": {
+			clientCfg:       &Config{CipherSuites: clientCipherSuites},
+			serverCfg:       &Config{CipherSuites: serverCipherSuites},
+			wantCipherSuite: TLS_ECDHE_ECDSA_WITH_AES_128_CCM_8,
+			wantCurve:       elliptic.X25519,
+		},
+		"ClientPreference": {
+			clientCfg:       &Config{CipherSuites: clientCipherSuites, CurvePreferences: []elliptic.Curve{elliptic.P384, elliptic.P256}},
+			serverCfg:       &Config{CipherSuites: serverCipherSuites, CurvePreferences: []elliptic.Curve{elliptic.P256, elliptic.P384}},
+			wantCipherSuite: TLS_ECDHE_ECDSA_WITH_AES_128_CCM_8,
+			wantCurve:       elliptic.P384,
+		},
+		"ServerPreference": {
+			clientCfg: &Config{CipherSuites: clientCipherSuites, CurvePreferences: []elliptic.Curve{elliptic.P384, elliptic.P256}},
+			serverCfg: &Config{
+				CipherSuites:             serverCipherSuites,
+				CurvePreferences:         []elliptic.Curve{elliptic.P256, elliptic.P384},
+				PreferServerCipherSuites: true,
+			},
+			wantCipherSuite: TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			wantCurve:       elliptic.P256,
+		},
+		"NoCommonCurve": {
+			clientCfg: &Config{CipherSuites: clientCipherSuites, CurvePreferences: []elliptic.Curve{elliptic.P384}},
+			serverCfg: &Config{CipherSuites: serverCipherSuites, CurvePreferences: []elliptic.Curve{elliptic.X25519, elliptic.P256}},
+			wantErr:   true,
+		},
+		"NoCommonCurvePSK": {
+			clientCfg: &Config{
+				CipherSuites:     []CipherSuiteID{TLS_ECDHE_PSK_WITH_AES_128_CBC_SHA256, TLS_PSK_WITH_AES_128_CCM_8},
+				CurvePreferences: []elliptic.Curve{elliptic.P384},
+				PSK:              func([]byte) ([]byte, error) { return []byte{0xAB, 0xC1, 0x23}, nil },
+				PSKIdentityHint:  []byte("Client"),
+			},
+			serverCfg: &Config{
+				CipherSuites:     []CipherSuiteID{TLS_PSK_WITH_AES_128_CCM_8},
+				CurvePreferences: []elliptic.Curve{elliptic.X25519, elliptic.P256},
+				PSK:              func([]byte) ([]byte, error) { return []byte{0xAB, 0xC1, 0x23}, nil },
+			},
+			wantCipherSuite: TLS_PSK_WITH_AES_128_CCM_8,
+		},
+	} {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			ca, cb := dpipe.Pipe()
+			type result struct {
+				c   *Conn
+				err error
+			}
+			c := make(chan result)
+
+			go func() {
+				client, err := testClient(context.Background(), ca, tt.clientCfg, false)
+				c <- result{client, err}
+			}()
+
+			server, err := testServer(context.Background(), cb, tt.serverCfg, tt.serverCfg.PSK == nil)
+			res := <-c
+			defer func() {
+				if err == nil {
+					_ = server.Close()
+				}
+				if res.err == nil {
+					_ = res.c.Close()
+				}
+			}()
+
+			if tt.wantErr {
+				if !errors.Is(err, errNoSupportedEllipticCurves) {
+					t.Errorf("Server error expected: \"%v\", got: \"%v\"", errNoSupportedEllipticCurves, err)
+				}
+				if res.err == nil {
+					t.Error("Client error expected")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Server failed(%v)", err)
+			}
+			if res.err != nil {
+				t.Fatalf("Client failed(%v)", res.err)
+			}
+
+			if id := res.c.state.cipherSuite.ID(); id != tt.wantCipherSuite {
+				t.Errorf("Cipher suite %s, expected %s", id, tt.wantCipherSuite)
+			}
+			if tt.wantCurve == 0 {
+				// No curve is needed without ECDHE
+				return
+			}
+			if curve := res.c.state.localKeypair.Curve; curve != tt.wantCurve || server.state.namedCurve != tt.wantCurve {
+				t.Errorf("Curve client(%#x) server(%#x), expected %#x", curve, server.state.namedCurve, tt.wantCurve)
+			}
+		})
+	}
+}
+
+func TestEllipticCurves(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	for name, tt := range map[string]struct {
+		clientCurves []elliptic.Curve
+		serverCurves []elliptic.Curve
+		wantCurve    elliptic.Curve
+		psk          bool
+	}{
+		"P521": {
+			clientCurves: []elliptic.Curve{elliptic.P521},
+			serverCurves: []elliptic.Curve{elliptic.P521},
+			wantCurve:    elliptic.P521,
+		},
+		"X448": {
+			clientCurves: []elliptic.Curve{elliptic.X448},
+			serverCurves: []elliptic.Curve{elliptic.X448},
+			wantCurve:    elliptic.X448,
+		},
+		"BrainpoolP256r1": {
+			clientCurves: []elliptic.Curve{elliptic.BrainpoolP256r1},
+			serverCurves: []elliptic.Curve{elliptic.BrainpoolP256r1},
+			wantCurve:    elliptic.BrainpoolP256r1,
+		},
+		"BrainpoolP384r1": {
+			clientCurves: []elliptic.Curve{elliptic.BrainpoolP384r1},
+			serverCurves: []elliptic.Curve{elliptic.BrainpoolP384r1},
+			wantCurve:    elliptic.BrainpoolP384r1,
+		},
+		"OptInCurvesNotDefault": {
+			clientCurves: []elliptic.Curve{elliptic.X448, elliptic.BrainpoolP256r1, elliptic.P521, elliptic.P384},
+			wantCurve:    elliptic.P384,
+		},
+		"X25519MLKEM768": {
+			clientCurves: []elliptic.Curve{elliptic.X25519MLKEM768Experimental, elliptic.X25519},
+			serverCurves: []elliptic.Curve{elliptic.X25519MLKEM768Experimental, elliptic.X25519},
+			wantCurve:    elliptic.X25519MLKEM768Experimental,
+		},
+		"X25519MLKEM768_PSK": {
+			clientCurves: []elliptic.Curve{elliptic.X25519MLKEM768Experimental},
+			serverCurves: []elliptic.Curve{elliptic.X25519MLKEM768Experimental},
+			wantCurve:    elliptic.X25519MLKEM768Experimental,
+			psk:          true,
+		},
+		"X25519MLKEM768NotDefault": {
+			clientCurves: []elliptic.Curve{elliptic.X25519MLKEM768Experimental, elliptic.X25519},
+			wantCurve:    elliptic.X25519,
+		},
+	} {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			for _, curve := range append(tt.clientCurves, tt.serverCurves...) {
+				if !elliptic.Curves()[curve] {
+					t.Skipf("Curve %#x is not supported by this Go version", curve)
+				}
+			}
+
+			clientConfig := &Config{CurvePreferences: tt.clientCurves}
+			serverConfig := &Config{CurvePreferences: tt.serverCurves}
+			if tt.psk {
+				for _, cfg := range []*Config{clientConfig, serverConfig} {
+					cfg.PSK = func([]byte) ([]byte, error) {
+						return []byte{0xAB, 0xC1, 0x23}, nil
+					}
+					cfg.PSKIdentityHint = []byte("Client Identity")
+					cfg.CipherSuites = []CipherSuiteID{TLS_ECDHE_PSK_WITH_AES_128_CBC_SHA256}
+				}
+			}
+
+			ca, cb := dpipe.Pipe()
+			type result struct {
+				c   *Conn
+				err error
+			}
+			c := make(chan result)
+
+			go func() {
+				client, err := testClient(context.Background(), ca, clientConfig, false)
+				c <- result{client, err}
+			}()
+
+			server, err := testServer(context.Background(), cb, serverConfig, !tt.psk)
+			res := <-c
+			if err != nil {
+				t.Fatalf("Server failed(%v)", err)
+			}
+			defer func() {
+				_ = server.Close()
+			}()
+			if res.err != nil {
+				t.Fatalf("Client failed(%v)", res.err)
+			}
+			defer func() {
+				_ = res.c.Close()
+			}()
+
+			if curve := res.c.state.localKeypair.Curve; curve != tt.wantCurve || server.state.namedCurve != tt.wantCurve {
+				t.Errorf("Curve client(%#x) server(%#x), expected %#x", curve, server.state.namedCurve, tt.wantCurve)
+			}
+		})
+	}
+}
+
+func TestX25519MLKEM768NotOffered(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	// The hybrid group uses a wire format of its own in DTLS 1.2, and must
+	// only be offered to peers known to share it
+	for name, tt := range map[string]struct {
+		curves      []elliptic.Curve
+		wantOffered bool
+	}{
+		"Default": {},
+		"NotListed": {
+			curves: []elliptic.Curve{elliptic.X25519, elliptic.P256, elliptic.P384, elliptic.P521},
+		},
+		"Listed": {
+			curves:      []elliptic.Curve{elliptic.X25519MLKEM768Experimental, elliptic.X25519},
+			wantOffered: true,
+		},
+	} {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			if tt.wantOffered && !elliptic.Curves()[elliptic.X25519MLKEM768Experimental] {
+				t.Skip("X25519MLKEM768 is not supported by this Go version")
+			}
+
+			ca, cb := dpipe.Pipe()
+			ctx, cancel := context.WithCancel(context.Background())
+			clientErr := make(chan error, 1)
+			go func() {
+				_, err := testClient(ctx, cb, &Config{CurvePreferences: tt.curves}, false)
+				clientErr <- err
+			}()
+			defer func() {
+				cancel()
+				<-clientErr
+				_ = ca.Close()
+			}()
+
+			resp := make([]byte, 8192)
+			n, err := ca.Read(resp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := &recordlayer.RecordLayer{}
+			if err = r.Unmarshal(resp[:n]); err != nil {
+				t.Fatal(err)
+			}
+			clientHello, ok := r.Content.(*handshake.Handshake).Message.(*handshake.MessageClientHello)
+			if !ok {
+				t.Fatal("Failed to cast MessageClientHello")
+			}
+
+			offered := false
+			for _, v := range clientHello.Extensions {
+				if e, ok := v.(*extension.SupportedEllipticCurves); ok {
+					for _, curve := range e.EllipticCurves {
+						offered = offered || curve == elliptic.X25519MLKEM768Experimental
+					}
+				}
+			}
+			if offered != tt.wantOffered {
+				t.Errorf("X25519MLKEM768 offered(%v), expected(%v)", offered, tt.wantOffered)
+			}
+		})
+	}
+}
+
+func TestECJPAKE(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	for name, tt := range map[string]struct {
+		clientPassphrase, serverPassphrase []byte
+		wantErr                            bool
+	}{
+		"SamePassphrase": {
+			clientPassphrase: []byte("threadjpaketest"),
+			serverPassphrase: []byte("threadjpaketest"),
+		},
+		"DifferentPassphrase": {
+			clientPassphrase: []byte("threadjpaketest"),
+			serverPassphrase: []byte("threadjpaketesT"),
+			wantErr:          true,
+		},
+	} {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			// With different passphrases the keys differ, so the Finished
+			// of the peer can't be decrypted and the handshake times out
+			timeout := 10 * time.Second
+			if tt.wantErr {
+				timeout = time.Second
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			ca, cb := dpipe.Pipe()
+			type result struct {
+				c   *Conn
+				err error
+			}
+			c := make(chan result, 1)
+
+			go func() {
+				client, err := testClient(ctx, ca, &Config{
+					ECJPAKEPassphrase: tt.clientPassphrase,
+					CipherSuites:      []CipherSuiteID{TLS_ECJPAKE_WITH_AES_128_CCM_8},
+				}, false)
+				c <- result{client, err}
+			}()
+
+			server, err := testServer(ctx, cb, &Config{
+				ECJPAKEPassphrase: tt.serverPassphrase,
+				CipherSuites:      []CipherSuiteID{TLS_ECJPAKE_WITH_AES_128_CCM_8},
+			}, false)
+			res := <-c
+			if err == nil {
+				defer func() {
+					_ = server.Close()
+				}()
+			}
+			if res.err == nil {
+				defer func() {
+					_ = res.c.Close()
+				}()
+			}
+
+			if tt.wantErr {
+				if err == nil || res.err == nil {
+					t.Fatalf("Expected the handshake to fail, got server(%v) client(%v)", err, res.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Server failed(%v)", err)
+			}
+			if res.err != nil {
+				t.Fatalf("Client failed(%v)", res.err)
+			}
+			if id := res.c.state.cipherSuite.ID(); id != TLS_ECJPAKE_WITH_AES_128_CCM_8 {
+				t.Errorf("Cipher suite %s, expected %s", id, TLS_ECJPAKE_WITH_AES_128_CCM_8)
+			}
+		})
+	}
+}
+
+func TestCryptoSigner(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Hide the type of the private keys, so they are only usable through
+	// crypto.Signer and ContextSigner
+	signerCertificate := func(contextSigner bool) tls.Certificate {
+		certificate, err := selfsign.GenerateSelfSigned()
+		if err != nil {
+			t.Fatal(err)
+		}
+		signer, ok := certificate.PrivateKey.(crypto.Signer)
+		if !ok {
+			t.Fatal("Private key is not a crypto.Signer")
+		}
+		if contextSigner {
+			certificate.PrivateKey = &testContextSigner{Signer: signer}
+		} else {
+			certificate.PrivateKey = &testSigner{signer}
+		}
+		return certificate
+	}
+
+	ca, cb := dpipe.Pipe()
+	type result struct {
+		c   *Conn
+		err error
+	}
+	c := make(chan result, 1)
+
+	go func() {
+		client, err := testClient(ctx, ca, &Config{
+			Certificates: []tls.Certificate{signerCertificate(false)},
+		}, false)
+		c <- result{client, err}
+	}()
+
+	server, err := testServer(ctx, cb, &Config{
+		Certificates: []tls.Certificate{signerCertificate(true)},
+		ClientAuth:   RequireAnyClientCert,
+	}, false)
+	res := <-c
+	if err != nil {
+		t.Fatalf("Server failed(%v)", err)
+	}
+	defer func() {
+		_ = server.Close()
+	}()
+	if res.err != nil {
+		t.Fatalf("Client failed(%v)", res.err)
+	}
+	defer func() {
+		_ = res.c.Close()
+	}()
+
+	if len(server.ConnectionState().PeerCertificates) == 0 {
+		t.Error("Server got no client certificate")
+	}
+}
+
+func TestServerGetCertificate(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	certificateECDSA, err := selfsign.GenerateSelfSignedWithDNS("test.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificateRSA, err := selfsign.WithDNS(rsaKey, "test.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	getCertificate := func(info *ClientHelloInfo) (*tls.Certificate, error) {
+		if info.ServerName != "test.test" || info.RemoteAddr == nil {
+			return nil, errUnexpectedClientHello
+		}
+		if len(info.SupportedProtos) != 1 || info.SupportedProtos[0] != "proto" {
+			return nil, errUnexpectedClientHello
+		}
+		if info.SupportsCertificate(&certificateECDSA) == nil {
+			return &certificateECDSA, nil
+		}
+		return &certificateRSA, nil
+	}
+
+	for name, test := range map[string]struct {
+		clientCipherSuites  []CipherSuiteID
+		serverConfig        *Config
+		expectedCertificate tls.Certificate
+	}{
+		"GetCertificateECDSA": {
+			clientCipherSuites:  []CipherSuiteID{TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+			serverConfig:        &Config{GetCertificate: getCertificate},
+			expectedCertificate: certificateECDSA,
+		},
+		"GetCertificateRSA": {
+			clientCipherSuites:  []CipherSuiteID{TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+			serverConfig:        &Config{GetCertificate: getCertificate},
+			expectedCertificate: certificateRSA,
+		},
+		"CertificatesRSA": {
+			clientCipherSuites: []CipherSuiteID{TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+			serverConfig: &Config{
+				Certificates: []tls.Certificate{certificateECDSA, certificateRSA},
+			},
+			expectedCertificate: certificateRSA,
+		},
+	} {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			ca, cb := dpipe.Pipe()
+			type result struct {
+				c   *Conn
+				err error
+			}
+			c := make(chan result, 1)
+
+			go func() {
+				client, err := testClient(ctx, ca, &Config{
+					CipherSuites:       test.clientCipherSuites,
+					ServerName:         "test.test",
+					SupportedProtocols: []string{"proto"},
+				}, false)
+				c <- result{client, err}
+			}()
+
+			test.serverConfig.SupportedProtocols = []string{"proto"}
+			server, err := testServer(ctx, cb, test.serverConfig, false)
+			res := <-c
+			if err != nil {
+				t.Fatalf("Server failed(%v)", err)
+			}
+			defer func() {
+				_ = server.Close()
+			}()
+			if res.err != nil {
+				t.Fatalf("Client failed(%v)", res.err)
+			}
+			defer func() {
+				_ = res.c.Close()
+			}()
+
+			peerCertificates := res.c.ConnectionState().PeerCertificates
+			if len(peerCertificates) != 1 || !bytes.Equal(peerCertificates[0], test.expectedCertificate.Certificate[0]) {
+				t.Error("Client got an unexpected server certificate")
+			}
+		})
+	}
+}
+
+func TestClientGetCertificate(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	caCertificate, _, certificateCA := createTestCertificates(t)
+	caPool := x509.NewCertPool()
+	caPool.AddCert(caCertificate)
+
+	certificateSelfSigned, err := selfsign.GenerateSelfSigned()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, test := range map[string]*Config{
+		"Certificates": {
+			Certificates: []tls.Certificate{certificateSelfSigned, certificateCA},
+		},
+		"GetClientCertificate": {
+			GetClientCertificate: func(info *CertificateRequestInfo) (*tls.Certificate, error) {
+				if len(info.AcceptableCAs) != 1 || !bytes.Equal(info.AcceptableCAs[0], caCertificate.RawSubject) {
+					return nil, errUnexpectedCertificateRequest
+				}
+				if info.SupportsCertificate(&certificateSelfSigned) == nil {
+					return &certificateSelfSigned, nil
+				}
+				return &certificateCA, nil
+			},
+		},
+	} {
+		clientConfig := test
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			ca, cb := dpipe.Pipe()
+			type result struct {
+				c   *Conn
+				err error
+			}
+			c := make(chan result, 1)
+
+			go func() {
+				client, err := testClient(ctx, ca, clientConfig, false)
+				c <- result{client, err}
+			}()
+
+			server, err := testServer(ctx, cb, &Config{
+				ClientCAs:  caPool,
+				ClientAuth: RequireAndVerifyClientCert,
+			}, true)
+			res := <-c
+			if err != nil {
+				t.Fatalf("Server failed(%v)", err)
+			}
+			defer func() {
+				_ = server.Close()
+			}()
+			if res.err != nil {
+				t.Fatalf("Client failed(%v)", res.err)
+			}
+			defer func() {
+				_ = res.c.Close()
+			}()
+
+			peerCertificates := server.ConnectionState().PeerCertificates
+			if len(peerCertificates) == 0 || !bytes.Equal(peerCertificates[0], certificateCA.Certificate[0]) {
+				t.Error("Server got an unexpected client certificate")
+			}
+		})
+	}
+}
+
+func TestGetConfigForClient(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	certificateA, err := selfsign.GenerateSelfSigned()
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificateB, err := selfsign.GenerateSelfSigned()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The connection IDs keep the length of the Config of the server
+	var calls int32
+	serverConfig := &Config{
+		CipherSuites:          []CipherSuiteID{TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+		Certificates:          []tls.Certificate{certificateA},
+		SupportedProtocols:    []string{"a"},
+		ConnectionIDGenerator: RandomCIDGenerator(8),
+		GetConfigForClient: func(info *ClientHelloInfo) (*Config, error) {
+			atomic.AddInt32(&calls, 1)
+			switch info.ServerName {
+			case "b.test":
+				return &Config{
+					CipherSuites:          []CipherSuiteID{TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384},
+					Certificates:          []tls.Certificate{certificateB},
+					SupportedProtocols:    []string{"b"},
+					ConnectionIDGenerator: RandomCIDGenerator(4),
+				}, nil
+			case "error.test":
+				return nil, errUnexpectedClientHello
+			}
+			return nil, nil
+		},
+	}
+
+	for name, test := range map[string]struct {
+		serverName          string
+		protocol            string
+		expectedCipherSuite CipherSuiteID
+		expectedCertificate tls.Certificate
+		wantErr             bool
+	}{
+		"Default": {
+			serverName:          "a.test",
+			protocol:            "a",
+			expectedCipherSuite: TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			expectedCertificate: certificateA,
+		},
+		"ConfigForClient": {
+			serverName:          "b.test",
+			protocol:            "b",
+			expectedCipherSuite: TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			expectedCertificate: certificateB,
+		},
+		"Error": {
+			serverName: "error.test",
+			protocol:   "a",
+			wantErr:    true,
+		},
+	} {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			atomic.StoreInt32(&calls, 0)
+
+			ca, cb := dpipe.Pipe()
+			type result struct {
+				c   *Conn
+				err error
+			}
+			c := make(chan result, 1)
+
+			go func() {
+				client, err := testClient(ctx, ca, &Config{
+					CipherSuites:          []CipherSuiteID{TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384},
+					ServerName:            test.serverName,
+					SupportedProtocols:    []string{test.protocol},
+					ConnectionIDGenerator: OnlySendCIDGenerator(),
+				}, false)
+				c <- result{client, err}
+			}()
+
+			// The handshake fails unless the server supports the protocol
+			// of the client
+			server, err := testServer(ctx, cb, serverConfig, false)
+			res := <-c
+			if test.wantErr {
+				if err == nil {
+					_ = server.Close()
+					t.Error("Expected the server to fail")
+				}
+				if res.err == nil {
+					_ = res.c.Close()
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Server failed(%v)", err)
+			}
+			defer func() {
+				_ = server.Close()
+			}()
+			if res.err != nil {
+				t.Fatalf("Client failed(%v)", res.err)
+			}
+			defer func() {
+				_ = res.c.Close()
+			}()
+
+			state := res.c.ConnectionState()
+			if state.cipherSuite.ID() != test.expectedCipherSuite {
+				t.Errorf("Expected CipherSuite(%s), got(%s)", test.expectedCipherSuite, state.cipherSuite.ID())
+			}
+			if len(state.PeerCertificates) == 0 || !bytes.Equal(state.PeerCertificates[0], test.expectedCertificate.Certificate[0]) {
+				t.Error("Client got an unexpected server certificate")
+			}
+			if l := len(server.state.getLocalConnectionID()); l != 8 {
+				t.Errorf("Expected a connection ID of 8 bytes, got %d", l)
+			}
+			// GetConfigForClient runs once for the handshake, which
+			// includes a HelloVerifyRequest
+			if n := atomic.LoadInt32(&calls); n != 1 {
+				t.Errorf("GetConfigForClient called %d times, expected once", n)
+			}
+		})
+	}
+}
+
+func TestGetPSK(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	serverConfig := &Config{
+		CipherSuites:    []CipherSuiteID{TLS_PSK_WITH_AES_128_CCM_8},
+		PSKIdentityHint: []byte("hint"),
+		GetPSK: func(ctx context.Context, identity []byte, info *ClientHelloInfo) ([]byte, error) {
+			if ctx.Err() != nil || info.RemoteAddr == nil || info.ServerName != "psk.test" {
+				return nil, errUnexpectedClientHello
+			}
+			if !bytes.Equal(identity, []byte("known")) {
+				return nil, fmt.Errorf("identity %q: %w", identity, ErrUnknownPSKIdentity)
+			}
+			return []byte{0xAB, 0xC1, 0x23}, nil
+		},
+	}
+
+	for name, test := range map[string]struct {
+		identity        []byte
+		wantServerError error
+		wantClientError error
+	}{
+		"Known": {
+			identity: []byte("known"),
+		},
+		"Unknown": {
+			identity:        []byte("unknown"),
+			wantServerError: ErrUnknownPSKIdentity,
+			wantClientError: &alertError{&alert.Alert{Level: alert.Fatal, Description: alert.UnknownPSKIdentity}},
+		},
+	} {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			ca, cb := dpipe.Pipe()
+			type result struct {
+				c   *Conn
+				err error
+			}
+			c := make(chan result, 1)
+
+			go func() {
+				client, err := testClient(ctx, ca, &Config{
+					CipherSuites: []CipherSuiteID{TLS_PSK_WITH_AES_128_CCM_8},
+					ServerName:   "psk.test",
+					GetClientPSK: func(ctx context.Context, identityHint []byte) ([]byte, []byte, error) {
+						if !bytes.Equal(identityHint, []byte("hint")) {
+							return nil, nil, errPSKRejected
+						}
+						return test.identity, []byte{0xAB, 0xC1, 0x23}, nil
+					},
+				}, false)
+				c <- result{client, err}
+			}()
+
+			server, err := testServer(ctx, cb, serverConfig, false)
+			res := <-c
+			if test.wantServerError != nil {
+				if !errors.Is(err, test.wantServerError) {
+					t.Errorf("Server error exp(%v) failed(%v)", test.wantServerError, err)
+				}
+				if !errors.Is(res.err, test.wantClientError) {
+					t.Errorf("Client error exp(%v) failed(%v)", test.wantClientError, res.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Server failed(%v)", err)
+			}
+			defer func() {
+				_ = server.Close()
+			}()
+			if res.err != nil {
+				t.Fatalf("Client failed(%v)", res.err)
+			}
+			defer func() {
+				_ = res.c.Close()
+			}()
+
+			if identity := server.ConnectionState().IdentityHint; !bytes.Equal(identity, test.identity) {
+				t.Errorf("Server got identity(%q), expected(%q)", identity, test.identity)
+			}
+		})
+	}
+}
+
+func TestServerPSKAuthTracker(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	var events []PSKAuthEventType
+	serverConfig := &Config{
+		CipherSuites:    []CipherSuiteID{TLS_PSK_WITH_AES_128_CCM_8},
+		PSKIdentityHint: []byte("hint"),
+		GetPSK: func(ctx context.Context, identity []byte, info *ClientHelloInfo) ([]byte, error) {
+			if !bytes.Equal(identity, []byte("known")) {
+				return nil, ErrUnknownPSKIdentity
+			}
+			return []byte{0xAB, 0xC1, 0x23}, nil
+		},
+		PSKAuthTracker: &PSKAuthTracker{
+			MaxFailures: 1,
+			Backoff:     time.Hour,
+			OnEvent: func(e PSKAuthEvent) {
+				events = append(events, e.Type)
+			},
+		},
+	}
+
+	// The same identity is rejected after a failure, and so is any identity
+	// from the same address
+	for _, test := range []struct {
+		identity        []byte
+		wantServerError error
+		wantClientError error
+	}{
+		{
+			identity:        []byte("unknown"),
+			wantServerError: ErrUnknownPSKIdentity,
+			wantClientError: &alertError{&alert.Alert{Level: alert.Fatal, Description: alert.UnknownPSKIdentity}},
+		},
+		{
+			identity:        []byte("known"),
+			wantServerError: errPSKAuthLockedOut,
+			wantClientError: &alertError{&alert.Alert{Level: alert.Fatal, Description: alert.AccessDenied}},
+		},
+	} {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+
+		ca, cb := dpipe.Pipe()
+		c := make(chan error, 1)
+
+		go func() {
+			_, err := testClient(ctx, ca, &Config{
+				CipherSuites: []CipherSuiteID{TLS_PSK_WITH_AES_128_CCM_8},
+				GetClientPSK: func(ctx context.Context, identityHint []byte) ([]byte, []byte, error) {
+					return test.identity, []byte{0xAB, 0xC1, 0x23}, nil
+				},
+			}, false)
+			c <- err
+		}()
+
+		_, err := testServer(ctx, cb, serverConfig, false)
+		clientErr := <-c
+		cancel()
+		if !errors.Is(err, test.wantServerError) {
+			t.Errorf("Server error with identity(%q) exp(%v) failed(%v)", test.identity, test.wantServerError, err)
+		}
+		if !errors.Is(clientErr, test.wantClientError) {
+			t.Errorf("Client error with identity(%q) exp(%v) failed(%v)", test.identity, test.wantClientError, clientErr)
+		}
+	}
+
+	expected := []PSKAuthEventType{PSKAuthFailed, PSKAuthLockedOut, PSKAuthRejected}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("Events: expected(%v) actual(%v)", expected, events)
+	}
+}
+
+// dropEncryptedConn drops the encrypted records written to it
+type dropEncryptedConn struct {
+	net.Conn
+}
+
+func (c *dropEncryptedConn) Write(b []byte) (int, error) {
+	pkts, err := recordlayer.UnpackDatagram(b)
+	if err != nil {
+		return 0, err
+	}
+	var out []byte
+	for _, p := range pkts {
+		h := &recordlayer.Header{}
+		if err := h.Unmarshal(p); err == nil && h.Epoch == 0 {
+			out = append(out, p...)
+		}
+	}
+	if len(out) > 0 {
+		if _, err := c.Conn.Write(out); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Assert that only a client failing to prove the pre-shared key of its
+// identity counts as a failed authentication
+func TestServerPSKAuthTrackerFinished(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	for name, test := range map[string]struct {
+		clientPSK      []byte
+		dropEncrypted  bool
+		expectedEvents []PSKAuthEventType
+	}{
+		"WrongKey": {
+			clientPSK:      []byte{0x01, 0x02, 0x03},
+			expectedEvents: []PSKAuthEventType{PSKAuthFailed, PSKAuthLockedOut},
+		},
+		// The Finished of the client is lost, the client may know the key
+		"Timeout": {
+			clientPSK:     []byte{0xAB, 0xC1, 0x23},
+			dropEncrypted: true,
+		},
+	} {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			var mu sync.Mutex
+			var events []PSKAuthEventType
+			serverConfig := &Config{
+				CipherSuites: []CipherSuiteID{TLS_PSK_WITH_AES_128_CCM_8},
+				GetPSK: func(ctx context.Context, identity []byte, info *ClientHelloInfo) ([]byte, error) {
+					return []byte{0xAB, 0xC1, 0x23}, nil
+				},
+				PSKAuthTracker: &PSKAuthTracker{
+					MaxFailures: 1,
+					OnEvent: func(e PSKAuthEvent) {
+						mu.Lock()
+						events = append(events, e.Type)
+						mu.Unlock()
+					},
+				},
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			ca, cb := dpipe.Pipe()
+			var clientConn net.Conn = ca
+			if test.dropEncrypted {
+				clientConn = &dropEncryptedConn{ca}
+			}
+			c := make(chan error, 1)
+			go func() {
+				_, err := testClient(ctx, clientConn, &Config{
+					CipherSuites:    []CipherSuiteID{TLS_PSK_WITH_AES_128_CCM_8},
+					PSKIdentityHint: []byte("known"),
+					PSK: func([]byte) ([]byte, error) {
+						return test.clientPSK, nil
+					},
+				}, false)
+				c <- err
+			}()
+
+			if _, err := testServer(ctx, cb, serverConfig, false); err == nil {
+				t.Error("Expected the server to fail")
+			}
+			if err := <-c; err == nil {
+				t.Error("Expected the client to fail")
+			}
+
+			// The handshake of the server is reported once it stopped, after
+			// the server returned
+			for i := 0; i < 20; i++ {
+				time.Sleep(10 * time.Millisecond)
+				mu.Lock()
+				n := len(events)
+				mu.Unlock()
+				if n > 0 && n >= len(test.expectedEvents) {
+					break
+				}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if !reflect.DeepEqual(events, test.expectedEvents) {
+				t.Errorf("Events: expected(%v) actual(%v)", test.expectedEvents, events)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package dtls
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+
+	"github.com/pion/dtls/v2/pkg/protocol"
+	"github.com/pion/dtls/v2/pkg/protocol/extension"
+	"github.com/pion/dtls/v2/pkg/protocol/handshake"
+	"github.com/pion/dtls/v2/pkg/protocol/recordlayer"
+)
+
+// RandomCIDGenerator is a random Connection ID generator where CID is the
+// specified size. Specifying a size of 0 will indicate to peers that sending
+// a Connection ID is not necessary.
+func RandomCIDGenerator(size int) func() []byte {
+	return func() []byte {
+		cid := make([]byte, size)
+		if _, err := rand.Read(cid); err != nil {
+			panic(err) //nolint -- nonrecoverable
+		}
+		return cid
+	}
+}
+
+// OnlySendCIDGenerator enables sending Connection IDs negotiated with a peer,
+// but indicates to the peer that sending Connection IDs in return is not
+// necessary.
+func OnlySendCIDGenerator() func() []byte {
+	return func() []byte {
+		return nil
+	}
+}
+
+// offersConnectionID reports whether a client offers connection IDs, which
+// requires every cipher suite it offers to support them
+func (c *handshakeConfig) offersConnectionID() bool {
+	if c.connectionIDGenerator == nil {
+		return false
+	}
+	for _, cipherSuite := range c.localCipherSuites {
+		if _, ok := cipherSuite.(connectionIDCipherSuite); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// cidDatagramRouter extracts connection IDs from incoming datagram payloads and
+// uses them to route to the proper connection.
+// NOTE: properly routing datagrams based on connection IDs requires using
+// constant size connection IDs.
+func cidDatagramRouter(size int) func([]byte) (string, bool) {
+	return func(packet []byte) (string, bool) {
+		pkts, err := recordlayer.ContentAwareUnpackDatagram(packet, size)
+		if err != nil {
+			return "", false
+		}
+		for _, pkt := range pkts {
+			h := &recordlayer.Header{
+				ConnectionID: make([]byte, size),
+			}
+			if err := h.Unmarshal(pkt); err != nil {
+				continue
+			}
+			if h.ContentType != protocol.ContentTypeConnectionID {
+				continue
+			}
+			return string(h.ConnectionID), true
+		}
+		return "", false
+	}
+}
+
+// cidConnIdentifier extracts connection IDs from outgoing ServerHello records
+// and associates them with the associated connection.
+// NOTE: a ServerHello is always the first record in a datagram, and later
+// records may already carry the peer's connection ID, so only the first
+// record is inspected.
+func cidConnIdentifier() func([]byte) (string, bool) {
+	return func(packet []byte) (string, bool) {
+		if len(packet) < recordlayer.HeaderSize || protocol.ContentType(packet[0]) != protocol.ContentTypeHandshake {
+			return "", false
+		}
+		pktLen := recordlayer.HeaderSize + int(binary.BigEndian.Uint16(packet[recordlayer.HeaderSize-2:]))
+		if pktLen > len(packet) {
+			return "", false
+		}
+		r := &recordlayer.RecordLayer{}
+		if err := r.Unmarshal(packet[:pktLen]); err != nil {
+			return "", false
+		}
+		h, ok := r.Content.(*handshake.Handshake)
+		if !ok {
+			return "", false
+		}
+		sh, ok := h.Message.(*handshake.MessageServerHello)
+		if !ok {
+			return "", false
+		}
+		for _, ext := range sh.Extensions {
+			if e, ok := ext.(*extension.ConnectionID); ok && len(e.CID) > 0 {
+				return string(e.CID), true
+			}
+		}
+		return "", false
+	}
+}
@@ -0,0 +1,208 @@
+package dtls
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pion/dtls/v2/pkg/protocol"
+	"github.com/pion/dtls/v2/pkg/protocol/handshake"
+	"github.com/pion/dtls/v2/pkg/protocol/recordlayer"
+)
+
+const (
+	// CookieSecretLength is the length of a HelloVerifyRequest cookie secret
+	CookieSecretLength = 32
+
+	// Cookies are only needed for a single round trip, so a rotated out
+	// secret is kept just long enough for exchanges in flight to complete
+	maxCookieSecrets = 2
+
+	// Cookies are bound to the time window they were generated in, and
+	// accepted in the following window too, so a cookie expires after one
+	// to two windows
+	cookieWindow = time.Minute
+
+	clientHelloVersionLength = 2
+)
+
+// CookieSecrets is the set of secrets a listener uses to generate and
+// verify HelloVerifyRequest cookies statelessly, as an HMAC over the client
+// address, the fields of its ClientHello preceding the cookie and a coarse
+// timestamp (RFC 6347 Section 4.2.1).
+// Cookies expire after one to two minutes. Cookies are
+// generated with the first secret and verified against every secret, so the
+// secret can be rotated while cookie exchanges are in flight. A single
+// CookieSecrets can be shared by the Config of several servers, so any of
+// them can verify cookies issued by another one.
+// CookieSecrets is safe for concurrent use.
+type CookieSecrets struct {
+	mu      sync.RWMutex
+	secrets [][CookieSecretLength]byte
+	now     func() time.Time
+}
+
+// acceptedClientHello describes the ClientHello a listener created a Conn
+// for. The Conn continues the sequence numbers of the ClientHello, skips
+// the cookie exchange if the cookie was already verified, and decides on
+// the admission of the handshake unless the listener already admitted it.
+type acceptedClientHello struct {
+	messageSequence uint16
+	recordSequence  uint64
+	cookieVerified  bool
+	admitted        bool
+}
+
+// NewCookieSecrets creates a secret set from one or more secrets, the first
+// of which is used to generate new cookies. Secrets should be generated
+// with a cryptographically secure random number generator.
+func NewCookieSecrets(secrets ...[CookieSecretLength]byte) (*CookieSecrets, error) {
+	s := &CookieSecrets{}
+	if err := s.SetSecrets(secrets...); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SetSecrets replaces all secrets of the set
+func (s *CookieSecrets) SetSecrets(secrets ...[CookieSecretLength]byte) error {
+	if len(secrets) == 0 {
+		return errNoCookieSecrets
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.secrets = append([][CookieSecretLength]byte{}, secrets...)
+	return nil
+}
+
+// Rotate makes secret the one used to generate new cookies. Cookies
+// generated with the previous secret are still accepted until the next
+// rotation.
+func (s *CookieSecrets) Rotate(secret [CookieSecretLength]byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.secrets = append([][CookieSecretLength]byte{secret}, s.secrets...)
+	if len(s.secrets) > maxCookieSecrets {
+		s.secrets = s.secrets[:maxCookieSecrets]
+	}
+}
+
+func (s *CookieSecrets) generate(addr net.Addr, clientHello []byte) []byte {
+	s.mu.RLock()
+	secret := s.secrets[0]
+	s.mu.RUnlock()
+
+	return cookieMAC(secret[:], s.window(), addr, clientHello)
+}
+
+func (s *CookieSecrets) verify(cookie []byte, addr net.Addr, clientHello []byte) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	window := s.window()
+	for _, secret := range s.secrets {
+		for _, w := range []uint64{window, window - 1} {
+			if hmac.Equal(cookie, cookieMAC(secret[:], w, addr, clientHello)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// window returns the index of the current cookie time window
+func (s *CookieSecrets) window() uint64 {
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	return uint64(now().Unix() / int64(cookieWindow/time.Second))
+}
+
+func cookieMAC(secret []byte, window uint64, addr net.Addr, clientHello []byte) []byte {
+	var w [8]byte
+	binary.BigEndian.PutUint64(w[:], window)
+
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write(w[:])
+	_, _ = mac.Write([]byte(addr.String()))
+	_, _ = mac.Write(clientHello)
+	return mac.Sum(nil)
+}
+
+// splitClientHelloCookie splits the body of a ClientHello, or its first
+// fragment, into its cookie and the fields preceding it which the cookie is
+// bound to: the version, the random and the session_id. They fit the first
+// fragment of a fragmented ClientHello, so cookies verify alike whether the
+// ClientHello is fragmented or not, which may change once the cookie is added.
+func splitClientHelloCookie(body []byte) (cookie, params []byte, ok bool) {
+	offset := clientHelloVersionLength + handshake.RandomLength
+	if len(body) < offset+1 {
+		return nil, nil, false
+	}
+	offset += 1 + int(body[offset]) // session_id
+	if len(body) < offset+1 {
+		return nil, nil, false
+	}
+	cookieEnd := offset + 1 + int(body[offset])
+	if len(body) < cookieEnd {
+		return nil, nil, false
+	}
+
+	return body[offset+1 : cookieEnd], body[:offset], true
+}
+
+// parseInitialClientHello extracts the record and handshake headers and the
+// body of a ClientHello at the start of a datagram. The body is only the
+// first fragment of a fragmented ClientHello, and nil for its later
+// fragments.
+func parseInitialClientHello(packet []byte) (*recordlayer.Header, *handshake.Header, []byte, bool) {
+	pkts, err := recordlayer.UnpackDatagram(packet)
+	if err != nil || len(pkts) < 1 {
+		return nil, nil, nil, false
+	}
+
+	h := &recordlayer.Header{}
+	if err := h.Unmarshal(pkts[0]); err != nil || h.ContentType != protocol.ContentTypeHandshake || h.Epoch != 0 {
+		return nil, nil, nil, false
+	}
+
+	hs := &handshake.Header{}
+	data := pkts[0][recordlayer.HeaderSize:]
+	if err := hs.Unmarshal(data); err != nil {
+		return nil, nil, nil, false
+	}
+	if hs.Type != handshake.TypeClientHello {
+		return nil, nil, nil, false
+	}
+	body := data[handshake.HeaderLength:]
+	if hs.FragmentOffset != 0 || hs.FragmentLength > hs.Length || int(hs.FragmentLength) > len(body) {
+		return h, hs, nil, true
+	}
+	return h, hs, body[:hs.FragmentLength], true
+}
+
+// helloVerifyRequest builds a HelloVerifyRequest datagram answering the
+// given ClientHello. The record sequence number of the ClientHello is
+// reused, as required of stateless servers by RFC 6347 Section 4.2.1.
+func helloVerifyRequest(h *recordlayer.Header, hs *handshake.Header, cookie []byte) ([]byte, error) {
+	return (&recordlayer.RecordLayer{
+		Header: recordlayer.Header{
+			Version:        protocol.Version1_2,
+			SequenceNumber: h.SequenceNumber,
+		},
+		Content: &handshake.Handshake{
+			Header: handshake.Header{
+				MessageSequence: hs.MessageSequence,
+			},
+			Message: &handshake.MessageHelloVerifyRequest{
+				Version: protocol.Version1_2,
+				Cookie:  cookie,
+			},
+		},
+	}).Marshal()
+}
@@ -0,0 +1,42 @@
+package dtls
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestCookieSecretsExpiry(t *testing.T) {
+	now := time.Unix(1200, 0)
+	secrets, err := NewCookieSecrets([CookieSecretLength]byte{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	secrets.now = func() time.Time { return now }
+
+	addr := &net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 5684}
+	clientHello := []byte{0x01, 0x02, 0x03}
+	cookie := secrets.generate(addr, clientHello)
+
+	if secrets.verify(cookie, &net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 5685}, clientHello) {
+		t.Error("cookie must be bound to the address")
+	}
+	if secrets.verify(cookie, addr, []byte{0x01}) {
+		t.Error("cookie must be bound to the ClientHello")
+	}
+
+	for _, test := range []struct {
+		elapsed time.Duration
+		valid   bool
+	}{
+		{0, true},
+		{cookieWindow, true},
+		{2*cookieWindow - time.Second, true},
+		{2 * cookieWindow, false},
+	} {
+		now = time.Unix(1200, 0).Add(test.elapsed)
+		if valid := secrets.verify(cookie, addr, clientHello); valid != test.valid {
+			t.Errorf("cookie after %s: valid(%v), expected(%v)", test.elapsed, valid, test.valid)
+		}
+	}
+}
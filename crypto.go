@@ -1,20 +1,25 @@
 package dtls
 
 import (
+	"context"
 	"crypto"
 	"crypto/ecdsa"
 	"crypto/ed25519"
+	ellipticStdlib "crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
-	"crypto/sha256"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/asn1"
 	"encoding/binary"
+	"fmt"
+	"io"
 	"math/big"
 	"time"
 
 	"github.com/pion/dtls/v2/pkg/crypto/elliptic"
-	"github.com/pion/dtls/v2/pkg/crypto/hash"
+	"github.com/pion/dtls/v2/pkg/crypto/signature"
+	"github.com/pion/dtls/v2/pkg/crypto/signaturehash"
 )
 
 type ecdsaSignature struct {
@@ -26,6 +31,11 @@ func valueKeyMessage(clientRandom, serverRandom, publicKey []byte, namedCurve el
 	serverECDHParams[0] = 3 // named curve
 	binary.BigEndian.PutUint16(serverECDHParams[1:], uint16(namedCurve))
 	serverECDHParams[3] = byte(len(publicKey))
+	if namedCurve.Hybrid() {
+		// The key shares of hybrid groups have a two byte length
+		serverECDHParams = append(serverECDHParams[:3], 0x00, 0x00)
+		binary.BigEndian.PutUint16(serverECDHParams[3:], uint16(len(publicKey)))
+	}
 
 	plaintext := []byte{}
 	plaintext = append(plaintext, clientRandom...)
@@ -41,33 +51,22 @@ func valueKeyMessage(clientRandom, serverRandom, publicKey []byte, namedCurve el
 // hash/signature algorithm pair that appears in that extension
 //
 // https://tools.ietf.org/html/rfc5246#section-7.4.2
-func generateKeySignature(clientRandom, serverRandom, publicKey []byte, namedCurve elliptic.Curve, privateKey crypto.PrivateKey, hashAlgorithm hash.Algorithm) ([]byte, error) {
+func generateKeySignature(ctx context.Context, clientRandom, serverRandom, publicKey []byte, namedCurve elliptic.Curve, privateKey crypto.PrivateKey, signatureHashAlgorithm signaturehash.Algorithm) ([]byte, error) {
 	msg := valueKeyMessage(clientRandom, serverRandom, publicKey, namedCurve)
-	switch p := privateKey.(type) {
-	case ed25519.PrivateKey:
-		// https://crypto.stackexchange.com/a/55483
-		return p.Sign(rand.Reader, msg, crypto.Hash(0))
-	case *ecdsa.PrivateKey:
-		hashed := hashAlgorithm.Digest(msg)
-		return p.Sign(rand.Reader, hashed, hashAlgorithm.CryptoHash())
-	case *rsa.PrivateKey:
-		hashed := hashAlgorithm.Digest(msg)
-		return p.Sign(rand.Reader, hashed, hashAlgorithm.CryptoHash())
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, errKeySignatureGenerateUnimplemented
 	}
-
-	return nil, errKeySignatureGenerateUnimplemented
+	return sign(ctx, signer, msg, signatureHashAlgorithm)
 }
 
-func verifyKeySignature(message, remoteKeySignature []byte, hashAlgorithm hash.Algorithm, rawCertificates [][]byte) error { //nolint:dupl
-	if len(rawCertificates) == 0 {
-		return errLengthMismatch
-	}
-	certificate, err := x509.ParseCertificate(rawCertificates[0])
+func verifyKeySignature(message, remoteKeySignature []byte, signatureHashAlgorithm signaturehash.Algorithm, rawCertificates [][]byte, certificateType CertificateType) error { //nolint:dupl
+	publicKey, err := peerPublicKey(rawCertificates, certificateType)
 	if err != nil {
 		return err
 	}
 
-	switch p := certificate.PublicKey.(type) {
+	switch p := publicKey.(type) {
 	case ed25519.PublicKey:
 		if ok := ed25519.Verify(p, message, remoteKeySignature); !ok {
 			return errKeySignatureMismatch
@@ -81,19 +80,17 @@ func verifyKeySignature(message, remoteKeySignature []byte, hashAlgorithm hash.A
 		if ecdsaSig.R.Sign() <= 0 || ecdsaSig.S.Sign() <= 0 {
 			return errInvalidECDSASignature
 		}
-		hashed := hashAlgorithm.Digest(message)
+		hashed := signatureHashAlgorithm.Digest(message)
 		if !ecdsa.Verify(p, hashed, ecdsaSig.R, ecdsaSig.S) {
 			return errKeySignatureMismatch
 		}
 		return nil
 	case *rsa.PublicKey:
-		switch certificate.SignatureAlgorithm {
-		case x509.SHA1WithRSA, x509.SHA256WithRSA, x509.SHA384WithRSA, x509.SHA512WithRSA:
-			hashed := hashAlgorithm.Digest(message)
-			return rsa.VerifyPKCS1v15(p, hashAlgorithm.CryptoHash(), hashed, remoteKeySignature)
-		default:
-			return errKeySignatureVerifyUnimplemented
-		}
+		hashed := signatureHashAlgorithm.Digest(message)
+		return verifyRSASignature(p, false, hashed, remoteKeySignature, signatureHashAlgorithm)
+	case *rsaPSSPublicKey:
+		hashed := signatureHashAlgorithm.Digest(message)
+		return verifyRSASignature(p.PublicKey, true, hashed, remoteKeySignature, signatureHashAlgorithm)
 	}
 
 	return errKeySignatureVerifyUnimplemented
@@ -107,36 +104,64 @@ func verifyKeySignature(message, remoteKeySignature []byte, hashAlgorithm hash.A
 // CertificateVerify message is sent to explicitly verify possession of
 // the private key in the certificate.
 // https://tools.ietf.org/html/rfc5246#section-7.3
-func generateCertificateVerify(handshakeBodies []byte, privateKey crypto.PrivateKey, hashAlgorithm hash.Algorithm) ([]byte, error) {
-	h := sha256.New()
-	if _, err := h.Write(handshakeBodies); err != nil {
-		return nil, err
+func generateCertificateVerify(ctx context.Context, handshakeBodies []byte, privateKey crypto.PrivateKey, signatureHashAlgorithm signaturehash.Algorithm) ([]byte, error) {
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, errInvalidSignatureAlgorithm
 	}
-	hashed := h.Sum(nil)
+	return sign(ctx, signer, handshakeBodies, signatureHashAlgorithm)
+}
 
-	switch p := privateKey.(type) {
-	case ed25519.PrivateKey:
-		// https://crypto.stackexchange.com/a/55483
-		return p.Sign(rand.Reader, hashed, crypto.Hash(0))
-	case *ecdsa.PrivateKey:
-		return p.Sign(rand.Reader, hashed, hashAlgorithm.CryptoHash())
-	case *rsa.PrivateKey:
-		return p.Sign(rand.Reader, hashed, hashAlgorithm.CryptoHash())
-	}
+// ContextSigner is a crypto.Signer whose signatures may be slow, like those of
+// a key held by a remote service. The handshake waits for SignContext
+// asynchronously, and gives up on the signature once the handshake is
+// canceled or times out.
+type ContextSigner interface {
+	crypto.Signer
 
-	return nil, errInvalidSignatureAlgorithm
+	// SignContext signs digest like Sign, and should return early once ctx
+	// is done
+	SignContext(ctx context.Context, rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error)
 }
 
-func verifyCertificateVerify(handshakeBodies []byte, hashAlgorithm hash.Algorithm, remoteKeySignature []byte, rawCertificates [][]byte) error { //nolint:dupl
-	if len(rawCertificates) == 0 {
-		return errLengthMismatch
+// sign signs the message with the signature scheme. Ed25519 signs the message
+// itself, and the other algorithms its digest.
+func sign(ctx context.Context, signer crypto.Signer, message []byte, signatureHashAlgorithm signaturehash.Algorithm) ([]byte, error) {
+	// https://crypto.stackexchange.com/a/55483
+	digest, opts := message, crypto.SignerOpts(crypto.Hash(0))
+	if _, ok := signer.Public().(ed25519.PublicKey); !ok {
+		digest, opts = signatureHashAlgorithm.Digest(message), signerOpts(signatureHashAlgorithm)
 	}
-	certificate, err := x509.ParseCertificate(rawCertificates[0])
+
+	contextSigner, ok := signer.(ContextSigner)
+	if !ok {
+		return signer.Sign(rand.Reader, digest, opts)
+	}
+
+	type result struct {
+		signature []byte
+		err       error
+	}
+	res := make(chan result, 1)
+	go func() {
+		signature, err := contextSigner.SignContext(ctx, rand.Reader, digest, opts)
+		res <- result{signature, err}
+	}()
+	select {
+	case r := <-res:
+		return r.signature, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func verifyCertificateVerify(handshakeBodies []byte, signatureHashAlgorithm signaturehash.Algorithm, remoteKeySignature []byte, rawCertificates [][]byte, certificateType CertificateType) error { //nolint:dupl
+	publicKey, err := peerPublicKey(rawCertificates, certificateType)
 	if err != nil {
 		return err
 	}
 
-	switch p := certificate.PublicKey.(type) {
+	switch p := publicKey.(type) {
 	case ed25519.PublicKey:
 		if ok := ed25519.Verify(p, handshakeBodies, remoteKeySignature); !ok {
 			return errKeySignatureMismatch
@@ -150,22 +175,190 @@ func verifyCertificateVerify(handshakeBodies []byte, hashAlgorithm hash.Algorith
 		if ecdsaSig.R.Sign() <= 0 || ecdsaSig.S.Sign() <= 0 {
 			return errInvalidECDSASignature
 		}
-		hash := hashAlgorithm.Digest(handshakeBodies)
+		hash := signatureHashAlgorithm.Digest(handshakeBodies)
 		if !ecdsa.Verify(p, hash, ecdsaSig.R, ecdsaSig.S) {
 			return errKeySignatureMismatch
 		}
 		return nil
 	case *rsa.PublicKey:
+		hash := signatureHashAlgorithm.Digest(handshakeBodies)
+		return verifyRSASignature(p, false, hash, remoteKeySignature, signatureHashAlgorithm)
+	case *rsaPSSPublicKey:
+		hash := signatureHashAlgorithm.Digest(handshakeBodies)
+		return verifyRSASignature(p.PublicKey, true, hash, remoteKeySignature, signatureHashAlgorithm)
+	}
+
+	return errKeySignatureVerifyUnimplemented
+}
+
+// signerOpts returns the options to sign a digest with the signature scheme.
+// RSASSA-PSS salts are as long as the digest.
+// https://tools.ietf.org/html/rfc8446#section-4.2.3
+func signerOpts(signatureHashAlgorithm signaturehash.Algorithm) crypto.SignerOpts {
+	if signatureHashAlgorithm.Signature.IsRSAPSS() {
+		return &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: signatureHashAlgorithm.CryptoHash()}
+	}
+	return signatureHashAlgorithm.CryptoHash()
+}
+
+// verifyRSASignature verifies a PKCS #1 v1.5 or RSASSA-PSS signature. The
+// rsa_pss_pss schemes are only valid for RSASSA-PSS public keys, and the
+// others only for rsaEncryption public keys.
+func verifyRSASignature(publicKey *rsa.PublicKey, rsaPSSKey bool, hashed, remoteKeySignature []byte, signatureHashAlgorithm signaturehash.Algorithm) error {
+	switch {
+	case signatureHashAlgorithm.Signature.IsRSAPSSPSS() != rsaPSSKey:
+		return errInvalidSignatureAlgorithm
+	case signatureHashAlgorithm.Signature.IsRSAPSS():
+		return rsa.VerifyPSS(publicKey, signatureHashAlgorithm.CryptoHash(), hashed, remoteKeySignature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	case signatureHashAlgorithm.Signature == signature.RSA:
+		return rsa.VerifyPKCS1v15(publicKey, signatureHashAlgorithm.CryptoHash(), hashed, remoteKeySignature)
+	}
+	return errInvalidSignatureAlgorithm
+}
+
+// rsaPSSPublicKey is an RSA public key restricted to RSASSA-PSS by the
+// id-RSASSA-PSS algorithm identifier of RFC 4055, which crypto/x509 leaves
+// unparsed
+type rsaPSSPublicKey struct {
+	*rsa.PublicKey
+}
+
+//nolint:gochecknoglobals
+var (
+	oidPublicKeyRSAPSS           = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 10}
+	oidPublicKeyECDSA            = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidNamedCurveBrainpoolP256r1 = asn1.ObjectIdentifier{1, 3, 36, 3, 3, 2, 8, 1, 1, 7}
+	oidNamedCurveBrainpoolP384r1 = asn1.ObjectIdentifier{1, 3, 36, 3, 3, 2, 8, 1, 1, 11}
+)
+
+type subjectPublicKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+func parseRSAPSSPublicKey(rawSubjectPublicKeyInfo []byte) (*rsaPSSPublicKey, error) {
+	var spki subjectPublicKeyInfo
+	if rest, err := asn1.Unmarshal(rawSubjectPublicKeyInfo, &spki); err != nil {
+		return nil, err
+	} else if len(rest) != 0 || !spki.Algorithm.Algorithm.Equal(oidPublicKeyRSAPSS) {
+		return nil, errKeySignatureVerifyUnimplemented
+	}
+
+	publicKey, err := x509.ParsePKCS1PublicKey(spki.PublicKey.RightAlign())
+	if err != nil {
+		return nil, err
+	}
+	return &rsaPSSPublicKey{publicKey}, nil
+}
+
+// parseBrainpoolPublicKey parses an ECDSA public key on one of the RFC 5639
+// Brainpool curves, which crypto/x509 does not support
+func parseBrainpoolPublicKey(rawSubjectPublicKeyInfo []byte) (*ecdsa.PublicKey, error) {
+	var spki subjectPublicKeyInfo
+	if rest, err := asn1.Unmarshal(rawSubjectPublicKeyInfo, &spki); err != nil {
+		return nil, err
+	} else if len(rest) != 0 || !spki.Algorithm.Algorithm.Equal(oidPublicKeyECDSA) {
+		return nil, errKeySignatureVerifyUnimplemented
+	}
+
+	var namedCurve asn1.ObjectIdentifier
+	if _, err := asn1.Unmarshal(spki.Algorithm.Parameters.FullBytes, &namedCurve); err != nil {
+		return nil, err
+	}
+	var curve elliptic.Curve
+	switch {
+	case namedCurve.Equal(oidNamedCurveBrainpoolP256r1):
+		curve = elliptic.BrainpoolP256r1
+	case namedCurve.Equal(oidNamedCurveBrainpoolP384r1):
+		curve = elliptic.BrainpoolP384r1
+	default:
+		return nil, errKeySignatureVerifyUnimplemented
+	}
+
+	x, y := ellipticStdlib.Unmarshal(curve.EllipticCurve(), spki.PublicKey.RightAlign())
+	if x == nil {
+		return nil, errInvalidECDSAPublicKey
+	}
+	return &ecdsa.PublicKey{Curve: curve.EllipticCurve(), X: x, Y: y}, nil
+}
+
+// hasRSAPSSPublicKey returns if the leaf of a certificate chain has an
+// RSASSA-PSS public key
+func hasRSAPSSPublicKey(certificate [][]byte) bool {
+	if len(certificate) == 0 {
+		return false
+	}
+	leaf, err := x509.ParseCertificate(certificate[0])
+	if err != nil {
+		return false
+	}
+	_, err = parseRSAPSSPublicKey(leaf.RawSubjectPublicKeyInfo)
+	return err == nil
+}
+
+// selectSignatureScheme returns the most preferred local signature scheme
+// the peer advertised, that the certificate can sign with. If the peer
+// didn't advertise any, the local preference alone decides.
+func selectSignatureScheme(local, remote []signaturehash.Algorithm, certificate [][]byte, privateKey crypto.PrivateKey) (signaturehash.Algorithm, error) {
+	rsaPSSKey := hasRSAPSSPublicKey(certificate)
+
+	candidates := []signaturehash.Algorithm{}
+	for _, ss := range local {
+		if ss.Signature.IsRSAPSSPSS() != rsaPSSKey {
+			continue
+		}
+		if len(remote) > 0 && !containsSignatureScheme(remote, ss) {
+			continue
+		}
+		candidates = append(candidates, ss)
+	}
+	if len(candidates) == 0 {
+		return signaturehash.Algorithm{}, errNoAvailableSignatureSchemes
+	}
+	return signaturehash.SelectSignatureScheme(candidates, privateKey)
+}
+
+func containsSignatureScheme(schemes []signaturehash.Algorithm, scheme signaturehash.Algorithm) bool {
+	for _, ss := range schemes {
+		if ss.Hash == scheme.Hash && ss.Signature == scheme.Signature {
+			return true
+		}
+	}
+	return false
+}
+
+// peerPublicKey returns the public key of the leaf of the peer's certificate
+// chain, or the raw public key it authenticates with (RFC 7250)
+func peerPublicKey(rawCertificates [][]byte, certificateType CertificateType) (crypto.PublicKey, error) {
+	if len(rawCertificates) == 0 {
+		return nil, errLengthMismatch
+	}
+	if certificateType == CertificateTypeRawPublicKey {
+		if publicKey, err := parseRSAPSSPublicKey(rawCertificates[0]); err == nil {
+			return publicKey, nil
+		}
+		if publicKey, err := parseBrainpoolPublicKey(rawCertificates[0]); err == nil {
+			return publicKey, nil
+		}
+		return x509.ParsePKIXPublicKey(rawCertificates[0])
+	}
+
+	certificate, err := x509.ParseCertificate(rawCertificates[0])
+	if err != nil {
+		return nil, err
+	}
+	if certificate.PublicKeyAlgorithm == x509.UnknownPublicKeyAlgorithm {
+		return parseRSAPSSPublicKey(certificate.RawSubjectPublicKeyInfo)
+	}
+	if _, ok := certificate.PublicKey.(*rsa.PublicKey); ok {
 		switch certificate.SignatureAlgorithm {
-		case x509.SHA1WithRSA, x509.SHA256WithRSA, x509.SHA384WithRSA, x509.SHA512WithRSA:
-			hash := hashAlgorithm.Digest(handshakeBodies)
-			return rsa.VerifyPKCS1v15(p, hashAlgorithm.CryptoHash(), hash, remoteKeySignature)
+		case x509.SHA1WithRSA, x509.SHA256WithRSA, x509.SHA384WithRSA, x509.SHA512WithRSA,
+			x509.SHA256WithRSAPSS, x509.SHA384WithRSAPSS, x509.SHA512WithRSAPSS:
 		default:
-			return errKeySignatureVerifyUnimplemented
+			return nil, errKeySignatureVerifyUnimplemented
 		}
 	}
-
-	return errKeySignatureVerifyUnimplemented
+	return certificate.PublicKey, nil
 }
 
 func loadCerts(rawCertificates [][]byte) ([]*x509.Certificate, error) {
@@ -184,7 +377,9 @@ func loadCerts(rawCertificates [][]byte) ([]*x509.Certificate, error) {
 	return certs, nil
 }
 
-func verifyClientCert(rawCertificates [][]byte, roots *x509.CertPool) (chains [][]*x509.Certificate, err error) {
+// verifyClientCert verifies the certificate chain of the client, and that
+// it has not been revoked if revocationChecker is not nil
+func verifyClientCert(rawCertificates [][]byte, roots *x509.CertPool, revocationChecker RevocationChecker) (chains [][]*x509.Certificate, err error) {
 	certificate, err := loadCerts(rawCertificates)
 	if err != nil {
 		return nil, err
@@ -199,10 +394,19 @@ func verifyClientCert(rawCertificates [][]byte, roots *x509.CertPool) (chains []
 		Intermediates: intermediateCAPool,
 		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
 	}
-	return certificate[0].Verify(opts)
+	if chains, err = certificate[0].Verify(opts); err != nil {
+		return nil, err
+	}
+	if err = checkRevocation(chains, revocationChecker); err != nil {
+		return nil, err
+	}
+	return chains, nil
 }
 
-func verifyServerCert(rawCertificates [][]byte, roots *x509.CertPool, serverName string) (chains [][]*x509.Certificate, err error) {
+// verifyServerCert verifies the certificate chain of the server, that it
+// has not been revoked if revocationChecker is not nil, and the stapled OCSP
+// response with the verifyOCSPResponse policy if not nil
+func verifyServerCert(rawCertificates [][]byte, roots *x509.CertPool, serverName string, revocationChecker RevocationChecker, ocspResponse []byte, verifyOCSPResponse func([]byte, [][]*x509.Certificate) error) (chains [][]*x509.Certificate, err error) {
 	certificate, err := loadCerts(rawCertificates)
 	if err != nil {
 		return nil, err
@@ -217,5 +421,26 @@ func verifyServerCert(rawCertificates [][]byte, roots *x509.CertPool, serverName
 		DNSName:       serverName,
 		Intermediates: intermediateCAPool,
 	}
-	return certificate[0].Verify(opts)
+	if chains, err = certificate[0].Verify(opts); err != nil {
+		return nil, err
+	}
+	if err = checkRevocation(chains, revocationChecker); err != nil {
+		return nil, err
+	}
+	if verifyOCSPResponse != nil {
+		if err = verifyOCSPResponse(ocspResponse, chains); err != nil {
+			return nil, fmt.Errorf("%w: %v", errInvalidOCSPResponse, err)
+		}
+	}
+	return chains, nil
+}
+
+func checkRevocation(chains [][]*x509.Certificate, revocationChecker RevocationChecker) error {
+	if revocationChecker == nil {
+		return nil
+	}
+	if err := revocationChecker.CheckRevocation(chains); err != nil {
+		return fmt.Errorf("%w: %v", errCertificateRevoked, err)
+	}
+	return nil
 }
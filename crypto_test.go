@@ -2,12 +2,27 @@ package dtls
 
 import (
 	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	ellipticStdlib "crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
 	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
 	"encoding/pem"
+	"errors"
+	"io"
 	"testing"
+	"time"
 
 	"github.com/pion/dtls/v2/pkg/crypto/elliptic"
 	"github.com/pion/dtls/v2/pkg/crypto/hash"
+	"github.com/pion/dtls/v2/pkg/crypto/selfsign"
+	"github.com/pion/dtls/v2/pkg/crypto/signature"
+	"github.com/pion/dtls/v2/pkg/crypto/signaturehash"
 )
 
 const rawPrivateKey = `
@@ -64,10 +79,253 @@ func TestGenerateKeySignature(t *testing.T) {
 		0x87, 0x5e, 0x5c, 0x36, 0x75, 0x86,
 	}
 
-	signature, err := generateKeySignature(clientRandom, serverRandom, publicKey, elliptic.X25519, key, hash.SHA256)
+	sig, err := generateKeySignature(context.Background(), clientRandom, serverRandom, publicKey, elliptic.X25519, key, signaturehash.Algorithm{Hash: hash.SHA256, Signature: signature.RSA})
 	if err != nil {
 		t.Error(err)
-	} else if !bytes.Equal(expectedSignature, signature) {
-		t.Errorf("Signature generation failed \nexp % 02x \nactual % 02x ", expectedSignature, signature)
+	} else if !bytes.Equal(expectedSignature, sig) {
+		t.Errorf("Signature generation failed \nexp % 02x \nactual % 02x ", expectedSignature, sig)
+	}
+}
+
+func TestRSAPSSSignature(t *testing.T) {
+	block, _ := pem.Decode([]byte(rawPrivateKey))
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A certificate with a rsaEncryption public key, and an RSASSA-PSS
+	// public key sent as a raw public key
+	certificate, err := selfsign.SelfSign(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs1PublicKey := x509.MarshalPKCS1PublicKey(&key.PublicKey)
+	rsaPSSKey, err := asn1.Marshal(struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyRSAPSS},
+		PublicKey: asn1.BitString{Bytes: pkcs1PublicKey, BitLength: 8 * len(pkcs1PublicKey)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hasRSAPSSPublicKey(certificate.Certificate) {
+		t.Error("Expected a rsaEncryption public key")
+	}
+
+	rsae := signaturehash.Algorithm{Hash: hash.Intrinsic, Signature: signature.RSA_PSS_RSAE_SHA384}
+	pss := signaturehash.Algorithm{Hash: hash.Intrinsic, Signature: signature.RSA_PSS_PSS_SHA256}
+	pkcs1 := signaturehash.Algorithm{Hash: hash.SHA384, Signature: signature.RSA}
+
+	message := []byte("handshake messages")
+	for name, test := range map[string]struct {
+		Sign, Verify    signaturehash.Algorithm
+		Certificate     [][]byte
+		CertificateType CertificateType
+		WantErr         error
+	}{
+		"RSAE": {
+			Sign: rsae, Verify: rsae,
+			Certificate: certificate.Certificate, CertificateType: CertificateTypeX509,
+		},
+		"PSS": {
+			Sign: pss, Verify: pss,
+			Certificate: [][]byte{rsaPSSKey}, CertificateType: CertificateTypeRawPublicKey,
+		},
+		"PSSSchemeWithRSAEKey": {
+			Sign: pss, Verify: pss,
+			Certificate: certificate.Certificate, CertificateType: CertificateTypeX509,
+			WantErr: errInvalidSignatureAlgorithm,
+		},
+		"RSAESchemeWithPSSKey": {
+			Sign: rsae, Verify: rsae,
+			Certificate: [][]byte{rsaPSSKey}, CertificateType: CertificateTypeRawPublicKey,
+			WantErr: errInvalidSignatureAlgorithm,
+		},
+		"PKCS1VerifiedAsPSS": {
+			Sign: pkcs1, Verify: rsae,
+			Certificate: certificate.Certificate, CertificateType: CertificateTypeX509,
+			WantErr: rsa.ErrVerification,
+		},
+	} {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			sig, err := generateCertificateVerify(context.Background(), message, key, test.Sign)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = verifyCertificateVerify(message, test.Verify, sig, test.Certificate, test.CertificateType)
+			if !errors.Is(err, test.WantErr) {
+				t.Errorf("Expected error: %v, got: %v", test.WantErr, err)
+			}
+		})
+	}
+}
+
+func TestEd25519CertificateVerify(t *testing.T) {
+	publicKey, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, err := selfsign.SelfSign(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ed25519Scheme := signaturehash.Algorithm{Hash: hash.Ed25519, Signature: signature.Ed25519}
+
+	message := []byte("handshake bodies")
+	sig, err := generateCertificateVerify(context.Background(), message, key, ed25519Scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Ed25519 signs the transcript itself, not a digest of it
+	if !ed25519.Verify(publicKey, message, sig) {
+		t.Error("CertificateVerify is not a signature of the raw transcript")
+	}
+	if err := verifyCertificateVerify(message, ed25519Scheme, sig, certificate.Certificate, CertificateTypeX509); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestECDSAKeySignatureCurves(t *testing.T) {
+	clientRandom := make([]byte, 32)
+	serverRandom := make([]byte, 32)
+	publicKey := make([]byte, 32)
+	sha512 := signaturehash.Algorithm{Hash: hash.SHA512, Signature: signature.ECDSA}
+
+	t.Run("P521Certificate", func(t *testing.T) {
+		key, err := ecdsa.GenerateKey(ellipticStdlib.P521(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		certificate, err := selfsign.SelfSign(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sig, err := generateKeySignature(context.Background(), clientRandom, serverRandom, publicKey, elliptic.P521, key, sha512)
+		if err != nil {
+			t.Fatal(err)
+		}
+		message := valueKeyMessage(clientRandom, serverRandom, publicKey, elliptic.P521)
+		if err := verifyKeySignature(message, sig, sha512, certificate.Certificate, CertificateTypeX509); err != nil {
+			t.Error(err)
+		}
+	})
+
+	for curve, namedCurve := range map[elliptic.Curve]asn1.ObjectIdentifier{
+		elliptic.BrainpoolP256r1: oidNamedCurveBrainpoolP256r1,
+		elliptic.BrainpoolP384r1: oidNamedCurveBrainpoolP384r1,
+	} {
+		curve, namedCurve := curve, namedCurve
+		t.Run(curve.EllipticCurve().Params().Name+"RawPublicKey", func(t *testing.T) {
+			key, err := ecdsa.GenerateKey(curve.EllipticCurve(), rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parameters, err := asn1.Marshal(namedCurve)
+			if err != nil {
+				t.Fatal(err)
+			}
+			point := ellipticStdlib.Marshal(key.Curve, key.X, key.Y) //nolint:staticcheck
+			rawPublicKey, err := asn1.Marshal(subjectPublicKeyInfo{
+				Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyECDSA, Parameters: asn1.RawValue{FullBytes: parameters}},
+				PublicKey: asn1.BitString{Bytes: point, BitLength: 8 * len(point)},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sig, err := generateKeySignature(context.Background(), clientRandom, serverRandom, publicKey, curve, key, sha512)
+			if err != nil {
+				t.Fatal(err)
+			}
+			message := valueKeyMessage(clientRandom, serverRandom, publicKey, curve)
+			if err := verifyKeySignature(message, sig, sha512, [][]byte{rawPublicKey}, CertificateTypeRawPublicKey); err != nil {
+				t.Error(err)
+			}
+
+			message[0] ^= 0xff
+			if err := verifyKeySignature(message, sig, sha512, [][]byte{rawPublicKey}, CertificateTypeRawPublicKey); !errors.Is(err, errKeySignatureMismatch) {
+				t.Errorf("Expected error: %v, got: %v", errKeySignatureMismatch, err)
+			}
+		})
+	}
+}
+
+// testSigner hides the type of a private key, like a key held by a hardware
+// token, which is only available as a crypto.Signer
+type testSigner struct {
+	crypto.Signer
+}
+
+// testContextSigner signs after delay, unless ctx is done first
+type testContextSigner struct {
+	crypto.Signer
+	delay time.Duration
+}
+
+func (s *testContextSigner) SignContext(ctx context.Context, rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	select {
+	case <-time.After(s.delay):
+		return s.Sign(rand, digest, opts)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestSigner(t *testing.T) {
+	certificate, err := selfsign.GenerateSelfSigned()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, ok := certificate.PrivateKey.(crypto.Signer)
+	if !ok {
+		t.Fatal("Private key is not a crypto.Signer")
+	}
+	message := []byte("handshake bodies")
+	sha256 := signaturehash.Algorithm{Hash: hash.SHA256, Signature: signature.ECDSA}
+
+	for name, test := range map[string]struct {
+		signer  crypto.Signer
+		timeout time.Duration
+		wantErr error
+	}{
+		"Signer": {
+			signer:  &testSigner{key},
+			timeout: time.Second,
+		},
+		"ContextSigner": {
+			signer:  &testContextSigner{Signer: key},
+			timeout: time.Second,
+		},
+		"ContextSignerTimeout": {
+			signer:  &testContextSigner{Signer: key, delay: time.Second},
+			timeout: 10 * time.Millisecond,
+			wantErr: context.DeadlineExceeded,
+		},
+	} {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			if _, err := signaturehash.SelectSignatureScheme([]signaturehash.Algorithm{sha256}, test.signer); err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), test.timeout)
+			defer cancel()
+			sig, err := generateCertificateVerify(ctx, message, test.signer, sha256)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("Expected error: %v, got: %v", test.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if err := verifyCertificateVerify(message, sha256, sig, certificate.Certificate, CertificateTypeX509); err != nil {
+				t.Error(err)
+			}
+		})
 	}
 }
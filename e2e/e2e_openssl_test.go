@@ -169,6 +169,8 @@ func ciphersOpenSSL(cfg *dtls.Config) string {
 	translate := map[dtls.CipherSuiteID]string{
 		dtls.TLS_ECDHE_ECDSA_WITH_AES_128_CCM:        "ECDHE-ECDSA-AES128-CCM",
 		dtls.TLS_ECDHE_ECDSA_WITH_AES_128_CCM_8:      "ECDHE-ECDSA-AES128-CCM8",
+		dtls.TLS_ECDHE_ECDSA_WITH_AES_256_CCM:        "ECDHE-ECDSA-AES256-CCM",
+		dtls.TLS_ECDHE_ECDSA_WITH_AES_256_CCM_8:      "ECDHE-ECDSA-AES256-CCM8",
 		dtls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256: "ECDHE-ECDSA-AES128-GCM-SHA256",
 		dtls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256:   "ECDHE-RSA-AES128-GCM-SHA256",
 		dtls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384: "ECDHE-ECDSA-AES256-GCM-SHA384",
@@ -177,12 +179,26 @@ func ciphersOpenSSL(cfg *dtls.Config) string {
 		dtls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA: "ECDHE-ECDSA-AES256-SHA",
 		dtls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA:   "ECDHE-RSA-AES256-SHA",
 
+		dtls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256: "ECDHE-ECDSA-AES128-SHA256",
+		dtls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA384: "ECDHE-ECDSA-AES256-SHA384",
+		dtls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256:   "ECDHE-RSA-AES128-SHA256",
+		dtls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA384:   "ECDHE-RSA-AES256-SHA384",
+
 		dtls.TLS_PSK_WITH_AES_128_CCM:        "PSK-AES128-CCM",
 		dtls.TLS_PSK_WITH_AES_128_CCM_8:      "PSK-AES128-CCM8",
 		dtls.TLS_PSK_WITH_AES_256_CCM_8:      "PSK-AES256-CCM8",
 		dtls.TLS_PSK_WITH_AES_128_GCM_SHA256: "PSK-AES128-GCM-SHA256",
+		dtls.TLS_PSK_WITH_AES_256_CCM:        "PSK-AES256-CCM",
+		dtls.TLS_PSK_WITH_AES_256_GCM_SHA384: "PSK-AES256-GCM-SHA384",
+		dtls.TLS_PSK_WITH_AES_256_CBC_SHA384: "PSK-AES256-CBC-SHA384",
 
 		dtls.TLS_ECDHE_PSK_WITH_AES_128_CBC_SHA256: "ECDHE-PSK-AES128-CBC-SHA256",
+		dtls.TLS_ECDHE_PSK_WITH_AES_256_CBC_SHA384: "ECDHE-PSK-AES256-CBC-SHA384",
+
+		dtls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256: "ECDHE-ECDSA-CHACHA20-POLY1305",
+		dtls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256:   "ECDHE-RSA-CHACHA20-POLY1305",
+		dtls.TLS_PSK_WITH_CHACHA20_POLY1305_SHA256:         "PSK-CHACHA20-POLY1305",
+		dtls.TLS_ECDHE_PSK_WITH_CHACHA20_POLY1305_SHA256:   "ECDHE-PSK-CHACHA20-POLY1305",
 	}
 
 	var ciphers []string
@@ -229,6 +229,11 @@ func testPionE2ESimple(t *testing.T, server, client func(*comm)) {
 		dtls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
 		dtls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
 		dtls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+		dtls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+		dtls.TLS_ECDHE_ECDSA_WITH_AES_256_CCM,
+		dtls.TLS_ECDHE_ECDSA_WITH_AES_256_CCM_8,
+		dtls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256,
+		dtls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA384,
 	} {
 		cipherSuite := cipherSuite
 		t.Run(cipherSuite.String(), func(t *testing.T) {
@@ -253,19 +258,29 @@ func testPionE2ESimple(t *testing.T, server, client func(*comm)) {
 }
 
 func testPionE2ESimplePSK(t *testing.T, server, client func(*comm)) {
+	testPionE2ESimplePSKCipherSuites(t, server, client, []dtls.CipherSuiteID{
+		dtls.TLS_PSK_WITH_AES_128_CCM,
+		dtls.TLS_PSK_WITH_AES_128_CCM_8,
+		dtls.TLS_PSK_WITH_AES_256_CCM_8,
+		dtls.TLS_PSK_WITH_AES_128_GCM_SHA256,
+		dtls.TLS_ECDHE_PSK_WITH_AES_128_CBC_SHA256,
+		dtls.TLS_PSK_WITH_CHACHA20_POLY1305_SHA256,
+		dtls.TLS_ECDHE_PSK_WITH_CHACHA20_POLY1305_SHA256,
+		dtls.TLS_PSK_WITH_AES_256_CCM,
+		dtls.TLS_PSK_WITH_AES_256_GCM_SHA384,
+		dtls.TLS_PSK_WITH_AES_256_CBC_SHA384,
+		dtls.TLS_ECDHE_PSK_WITH_AES_256_CBC_SHA384,
+	})
+}
+
+func testPionE2ESimplePSKCipherSuites(t *testing.T, server, client func(*comm), cipherSuites []dtls.CipherSuiteID) {
 	lim := test.TimeOut(time.Second * 30)
 	defer lim.Stop()
 
 	report := test.CheckRoutines(t)
 	defer report()
 
-	for _, cipherSuite := range []dtls.CipherSuiteID{
-		dtls.TLS_PSK_WITH_AES_128_CCM,
-		dtls.TLS_PSK_WITH_AES_128_CCM_8,
-		dtls.TLS_PSK_WITH_AES_256_CCM_8,
-		dtls.TLS_PSK_WITH_AES_128_GCM_SHA256,
-		dtls.TLS_ECDHE_PSK_WITH_AES_128_CBC_SHA256,
-	} {
+	for _, cipherSuite := range cipherSuites {
 		cipherSuite := cipherSuite
 		t.Run(cipherSuite.String(), func(t *testing.T) {
 			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
@@ -328,6 +343,14 @@ func TestPionE2ESimplePSK(t *testing.T) {
 	testPionE2ESimplePSK(t, serverPion, clientPion)
 }
 
+func TestPionE2ESimpleECDHEPSK(t *testing.T) {
+	// OpenSSL doesn't implement the ECDHE_PSK AEAD cipher suites of RFC 8442
+	testPionE2ESimplePSKCipherSuites(t, serverPion, clientPion, []dtls.CipherSuiteID{
+		dtls.TLS_ECDHE_PSK_WITH_AES_128_GCM_SHA256,
+		dtls.TLS_ECDHE_PSK_WITH_AES_128_CCM_SHA256,
+	})
+}
+
 func TestPionE2EMTUs(t *testing.T) {
 	testPionE2EMTUs(t, serverPion, clientPion)
 }
@@ -0,0 +1,72 @@
+package dtls
+
+import (
+	"github.com/pion/dtls/v2/pkg/crypto/ecjpake"
+	"github.com/pion/dtls/v2/pkg/protocol/alert"
+	"github.com/pion/dtls/v2/pkg/protocol/extension"
+)
+
+func isECJPAKECipherSuite(c CipherSuite) bool {
+	return c.KeyExchangeAlgorithm().Has(CipherSuiteKeyExchangeAlgorithmEcjpake)
+}
+
+// clientECJPAKEExtensions returns the ecjpake_key_kp_pair extension a client
+// offers EC J-PAKE cipher suites with. The keys of round one are generated
+// once per handshake, so a ClientHello answering a HelloVerifyRequest
+// carries the same keys.
+func clientECJPAKEExtensions(state *State, cfg *handshakeConfig) ([]extension.Extension, error) {
+	if cfg.localECJPAKEPassphrase == nil {
+		return nil, nil
+	}
+
+	var offered bool
+	for _, c := range cfg.localCipherSuites {
+		if isECJPAKECipherSuite(c) {
+			offered = true
+			break
+		}
+	}
+	if !offered {
+		return nil, nil
+	}
+
+	if state.ecjpake == nil {
+		var err error
+		if state.ecjpake, err = ecjpake.New(ecjpake.Client, cfg.localECJPAKEPassphrase); err != nil {
+			return nil, err
+		}
+	}
+	return []extension.Extension{&extension.ECJPAKEKeyKPPair{KeyKPPairList: state.ecjpake.RoundOne()}}, nil
+}
+
+// handleECJPAKERoundOne reads the round one of the peer from the
+// ecjpake_key_kp_pair extension of its hello, nil if absent. It is only
+// required once an EC J-PAKE cipher suite has been selected.
+func handleECJPAKERoundOne(state *State, cfg *handshakeConfig, role ecjpake.Role, keyKPPair *extension.ECJPAKEKeyKPPair) (*alert.Alert, error) {
+	if !isECJPAKECipherSuite(state.cipherSuite) {
+		return nil, nil //nolint:nilnil
+	}
+	if keyKPPair == nil {
+		return &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, errNoECJPAKEKeyKPPair
+	}
+
+	if state.ecjpake == nil {
+		var err error
+		if state.ecjpake, err = ecjpake.New(role, cfg.localECJPAKEPassphrase); err != nil {
+			return &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
+		}
+	}
+	if err := state.ecjpake.ReadRoundOne(keyKPPair.KeyKPPairList); err != nil {
+		return &alert.Alert{Level: alert.Fatal, Description: alert.IllegalParameter}, err
+	}
+	return nil, nil //nolint:nilnil
+}
+
+// ecjpakePreMasterSecret reads the round two of the peer, and derives the
+// premaster secret from it
+func ecjpakePreMasterSecret(state *State, keyKP []byte) ([]byte, error) {
+	if err := state.ecjpake.ReadRoundTwo(keyKP); err != nil {
+		return nil, err
+	}
+	return state.ecjpake.PreMasterSecret()
+}
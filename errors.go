@@ -15,6 +15,9 @@ import (
 // Typed errors
 var (
 	ErrConnClosed = &FatalError{Err: errors.New("conn is closed")} //nolint:goerr113
+	// ErrUnknownPSKIdentity is returned by a PSK callback for an identity
+	// without a pre-shared key, and sends the unknown_psk_identity alert
+	ErrUnknownPSKIdentity = &FatalError{Err: errors.New("unknown PSK identity")} //nolint:goerr113
 
 	errDeadlineExceeded   = &TimeoutError{Err: fmt.Errorf("read/write timeout: %w", context.DeadlineExceeded)}
 	errInvalidContentType = &TemporaryError{Err: errors.New("invalid content type")} //nolint:goerr113
@@ -25,35 +28,69 @@ var (
 	errReservedExportKeyingMaterial = &TemporaryError{Err: errors.New("ExportKeyingMaterial can not be used with a reserved label")} //nolint:goerr113
 	errApplicationDataEpochZero     = &TemporaryError{Err: errors.New("ApplicationData with epoch of 0")}                            //nolint:goerr113
 	errUnhandledContextType         = &TemporaryError{Err: errors.New("unhandled contentType")}                                      //nolint:goerr113
-
-	errCertificateVerifyNoCertificate    = &FatalError{Err: errors.New("client sent certificate verify but we have no certificate to verify")}                      //nolint:goerr113
-	errCipherSuiteNoIntersection         = &FatalError{Err: errors.New("client+server do not support any shared cipher suites")}                                    //nolint:goerr113
-	errClientCertificateNotVerified      = &FatalError{Err: errors.New("client sent certificate but did not verify it")}                                            //nolint:goerr113
-	errClientCertificateRequired         = &FatalError{Err: errors.New("server required client verification, but got none")}                                        //nolint:goerr113
-	errClientNoMatchingSRTPProfile       = &FatalError{Err: errors.New("server responded with SRTP Profile we do not support")}                                     //nolint:goerr113
-	errClientRequiredButNoServerEMS      = &FatalError{Err: errors.New("client required Extended Master Secret extension, but server does not support it")}         //nolint:goerr113
-	errCookieMismatch                    = &FatalError{Err: errors.New("client+server cookie does not match")}                                                      //nolint:goerr113
-	errIdentityNoPSK                     = &FatalError{Err: errors.New("PSK Identity Hint provided but PSK is nil")}                                                //nolint:goerr113
-	errInvalidCertificate                = &FatalError{Err: errors.New("no certificate provided")}                                                                  //nolint:goerr113
-	errInvalidCipherSuite                = &FatalError{Err: errors.New("invalid or unknown cipher suite")}                                                          //nolint:goerr113
-	errInvalidECDSASignature             = &FatalError{Err: errors.New("ECDSA signature contained zero or negative values")}                                        //nolint:goerr113
-	errInvalidPrivateKey                 = &FatalError{Err: errors.New("invalid private key type")}                                                                 //nolint:goerr113
-	errInvalidSignatureAlgorithm         = &FatalError{Err: errors.New("invalid signature algorithm")}                                                              //nolint:goerr113
-	errKeySignatureMismatch              = &FatalError{Err: errors.New("expected and actual key signature do not match")}                                           //nolint:goerr113
-	errNilNextConn                       = &FatalError{Err: errors.New("Conn can not be created with a nil nextConn")}                                              //nolint:goerr113
-	errNoAvailableCipherSuites           = &FatalError{Err: errors.New("connection can not be created, no CipherSuites satisfy this Config")}                       //nolint:goerr113
-	errNoAvailablePSKCipherSuite         = &FatalError{Err: errors.New("connection can not be created, pre-shared key present but no compatible CipherSuite")}      //nolint:goerr113
-	errNoAvailableCertificateCipherSuite = &FatalError{Err: errors.New("connection can not be created, certificate present but no compatible CipherSuite")}         //nolint:goerr113
-	errNoAvailableSignatureSchemes       = &FatalError{Err: errors.New("connection can not be created, no SignatureScheme satisfy this Config")}                    //nolint:goerr113
-	errNoCertificates                    = &FatalError{Err: errors.New("no certificates configured")}                                                               //nolint:goerr113
-	errNoConfigProvided                  = &FatalError{Err: errors.New("no config provided")}                                                                       //nolint:goerr113
-	errNoSupportedEllipticCurves         = &FatalError{Err: errors.New("client requested zero or more elliptic curves that are not supported by the server")}       //nolint:goerr113
-	errUnsupportedProtocolVersion        = &FatalError{Err: errors.New("unsupported protocol version")}                                                             //nolint:goerr113
-	errPSKAndIdentityMustBeSetForClient  = &FatalError{Err: errors.New("PSK and PSK Identity Hint must both be set for client")}                                    //nolint:goerr113
-	errRequestedButNoSRTPExtension       = &FatalError{Err: errors.New("SRTP support was requested but server did not respond with use_srtp extension")}            //nolint:goerr113
-	errServerNoMatchingSRTPProfile       = &FatalError{Err: errors.New("client requested SRTP but we have no matching profiles")}                                   //nolint:goerr113
-	errServerRequiredButNoClientEMS      = &FatalError{Err: errors.New("server requires the Extended Master Secret extension, but the client does not support it")} //nolint:goerr113
-	errVerifyDataMismatch                = &FatalError{Err: errors.New("expected and actual verify data does not match")}                                           //nolint:goerr113
+	errRecordOverflow               = &TemporaryError{Err: errors.New("record is larger than the record size limit")}                //nolint:goerr113
+	errRecordSizeLimitExceeded      = &TemporaryError{Err: errors.New("data is larger than the record size limit of the peer")}      //nolint:goerr113
+
+	errCertificateVerifyNoCertificate    = &FatalError{Err: errors.New("client sent certificate verify but we have no certificate to verify")}                       //nolint:goerr113
+	errCipherSuiteNoIntersection         = &FatalError{Err: errors.New("client+server do not support any shared cipher suites")}                                     //nolint:goerr113
+	errClientCertificateNotVerified      = &FatalError{Err: errors.New("client sent certificate but did not verify it")}                                             //nolint:goerr113
+	errClientCertificateRequired         = &FatalError{Err: errors.New("server required client verification, but got none")}                                         //nolint:goerr113
+	errClientNoMatchingSRTPProfile       = &FatalError{Err: errors.New("server responded with SRTP Profile we do not support")}                                      //nolint:goerr113
+	errClientRequiredButNoServerEMS      = &FatalError{Err: errors.New("client required Extended Master Secret extension, but server does not support it")}          //nolint:goerr113
+	errClientRequiredButNoServerEtM      = &FatalError{Err: errors.New("client required Encrypt-then-MAC extension, but server does not support it")}                //nolint:goerr113
+	errCookieMismatch                    = &FatalError{Err: errors.New("client+server cookie does not match")}                                                       //nolint:goerr113
+	errIdentityNoPSK                     = &FatalError{Err: errors.New("PSK Identity Hint provided but PSK is nil")}                                                 //nolint:goerr113
+	errInvalidCertificate                = &FatalError{Err: errors.New("no certificate provided")}                                                                   //nolint:goerr113
+	errInvalidCertificateType            = &FatalError{Err: errors.New("invalid or unknown certificate type")}                                                       //nolint:goerr113
+	errInvalidCipherSuite                = &FatalError{Err: errors.New("invalid or unknown cipher suite")}                                                           //nolint:goerr113
+	errInvalidMaxFragmentLength          = &FatalError{Err: errors.New("invalid or unknown max fragment length")}                                                    //nolint:goerr113
+	errInvalidRecordSizeExtension        = &FatalError{Err: errors.New("invalid record_size_limit or max_fragment_length extension from peer")}                      //nolint:goerr113
+	errInvalidRecordSizeLimit            = &FatalError{Err: errors.New("record size limit must be between 64 and 16384 bytes")}                                      //nolint:goerr113
+	errInvalidEncryptThenMAC             = &FatalError{Err: errors.New("server selected Encrypt-then-MAC for a cipher suite it does not apply to")}                  //nolint:goerr113
+	errInvalidECDSASignature             = &FatalError{Err: errors.New("ECDSA signature contained zero or negative values")}                                         //nolint:goerr113
+	errInvalidECDSAPublicKey             = &FatalError{Err: errors.New("ECDSA public key is not on the curve")}                                                      //nolint:goerr113
+	errInvalidPrivateKey                 = &FatalError{Err: errors.New("invalid private key type")}                                                                  //nolint:goerr113
+	errInvalidSignatureAlgorithm         = &FatalError{Err: errors.New("invalid signature algorithm")}                                                               //nolint:goerr113
+	errKeySignatureMismatch              = &FatalError{Err: errors.New("expected and actual key signature do not match")}                                            //nolint:goerr113
+	errNilNextConn                       = &FatalError{Err: errors.New("Conn can not be created with a nil nextConn")}                                               //nolint:goerr113
+	errNoAvailableCipherSuites           = &FatalError{Err: errors.New("connection can not be created, no CipherSuites satisfy this Config")}                        //nolint:goerr113
+	errNoAvailablePSKCipherSuite         = &FatalError{Err: errors.New("connection can not be created, pre-shared key present but no compatible CipherSuite")}       //nolint:goerr113
+	errNoAvailablePasswordCipherSuite    = &FatalError{Err: errors.New("connection can not be created, EC J-PAKE passphrase present but no compatible CipherSuite")} //nolint:goerr113
+	errNoECJPAKEKeyKPPair                = &FatalError{Err: errors.New("EC J-PAKE cipher suite selected without the ecjpake_key_kp_pair extension")}                 //nolint:goerr113
+	errNoAvailableCertificateCipherSuite = &FatalError{Err: errors.New("connection can not be created, certificate present but no compatible CipherSuite")}          //nolint:goerr113
+	errNoAvailableSignatureSchemes       = &FatalError{Err: errors.New("connection can not be created, no SignatureScheme satisfy this Config")}                     //nolint:goerr113
+	errCertificateRevoked                = &FatalError{Err: errors.New("certificate revoked")}                                                                       //nolint:goerr113
+	errInvalidOCSPResponse               = &FatalError{Err: errors.New("invalid OCSP response")}                                                                     //nolint:goerr113
+	errUnexpectedCertificateStatus       = &FatalError{Err: errors.New("server sent a certificate status that was not requested")}                                   //nolint:goerr113
+	errNoOCSPResponse                    = &FatalError{Err: errors.New("no OCSP response stapled")}                                                                  //nolint:goerr113
+	errNoOCSPIssuer                      = &FatalError{Err: errors.New("no issuer to verify OCSP response")}                                                         //nolint:goerr113
+	errOCSPResponseNotGood               = &FatalError{Err: errors.New("OCSP response status is not good")}                                                          //nolint:goerr113
+	errOCSPResponseExpired               = &FatalError{Err: errors.New("OCSP response is not current")}                                                              //nolint:goerr113
+	errCertificateListed                 = &FatalError{Err: errors.New("certificate is listed in a CRL")}                                                            //nolint:goerr113
+	errCRLNotCurrent                     = &FatalError{Err: errors.New("CRL is not current")}                                                                        //nolint:goerr113
+	errNoCertificates                    = &FatalError{Err: errors.New("no certificates configured")}                                                                //nolint:goerr113
+	errPSKAuthLockedOut                  = &FatalError{Err: errors.New("PSK identity or address locked out after failed authentications")}                           //nolint:goerr113
+	errHandshakeDropped                  = &FatalError{Err: errors.New("handshake dropped by the admission control of the listener")}                                //nolint:goerr113
+	errHandshakeRejected                 = &FatalError{Err: errors.New("handshake rejected by the admission control of the listener")}                               //nolint:goerr113
+	errListenerClosed                    = &FatalError{Err: errors.New("listener closed")}                                                                           //nolint:goerr113
+	errCertificateTypeNotAccepted        = &FatalError{Err: errors.New("the key of the certificate is not of a type accepted by the server")}                        //nolint:goerr113
+	errCertificateAuthorityNotAccepted   = &FatalError{Err: errors.New("the certificate is not issued by an authority accepted by the server")}                      //nolint:goerr113
+	errCertificateCurveNotSupported      = &FatalError{Err: errors.New("the curve of the certificate key is not supported by the client")}                           //nolint:goerr113
+	errNoConfigProvided                  = &FatalError{Err: errors.New("no config provided")}                                                                        //nolint:goerr113
+	errNoCookieSecrets                   = &FatalError{Err: errors.New("cookie secret set must contain at least one secret")}                                        //nolint:goerr113
+	errNoSessionTicketKeys               = &FatalError{Err: errors.New("session ticket key set must contain at least one key")}                                      //nolint:goerr113
+	errNoRawPublicKeyVerifier            = &FatalError{Err: errors.New("raw public keys are accepted, but VerifyPeerRawPublicKey is not set")}                       //nolint:goerr113
+	errInvalidEllipticCurve              = &FatalError{Err: errors.New("unsupported elliptic curve")}                                                                //nolint:goerr113
+	errInvalidNamedCurve                 = &FatalError{Err: errors.New("server selected an elliptic curve that was not offered")}                                    //nolint:goerr113
+	errNoSupportedEllipticCurves         = &FatalError{Err: errors.New("client requested zero or more elliptic curves that are not supported by the server")}        //nolint:goerr113
+	errUnsupportedCertificateType        = &FatalError{Err: errors.New("no certificate type is supported by both client+server")}                                    //nolint:goerr113
+	errUnsupportedProtocolVersion        = &FatalError{Err: errors.New("unsupported protocol version")}                                                              //nolint:goerr113
+	errPSKAndIdentityMustBeSetForClient  = &FatalError{Err: errors.New("PSK and PSK Identity Hint must both be set for client")}                                     //nolint:goerr113
+	errRequestedButNoSRTPExtension       = &FatalError{Err: errors.New("SRTP support was requested but server did not respond with use_srtp extension")}             //nolint:goerr113
+	errServerNoMatchingSRTPProfile       = &FatalError{Err: errors.New("client requested SRTP but we have no matching profiles")}                                    //nolint:goerr113
+	errServerRequiredButNoClientEMS      = &FatalError{Err: errors.New("server requires the Extended Master Secret extension, but the client does not support it")}  //nolint:goerr113
+	errServerRequiredButNoClientEtM      = &FatalError{Err: errors.New("server requires the Encrypt-then-MAC extension, but the client does not support it")}        //nolint:goerr113
+	errVerifyDataMismatch                = &FatalError{Err: errors.New("expected and actual verify data does not match")}                                            //nolint:goerr113
 
 	errInvalidFlight                     = &InternalError{Err: errors.New("invalid flight number")}                           //nolint:goerr113
 	errKeySignatureGenerateUnimplemented = &InternalError{Err: errors.New("unable to generate key signature, unimplemented")} //nolint:goerr113
@@ -3,7 +3,9 @@ package dtls
 import (
 	"context"
 	"crypto/rand"
+	"time"
 
+	"github.com/pion/dtls/v2/pkg/crypto/ecjpake"
 	"github.com/pion/dtls/v2/pkg/crypto/elliptic"
 	"github.com/pion/dtls/v2/pkg/protocol"
 	"github.com/pion/dtls/v2/pkg/protocol/alert"
@@ -12,7 +14,8 @@ import (
 )
 
 func flight0Parse(ctx context.Context, c flightConn, state *State, cache *handshakeCache, cfg *handshakeConfig) (flightVal, *alert.Alert, error) {
-	seq, msgs, ok := cache.fullPullMap(0, state.cipherSuite,
+	// The ClientHello may answer a HelloVerifyRequest sent statelessly by the listener
+	seq, msgs, ok := cache.fullPullMap(cfg.clientHelloSequence, state,
 		handshakeCachePullRule{handshake.TypeClientHello, cfg.initialEpoch, true, false},
 	)
 	if !ok {
@@ -34,24 +37,56 @@ func flight0Parse(ctx context.Context, c flightConn, state *State, cache *handsh
 
 	state.remoteRandom = clientHello.Random
 
-	cipherSuites := []CipherSuite{}
-	for _, id := range clientHello.CipherSuiteIDs {
-		if c := cipherSuiteForID(CipherSuiteID(id), cfg.customCipherSuites); c != nil {
-			cipherSuites = append(cipherSuites, c)
+	clientHelloInfo := newClientHelloInfo(c.RemoteAddr(), cfg, clientHello)
+	state.clientHelloInfo = clientHelloInfo
+	if cfg.admitHandshake != nil {
+		decision := cfg.admitHandshake(clientHelloInfo)
+		cfg.admitHandshake = nil
+		if decision != AdmissionAccept {
+			alertPtr, err := admissionError(decision)
+			return 0, alertPtr, err
 		}
 	}
-
-	if state.cipherSuite, ok = findMatchingCipherSuite(cipherSuites, cfg.localCipherSuites); !ok {
-		return 0, &alert.Alert{Level: alert.Fatal, Description: alert.InsufficientSecurity}, errCipherSuiteNoIntersection
+	if cfg.getConfigForClient != nil {
+		// The ClientHello repeated after a HelloVerifyRequest keeps the
+		// Config chosen for the first one
+		config, err := cfg.getConfigForClient(clientHelloInfo)
+		cfg.getConfigForClient = nil
+		if err != nil {
+			return 0, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
+		}
+		if config != nil {
+			if err := validateConfig(config); err != nil {
+				return 0, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
+			}
+			if err := cfg.setConfig(config); err != nil {
+				return 0, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
+			}
+			state.namedCurve = defaultCurvePreferences(cfg.ellipticCurves)[0]
+		}
 	}
 
+	state.remoteConnectionID = nil
+	state.useSessionTicket = false
+	state.remoteRequestedCertificateStatus = false
+	state.remoteSignatureSchemes = nil
+	var sessionTicket []byte
+	var clientCertificateTypes, serverCertificateTypes []CertificateType
+	var encryptThenMAC bool
+	var recordSizeLimit *extension.RecordSizeLimit
+	var maxFragmentLength *extension.MaxFragmentLength
+	var ecjpakeKeyKPPair *extension.ECJPAKEKeyKPPair
 	for _, val := range clientHello.Extensions {
 		switch e := val.(type) {
 		case *extension.SupportedEllipticCurves:
-			if len(e.EllipticCurves) == 0 {
-				return 0, &alert.Alert{Level: alert.Fatal, Description: alert.InsufficientSecurity}, errNoSupportedEllipticCurves
+			// Without a common curve, only cipher suites without ECDHE can
+			// be negotiated, the curve is zero
+			localCurves := defaultCurvePreferences(cfg.ellipticCurves)
+			if cfg.preferServerCipherSuites {
+				state.namedCurve, _ = findMatchingEllipticCurve(localCurves, e.EllipticCurves)
+			} else {
+				state.namedCurve, _ = findMatchingEllipticCurve(e.EllipticCurves, localCurves)
 			}
-			state.namedCurve = e.EllipticCurves[0]
 		case *extension.UseSRTP:
 			profile, ok := findMatchingSRTPProfile(e.ProtectionProfiles, cfg.localSRTPProtectionProfiles)
 			if !ok {
@@ -62,18 +97,102 @@ func flight0Parse(ctx context.Context, c flightConn, state *State, cache *handsh
 			if cfg.extendedMasterSecret != DisableExtendedMasterSecret {
 				state.extendedMasterSecret = true
 			}
+		case *extension.EncryptThenMAC:
+			encryptThenMAC = cfg.encryptThenMAC != DisableEncryptThenMAC
 		case *extension.ServerName:
 			state.serverName = e.ServerName // remote server name
 		case *extension.ALPN:
 			state.peerSupportedProtocols = e.ProtocolNameList
+		case *extension.SessionTicket:
+			if cfg.sessionTicketKeys != nil {
+				state.useSessionTicket = true
+				sessionTicket = e.Ticket
+			}
+		case *extension.ClientCertificateType:
+			clientCertificateTypes = e.CertificateTypes
+		case *extension.ServerCertificateType:
+			serverCertificateTypes = e.CertificateTypes
+		case *extension.StatusRequest:
+			state.remoteRequestedCertificateStatus = e.StatusType == extension.StatusTypeOCSP
+		case *extension.SupportedSignatureAlgorithms:
+			state.remoteSignatureSchemes = e.SignatureHashAlgorithms
+		case *extension.RecordSizeLimit:
+			recordSizeLimit = e
+		case *extension.MaxFragmentLength:
+			maxFragmentLength = e
+		case *extension.ECJPAKEKeyKPPair:
+			ecjpakeKeyKPPair = e
+		case *extension.ConnectionID:
+			// Only use a connection ID if the server supports them
+			if cfg.connectionIDGenerator != nil {
+				state.remoteConnectionID = e.CID
+			}
+		}
+	}
+
+	// The cipher suite must authenticate with the key of the certificate,
+	// which is selected first. RFC 5246 Section 7.4.3
+	localCipherSuites, alertPtr, err := selectServerCertificate(state, cfg, clientHelloInfo)
+	if err != nil {
+		return 0, alertPtr, err
+	}
+
+	cipherSuites := []CipherSuite{}
+	for _, id := range clientHello.CipherSuiteIDs {
+		if c := cipherSuiteForID(CipherSuiteID(id), cfg.customCipherSuites); c != nil {
+			cipherSuites = append(cipherSuites, c)
 		}
 	}
 
+	if cfg.preferServerCipherSuites {
+		state.cipherSuite, ok = findMatchingCipherSuite(localCipherSuites, cipherSuites)
+	} else {
+		state.cipherSuite, ok = findMatchingCipherSuite(cipherSuites, localCipherSuites)
+	}
+	if !ok {
+		return 0, &alert.Alert{Level: alert.Fatal, Description: alert.InsufficientSecurity}, errCipherSuiteNoIntersection
+	}
+	if state.namedCurve == 0 && state.cipherSuite.KeyExchangeAlgorithm().Has(CipherSuiteKeyExchangeAlgorithmEcdhe) {
+		return 0, &alert.Alert{Level: alert.Fatal, Description: alert.InsufficientSecurity}, errNoSupportedEllipticCurves
+	}
+
+	// Generate the connection ID the client must send to us, unless the
+	// client or the cipher suite does not support connection IDs
+	if _, ok := state.cipherSuite.(connectionIDCipherSuite); !ok {
+		state.remoteConnectionID = nil
+	}
+	if state.remoteConnectionID == nil {
+		state.setLocalConnectionID(nil)
+	} else if state.getLocalConnectionID() == nil {
+		state.setLocalConnectionID(cfg.connectionIDGenerator())
+	}
+
 	if cfg.extendedMasterSecret == RequireExtendedMasterSecret && !state.extendedMasterSecret {
 		return 0, &alert.Alert{Level: alert.Fatal, Description: alert.InsufficientSecurity}, errServerRequiredButNoClientEMS
 	}
 
-	if state.localKeypair == nil {
+	// Encrypt-then-MAC only applies to CBC cipher suites. RFC 7366 Section 2
+	_, isCBC := state.cipherSuite.(encryptThenMACCipherSuite)
+	if isCBC && !encryptThenMAC && cfg.encryptThenMAC == RequireEncryptThenMAC {
+		return 0, &alert.Alert{Level: alert.Fatal, Description: alert.InsufficientSecurity}, errServerRequiredButNoClientEtM
+	}
+	state.setEncryptThenMAC(isCBC && encryptThenMAC)
+
+	if alertPtr, err := negotiateRecordSize(state, cfg, recordSizeLimit, maxFragmentLength); err != nil {
+		return 0, alertPtr, err
+	}
+
+	if alertPtr, err := handleECJPAKERoundOne(state, cfg, ecjpake.Server, ecjpakeKeyKPPair); err != nil {
+		return 0, alertPtr, err
+	}
+
+	if state.cipherSuite.AuthenticationType() == CipherSuiteAuthenticationTypeCertificate {
+		if alertPtr, err := negotiateCertificateTypes(state, cfg, clientCertificateTypes, serverCertificateTypes); err != nil {
+			return 0, alertPtr, err
+		}
+	}
+
+	if state.localKeypair == nil && state.namedCurve != 0 {
 		var err error
 		state.localKeypair, err = elliptic.GenerateKeypair(state.namedCurve)
 		if err != nil {
@@ -81,33 +200,57 @@ func flight0Parse(ctx context.Context, c flightConn, state *State, cache *handsh
 		}
 	}
 
-	return handleHelloResume(clientHello.SessionID, state, cfg, flight2)
+	next := flight2
+	if cfg.skipHelloVerify {
+		next = flight4
+	}
+	return handleHelloResume(ctx, clientHello.SessionID, sessionTicket, state, cfg, next)
 }
 
-func handleHelloResume(sessionID []byte, state *State, cfg *handshakeConfig, next flightVal) (flightVal, *alert.Alert, error) {
-	if len(sessionID) > 0 && cfg.sessionStore != nil {
+func handleHelloResume(ctx context.Context, sessionID, sessionTicket []byte, state *State, cfg *handshakeConfig, next flightVal) (flightVal, *alert.Alert, error) {
+	if len(sessionID) == 0 {
+		return next, nil, nil
+	}
+
+	var secret []byte
+	state.sessionCreatedAt = time.Time{}
+	// A ticket which can't be decrypted or resumed is ignored, and a full
+	// handshake is performed instead. RFC 5077 Section 3.4
+	if len(sessionTicket) > 0 && cfg.sessionTicketKeys != nil {
+		if s, ok := cfg.sessionTicketKeys.decrypt(sessionTicket); ok && s.resumable(state, time.Now(), cfg.sessionTicketLifetime) &&
+			(s.identity == nil || pskIdentityResumable(ctx, state, cfg, s.identity)) {
+			cfg.log.Tracef("[handshake] resume session from ticket: %x", sessionID)
+			secret = s.masterSecret
+			state.sessionCreatedAt = s.createdAt
+			state.IdentityHint = s.identity
+		}
+	}
+	if secret == nil && cfg.sessionStore != nil {
 		if s, err := cfg.sessionStore.Get(sessionID); err != nil {
 			return 0, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
 		} else if s.ID != nil {
 			cfg.log.Tracef("[handshake] resume session: %x", sessionID)
+			secret = s.Secret
+		}
+	}
+	if secret == nil {
+		return next, nil, nil
+	}
 
-			state.SessionID = sessionID
-			state.masterSecret = s.Secret
+	state.SessionID = sessionID
+	state.masterSecret = secret
 
-			if err := state.initCipherSuite(); err != nil {
-				return 0, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
-			}
+	if err := state.initCipherSuite(); err != nil {
+		return 0, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
+	}
 
-			clientRandom := state.localRandom.MarshalFixed()
-			cfg.writeKeyLog(keyLogLabelTLS12, clientRandom[:], state.masterSecret)
+	clientRandom := state.localRandom.MarshalFixed()
+	cfg.writeKeyLog(keyLogLabelTLS12, clientRandom[:], state.masterSecret)
 
-			return flight4b, nil, nil
-		}
-	}
-	return next, nil, nil
+	return flight4b, nil, nil
 }
 
-func flight0Generate(c flightConn, state *State, cache *handshakeCache, cfg *handshakeConfig) ([]*packet, *alert.Alert, error) {
+func flight0Generate(ctx context.Context, c flightConn, state *State, cache *handshakeCache, cfg *handshakeConfig) ([]*packet, *alert.Alert, error) {
 	// Initialize
 	state.cookie = make([]byte, cookieLength)
 	if _, err := rand.Read(state.cookie); err != nil {
@@ -117,7 +260,9 @@ func flight0Generate(c flightConn, state *State, cache *handshakeCache, cfg *han
 	var zeroEpoch uint16
 	state.localEpoch.Store(zeroEpoch)
 	state.remoteEpoch.Store(zeroEpoch)
-	state.namedCurve = defaultNamedCurve
+	// Without the supported_elliptic_curves extension, the client supports
+	// any curve. RFC 8422 Section 4
+	state.namedCurve = defaultCurvePreferences(cfg.ellipticCurves)[0]
 
 	if err := state.localRandom.Populate(); err != nil {
 		return nil, nil, err
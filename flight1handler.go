@@ -14,7 +14,7 @@ import (
 func flight1Parse(ctx context.Context, c flightConn, state *State, cache *handshakeCache, cfg *handshakeConfig) (flightVal, *alert.Alert, error) {
 	// HelloVerifyRequest can be skipped by the server,
 	// so allow ServerHello during flight1 also
-	seq, msgs, ok := cache.fullPullMap(state.handshakeRecvSequence, state.cipherSuite,
+	seq, msgs, ok := cache.fullPullMap(state.handshakeRecvSequence, state,
 		handshakeCachePullRule{handshake.TypeHelloVerifyRequest, cfg.initialEpoch, false, true},
 		handshakeCachePullRule{handshake.TypeServerHello, cfg.initialEpoch, false, true},
 	)
@@ -43,12 +43,14 @@ func flight1Parse(ctx context.Context, c flightConn, state *State, cache *handsh
 	return 0, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, nil
 }
 
-func flight1Generate(c flightConn, state *State, cache *handshakeCache, cfg *handshakeConfig) ([]*packet, *alert.Alert, error) {
+func flight1Generate(ctx context.Context, c flightConn, state *State, cache *handshakeCache, cfg *handshakeConfig) ([]*packet, *alert.Alert, error) {
 	var zeroEpoch uint16
 	state.localEpoch.Store(zeroEpoch)
 	state.remoteEpoch.Store(zeroEpoch)
 	state.namedCurve = defaultNamedCurve
 	state.cookie = nil
+	state.sessionTicket = nil
+	state.ecjpake = nil
 
 	if err := state.localRandom.Populate(); err != nil {
 		return nil, nil, err
@@ -74,7 +76,7 @@ func flight1Generate(c flightConn, state *State, cache *handshakeCache, cfg *han
 	if setEllipticCurveCryptographyClientHelloExtensions {
 		extensions = append(extensions, []extension.Extension{
 			&extension.SupportedEllipticCurves{
-				EllipticCurves: []elliptic.Curve{elliptic.X25519, elliptic.P256, elliptic.P384},
+				EllipticCurves: defaultCurvePreferences(cfg.ellipticCurves),
 			},
 			&extension.SupportedPointFormats{
 				PointFormats: []elliptic.CurvePointFormat{elliptic.CurvePointFormatUncompressed},
@@ -95,6 +97,12 @@ func flight1Generate(c flightConn, state *State, cache *handshakeCache, cfg *han
 		})
 	}
 
+	if cfg.encryptThenMAC != DisableEncryptThenMAC && encryptThenMACSupported(cfg.localCipherSuites) {
+		extensions = append(extensions, &extension.EncryptThenMAC{
+			Supported: true,
+		})
+	}
+
 	if len(cfg.serverName) > 0 {
 		extensions = append(extensions, &extension.ServerName{ServerName: cfg.serverName})
 	}
@@ -103,6 +111,11 @@ func flight1Generate(c flightConn, state *State, cache *handshakeCache, cfg *han
 		extensions = append(extensions, &extension.ALPN{ProtocolNameList: cfg.supportedProtocols})
 	}
 
+	if cfg.offersConnectionID() {
+		state.setLocalConnectionID(cfg.connectionIDGenerator())
+		extensions = append(extensions, &extension.ConnectionID{CID: state.getLocalConnectionID()})
+	}
+
 	if cfg.sessionStore != nil {
 		cfg.log.Tracef("[handshake] try to resume session")
 		if s, err := cfg.sessionStore.Get(c.sessionKey()); err != nil {
@@ -112,7 +125,23 @@ func flight1Generate(c flightConn, state *State, cache *handshakeCache, cfg *han
 
 			state.SessionID = s.ID
 			state.masterSecret = s.Secret
+			state.sessionTicket = s.Ticket
 		}
+		// Request a new ticket even if there is none to offer
+		extensions = append(extensions, &extension.SessionTicket{Ticket: state.sessionTicket})
+	}
+
+	extensions = append(extensions, clientCertificateTypeExtensions(cfg)...)
+	extensions = append(extensions, clientRecordSizeExtensions(cfg)...)
+
+	ecjpakeExtensions, err := clientECJPAKEExtensions(state, cfg)
+	if err != nil {
+		return nil, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
+	}
+	extensions = append(extensions, ecjpakeExtensions...)
+
+	if cfg.verifyOCSPResponse != nil {
+		extensions = append(extensions, &extension.StatusRequest{StatusType: extension.StatusTypeOCSP})
 	}
 
 	return []*packet{
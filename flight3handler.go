@@ -5,6 +5,7 @@ import (
 	"context"
 
 	"github.com/pion/dtls/v2/internal/ciphersuite/types"
+	"github.com/pion/dtls/v2/pkg/crypto/ecjpake"
 	"github.com/pion/dtls/v2/pkg/crypto/elliptic"
 	"github.com/pion/dtls/v2/pkg/crypto/prf"
 	"github.com/pion/dtls/v2/pkg/protocol"
@@ -18,7 +19,7 @@ func flight3Parse(ctx context.Context, c flightConn, state *State, cache *handsh
 	// Clients may receive multiple HelloVerifyRequest messages with different cookies.
 	// Clients SHOULD handle this by sending a new ClientHello with a cookie in response
 	// to the new HelloVerifyRequest. RFC 6347 Section 4.2.1
-	seq, msgs, ok := cache.fullPullMap(state.handshakeRecvSequence, state.cipherSuite,
+	seq, msgs, ok := cache.fullPullMap(state.handshakeRecvSequence, state,
 		handshakeCachePullRule{handshake.TypeHelloVerifyRequest, cfg.initialEpoch, false, true},
 	)
 	if ok {
@@ -34,7 +35,7 @@ func flight3Parse(ctx context.Context, c flightConn, state *State, cache *handsh
 		}
 	}
 
-	_, msgs, ok = cache.fullPullMap(state.handshakeRecvSequence, state.cipherSuite,
+	_, msgs, ok = cache.fullPullMap(state.handshakeRecvSequence, state,
 		handshakeCachePullRule{handshake.TypeServerHello, cfg.initialEpoch, false, false},
 	)
 	if !ok {
@@ -46,6 +47,14 @@ func flight3Parse(ctx context.Context, c flightConn, state *State, cache *handsh
 		if !h.Version.Equal(protocol.Version1_2) {
 			return 0, &alert.Alert{Level: alert.Fatal, Description: alert.ProtocolVersion}, errUnsupportedProtocolVersion
 		}
+		state.useSessionTicket = false
+		state.localCertificateType = CertificateTypeX509
+		state.remoteCertificateType = CertificateTypeX509
+		var encryptThenMAC bool
+		var recordSizeLimit *extension.RecordSizeLimit
+		var maxFragmentLength *extension.MaxFragmentLength
+		var ecjpakeKeyKPPair *extension.ECJPAKEKeyKPPair
+		state.certificateStatusNegotiated = false
 		for _, v := range h.Extensions {
 			switch e := v.(type) {
 			case *extension.UseSRTP:
@@ -58,13 +67,54 @@ func flight3Parse(ctx context.Context, c flightConn, state *State, cache *handsh
 				if cfg.extendedMasterSecret != DisableExtendedMasterSecret {
 					state.extendedMasterSecret = true
 				}
+			case *extension.EncryptThenMAC:
+				encryptThenMAC = true
 			case *extension.ALPN:
 				if len(e.ProtocolNameList) > 1 { // This should be exactly 1, the zero case is handle when unmarshalling
 					return 0, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, extension.ErrALPNInvalidFormat // Meh, internal error?
 				}
 				state.NegotiatedProtocol = e.ProtocolNameList[0]
+			case *extension.ConnectionID:
+				// Only use a connection ID if we offered one
+				if cfg.offersConnectionID() {
+					state.remoteConnectionID = e.CID
+				}
+			case *extension.SessionTicket:
+				// Only expect a ticket if we requested one
+				if cfg.sessionStore != nil {
+					state.useSessionTicket = true
+				}
+			case *extension.ClientCertificateType:
+				if !e.Selected || !containsCertificateType(cfg.localCertificateTypes, e.CertificateTypes[0]) {
+					return 0, &alert.Alert{Level: alert.Fatal, Description: alert.UnsupportedCertificate}, errUnsupportedCertificateType
+				}
+				state.localCertificateType = e.CertificateTypes[0]
+			case *extension.ServerCertificateType:
+				if !e.Selected || !containsCertificateType(cfg.peerCertificateTypes, e.CertificateTypes[0]) {
+					return 0, &alert.Alert{Level: alert.Fatal, Description: alert.UnsupportedCertificate}, errUnsupportedCertificateType
+				}
+				state.remoteCertificateType = e.CertificateTypes[0]
+			case *extension.StatusRequest:
+				// Only expect a certificate status if we requested one
+				if cfg.verifyOCSPResponse == nil {
+					return 0, &alert.Alert{Level: alert.Fatal, Description: alert.UnsupportedExtension}, errUnexpectedCertificateStatus
+				}
+				state.certificateStatusNegotiated = true
+			case *extension.RecordSizeLimit:
+				recordSizeLimit = e
+			case *extension.MaxFragmentLength:
+				maxFragmentLength = e
+			case *extension.ECJPAKEKeyKPPair:
+				ecjpakeKeyKPPair = e
 			}
 		}
+		if alertPtr, err := handleServerRecordSize(state, cfg, recordSizeLimit, maxFragmentLength); err != nil {
+			return 0, alertPtr, err
+		}
+		// If the server doesn't support connection IDs, it will not send them
+		if state.remoteConnectionID == nil {
+			state.setLocalConnectionID(nil)
+		}
 		if cfg.extendedMasterSecret == RequireExtendedMasterSecret && !state.extendedMasterSecret {
 			return 0, &alert.Alert{Level: alert.Fatal, Description: alert.InsufficientSecurity}, errClientRequiredButNoServerEMS
 		}
@@ -86,6 +136,20 @@ func flight3Parse(ctx context.Context, c flightConn, state *State, cache *handsh
 		state.remoteRandom = h.Random
 		cfg.log.Tracef("[handshake] use cipher suite: %s", selectedCipherSuite.String())
 
+		// Encrypt-then-MAC only applies to CBC cipher suites. RFC 7366 Section 2
+		_, isCBC := selectedCipherSuite.(encryptThenMACCipherSuite)
+		switch {
+		case encryptThenMAC && (!isCBC || cfg.encryptThenMAC == DisableEncryptThenMAC):
+			return 0, &alert.Alert{Level: alert.Fatal, Description: alert.IllegalParameter}, errInvalidEncryptThenMAC
+		case isCBC && !encryptThenMAC && cfg.encryptThenMAC == RequireEncryptThenMAC:
+			return 0, &alert.Alert{Level: alert.Fatal, Description: alert.InsufficientSecurity}, errClientRequiredButNoServerEtM
+		}
+		state.setEncryptThenMAC(encryptThenMAC)
+
+		if alertPtr, err := handleECJPAKERoundOne(state, cfg, ecjpake.Client, ecjpakeKeyKPPair); err != nil {
+			return 0, alertPtr, err
+		}
+
 		if len(h.SessionID) > 0 && bytes.Equal(state.SessionID, h.SessionID) {
 			return handleResumption(ctx, c, state, cache, cfg)
 		}
@@ -106,14 +170,18 @@ func flight3Parse(ctx context.Context, c flightConn, state *State, cache *handsh
 		state.masterSecret = []byte{}
 	}
 
-	if cfg.localPSKCallback != nil {
-		seq, msgs, ok = cache.fullPullMap(state.handshakeRecvSequence+1, state.cipherSuite,
+	// The ServerKeyExchange is optional for PSK and custom key exchanges,
+	// which exchange no certificates
+	_, customKeyExchange := state.cipherSuite.(KeyExchangeCipherSuite)
+	if customKeyExchange || cfg.usesPSK() {
+		seq, msgs, ok = cache.fullPullMap(state.handshakeRecvSequence+1, state,
 			handshakeCachePullRule{handshake.TypeServerKeyExchange, cfg.initialEpoch, false, true},
 			handshakeCachePullRule{handshake.TypeServerHelloDone, cfg.initialEpoch, false, false},
 		)
 	} else {
-		seq, msgs, ok = cache.fullPullMap(state.handshakeRecvSequence+1, state.cipherSuite,
+		seq, msgs, ok = cache.fullPullMap(state.handshakeRecvSequence+1, state,
 			handshakeCachePullRule{handshake.TypeCertificate, cfg.initialEpoch, false, true},
+			handshakeCachePullRule{handshake.TypeCertificateStatus, cfg.initialEpoch, false, true},
 			handshakeCachePullRule{handshake.TypeServerKeyExchange, cfg.initialEpoch, false, false},
 			handshakeCachePullRule{handshake.TypeCertificateRequest, cfg.initialEpoch, false, true},
 			handshakeCachePullRule{handshake.TypeServerHelloDone, cfg.initialEpoch, false, false},
@@ -126,20 +194,36 @@ func flight3Parse(ctx context.Context, c flightConn, state *State, cache *handsh
 	state.handshakeRecvSequence = seq
 
 	if h, ok := msgs[handshake.TypeCertificate].(*handshake.MessageCertificate); ok {
+		if !containsCertificateType(cfg.peerCertificateTypes, state.remoteCertificateType) {
+			return 0, &alert.Alert{Level: alert.Fatal, Description: alert.UnsupportedCertificate}, errUnsupportedCertificateType
+		}
 		state.PeerCertificates = h.Certificate
 	} else if state.cipherSuite.AuthenticationType() == CipherSuiteAuthenticationTypeCertificate {
 		return 0, &alert.Alert{Level: alert.Fatal, Description: alert.NoCertificate}, errInvalidCertificate
 	}
 
+	state.ocspResponse = nil
+	if h, ok := msgs[handshake.TypeCertificateStatus].(*handshake.MessageCertificateStatus); ok {
+		// A CertificateStatus is only sent if the ServerHello acknowledged
+		// the status_request, RFC 6066 Section 8
+		if !state.certificateStatusNegotiated {
+			return 0, &alert.Alert{Level: alert.Fatal, Description: alert.UnexpectedMessage}, errUnexpectedCertificateStatus
+		}
+		state.ocspResponse = h.Response
+	}
+
 	if h, ok := msgs[handshake.TypeServerKeyExchange].(*handshake.MessageServerKeyExchange); ok {
-		alertPtr, err := handleServerKeyExchange(c, state, cfg, h)
+		alertPtr, err := handleServerKeyExchange(ctx, c, state, cfg, h)
 		if err != nil {
 			return 0, alertPtr, err
 		}
 	}
 
-	if _, ok := msgs[handshake.TypeCertificateRequest].(*handshake.MessageCertificateRequest); ok {
+	if h, ok := msgs[handshake.TypeCertificateRequest].(*handshake.MessageCertificateRequest); ok {
 		state.remoteRequestedCertificate = true
+		state.remoteSignatureSchemes = h.SignatureHashAlgorithms
+		state.remoteCertificateTypes = h.CertificateTypes
+		state.remoteCertificateAuthorities = h.CertificateAuthorities
 	}
 
 	return flight5, nil, nil
@@ -155,7 +239,8 @@ func handleResumption(ctx context.Context, c flightConn, state *State, cache *ha
 		return 0, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
 	}
 
-	_, msgs, ok := cache.fullPullMap(state.handshakeRecvSequence+1, state.cipherSuite,
+	_, msgs, ok := cache.fullPullMap(state.handshakeRecvSequence+1, state,
+		handshakeCachePullRule{handshake.TypeNewSessionTicket, cfg.initialEpoch, false, !state.useSessionTicket},
 		handshakeCachePullRule{handshake.TypeFinished, cfg.initialEpoch + 1, false, false},
 	)
 	if !ok {
@@ -170,6 +255,7 @@ func handleResumption(ctx context.Context, c flightConn, state *State, cache *ha
 	plainText := cache.pullAndMerge(
 		handshakeCachePullRule{handshake.TypeClientHello, cfg.initialEpoch, true, false},
 		handshakeCachePullRule{handshake.TypeServerHello, cfg.initialEpoch, false, false},
+		handshakeCachePullRule{handshake.TypeNewSessionTicket, cfg.initialEpoch, false, false},
 	)
 
 	expectedVerifyData, err := prf.VerifyDataServer(state.masterSecret, plainText, state.cipherSuite.HashFunc())
@@ -180,43 +266,75 @@ func handleResumption(ctx context.Context, c flightConn, state *State, cache *ha
 		return 0, &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, errVerifyDataMismatch
 	}
 
+	// The server may replace the ticket used for resumption
+	if h, ok := msgs[handshake.TypeNewSessionTicket].(*handshake.MessageNewSessionTicket); ok && len(h.Ticket) > 0 {
+		s := Session{
+			ID:     state.SessionID,
+			Secret: state.masterSecret,
+			Ticket: h.Ticket,
+		}
+		cfg.log.Tracef("[handshake] save new session ticket: %x", s.ID)
+		if err := cfg.sessionStore.Set(c.sessionKey(), s); err != nil {
+			return 0, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
+		}
+	}
+
 	clientRandom := state.localRandom.MarshalFixed()
 	cfg.writeKeyLog(keyLogLabelTLS12, clientRandom[:], state.masterSecret)
 
 	return flight5b, nil, nil
 }
 
-func handleServerKeyExchange(_ flightConn, state *State, cfg *handshakeConfig, h *handshake.MessageServerKeyExchange) (*alert.Alert, error) {
+func handleServerKeyExchange(ctx context.Context, c flightConn, state *State, cfg *handshakeConfig, h *handshake.MessageServerKeyExchange) (*alert.Alert, error) {
 	var err error
 	if state.cipherSuite == nil {
 		return &alert.Alert{Level: alert.Fatal, Description: alert.InsufficientSecurity}, errInvalidCipherSuite
 	}
-	if cfg.localPSKCallback != nil {
+	if state.keyExchange, err = newKeyExchange(ctx, c, state, cfg); err != nil {
+		return &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
+	}
+	if state.keyExchange != nil {
+		if err = state.keyExchange.ProcessServerKeyExchange(h.KeyExchangeData); err != nil {
+			return &alert.Alert{Level: alert.Fatal, Description: alert.IllegalParameter}, err
+		}
+		return nil, nil //nolint:nilnil
+	}
+	// The server must select one of the curves we offered. RFC 8422 Section 5.4
+	if state.cipherSuite.KeyExchangeAlgorithm().Has(types.KeyExchangeAlgorithmEcdhe) {
+		if _, ok := findMatchingEllipticCurve([]elliptic.Curve{h.NamedCurve}, defaultCurvePreferences(cfg.ellipticCurves)); !ok {
+			return &alert.Alert{Level: alert.Fatal, Description: alert.IllegalParameter}, errInvalidNamedCurve
+		}
+	}
+	if isECJPAKECipherSuite(state.cipherSuite) {
+		if h.NamedCurve != elliptic.P256 {
+			return &alert.Alert{Level: alert.Fatal, Description: alert.IllegalParameter}, errInvalidNamedCurve
+		}
+		if state.preMasterSecret, err = ecjpakePreMasterSecret(state, h.ECJPAKEKeyKP); err != nil {
+			return &alert.Alert{Level: alert.Fatal, Description: alert.IllegalParameter}, err
+		}
+		return nil, nil //nolint:nilnil
+	}
+	if cfg.usesPSK() {
 		var psk []byte
-		if psk, err = cfg.localPSKCallback(h.IdentityHint); err != nil {
-			return &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
+		var alertPtr *alert.Alert
+		if state.localPSKIdentity, psk, alertPtr, err = clientPSK(ctx, cfg, h.IdentityHint); err != nil {
+			return alertPtr, err
 		}
 		state.IdentityHint = h.IdentityHint
 		switch state.cipherSuite.KeyExchangeAlgorithm() {
 		case types.KeyExchangeAlgorithmPsk:
 			state.preMasterSecret = prf.PSKPreMasterSecret(psk)
 		case (types.KeyExchangeAlgorithmEcdhe | types.KeyExchangeAlgorithmPsk):
-			if state.localKeypair, err = elliptic.GenerateKeypair(h.NamedCurve); err != nil {
-				return &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
-			}
-			state.preMasterSecret, err = prf.EcdhePSKPreMasterSecret(psk, h.PublicKey, state.localKeypair.PrivateKey, state.localKeypair.Curve)
-			if err != nil {
+			var sharedSecret []byte
+			if state.localKeypair, sharedSecret, err = generateClientKeyShare(h.NamedCurve, h.PublicKey); err != nil {
 				return &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
 			}
+			state.preMasterSecret = prf.EcdhePSKPreMasterSecretFromSharedSecret(psk, sharedSecret)
 		default:
 			return &alert.Alert{Level: alert.Fatal, Description: alert.InsufficientSecurity}, errInvalidCipherSuite
 		}
 	} else {
-		if state.localKeypair, err = elliptic.GenerateKeypair(h.NamedCurve); err != nil {
-			return &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
-		}
-
-		if state.preMasterSecret, err = prf.PreMasterSecret(h.PublicKey, state.localKeypair.PrivateKey, state.localKeypair.Curve); err != nil {
+		if state.localKeypair, state.preMasterSecret, err = generateClientKeyShare(h.NamedCurve, h.PublicKey); err != nil {
 			return &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
 		}
 	}
@@ -224,7 +342,26 @@ func handleServerKeyExchange(_ flightConn, state *State, cfg *handshakeConfig, h
 	return nil, nil //nolint:nilnil
 }
 
-func flight3Generate(c flightConn, state *State, cache *handshakeCache, cfg *handshakeConfig) ([]*packet, *alert.Alert, error) {
+// generateClientKeyShare returns the keypair of the client for the key share
+// of the server, and the shared secret. Hybrid groups encapsulate a secret to
+// the key share of the server instead of a Diffie-Hellman exchange.
+func generateClientKeyShare(curve elliptic.Curve, serverPublicKey []byte) (*elliptic.Keypair, []byte, error) {
+	if curve.Hybrid() {
+		return elliptic.Encapsulate(curve, serverPublicKey)
+	}
+
+	keypair, err := elliptic.GenerateKeypair(curve)
+	if err != nil {
+		return nil, nil, err
+	}
+	sharedSecret, err := prf.PreMasterSecret(serverPublicKey, keypair.PrivateKey, keypair.Curve)
+	if err != nil {
+		return nil, nil, err
+	}
+	return keypair, sharedSecret, nil
+}
+
+func flight3Generate(ctx context.Context, c flightConn, state *State, cache *handshakeCache, cfg *handshakeConfig) ([]*packet, *alert.Alert, error) {
 	extensions := []extension.Extension{
 		&extension.SupportedSignatureAlgorithms{
 			SignatureHashAlgorithms: cfg.localSignatureSchemes,
@@ -236,7 +373,7 @@ func flight3Generate(c flightConn, state *State, cache *handshakeCache, cfg *han
 	if state.namedCurve != 0 {
 		extensions = append(extensions, []extension.Extension{
 			&extension.SupportedEllipticCurves{
-				EllipticCurves: []elliptic.Curve{elliptic.X25519, elliptic.P256, elliptic.P384},
+				EllipticCurves: defaultCurvePreferences(cfg.ellipticCurves),
 			},
 			&extension.SupportedPointFormats{
 				PointFormats: []elliptic.CurvePointFormat{elliptic.CurvePointFormatUncompressed},
@@ -257,6 +394,12 @@ func flight3Generate(c flightConn, state *State, cache *handshakeCache, cfg *han
 		})
 	}
 
+	if cfg.encryptThenMAC != DisableEncryptThenMAC && encryptThenMACSupported(cfg.localCipherSuites) {
+		extensions = append(extensions, &extension.EncryptThenMAC{
+			Supported: true,
+		})
+	}
+
 	if len(cfg.serverName) > 0 {
 		extensions = append(extensions, &extension.ServerName{ServerName: cfg.serverName})
 	}
@@ -265,6 +408,27 @@ func flight3Generate(c flightConn, state *State, cache *handshakeCache, cfg *han
 		extensions = append(extensions, &extension.ALPN{ProtocolNameList: cfg.supportedProtocols})
 	}
 
+	if cfg.offersConnectionID() {
+		extensions = append(extensions, &extension.ConnectionID{CID: state.getLocalConnectionID()})
+	}
+
+	if cfg.sessionStore != nil {
+		extensions = append(extensions, &extension.SessionTicket{Ticket: state.sessionTicket})
+	}
+
+	extensions = append(extensions, clientCertificateTypeExtensions(cfg)...)
+	extensions = append(extensions, clientRecordSizeExtensions(cfg)...)
+
+	ecjpakeExtensions, err := clientECJPAKEExtensions(state, cfg)
+	if err != nil {
+		return nil, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
+	}
+	extensions = append(extensions, ecjpakeExtensions...)
+
+	if cfg.verifyOCSPResponse != nil {
+		extensions = append(extensions, &extension.StatusRequest{StatusType: extension.StatusTypeOCSP})
+	}
+
 	return []*packet{
 		{
 			record: &recordlayer.RecordLayer{
@@ -13,7 +13,7 @@ import (
 )
 
 func flight4bParse(ctx context.Context, c flightConn, state *State, cache *handshakeCache, cfg *handshakeConfig) (flightVal, *alert.Alert, error) {
-	_, msgs, ok := cache.fullPullMap(state.handshakeRecvSequence, state.cipherSuite,
+	_, msgs, ok := cache.fullPullMap(state.handshakeRecvSequence, state,
 		handshakeCachePullRule{handshake.TypeFinished, cfg.initialEpoch + 1, true, false},
 	)
 	if !ok {
@@ -29,6 +29,7 @@ func flight4bParse(ctx context.Context, c flightConn, state *State, cache *hands
 	plainText := cache.pullAndMerge(
 		handshakeCachePullRule{handshake.TypeClientHello, cfg.initialEpoch, true, false},
 		handshakeCachePullRule{handshake.TypeServerHello, cfg.initialEpoch, false, false},
+		handshakeCachePullRule{handshake.TypeNewSessionTicket, cfg.initialEpoch, false, false},
 		handshakeCachePullRule{handshake.TypeFinished, cfg.initialEpoch + 1, false, false},
 	)
 
@@ -44,7 +45,7 @@ func flight4bParse(ctx context.Context, c flightConn, state *State, cache *hands
 	return flight4b, nil, nil
 }
 
-func flight4bGenerate(c flightConn, state *State, cache *handshakeCache, cfg *handshakeConfig) ([]*packet, *alert.Alert, error) {
+func flight4bGenerate(ctx context.Context, c flightConn, state *State, cache *handshakeCache, cfg *handshakeConfig) ([]*packet, *alert.Alert, error) {
 	var pkts []*packet
 
 	extensions := []extension.Extension{&extension.RenegotiationInfo{
@@ -56,12 +57,29 @@ func flight4bGenerate(c flightConn, state *State, cache *handshakeCache, cfg *ha
 			Supported: true,
 		})
 	}
+	if state.encryptThenMAC {
+		extensions = append(extensions, &extension.EncryptThenMAC{
+			Supported: true,
+		})
+	}
 	if state.srtpProtectionProfile != 0 {
 		extensions = append(extensions, &extension.UseSRTP{
 			ProtectionProfiles: []SRTPProtectionProfile{state.srtpProtectionProfile},
 		})
 	}
 
+	if state.remoteConnectionID != nil {
+		extensions = append(extensions, &extension.ConnectionID{CID: state.getLocalConnectionID()})
+	}
+
+	if state.useSessionTicket {
+		extensions = append(extensions, &extension.SessionTicket{})
+	}
+
+	if state.recordSizeExtension != nil {
+		extensions = append(extensions, state.recordSizeExtension)
+	}
+
 	selectedProto, err := extension.ALPNProtocolSelection(cfg.supportedProtocols, state.peerSupportedProtocols)
 	if err != nil {
 		return nil, &alert.Alert{Level: alert.Fatal, Description: alert.NoApplicationProtocol}, err
@@ -87,6 +105,14 @@ func flight4bGenerate(c flightConn, state *State, cache *handshakeCache, cfg *ha
 
 	serverHello.Header.MessageSequence = uint16(state.handshakeSendSequence)
 
+	var newSessionTicket *handshake.Handshake
+	if state.useSessionTicket {
+		if newSessionTicket, err = newSessionTicketHandshake(state, cfg); err != nil {
+			return nil, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
+		}
+		newSessionTicket.Header.MessageSequence = uint16(state.handshakeSendSequence + 1)
+	}
+
 	if len(state.localVerifyData) == 0 {
 		plainText := cache.pullAndMerge(
 			handshakeCachePullRule{handshake.TypeClientHello, cfg.initialEpoch, true, false},
@@ -97,6 +123,14 @@ func flight4bGenerate(c flightConn, state *State, cache *handshakeCache, cfg *ha
 		}
 		plainText = append(plainText, raw...)
 
+		if newSessionTicket != nil {
+			raw, err = newSessionTicket.Marshal()
+			if err != nil {
+				return nil, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
+			}
+			plainText = append(plainText, raw...)
+		}
+
 		state.localVerifyData, err = prf.VerifyDataServer(state.masterSecret, plainText, state.cipherSuite.HashFunc())
 		if err != nil {
 			return nil, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
@@ -112,6 +146,20 @@ func flight4bGenerate(c flightConn, state *State, cache *handshakeCache, cfg *ha
 				Content: serverHello,
 			},
 		},
+	)
+
+	if newSessionTicket != nil {
+		pkts = append(pkts, &packet{
+			record: &recordlayer.RecordLayer{
+				Header: recordlayer.Header{
+					Version: protocol.Version1_2,
+				},
+				Content: newSessionTicket,
+			},
+		})
+	}
+
+	pkts = append(pkts,
 		&packet{
 			record: &recordlayer.RecordLayer{
 				Header: recordlayer.Header{
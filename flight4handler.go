@@ -1,9 +1,12 @@
 package dtls
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
+	"crypto/tls"
 	"crypto/x509"
+	"errors"
 
 	"github.com/pion/dtls/v2/pkg/crypto/clientcertificate"
 	"github.com/pion/dtls/v2/pkg/crypto/elliptic"
@@ -17,7 +20,7 @@ import (
 )
 
 func flight4Parse(ctx context.Context, c flightConn, state *State, cache *handshakeCache, cfg *handshakeConfig) (flightVal, *alert.Alert, error) { //nolint:gocognit
-	seq, msgs, ok := cache.fullPullMap(state.handshakeRecvSequence, state.cipherSuite,
+	seq, msgs, ok := cache.fullPullMap(state.handshakeRecvSequence, state,
 		handshakeCachePullRule{handshake.TypeCertificate, cfg.initialEpoch, true, true},
 		handshakeCachePullRule{handshake.TypeClientKeyExchange, cfg.initialEpoch, true, false},
 		handshakeCachePullRule{handshake.TypeCertificateVerify, cfg.initialEpoch, true, true},
@@ -34,6 +37,9 @@ func flight4Parse(ctx context.Context, c flightConn, state *State, cache *handsh
 	}
 
 	if h, hasCert := msgs[handshake.TypeCertificate].(*handshake.MessageCertificate); hasCert {
+		if len(h.Certificate) > 0 && !containsCertificateType(cfg.peerCertificateTypes, state.remoteCertificateType) {
+			return 0, &alert.Alert{Level: alert.Fatal, Description: alert.UnsupportedCertificate}, errUnsupportedCertificateType
+		}
 		state.PeerCertificates = h.Certificate
 		// If the client offer its certificate, just disable session resumption.
 		// Otherwise, we have to store the certificate identitfication and expire time.
@@ -52,6 +58,7 @@ func flight4Parse(ctx context.Context, c flightConn, state *State, cache *handsh
 			handshakeCachePullRule{handshake.TypeClientHello, cfg.initialEpoch, true, false},
 			handshakeCachePullRule{handshake.TypeServerHello, cfg.initialEpoch, false, false},
 			handshakeCachePullRule{handshake.TypeCertificate, cfg.initialEpoch, false, false},
+			handshakeCachePullRule{handshake.TypeCertificateStatus, cfg.initialEpoch, false, false},
 			handshakeCachePullRule{handshake.TypeServerKeyExchange, cfg.initialEpoch, false, false},
 			handshakeCachePullRule{handshake.TypeCertificateRequest, cfg.initialEpoch, false, false},
 			handshakeCachePullRule{handshake.TypeServerHelloDone, cfg.initialEpoch, false, false},
@@ -71,19 +78,30 @@ func flight4Parse(ctx context.Context, c flightConn, state *State, cache *handsh
 			return 0, &alert.Alert{Level: alert.Fatal, Description: alert.InsufficientSecurity}, errNoAvailableSignatureSchemes
 		}
 
-		if err := verifyCertificateVerify(plainText, h.HashAlgorithm, h.Signature, state.PeerCertificates); err != nil {
+		signatureHashAlgorithm := signaturehash.Algorithm{Hash: h.HashAlgorithm, Signature: h.SignatureAlgorithm}
+		if err := verifyCertificateVerify(plainText, signatureHashAlgorithm, h.Signature, state.PeerCertificates, state.remoteCertificateType); err != nil {
 			return 0, &alert.Alert{Level: alert.Fatal, Description: alert.BadCertificate}, err
 		}
 		var chains [][]*x509.Certificate
 		var err error
 		var verified bool
-		if cfg.clientAuth >= VerifyClientCertIfGiven {
-			if chains, err = verifyClientCert(state.PeerCertificates, cfg.clientCAs); err != nil {
+		switch {
+		case state.remoteCertificateType == CertificateTypeRawPublicKey:
+			// A raw public key has no chain, it is only trusted by the callback
+			if err = cfg.verifyPeerRawPublicKey(state.PeerCertificates[0]); err != nil {
+				return 0, &alert.Alert{Level: alert.Fatal, Description: alert.BadCertificate}, err
+			}
+			verified = true
+		case cfg.clientAuth >= VerifyClientCertIfGiven:
+			if chains, err = verifyClientCert(state.PeerCertificates, cfg.clientCAs, cfg.revocationChecker); err != nil {
+				if errors.Is(err, errCertificateRevoked) {
+					return 0, &alert.Alert{Level: alert.Fatal, Description: alert.CertificateRevoked}, err
+				}
 				return 0, &alert.Alert{Level: alert.Fatal, Description: alert.BadCertificate}, err
 			}
 			verified = true
 		}
-		if cfg.verifyPeerCertificate != nil {
+		if cfg.verifyPeerCertificate != nil && state.remoteCertificateType == CertificateTypeX509 {
 			if err := cfg.verifyPeerCertificate(state.PeerCertificates, chains); err != nil {
 				return 0, &alert.Alert{Level: alert.Fatal, Description: alert.BadCertificate}, err
 			}
@@ -97,10 +115,15 @@ func flight4Parse(ctx context.Context, c flightConn, state *State, cache *handsh
 
 		var err error
 		var preMasterSecret []byte
-		if state.cipherSuite.AuthenticationType() == CipherSuiteAuthenticationTypePreSharedKey {
+		if state.keyExchange != nil {
+			if preMasterSecret, err = state.keyExchange.ProcessClientKeyExchange(clientKeyExchange.KeyExchangeData); err != nil {
+				return 0, &alert.Alert{Level: alert.Fatal, Description: alert.IllegalParameter}, err
+			}
+		} else if state.cipherSuite.AuthenticationType() == CipherSuiteAuthenticationTypePreSharedKey {
 			var psk []byte
-			if psk, err = cfg.localPSKCallback(clientKeyExchange.IdentityHint); err != nil {
-				return 0, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
+			var alertPtr *alert.Alert
+			if psk, alertPtr, err = serverPSK(ctx, state, cfg, clientKeyExchange.IdentityHint); err != nil {
+				return 0, alertPtr, err
 			}
 			state.IdentityHint = clientKeyExchange.IdentityHint
 			switch state.cipherSuite.KeyExchangeAlgorithm() {
@@ -113,6 +136,10 @@ func flight4Parse(ctx context.Context, c flightConn, state *State, cache *handsh
 			default:
 				return 0, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, errInvalidCipherSuite
 			}
+		} else if state.cipherSuite.AuthenticationType() == CipherSuiteAuthenticationTypePassword {
+			if preMasterSecret, err = ecjpakePreMasterSecret(state, clientKeyExchange.ECJPAKEKeyKP); err != nil {
+				return 0, &alert.Alert{Level: alert.Fatal, Description: alert.IllegalParameter}, err
+			}
 		} else {
 			preMasterSecret, err = prf.PreMasterSecret(clientKeyExchange.PublicKey, state.localKeypair.PrivateKey, state.localKeypair.Curve)
 			if err != nil {
@@ -160,7 +187,7 @@ func flight4Parse(ctx context.Context, c flightConn, state *State, cache *handsh
 		return 0, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
 	}
 
-	seq, msgs, ok = cache.fullPullMap(seq, state.cipherSuite,
+	seq, msgs, ok = cache.fullPullMap(seq, state,
 		handshakeCachePullRule{handshake.TypeFinished, cfg.initialEpoch + 1, true, false},
 	)
 	if !ok {
@@ -169,11 +196,37 @@ func flight4Parse(ctx context.Context, c flightConn, state *State, cache *handsh
 	}
 	state.handshakeRecvSequence = seq
 
-	if _, ok = msgs[handshake.TypeFinished].(*handshake.MessageFinished); !ok {
+	var finished *handshake.MessageFinished
+	if finished, ok = msgs[handshake.TypeFinished].(*handshake.MessageFinished); !ok {
 		return 0, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, nil
 	}
 
-	if state.cipherSuite.AuthenticationType() == CipherSuiteAuthenticationTypeAnonymous {
+	plainText := cache.pullAndMerge(
+		handshakeCachePullRule{handshake.TypeClientHello, cfg.initialEpoch, true, false},
+		handshakeCachePullRule{handshake.TypeServerHello, cfg.initialEpoch, false, false},
+		handshakeCachePullRule{handshake.TypeCertificate, cfg.initialEpoch, false, false},
+		handshakeCachePullRule{handshake.TypeCertificateStatus, cfg.initialEpoch, false, false},
+		handshakeCachePullRule{handshake.TypeServerKeyExchange, cfg.initialEpoch, false, false},
+		handshakeCachePullRule{handshake.TypeCertificateRequest, cfg.initialEpoch, false, false},
+		handshakeCachePullRule{handshake.TypeServerHelloDone, cfg.initialEpoch, false, false},
+		handshakeCachePullRule{handshake.TypeCertificate, cfg.initialEpoch, true, false},
+		handshakeCachePullRule{handshake.TypeClientKeyExchange, cfg.initialEpoch, true, false},
+		handshakeCachePullRule{handshake.TypeCertificateVerify, cfg.initialEpoch, true, false},
+	)
+	expectedVerifyData, err := prf.VerifyDataClient(state.masterSecret, plainText, state.cipherSuite.HashFunc())
+	if err != nil {
+		return 0, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
+	}
+	if !bytes.Equal(expectedVerifyData, finished.VerifyData) {
+		// The client doesn't know the pre-shared key of its identity
+		reportPSKAuth(state, cfg, false)
+		return 0, &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, errVerifyDataMismatch
+	}
+
+	// Neither anonymous nor password authenticated cipher suites request a
+	// client certificate
+	if state.cipherSuite.AuthenticationType() == CipherSuiteAuthenticationTypeAnonymous ||
+		state.cipherSuite.AuthenticationType() == CipherSuiteAuthenticationTypePassword {
 		return flight6, nil, nil
 	}
 
@@ -200,7 +253,7 @@ func flight4Parse(ctx context.Context, c flightConn, state *State, cache *handsh
 	return flight6, nil, nil
 }
 
-func flight4Generate(c flightConn, state *State, cache *handshakeCache, cfg *handshakeConfig) ([]*packet, *alert.Alert, error) {
+func flight4Generate(ctx context.Context, c flightConn, state *State, cache *handshakeCache, cfg *handshakeConfig) ([]*packet, *alert.Alert, error) {
 	extensions := []extension.Extension{&extension.RenegotiationInfo{
 		RenegotiatedConnection: 0,
 	}}
@@ -210,16 +263,53 @@ func flight4Generate(c flightConn, state *State, cache *handshakeCache, cfg *han
 			Supported: true,
 		})
 	}
+	if state.encryptThenMAC {
+		extensions = append(extensions, &extension.EncryptThenMAC{
+			Supported: true,
+		})
+	}
 	if state.srtpProtectionProfile != 0 {
 		extensions = append(extensions, &extension.UseSRTP{
 			ProtectionProfiles: []SRTPProtectionProfile{state.srtpProtectionProfile},
 		})
 	}
-	if state.cipherSuite.AuthenticationType() == CipherSuiteAuthenticationTypeCertificate {
+	if state.cipherSuite.AuthenticationType() == CipherSuiteAuthenticationTypeCertificate || isECJPAKECipherSuite(state.cipherSuite) {
 		extensions = append(extensions, &extension.SupportedPointFormats{
 			PointFormats: []elliptic.CurvePointFormat{elliptic.CurvePointFormatUncompressed},
 		})
 	}
+	if isECJPAKECipherSuite(state.cipherSuite) {
+		extensions = append(extensions, &extension.ECJPAKEKeyKPPair{KeyKPPairList: state.ecjpake.RoundOne()})
+	}
+
+	if state.remoteConnectionID != nil {
+		extensions = append(extensions, &extension.ConnectionID{CID: state.getLocalConnectionID()})
+	}
+
+	if state.useSessionTicket {
+		extensions = append(extensions, &extension.SessionTicket{})
+	}
+
+	extensions = append(extensions, state.certificateTypeExtensions...)
+
+	if state.recordSizeExtension != nil {
+		extensions = append(extensions, state.recordSizeExtension)
+	}
+
+	var certificate *tls.Certificate
+	if state.cipherSuite.AuthenticationType() == CipherSuiteAuthenticationTypeCertificate {
+		if certificate = state.localCertificate; certificate == nil {
+			return nil, &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, errNoCertificates
+		}
+	}
+
+	// Only staple an OCSP response if the client asked for one, and the
+	// response belongs to the X.509 certificate sent. RFC 6066 Section 8
+	stapleOCSP := certificate != nil && state.remoteRequestedCertificateStatus &&
+		state.localCertificateType == CertificateTypeX509 && len(certificate.OCSPStaple) > 0
+	if stapleOCSP {
+		extensions = append(extensions, &extension.StatusRequest{})
+	}
 
 	selectedProto, err := extension.ALPNProtocolSelection(cfg.supportedProtocols, state.peerSupportedProtocols)
 	if err != nil {
@@ -232,6 +322,10 @@ func flight4Generate(c flightConn, state *State, cache *handshakeCache, cfg *han
 		state.NegotiatedProtocol = selectedProto
 	}
 
+	if state.keyExchange, err = newKeyExchange(ctx, c, state, cfg); err != nil {
+		return nil, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
+	}
+
 	var pkts []*packet
 	cipherSuiteID := uint16(state.cipherSuite.ID())
 
@@ -261,35 +355,67 @@ func flight4Generate(c flightConn, state *State, cache *handshakeCache, cfg *han
 	})
 
 	switch {
+	case state.keyExchange != nil:
+		payload, err := state.keyExchange.ServerKeyExchange()
+		if err != nil {
+			return nil, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
+		}
+		if payload != nil {
+			pkts = append(pkts, &packet{
+				record: &recordlayer.RecordLayer{
+					Header: recordlayer.Header{
+						Version: protocol.Version1_2,
+					},
+					Content: &handshake.Handshake{
+						Message: &handshake.MessageServerKeyExchange{
+							KeyExchangeData: payload,
+						},
+					},
+				},
+			})
+		}
 	case state.cipherSuite.AuthenticationType() == CipherSuiteAuthenticationTypeCertificate:
-		certificate, err := cfg.getCertificate(state.serverName)
+		certificateMsg, err := certificateMessage(certificate.Certificate, state.localCertificateType)
 		if err != nil {
-			return nil, &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, err
+			return nil, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
 		}
-
 		pkts = append(pkts, &packet{
 			record: &recordlayer.RecordLayer{
 				Header: recordlayer.Header{
 					Version: protocol.Version1_2,
 				},
 				Content: &handshake.Handshake{
-					Message: &handshake.MessageCertificate{
-						Certificate: certificate.Certificate,
-					},
+					Message: certificateMsg,
 				},
 			},
 		})
 
+		if stapleOCSP {
+			pkts = append(pkts, &packet{
+				record: &recordlayer.RecordLayer{
+					Header: recordlayer.Header{
+						Version: protocol.Version1_2,
+					},
+					Content: &handshake.Handshake{
+						Message: &handshake.MessageCertificateStatus{
+							StatusType: extension.StatusTypeOCSP,
+							Response:   certificate.OCSPStaple,
+						},
+					},
+				},
+			})
+		}
+
 		serverRandom := state.localRandom.MarshalFixed()
 		clientRandom := state.remoteRandom.MarshalFixed()
 
 		// Find compatible signature scheme
-		signatureHashAlgo, err := signaturehash.SelectSignatureScheme(cfg.localSignatureSchemes, certificate.PrivateKey)
+		signatureHashAlgo, err := selectSignatureScheme(cfg.localSignatureSchemes, state.remoteSignatureSchemes, certificate.Certificate, certificate.PrivateKey)
 		if err != nil {
 			return nil, &alert.Alert{Level: alert.Fatal, Description: alert.InsufficientSecurity}, err
 		}
 
-		signature, err := generateKeySignature(clientRandom[:], serverRandom[:], state.localKeypair.PublicKey, state.namedCurve, certificate.PrivateKey, signatureHashAlgo.Hash)
+		signature, err := generateKeySignature(ctx, clientRandom[:], serverRandom[:], state.localKeypair.PublicKey, state.namedCurve, certificate.PrivateKey, signatureHashAlgo)
 		if err != nil {
 			return nil, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
 		}
@@ -314,6 +440,12 @@ func flight4Generate(c flightConn, state *State, cache *handshakeCache, cfg *han
 		})
 
 		if cfg.clientAuth > NoClientCert {
+			// Clients holding several identities select the one issued by
+			// an authority we verify. RFC 5246 Section 7.4.4
+			var certificateAuthorities [][]byte
+			if cfg.clientCAs != nil && state.remoteCertificateType == CertificateTypeX509 {
+				certificateAuthorities = cfg.clientCAs.Subjects() //nolint:staticcheck
+			}
 			pkts = append(pkts, &packet{
 				record: &recordlayer.RecordLayer{
 					Header: recordlayer.Header{
@@ -323,11 +455,33 @@ func flight4Generate(c flightConn, state *State, cache *handshakeCache, cfg *han
 						Message: &handshake.MessageCertificateRequest{
 							CertificateTypes:        []clientcertificate.Type{clientcertificate.RSASign, clientcertificate.ECDSASign},
 							SignatureHashAlgorithms: cfg.localSignatureSchemes,
+							CertificateAuthorities:  certificateAuthorities,
 						},
 					},
 				},
 			})
 		}
+	case isECJPAKECipherSuite(state.cipherSuite):
+		// The round two of EC J-PAKE replaces the public key of ECDHE, and
+		// is only defined for secp256r1
+		roundTwo, err := state.ecjpake.RoundTwo()
+		if err != nil {
+			return nil, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
+		}
+		pkts = append(pkts, &packet{
+			record: &recordlayer.RecordLayer{
+				Header: recordlayer.Header{
+					Version: protocol.Version1_2,
+				},
+				Content: &handshake.Handshake{
+					Message: &handshake.MessageServerKeyExchange{
+						EllipticCurveType: elliptic.CurveTypeNamedCurve,
+						NamedCurve:        elliptic.P256,
+						ECJPAKEKeyKP:      roundTwo,
+					},
+				},
+			},
+		})
 	case cfg.localPSKIdentityHint != nil || state.cipherSuite.KeyExchangeAlgorithm().Has(CipherSuiteKeyExchangeAlgorithmEcdhe):
 		// To help the client in selecting which identity to use, the server
 		// can provide a "PSK identity hint" in the ServerKeyExchange message.
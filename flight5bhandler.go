@@ -11,7 +11,7 @@ import (
 )
 
 func flight5bParse(ctx context.Context, c flightConn, state *State, cache *handshakeCache, cfg *handshakeConfig) (flightVal, *alert.Alert, error) {
-	_, msgs, ok := cache.fullPullMap(state.handshakeRecvSequence-1, state.cipherSuite,
+	_, msgs, ok := cache.fullPullMap(state.handshakeRecvSequence-1, state,
 		handshakeCachePullRule{handshake.TypeFinished, cfg.initialEpoch + 1, false, false},
 	)
 	if !ok {
@@ -27,7 +27,7 @@ func flight5bParse(ctx context.Context, c flightConn, state *State, cache *hands
 	return flight5b, nil, nil
 }
 
-func flight5bGenerate(c flightConn, state *State, cache *handshakeCache, cfg *handshakeConfig) ([]*packet, *alert.Alert, error) { //nolint:gocognit
+func flight5bGenerate(ctx context.Context, c flightConn, state *State, cache *handshakeCache, cfg *handshakeConfig) ([]*packet, *alert.Alert, error) { //nolint:gocognit
 	var pkts []*packet
 
 	pkts = append(pkts,
@@ -44,6 +44,7 @@ func flight5bGenerate(c flightConn, state *State, cache *handshakeCache, cfg *ha
 		plainText := cache.pullAndMerge(
 			handshakeCachePullRule{handshake.TypeClientHello, cfg.initialEpoch, true, false},
 			handshakeCachePullRule{handshake.TypeServerHello, cfg.initialEpoch, false, false},
+			handshakeCachePullRule{handshake.TypeNewSessionTicket, cfg.initialEpoch, false, false},
 			handshakeCachePullRule{handshake.TypeFinished, cfg.initialEpoch + 1, false, false},
 		)
 
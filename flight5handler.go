@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"context"
 	"crypto"
+	"crypto/rand"
 	"crypto/x509"
+	"errors"
 
 	"github.com/pion/dtls/v2/pkg/crypto/prf"
 	"github.com/pion/dtls/v2/pkg/crypto/signaturehash"
@@ -15,7 +17,8 @@ import (
 )
 
 func flight5Parse(ctx context.Context, c flightConn, state *State, cache *handshakeCache, cfg *handshakeConfig) (flightVal, *alert.Alert, error) {
-	_, msgs, ok := cache.fullPullMap(state.handshakeRecvSequence, state.cipherSuite,
+	_, msgs, ok := cache.fullPullMap(state.handshakeRecvSequence, state,
+		handshakeCachePullRule{handshake.TypeNewSessionTicket, cfg.initialEpoch, false, !state.useSessionTicket},
 		handshakeCachePullRule{handshake.TypeFinished, cfg.initialEpoch + 1, false, false},
 	)
 	if !ok {
@@ -31,6 +34,7 @@ func flight5Parse(ctx context.Context, c flightConn, state *State, cache *handsh
 		handshakeCachePullRule{handshake.TypeClientHello, cfg.initialEpoch, true, false},
 		handshakeCachePullRule{handshake.TypeServerHello, cfg.initialEpoch, false, false},
 		handshakeCachePullRule{handshake.TypeCertificate, cfg.initialEpoch, false, false},
+		handshakeCachePullRule{handshake.TypeCertificateStatus, cfg.initialEpoch, false, false},
 		handshakeCachePullRule{handshake.TypeServerKeyExchange, cfg.initialEpoch, false, false},
 		handshakeCachePullRule{handshake.TypeCertificateRequest, cfg.initialEpoch, false, false},
 		handshakeCachePullRule{handshake.TypeServerHelloDone, cfg.initialEpoch, false, false},
@@ -38,6 +42,7 @@ func flight5Parse(ctx context.Context, c flightConn, state *State, cache *handsh
 		handshakeCachePullRule{handshake.TypeClientKeyExchange, cfg.initialEpoch, true, false},
 		handshakeCachePullRule{handshake.TypeCertificateVerify, cfg.initialEpoch, true, false},
 		handshakeCachePullRule{handshake.TypeFinished, cfg.initialEpoch + 1, true, false},
+		handshakeCachePullRule{handshake.TypeNewSessionTicket, cfg.initialEpoch, false, false},
 	)
 
 	expectedVerifyData, err := prf.VerifyDataServer(state.masterSecret, plainText, state.cipherSuite.HashFunc())
@@ -48,10 +53,25 @@ func flight5Parse(ctx context.Context, c flightConn, state *State, cache *handsh
 		return 0, &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, errVerifyDataMismatch
 	}
 
+	var ticket []byte
+	if h, ok := msgs[handshake.TypeNewSessionTicket].(*handshake.MessageNewSessionTicket); ok {
+		ticket = h.Ticket
+	}
+
+	// A ticket is resumed by offering it along with a session ID of our
+	// choosing, which the server echoes when it accepts the ticket
+	if len(ticket) > 0 && len(state.SessionID) == 0 {
+		state.SessionID = make([]byte, sessionLength)
+		if _, err := rand.Read(state.SessionID); err != nil {
+			return 0, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
+		}
+	}
+
 	if len(state.SessionID) > 0 {
 		s := Session{
 			ID:     state.SessionID,
 			Secret: state.masterSecret,
+			Ticket: ticket,
 		}
 		cfg.log.Tracef("[handshake] save new session: %x", s.ID)
 		if err := cfg.sessionStore.Set(c.sessionKey(), s); err != nil {
@@ -62,13 +82,14 @@ func flight5Parse(ctx context.Context, c flightConn, state *State, cache *handsh
 	return flight5, nil, nil
 }
 
-func flight5Generate(c flightConn, state *State, cache *handshakeCache, cfg *handshakeConfig) ([]*packet, *alert.Alert, error) { //nolint:gocognit
+func flight5Generate(ctx context.Context, c flightConn, state *State, cache *handshakeCache, cfg *handshakeConfig) ([]*packet, *alert.Alert, error) { //nolint:gocognit
 	var certBytes [][]byte
 	var privateKey crypto.PrivateKey
-	if len(cfg.localCertificates) > 0 {
-		certificate, err := cfg.getCertificate(cfg.serverName)
+	// Without a common type of certificate, an empty certificate is sent
+	if state.remoteRequestedCertificate && containsCertificateType(cfg.localCertificateTypes, state.localCertificateType) {
+		certificate, err := cfg.getClientCertificate(newCertificateRequestInfo(state, cfg))
 		if err != nil {
-			return nil, &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, err
+			return nil, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
 		}
 		certBytes = certificate.Certificate
 		privateKey = certificate.PrivateKey
@@ -77,6 +98,10 @@ func flight5Generate(c flightConn, state *State, cache *handshakeCache, cfg *han
 	var pkts []*packet
 
 	if state.remoteRequestedCertificate {
+		certificateMsg, err := certificateMessage(certBytes, state.localCertificateType)
+		if err != nil {
+			return nil, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
+		}
 		pkts = append(pkts,
 			&packet{
 				record: &recordlayer.RecordLayer{
@@ -84,51 +109,27 @@ func flight5Generate(c flightConn, state *State, cache *handshakeCache, cfg *han
 						Version: protocol.Version1_2,
 					},
 					Content: &handshake.Handshake{
-						Message: &handshake.MessageCertificate{
-							Certificate: certBytes,
-						},
+						Message: certificateMsg,
 					},
 				},
 			})
 	}
 
-	clientKeyExchange := &handshake.MessageClientKeyExchange{}
-	if cfg.localPSKCallback == nil {
-		clientKeyExchange.PublicKey = state.localKeypair.PublicKey
-	} else {
-		clientKeyExchange.IdentityHint = cfg.localPSKIdentityHint
-	}
-	if state != nil && state.localKeypair != nil && len(state.localKeypair.PublicKey) > 0 {
-		clientKeyExchange.PublicKey = state.localKeypair.PublicKey
-	}
-
-	pkts = append(pkts,
-		&packet{
-			record: &recordlayer.RecordLayer{
-				Header: recordlayer.Header{
-					Version: protocol.Version1_2,
-				},
-				Content: &handshake.Handshake{
-					Message: clientKeyExchange,
-				},
-			},
-		})
-
 	serverKeyExchangeData := cache.pullAndMerge(
 		handshakeCachePullRule{handshake.TypeServerKeyExchange, cfg.initialEpoch, false, false},
 	)
 
 	serverKeyExchange := &handshake.MessageServerKeyExchange{}
 
-	// handshakeMessageServerKeyExchange is optional for PSK
+	// handshakeMessageServerKeyExchange is optional for PSK and custom key exchanges
 	if len(serverKeyExchangeData) == 0 {
-		alertPtr, err := handleServerKeyExchange(c, state, cfg, &handshake.MessageServerKeyExchange{})
+		alertPtr, err := handleServerKeyExchange(ctx, c, state, cfg, &handshake.MessageServerKeyExchange{})
 		if err != nil {
 			return nil, alertPtr, err
 		}
 	} else {
 		rawHandshake := &handshake.Handshake{
-			KeyExchangeAlgorithm: state.cipherSuite.KeyExchangeAlgorithm(),
+			KeyExchangeAlgorithm: messageKeyExchangeAlgorithm(state.cipherSuite),
 		}
 		err := rawHandshake.Unmarshal(serverKeyExchangeData)
 		if err != nil {
@@ -143,6 +144,43 @@ func flight5Generate(c flightConn, state *State, cache *handshakeCache, cfg *han
 		}
 	}
 
+	clientKeyExchange := &handshake.MessageClientKeyExchange{}
+	switch {
+	case isECJPAKECipherSuite(state.cipherSuite):
+		roundTwo, err := state.ecjpake.RoundTwo()
+		if err != nil {
+			return nil, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
+		}
+		clientKeyExchange.ECJPAKEKeyKP = roundTwo
+	case state.keyExchange != nil:
+		payload, preMasterSecret, err := state.keyExchange.ClientKeyExchange()
+		if err != nil {
+			return nil, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
+		}
+		clientKeyExchange.KeyExchangeData = payload
+		state.preMasterSecret = preMasterSecret
+	case !cfg.usesPSK():
+		clientKeyExchange.PublicKey = state.localKeypair.PublicKey
+	default:
+		clientKeyExchange.IdentityHint = state.localPSKIdentity
+	}
+	if state != nil && state.localKeypair != nil && len(state.localKeypair.PublicKey) > 0 {
+		clientKeyExchange.PublicKey = state.localKeypair.PublicKey
+		clientKeyExchange.NamedCurve = state.localKeypair.Curve
+	}
+
+	pkts = append(pkts,
+		&packet{
+			record: &recordlayer.RecordLayer{
+				Header: recordlayer.Header{
+					Version: protocol.Version1_2,
+				},
+				Content: &handshake.Handshake{
+					Message: clientKeyExchange,
+				},
+			},
+		})
+
 	// Append not-yet-sent packets
 	merged := []byte{}
 	seqPred := uint16(state.handshakeSendSequence)
@@ -167,11 +205,12 @@ func flight5Generate(c flightConn, state *State, cache *handshakeCache, cfg *han
 	// If the client has sent a certificate with signing ability, a digitally-signed
 	// CertificateVerify message is sent to explicitly verify possession of the
 	// private key in the certificate.
-	if state.remoteRequestedCertificate && len(cfg.localCertificates) > 0 {
+	if state.remoteRequestedCertificate && len(certBytes) > 0 {
 		plainText := append(cache.pullAndMerge(
 			handshakeCachePullRule{handshake.TypeClientHello, cfg.initialEpoch, true, false},
 			handshakeCachePullRule{handshake.TypeServerHello, cfg.initialEpoch, false, false},
 			handshakeCachePullRule{handshake.TypeCertificate, cfg.initialEpoch, false, false},
+			handshakeCachePullRule{handshake.TypeCertificateStatus, cfg.initialEpoch, false, false},
 			handshakeCachePullRule{handshake.TypeServerKeyExchange, cfg.initialEpoch, false, false},
 			handshakeCachePullRule{handshake.TypeCertificateRequest, cfg.initialEpoch, false, false},
 			handshakeCachePullRule{handshake.TypeServerHelloDone, cfg.initialEpoch, false, false},
@@ -180,12 +219,12 @@ func flight5Generate(c flightConn, state *State, cache *handshakeCache, cfg *han
 		), merged...)
 
 		// Find compatible signature scheme
-		signatureHashAlgo, err := signaturehash.SelectSignatureScheme(cfg.localSignatureSchemes, privateKey)
+		signatureHashAlgo, err := selectSignatureScheme(cfg.localSignatureSchemes, state.remoteSignatureSchemes, certBytes, privateKey)
 		if err != nil {
 			return nil, &alert.Alert{Level: alert.Fatal, Description: alert.InsufficientSecurity}, err
 		}
 
-		certVerify, err := generateCertificateVerify(plainText, privateKey, signatureHashAlgo.Hash)
+		certVerify, err := generateCertificateVerify(ctx, plainText, privateKey, signatureHashAlgo)
 		if err != nil {
 			return nil, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
 		}
@@ -235,6 +274,7 @@ func flight5Generate(c flightConn, state *State, cache *handshakeCache, cfg *han
 			handshakeCachePullRule{handshake.TypeClientHello, cfg.initialEpoch, true, false},
 			handshakeCachePullRule{handshake.TypeServerHello, cfg.initialEpoch, false, false},
 			handshakeCachePullRule{handshake.TypeCertificate, cfg.initialEpoch, false, false},
+			handshakeCachePullRule{handshake.TypeCertificateStatus, cfg.initialEpoch, false, false},
 			handshakeCachePullRule{handshake.TypeServerKeyExchange, cfg.initialEpoch, false, false},
 			handshakeCachePullRule{handshake.TypeCertificateRequest, cfg.initialEpoch, false, false},
 			handshakeCachePullRule{handshake.TypeServerHelloDone, cfg.initialEpoch, false, false},
@@ -313,16 +353,29 @@ func initalizeCipherSuite(state *State, cache *handshakeCache, cfg *handshakeCon
 		}
 
 		expectedMsg := valueKeyMessage(clientRandom[:], serverRandom[:], h.PublicKey, h.NamedCurve)
-		if err = verifyKeySignature(expectedMsg, h.Signature, h.HashAlgorithm, state.PeerCertificates); err != nil {
+		signatureHashAlgorithm := signaturehash.Algorithm{Hash: h.HashAlgorithm, Signature: h.SignatureAlgorithm}
+		if err = verifyKeySignature(expectedMsg, h.Signature, signatureHashAlgorithm, state.PeerCertificates, state.remoteCertificateType); err != nil {
 			return &alert.Alert{Level: alert.Fatal, Description: alert.BadCertificate}, err
 		}
 		var chains [][]*x509.Certificate
-		if !cfg.insecureSkipVerify {
-			if chains, err = verifyServerCert(state.PeerCertificates, cfg.rootCAs, cfg.serverName); err != nil {
+		switch {
+		case state.remoteCertificateType == CertificateTypeRawPublicKey:
+			// A raw public key has no chain, it is only trusted by the callback
+			if err = cfg.verifyPeerRawPublicKey(state.PeerCertificates[0]); err != nil {
+				return &alert.Alert{Level: alert.Fatal, Description: alert.BadCertificate}, err
+			}
+		case !cfg.insecureSkipVerify:
+			if chains, err = verifyServerCert(state.PeerCertificates, cfg.rootCAs, cfg.serverName, cfg.revocationChecker, state.ocspResponse, cfg.verifyOCSPResponse); err != nil {
+				switch {
+				case errors.Is(err, errCertificateRevoked):
+					return &alert.Alert{Level: alert.Fatal, Description: alert.CertificateRevoked}, err
+				case errors.Is(err, errInvalidOCSPResponse):
+					return &alert.Alert{Level: alert.Fatal, Description: alert.BadCertificateStatusResponse}, err
+				}
 				return &alert.Alert{Level: alert.Fatal, Description: alert.BadCertificate}, err
 			}
 		}
-		if cfg.verifyPeerCertificate != nil {
+		if cfg.verifyPeerCertificate != nil && state.remoteCertificateType == CertificateTypeX509 {
 			if err = cfg.verifyPeerCertificate(state.PeerCertificates, chains); err != nil {
 				return &alert.Alert{Level: alert.Fatal, Description: alert.BadCertificate}, err
 			}
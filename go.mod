@@ -1,11 +1,12 @@
 module github.com/pion/dtls/v2
 
 require (
+	filippo.io/bigmod v0.0.3
+	github.com/cloudflare/circl v1.3.7
 	github.com/pion/logging v0.2.2
 	github.com/pion/transport v0.13.0
-	github.com/pion/udp v0.1.1
-	golang.org/x/crypto v0.0.0-20220427172511-eb4f295cb31f
-	golang.org/x/net v0.0.0-20220425223048-2871e0cb64e4
+	golang.org/x/crypto v0.17.0
+	golang.org/x/net v0.10.0
 )
 
 go 1.13
@@ -69,7 +69,8 @@ func (h *handshakeCache) pull(rules ...handshakeCachePullRule) []*handshakeCache
 }
 
 // fullPullMap pulls all handshakes between rules[0] to rules[len(rules)-1] as map.
-func (h *handshakeCache) fullPullMap(startSeq int, cipherSuite CipherSuite, rules ...handshakeCachePullRule) (int, map[handshake.Type]handshake.Message, bool) {
+// Messages are decoded using the negotiated cipher suite and peer certificate type of state.
+func (h *handshakeCache) fullPullMap(startSeq int, state *State, rules ...handshakeCachePullRule) (int, map[handshake.Type]handshake.Message, bool) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
@@ -101,11 +102,13 @@ func (h *handshakeCache) fullPullMap(startSeq int, cipherSuite CipherSuite, rule
 			continue
 		}
 		var keyExchangeAlgorithm CipherSuiteKeyExchangeAlgorithm
-		if cipherSuite != nil {
-			keyExchangeAlgorithm = cipherSuite.KeyExchangeAlgorithm()
+		if state.cipherSuite != nil {
+			keyExchangeAlgorithm = messageKeyExchangeAlgorithm(state.cipherSuite)
 		}
 		rawHandshake := &handshake.Handshake{
 			KeyExchangeAlgorithm: keyExchangeAlgorithm,
+			CertificateType:      state.remoteCertificateType,
+			NamedCurve:           state.namedCurve,
 		}
 		if err := rawHandshake.Unmarshal(i.data); err != nil {
 			return startSeq, nil, false
@@ -142,6 +145,7 @@ func (h *handshakeCache) sessionHash(hf prf.HashFunc, epoch uint16, additional .
 		handshakeCachePullRule{handshake.TypeClientHello, epoch, true, false},
 		handshakeCachePullRule{handshake.TypeServerHello, epoch, false, false},
 		handshakeCachePullRule{handshake.TypeCertificate, epoch, false, false},
+		handshakeCachePullRule{handshake.TypeCertificateStatus, epoch, false, false},
 		handshakeCachePullRule{handshake.TypeServerKeyExchange, epoch, false, false},
 		handshakeCachePullRule{handshake.TypeCertificateRequest, epoch, false, false},
 		handshakeCachePullRule{handshake.TypeServerHelloDone, epoch, false, false},
@@ -6,9 +6,12 @@ import (
 	"crypto/x509"
 	"fmt"
 	"io"
+	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/pion/dtls/v2/pkg/crypto/elliptic"
 	"github.com/pion/dtls/v2/pkg/crypto/signaturehash"
 	"github.com/pion/dtls/v2/pkg/protocol/alert"
 	"github.com/pion/dtls/v2/pkg/protocol/handshake"
@@ -90,22 +93,45 @@ type handshakeFSM struct {
 type handshakeConfig struct {
 	localPSKCallback            PSKCallback
 	localPSKIdentityHint        []byte
+	localGetPSK                 func(context.Context, []byte, *ClientHelloInfo) ([]byte, error)
+	localGetClientPSK           func(context.Context, []byte) ([]byte, []byte, error)
+	pskAuthTracker              *PSKAuthTracker
+	localECJPAKEPassphrase      []byte
 	localCipherSuites           []CipherSuite             // Available CipherSuites
 	localSignatureSchemes       []signaturehash.Algorithm // Available signature schemes
+	ellipticCurves              []elliptic.Curve          // Available elliptic curves, if empty the defaults
+	preferServerCipherSuites    bool                      // Select the cipher suite and curve by the server's preference
 	extendedMasterSecret        ExtendedMasterSecretType  // Policy for the Extended Master Support extension
+	encryptThenMAC              EncryptThenMACType        // Policy for the Encrypt-then-MAC extension
 	localSRTPProtectionProfiles []SRTPProtectionProfile   // Available SRTPProtectionProfiles, if empty no SRTP support
 	serverName                  string
 	supportedProtocols          []string
 	clientAuth                  ClientAuthType // If we are a client should we request a client certificate
 	localCertificates           []tls.Certificate
-	nameToCertificate           map[string]*tls.Certificate
+	nameToCertificate           map[string][]*tls.Certificate
+	localGetCertificate         func(*ClientHelloInfo) (*tls.Certificate, error)
+	localGetClientCertificate   func(*CertificateRequestInfo) (*tls.Certificate, error)
 	insecureSkipVerify          bool
 	verifyPeerCertificate       func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error
+	localCertificateTypes       []CertificateType // Types of certificate we can authenticate with, X.509 if empty
+	peerCertificateTypes        []CertificateType // Types of certificate accepted from the peer, X.509 if empty
+	verifyPeerRawPublicKey      func(spki []byte) error
+	revocationChecker           RevocationChecker
+	verifyOCSPResponse          func(ocspResponse []byte, verifiedChains [][]*x509.Certificate) error
+	recordSizeLimit             int
+	maxFragmentLength           FragmentLength
 	sessionStore                SessionStore
+	sessionTicketKeys           *SessionTicketKeys
+	sessionTicketLifetime       time.Duration
+	skipHelloVerify             bool
+	clientHelloSequence         int
+	admitHandshake              func(*ClientHelloInfo) AdmissionDecision // Admission by the listener, nil once decided
 	rootCAs                     *x509.CertPool
 	clientCAs                   *x509.CertPool
 	retransmitInterval          time.Duration
 	customCipherSuites          func() []CipherSuite
+	connectionIDGenerator       func() []byte
+	getConfigForClient          func(*ClientHelloInfo) (*Config, error)
 
 	onFlightState func(flightVal, handshakeState)
 	log           logging.LeveledLogger
@@ -123,6 +149,79 @@ type flightConn interface {
 	setLocalEpoch(epoch uint16)
 	handleQueuedPackets(context.Context) error
 	sessionKey() []byte
+	RemoteAddr() net.Addr
+}
+
+// setConfig sets the settings of config which apply to the handshake. A
+// server may replace them for a ClientHello, with GetConfigForClient. The
+// connection ID generator and session ticket keys are shared with the
+// listener, they are set once with the Config of the listener.
+func (c *handshakeConfig) setConfig(config *Config) error {
+	cipherSuites, err := parseCipherSuites(config.CipherSuites, config.CustomCipherSuites, (!config.includesPSK() && config.ECJPAKEPassphrase == nil) || len(config.Certificates) > 0 || config.GetCertificate != nil || config.GetClientCertificate != nil, config.includesPSK(), config.ECJPAKEPassphrase != nil)
+	if err != nil {
+		return err
+	}
+
+	signatureSchemes, err := signaturehash.ParseSignatureSchemes(config.SignatureSchemes, config.InsecureHashes)
+	if err != nil {
+		return err
+	}
+
+	retransmitInterval := initialTickerInterval
+	if config.FlightInterval != 0 {
+		retransmitInterval = config.FlightInterval
+	}
+
+	serverName := config.ServerName
+	// Do not allow the use of an IP address literal as an SNI value.
+	// See RFC 6066, Section 3.
+	if net.ParseIP(serverName) != nil {
+		serverName = ""
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.localPSKCallback = config.PSK
+	c.localPSKIdentityHint = config.PSKIdentityHint
+	c.localGetPSK = config.GetPSK
+	c.localGetClientPSK = config.GetClientPSK
+	c.pskAuthTracker = config.PSKAuthTracker
+	c.localECJPAKEPassphrase = config.ECJPAKEPassphrase
+	c.localCipherSuites = cipherSuites
+	c.localSignatureSchemes = signatureSchemes
+	c.ellipticCurves = config.CurvePreferences
+	c.preferServerCipherSuites = config.PreferServerCipherSuites
+	c.extendedMasterSecret = config.ExtendedMasterSecret
+	c.encryptThenMAC = config.EncryptThenMAC
+	c.localSRTPProtectionProfiles = config.SRTPProtectionProfiles
+	c.serverName = serverName
+	c.supportedProtocols = config.SupportedProtocols
+	c.clientAuth = config.ClientAuth
+	c.localCertificates = config.Certificates
+	c.nameToCertificate = nil
+	c.insecureSkipVerify = config.InsecureSkipVerify
+	c.verifyPeerCertificate = config.VerifyPeerCertificate
+	c.localCertificateTypes = config.CertificateTypes
+	c.peerCertificateTypes = config.PeerCertificateTypes
+	c.verifyPeerRawPublicKey = config.VerifyPeerRawPublicKey
+	c.revocationChecker = config.RevocationChecker
+	c.verifyOCSPResponse = config.VerifyOCSPResponse
+	c.recordSizeLimit = config.RecordSizeLimit
+	c.maxFragmentLength = config.MaxFragmentLength
+	c.rootCAs = config.RootCAs
+	c.clientCAs = config.ClientCAs
+	c.customCipherSuites = config.CustomCipherSuites
+	c.retransmitInterval = retransmitInterval
+	c.keyLogWriter = config.KeyLogWriter
+	c.sessionStore = config.SessionStore
+	c.sessionTicketLifetime = config.SessionTicketLifetime
+	if c.sessionTicketLifetime <= 0 {
+		c.sessionTicketLifetime = defaultSessionTicketLifetime
+	}
+	c.localGetCertificate = config.GetCertificate
+	c.localGetClientCertificate = config.GetClientCertificate
+	return nil
 }
 
 func (c *handshakeConfig) writeKeyLog(label string, clientRandom, secret []byte) {
@@ -160,10 +259,20 @@ func newHandshakeFSM(
 func (s *handshakeFSM) Run(ctx context.Context, c flightConn, initialState handshakeState) error {
 	state := initialState
 	defer func() {
+		// A handshake ending before it finished failed to authenticate if
+		// the Finished of the client couldn't be decrypted with the keys
+		// of the pre-shared key. Other failures, like timeouts, don't tell
+		// whether the client knows it.
+		if atomic.LoadInt32(&s.state.handshakeDecryptFailed) == 1 {
+			reportPSKAuth(s.state, s.cfg, false)
+		}
 		close(s.closed)
 	}()
 	for {
 		s.cfg.log.Tracef("[handshake:%s] %s: %s", srvCliStr(s.state.isClient), s.currentFlight.String(), state.String())
+		if state == handshakeFinished {
+			reportPSKAuth(s.state, s.cfg, true)
+		}
 		if s.cfg.onFlightState != nil {
 			s.cfg.onFlightState(s.currentFlight, state)
 		}
@@ -203,7 +312,7 @@ func (s *handshakeFSM) prepare(ctx context.Context, c flightConn) (handshakeStat
 		err = errFlight
 		a = &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}
 	} else {
-		pkts, a, err = gen(c, s.state, s.cache, s.cfg)
+		pkts, a, err = gen(ctx, c, s.state, s.cache, s.cfg)
 		s.retransmit = retransmit
 	}
 	if a != nil {
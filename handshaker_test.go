@@ -5,6 +5,7 @@ import (
 	"context"
 	"crypto/tls"
 	"errors"
+	"net"
 	"sync"
 	"testing"
 	"time"
@@ -49,7 +50,7 @@ func TestHandshaker(t *testing.T) {
 	loggerFactory := logging.NewDefaultLoggerFactory()
 	logger := loggerFactory.NewLogger("dtls")
 
-	cipherSuites, err := parseCipherSuites(nil, nil, true, false)
+	cipherSuites, err := parseCipherSuites(nil, nil, true, false, false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -440,3 +441,7 @@ func (c *flightTestConn) handleQueuedPackets(ctx context.Context) error {
 func (c *flightTestConn) sessionKey() []byte {
 	return nil
 }
+
+func (c *flightTestConn) RemoteAddr() net.Addr {
+	return nil
+}
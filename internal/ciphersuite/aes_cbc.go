@@ -0,0 +1,152 @@
+package ciphersuite
+
+import (
+	"fmt"
+	"hash"
+	"sync/atomic"
+
+	"github.com/pion/dtls/v2/pkg/crypto/ciphersuite"
+	"github.com/pion/dtls/v2/pkg/crypto/clientcertificate"
+	"github.com/pion/dtls/v2/pkg/crypto/prf"
+	"github.com/pion/dtls/v2/pkg/protocol/recordlayer"
+)
+
+// AesCbc is a base class used by multiple AES-CBC Ciphers that use the same
+// hash for the PRF and the HMAC
+type AesCbc struct {
+	cbc                   atomic.Value // *cryptoCBC
+	encryptThenMAC        bool
+	clientCertificateType clientcertificate.Type
+	id                    ID
+	psk                   bool
+	keyExchangeAlgorithm  KeyExchangeAlgorithm
+	ecc                   bool
+	keyLen                int
+	hashFunc              func() hash.Hash
+}
+
+func newAesCbc(clientCertificateType clientcertificate.Type, id ID, psk bool, keyExchangeAlgorithm KeyExchangeAlgorithm, ecc bool, keyLen int, hashFunc func() hash.Hash) *AesCbc {
+	return &AesCbc{
+		clientCertificateType: clientCertificateType,
+		id:                    id,
+		psk:                   psk,
+		keyExchangeAlgorithm:  keyExchangeAlgorithm,
+		ecc:                   ecc,
+		keyLen:                keyLen,
+		hashFunc:              hashFunc,
+	}
+}
+
+// CertificateType returns what type of certificate this CipherSuite exchanges
+func (c *AesCbc) CertificateType() clientcertificate.Type {
+	return c.clientCertificateType
+}
+
+// ID returns the ID of the CipherSuite
+func (c *AesCbc) ID() ID {
+	return c.id
+}
+
+func (c *AesCbc) String() string {
+	return c.id.String()
+}
+
+// ECC uses Elliptic Curve Cryptography
+func (c *AesCbc) ECC() bool {
+	return c.ecc
+}
+
+// KeyExchangeAlgorithm controls what key exchange algorithm is using during the handshake
+func (c *AesCbc) KeyExchangeAlgorithm() KeyExchangeAlgorithm {
+	return c.keyExchangeAlgorithm
+}
+
+// HashFunc returns the hashing func for this CipherSuite
+func (c *AesCbc) HashFunc() func() hash.Hash {
+	return c.hashFunc
+}
+
+// AuthenticationType controls what authentication method is using during the handshake
+func (c *AesCbc) AuthenticationType() AuthenticationType {
+	if c.psk {
+		return AuthenticationTypePreSharedKey
+	}
+	return AuthenticationTypeCertificate
+}
+
+// SetEncryptThenMAC selects Encrypt-then-MAC record protection, as negotiated
+// with the encrypt_then_mac extension. It must be called before Init.
+func (c *AesCbc) SetEncryptThenMAC(encryptThenMAC bool) {
+	c.encryptThenMAC = encryptThenMAC
+}
+
+// IsInitialized returns if the CipherSuite has keying material and can
+// encrypt/decrypt packets
+func (c *AesCbc) IsInitialized() bool {
+	return c.cbc.Load() != nil
+}
+
+// Init initializes the internal Cipher with keying material
+func (c *AesCbc) Init(masterSecret, clientRandom, serverRandom []byte, isClient bool) error {
+	const prfIvLen = 16
+	prfMacLen := c.hashFunc().Size()
+
+	keys, err := prf.GenerateEncryptionKeys(masterSecret, clientRandom, serverRandom, prfMacLen, c.keyLen, prfIvLen, c.HashFunc())
+	if err != nil {
+		return err
+	}
+
+	newCBC := ciphersuite.NewCBC
+	if c.encryptThenMAC {
+		newCBC = ciphersuite.NewCBCEncryptThenMAC
+	}
+
+	var cbc *ciphersuite.CBC
+	if isClient {
+		cbc, err = newCBC(
+			keys.ClientWriteKey, keys.ClientWriteIV, keys.ClientMACKey,
+			keys.ServerWriteKey, keys.ServerWriteIV, keys.ServerMACKey,
+			c.HashFunc(),
+		)
+	} else {
+		cbc, err = newCBC(
+			keys.ServerWriteKey, keys.ServerWriteIV, keys.ServerMACKey,
+			keys.ClientWriteKey, keys.ClientWriteIV, keys.ClientMACKey,
+			c.HashFunc(),
+		)
+	}
+	c.cbc.Store(cbc)
+
+	return err
+}
+
+// Encrypt encrypts a single TLS RecordLayer
+func (c *AesCbc) Encrypt(pkt *recordlayer.RecordLayer, raw []byte) ([]byte, error) {
+	cipherSuite, ok := c.cbc.Load().(*ciphersuite.CBC)
+	if !ok {
+		return nil, fmt.Errorf("%w, unable to encrypt", errCipherSuiteNotInit)
+	}
+
+	return cipherSuite.Encrypt(pkt, raw)
+}
+
+// Decrypt decrypts a single TLS RecordLayer
+func (c *AesCbc) Decrypt(raw []byte) ([]byte, error) {
+	cipherSuite, ok := c.cbc.Load().(*ciphersuite.CBC)
+	if !ok {
+		return nil, fmt.Errorf("%w, unable to decrypt", errCipherSuiteNotInit)
+	}
+
+	return cipherSuite.Decrypt(raw)
+}
+
+// DecryptWithHeader decrypts a single TLS RecordLayer whose header has
+// already been parsed, as required for records with a connection ID
+func (c *AesCbc) DecryptWithHeader(h recordlayer.Header, raw []byte) ([]byte, error) {
+	cipherSuite, ok := c.cbc.Load().(*ciphersuite.CBC)
+	if !ok {
+		return nil, fmt.Errorf("%w, unable to decrypt", errCipherSuiteNotInit)
+	}
+
+	return cipherSuite.DecryptWithHeader(h, raw)
+}
@@ -108,3 +108,14 @@ func (c *AesCcm) Decrypt(raw []byte) ([]byte, error) {
 
 	return cipherSuite.Decrypt(raw)
 }
+
+// DecryptWithHeader decrypts a single TLS RecordLayer whose header has
+// already been parsed, as required for records with a connection ID
+func (c *AesCcm) DecryptWithHeader(h recordlayer.Header, raw []byte) ([]byte, error) {
+	cipherSuite, ok := c.ccm.Load().(*ciphersuite.CCM)
+	if !ok {
+		return nil, fmt.Errorf("%w, unable to decrypt", errCipherSuiteNotInit)
+	}
+
+	return cipherSuite.DecryptWithHeader(h, raw)
+}
@@ -44,6 +44,40 @@ func (i ID) String() string {
 		return "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"
 	case TLS_ECDHE_PSK_WITH_AES_128_CBC_SHA256:
 		return "TLS_ECDHE_PSK_WITH_AES_128_CBC_SHA256"
+	case TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256:
+		return "TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256"
+	case TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256:
+		return "TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256"
+	case TLS_PSK_WITH_CHACHA20_POLY1305_SHA256:
+		return "TLS_PSK_WITH_CHACHA20_POLY1305_SHA256"
+	case TLS_ECDHE_PSK_WITH_CHACHA20_POLY1305_SHA256:
+		return "TLS_ECDHE_PSK_WITH_CHACHA20_POLY1305_SHA256"
+	case TLS_ECDHE_ECDSA_WITH_AES_256_CCM:
+		return "TLS_ECDHE_ECDSA_WITH_AES_256_CCM"
+	case TLS_ECDHE_ECDSA_WITH_AES_256_CCM_8:
+		return "TLS_ECDHE_ECDSA_WITH_AES_256_CCM_8"
+	case TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256:
+		return "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256"
+	case TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA384:
+		return "TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA384"
+	case TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256:
+		return "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256"
+	case TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA384:
+		return "TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA384"
+	case TLS_PSK_WITH_AES_256_CCM:
+		return "TLS_PSK_WITH_AES_256_CCM"
+	case TLS_PSK_WITH_AES_256_GCM_SHA384:
+		return "TLS_PSK_WITH_AES_256_GCM_SHA384"
+	case TLS_PSK_WITH_AES_256_CBC_SHA384:
+		return "TLS_PSK_WITH_AES_256_CBC_SHA384"
+	case TLS_ECDHE_PSK_WITH_AES_128_GCM_SHA256:
+		return "TLS_ECDHE_PSK_WITH_AES_128_GCM_SHA256"
+	case TLS_ECDHE_PSK_WITH_AES_128_CCM_SHA256:
+		return "TLS_ECDHE_PSK_WITH_AES_128_CCM_SHA256"
+	case TLS_ECDHE_PSK_WITH_AES_256_CBC_SHA384:
+		return "TLS_ECDHE_PSK_WITH_AES_256_CBC_SHA384"
+	case TLS_ECJPAKE_WITH_AES_128_CCM_8:
+		return "TLS_ECJPAKE_WITH_AES_128_CCM_8"
 	default:
 		return fmt.Sprintf("unknown(%v)", uint16(i))
 	}
@@ -55,6 +89,10 @@ const (
 	TLS_ECDHE_ECDSA_WITH_AES_128_CCM   ID = 0xc0ac //nolint:revive,stylecheck
 	TLS_ECDHE_ECDSA_WITH_AES_128_CCM_8 ID = 0xc0ae //nolint:revive,stylecheck
 
+	// AES-256-CCM
+	TLS_ECDHE_ECDSA_WITH_AES_256_CCM   ID = 0xc0ad //nolint:revive,stylecheck
+	TLS_ECDHE_ECDSA_WITH_AES_256_CCM_8 ID = 0xc0af //nolint:revive,stylecheck
+
 	// AES-128-GCM-SHA256
 	TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 ID = 0xc02b //nolint:revive,stylecheck
 	TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256   ID = 0xc02f //nolint:revive,stylecheck
@@ -65,13 +103,34 @@ const (
 	TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA ID = 0xc00a //nolint:revive,stylecheck
 	TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA   ID = 0xc014 //nolint:revive,stylecheck
 
+	// AES-CBC-SHA256/384
+	TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256 ID = 0xc023 //nolint:revive,stylecheck
+	TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA384 ID = 0xc024 //nolint:revive,stylecheck
+	TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256   ID = 0xc027 //nolint:revive,stylecheck
+	TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA384   ID = 0xc028 //nolint:revive,stylecheck
+
 	TLS_PSK_WITH_AES_128_CCM        ID = 0xc0a4 //nolint:revive,stylecheck
 	TLS_PSK_WITH_AES_128_CCM_8      ID = 0xc0a8 //nolint:revive,stylecheck
 	TLS_PSK_WITH_AES_256_CCM_8      ID = 0xc0a9 //nolint:revive,stylecheck
 	TLS_PSK_WITH_AES_128_GCM_SHA256 ID = 0x00a8 //nolint:revive,stylecheck
 	TLS_PSK_WITH_AES_128_CBC_SHA256 ID = 0x00ae //nolint:revive,stylecheck
+	TLS_PSK_WITH_AES_256_CCM        ID = 0xc0a5 //nolint:revive,stylecheck
+	TLS_PSK_WITH_AES_256_GCM_SHA384 ID = 0x00a9 //nolint:revive,stylecheck
+	TLS_PSK_WITH_AES_256_CBC_SHA384 ID = 0x00af //nolint:revive,stylecheck
 
 	TLS_ECDHE_PSK_WITH_AES_128_CBC_SHA256 ID = 0xC037 //nolint:revive,stylecheck
+	TLS_ECDHE_PSK_WITH_AES_256_CBC_SHA384 ID = 0xc038 //nolint:revive,stylecheck
+	TLS_ECDHE_PSK_WITH_AES_128_GCM_SHA256 ID = 0xd001 //nolint:revive,stylecheck
+	TLS_ECDHE_PSK_WITH_AES_128_CCM_SHA256 ID = 0xd005 //nolint:revive,stylecheck
+
+	// CHACHA20-POLY1305-SHA256
+	TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256 ID = 0xcca9 //nolint:revive,stylecheck
+	TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256   ID = 0xcca8 //nolint:revive,stylecheck
+	TLS_PSK_WITH_CHACHA20_POLY1305_SHA256         ID = 0xccab //nolint:revive,stylecheck
+	TLS_ECDHE_PSK_WITH_CHACHA20_POLY1305_SHA256   ID = 0xccac //nolint:revive,stylecheck
+
+	// EC J-PAKE, registered for Thread
+	TLS_ECJPAKE_WITH_AES_128_CCM_8 ID = 0xc0ff //nolint:revive,stylecheck
 )
 
 // AuthenticationType controls what authentication method is using during the handshake
@@ -82,6 +141,7 @@ const (
 	AuthenticationTypeCertificate  AuthenticationType = types.AuthenticationTypeCertificate
 	AuthenticationTypePreSharedKey AuthenticationType = types.AuthenticationTypePreSharedKey
 	AuthenticationTypeAnonymous    AuthenticationType = types.AuthenticationTypeAnonymous
+	AuthenticationTypePassword     AuthenticationType = types.AuthenticationTypePassword
 )
 
 // KeyExchangeAlgorithm controls what exchange algorithm was chosen.
@@ -89,7 +149,9 @@ type KeyExchangeAlgorithm = types.KeyExchangeAlgorithm
 
 // KeyExchangeAlgorithm Bitmask
 const (
-	KeyExchangeAlgorithmNone  KeyExchangeAlgorithm = types.KeyExchangeAlgorithmNone
-	KeyExchangeAlgorithmPsk   KeyExchangeAlgorithm = types.KeyExchangeAlgorithmPsk
-	KeyExchangeAlgorithmEcdhe KeyExchangeAlgorithm = types.KeyExchangeAlgorithmEcdhe
+	KeyExchangeAlgorithmNone    KeyExchangeAlgorithm = types.KeyExchangeAlgorithmNone
+	KeyExchangeAlgorithmPsk     KeyExchangeAlgorithm = types.KeyExchangeAlgorithmPsk
+	KeyExchangeAlgorithmEcdhe   KeyExchangeAlgorithm = types.KeyExchangeAlgorithmEcdhe
+	KeyExchangeAlgorithmEcjpake KeyExchangeAlgorithm = types.KeyExchangeAlgorithmEcjpake
+	KeyExchangeAlgorithmCustom  KeyExchangeAlgorithm = types.KeyExchangeAlgorithmCustom
 )
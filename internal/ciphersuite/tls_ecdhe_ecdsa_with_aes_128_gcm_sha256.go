@@ -103,3 +103,14 @@ func (c *TLSEcdheEcdsaWithAes128GcmSha256) Decrypt(raw []byte) ([]byte, error) {
 
 	return cipherSuite.Decrypt(raw)
 }
+
+// DecryptWithHeader decrypts a single TLS RecordLayer whose header has
+// already been parsed, as required for records with a connection ID
+func (c *TLSEcdheEcdsaWithAes128GcmSha256) DecryptWithHeader(h recordlayer.Header, raw []byte) ([]byte, error) {
+	cipherSuite, ok := c.gcm.Load().(*ciphersuite.GCM)
+	if !ok {
+		return nil, fmt.Errorf("%w, unable to decrypt", errCipherSuiteNotInit)
+	}
+
+	return cipherSuite.DecryptWithHeader(h, raw)
+}
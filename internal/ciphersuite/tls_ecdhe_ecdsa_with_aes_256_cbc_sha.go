@@ -15,7 +15,8 @@ import (
 
 // TLSEcdheEcdsaWithAes256CbcSha represents a TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA CipherSuite
 type TLSEcdheEcdsaWithAes256CbcSha struct {
-	cbc atomic.Value // *cryptoCBC
+	cbc            atomic.Value // *cryptoCBC
+	encryptThenMAC bool
 }
 
 // CertificateType returns what type of certficate this CipherSuite exchanges
@@ -52,6 +53,12 @@ func (c *TLSEcdheEcdsaWithAes256CbcSha) AuthenticationType() AuthenticationType
 	return AuthenticationTypeCertificate
 }
 
+// SetEncryptThenMAC selects Encrypt-then-MAC record protection, as negotiated
+// with the encrypt_then_mac extension. It must be called before Init.
+func (c *TLSEcdheEcdsaWithAes256CbcSha) SetEncryptThenMAC(encryptThenMAC bool) {
+	c.encryptThenMAC = encryptThenMAC
+}
+
 // IsInitialized returns if the CipherSuite has keying material and can
 // encrypt/decrypt packets
 func (c *TLSEcdheEcdsaWithAes256CbcSha) IsInitialized() bool {
@@ -71,15 +78,20 @@ func (c *TLSEcdheEcdsaWithAes256CbcSha) Init(masterSecret, clientRandom, serverR
 		return err
 	}
 
+	newCBC := ciphersuite.NewCBC
+	if c.encryptThenMAC {
+		newCBC = ciphersuite.NewCBCEncryptThenMAC
+	}
+
 	var cbc *ciphersuite.CBC
 	if isClient {
-		cbc, err = ciphersuite.NewCBC(
+		cbc, err = newCBC(
 			keys.ClientWriteKey, keys.ClientWriteIV, keys.ClientMACKey,
 			keys.ServerWriteKey, keys.ServerWriteIV, keys.ServerMACKey,
 			sha1.New,
 		)
 	} else {
-		cbc, err = ciphersuite.NewCBC(
+		cbc, err = newCBC(
 			keys.ServerWriteKey, keys.ServerWriteIV, keys.ServerMACKey,
 			keys.ClientWriteKey, keys.ClientWriteIV, keys.ClientMACKey,
 			sha1.New,
@@ -109,3 +121,14 @@ func (c *TLSEcdheEcdsaWithAes256CbcSha) Decrypt(raw []byte) ([]byte, error) {
 
 	return cipherSuite.Decrypt(raw)
 }
+
+// DecryptWithHeader decrypts a single TLS RecordLayer whose header has
+// already been parsed, as required for records with a connection ID
+func (c *TLSEcdheEcdsaWithAes256CbcSha) DecryptWithHeader(h recordlayer.Header, raw []byte) ([]byte, error) {
+	cipherSuite, ok := c.cbc.Load().(*ciphersuite.CBC)
+	if !ok {
+		return nil, fmt.Errorf("%w, unable to decrypt", errCipherSuiteNotInit)
+	}
+
+	return cipherSuite.DecryptWithHeader(h, raw)
+}
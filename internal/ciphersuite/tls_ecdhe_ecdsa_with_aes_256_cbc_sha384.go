@@ -0,0 +1,12 @@
+package ciphersuite
+
+import (
+	"crypto/sha512"
+
+	"github.com/pion/dtls/v2/pkg/crypto/clientcertificate"
+)
+
+// NewTLSEcdheEcdsaWithAes256CbcSha384 constructs a TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA384 Cipher
+func NewTLSEcdheEcdsaWithAes256CbcSha384() *AesCbc {
+	return newAesCbc(clientcertificate.ECDSASign, TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA384, false, KeyExchangeAlgorithmEcdhe, true, 32, sha512.New384)
+}
@@ -0,0 +1,11 @@
+package ciphersuite
+
+import (
+	"github.com/pion/dtls/v2/pkg/crypto/ciphersuite"
+	"github.com/pion/dtls/v2/pkg/crypto/clientcertificate"
+)
+
+// NewTLSEcdheEcdsaWithAes256Ccm constructs a TLS_ECDHE_ECDSA_WITH_AES_256_CCM Cipher
+func NewTLSEcdheEcdsaWithAes256Ccm() *Aes256Ccm {
+	return newAes256Ccm(clientcertificate.ECDSASign, TLS_ECDHE_ECDSA_WITH_AES_256_CCM, false, ciphersuite.CCMTagLength, KeyExchangeAlgorithmEcdhe, true)
+}
@@ -0,0 +1,11 @@
+package ciphersuite
+
+import (
+	"github.com/pion/dtls/v2/pkg/crypto/ciphersuite"
+	"github.com/pion/dtls/v2/pkg/crypto/clientcertificate"
+)
+
+// NewTLSEcdheEcdsaWithAes256Ccm8 constructs a TLS_ECDHE_ECDSA_WITH_AES_256_CCM_8 Cipher
+func NewTLSEcdheEcdsaWithAes256Ccm8() *Aes256Ccm {
+	return newAes256Ccm(clientcertificate.ECDSASign, TLS_ECDHE_ECDSA_WITH_AES_256_CCM_8, false, ciphersuite.CCMTagLength8, KeyExchangeAlgorithmEcdhe, true)
+}
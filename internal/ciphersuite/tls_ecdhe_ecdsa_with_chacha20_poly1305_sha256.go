@@ -0,0 +1,112 @@
+package ciphersuite
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"sync/atomic"
+
+	"github.com/pion/dtls/v2/pkg/crypto/ciphersuite"
+	"github.com/pion/dtls/v2/pkg/crypto/clientcertificate"
+	"github.com/pion/dtls/v2/pkg/crypto/prf"
+	"github.com/pion/dtls/v2/pkg/protocol/recordlayer"
+)
+
+// TLSEcdheEcdsaWithChacha20Poly1305Sha256  represents a TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256 CipherSuite
+type TLSEcdheEcdsaWithChacha20Poly1305Sha256 struct {
+	chacha20Poly1305 atomic.Value // *cryptoChaCha20Poly1305
+}
+
+// CertificateType returns what type of certficate this CipherSuite exchanges
+func (c *TLSEcdheEcdsaWithChacha20Poly1305Sha256) CertificateType() clientcertificate.Type {
+	return clientcertificate.ECDSASign
+}
+
+// KeyExchangeAlgorithm controls what key exchange algorithm is using during the handshake
+func (c *TLSEcdheEcdsaWithChacha20Poly1305Sha256) KeyExchangeAlgorithm() KeyExchangeAlgorithm {
+	return KeyExchangeAlgorithmEcdhe
+}
+
+// ECC uses Elliptic Curve Cryptography
+func (c *TLSEcdheEcdsaWithChacha20Poly1305Sha256) ECC() bool {
+	return true
+}
+
+// ID returns the ID of the CipherSuite
+func (c *TLSEcdheEcdsaWithChacha20Poly1305Sha256) ID() ID {
+	return TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256
+}
+
+func (c *TLSEcdheEcdsaWithChacha20Poly1305Sha256) String() string {
+	return "TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256"
+}
+
+// HashFunc returns the hashing func for this CipherSuite
+func (c *TLSEcdheEcdsaWithChacha20Poly1305Sha256) HashFunc() func() hash.Hash {
+	return sha256.New
+}
+
+// AuthenticationType controls what authentication method is using during the handshake
+func (c *TLSEcdheEcdsaWithChacha20Poly1305Sha256) AuthenticationType() AuthenticationType {
+	return AuthenticationTypeCertificate
+}
+
+// IsInitialized returns if the CipherSuite has keying material and can
+// encrypt/decrypt packets
+func (c *TLSEcdheEcdsaWithChacha20Poly1305Sha256) IsInitialized() bool {
+	return c.chacha20Poly1305.Load() != nil
+}
+
+// Init initializes the internal Cipher with keying material
+func (c *TLSEcdheEcdsaWithChacha20Poly1305Sha256) Init(masterSecret, clientRandom, serverRandom []byte, isClient bool) error {
+	const (
+		prfMacLen = 0
+		prfKeyLen = 32
+		prfIvLen  = 12
+	)
+
+	keys, err := prf.GenerateEncryptionKeys(masterSecret, clientRandom, serverRandom, prfMacLen, prfKeyLen, prfIvLen, c.HashFunc())
+	if err != nil {
+		return err
+	}
+
+	var chacha20Poly1305 *ciphersuite.ChaCha20Poly1305
+	if isClient {
+		chacha20Poly1305, err = ciphersuite.NewChaCha20Poly1305(keys.ClientWriteKey, keys.ClientWriteIV, keys.ServerWriteKey, keys.ServerWriteIV)
+	} else {
+		chacha20Poly1305, err = ciphersuite.NewChaCha20Poly1305(keys.ServerWriteKey, keys.ServerWriteIV, keys.ClientWriteKey, keys.ClientWriteIV)
+	}
+	c.chacha20Poly1305.Store(chacha20Poly1305)
+	return err
+}
+
+// Encrypt encrypts a single TLS RecordLayer
+func (c *TLSEcdheEcdsaWithChacha20Poly1305Sha256) Encrypt(pkt *recordlayer.RecordLayer, raw []byte) ([]byte, error) {
+	cipherSuite, ok := c.chacha20Poly1305.Load().(*ciphersuite.ChaCha20Poly1305)
+	if !ok {
+		return nil, fmt.Errorf("%w, unable to encrypt", errCipherSuiteNotInit)
+	}
+
+	return cipherSuite.Encrypt(pkt, raw)
+}
+
+// Decrypt decrypts a single TLS RecordLayer
+func (c *TLSEcdheEcdsaWithChacha20Poly1305Sha256) Decrypt(raw []byte) ([]byte, error) {
+	cipherSuite, ok := c.chacha20Poly1305.Load().(*ciphersuite.ChaCha20Poly1305)
+	if !ok {
+		return nil, fmt.Errorf("%w, unable to decrypt", errCipherSuiteNotInit)
+	}
+
+	return cipherSuite.Decrypt(raw)
+}
+
+// DecryptWithHeader decrypts a single TLS RecordLayer whose header has
+// already been parsed, as required for records with a connection ID
+func (c *TLSEcdheEcdsaWithChacha20Poly1305Sha256) DecryptWithHeader(h recordlayer.Header, raw []byte) ([]byte, error) {
+	cipherSuite, ok := c.chacha20Poly1305.Load().(*ciphersuite.ChaCha20Poly1305)
+	if !ok {
+		return nil, fmt.Errorf("%w, unable to decrypt", errCipherSuiteNotInit)
+	}
+
+	return cipherSuite.DecryptWithHeader(h, raw)
+}
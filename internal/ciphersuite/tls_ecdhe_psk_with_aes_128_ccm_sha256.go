@@ -0,0 +1,11 @@
+package ciphersuite
+
+import (
+	"github.com/pion/dtls/v2/pkg/crypto/ciphersuite"
+	"github.com/pion/dtls/v2/pkg/crypto/clientcertificate"
+)
+
+// NewTLSEcdhePskWithAes128CcmSha256 constructs a TLS_ECDHE_PSK_WITH_AES_128_CCM_SHA256 Cipher
+func NewTLSEcdhePskWithAes128CcmSha256() *Aes128Ccm {
+	return newAes128Ccm(clientcertificate.Type(0), TLS_ECDHE_PSK_WITH_AES_128_CCM_SHA256, true, ciphersuite.CCMTagLength, (KeyExchangeAlgorithmPsk | KeyExchangeAlgorithmEcdhe), true)
+}
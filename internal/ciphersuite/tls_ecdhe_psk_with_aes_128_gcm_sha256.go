@@ -0,0 +1,25 @@
+package ciphersuite
+
+// TLSEcdhePskWithAes128GcmSha256 implements the TLS_ECDHE_PSK_WITH_AES_128_GCM_SHA256 CipherSuite
+type TLSEcdhePskWithAes128GcmSha256 struct {
+	TLSPskWithAes128GcmSha256
+}
+
+// KeyExchangeAlgorithm controls what key exchange algorithm is using during the handshake
+func (c *TLSEcdhePskWithAes128GcmSha256) KeyExchangeAlgorithm() KeyExchangeAlgorithm {
+	return (KeyExchangeAlgorithmPsk | KeyExchangeAlgorithmEcdhe)
+}
+
+// ECC uses Elliptic Curve Cryptography
+func (c *TLSEcdhePskWithAes128GcmSha256) ECC() bool {
+	return true
+}
+
+// ID returns the ID of the CipherSuite
+func (c *TLSEcdhePskWithAes128GcmSha256) ID() ID {
+	return TLS_ECDHE_PSK_WITH_AES_128_GCM_SHA256
+}
+
+func (c *TLSEcdhePskWithAes128GcmSha256) String() string {
+	return "TLS_ECDHE_PSK_WITH_AES_128_GCM_SHA256"
+}
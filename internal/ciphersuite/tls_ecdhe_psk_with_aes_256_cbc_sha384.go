@@ -0,0 +1,12 @@
+package ciphersuite
+
+import (
+	"crypto/sha512"
+
+	"github.com/pion/dtls/v2/pkg/crypto/clientcertificate"
+)
+
+// NewTLSEcdhePskWithAes256CbcSha384 constructs a TLS_ECDHE_PSK_WITH_AES_256_CBC_SHA384 Cipher
+func NewTLSEcdhePskWithAes256CbcSha384() *AesCbc {
+	return newAesCbc(clientcertificate.Type(0), TLS_ECDHE_PSK_WITH_AES_256_CBC_SHA384, true, (KeyExchangeAlgorithmPsk | KeyExchangeAlgorithmEcdhe), true, 32, sha512.New384)
+}
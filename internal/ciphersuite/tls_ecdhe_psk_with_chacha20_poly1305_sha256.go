@@ -0,0 +1,25 @@
+package ciphersuite
+
+// TLSEcdhePskWithChacha20Poly1305Sha256 implements the TLS_ECDHE_PSK_WITH_CHACHA20_POLY1305_SHA256 CipherSuite
+type TLSEcdhePskWithChacha20Poly1305Sha256 struct {
+	TLSPskWithChacha20Poly1305Sha256
+}
+
+// KeyExchangeAlgorithm controls what key exchange algorithm is using during the handshake
+func (c *TLSEcdhePskWithChacha20Poly1305Sha256) KeyExchangeAlgorithm() KeyExchangeAlgorithm {
+	return (KeyExchangeAlgorithmPsk | KeyExchangeAlgorithmEcdhe)
+}
+
+// ECC uses Elliptic Curve Cryptography
+func (c *TLSEcdhePskWithChacha20Poly1305Sha256) ECC() bool {
+	return true
+}
+
+// ID returns the ID of the CipherSuite
+func (c *TLSEcdhePskWithChacha20Poly1305Sha256) ID() ID {
+	return TLS_ECDHE_PSK_WITH_CHACHA20_POLY1305_SHA256
+}
+
+func (c *TLSEcdhePskWithChacha20Poly1305Sha256) String() string {
+	return "TLS_ECDHE_PSK_WITH_CHACHA20_POLY1305_SHA256"
+}
@@ -0,0 +1,12 @@
+package ciphersuite
+
+import (
+	"crypto/sha256"
+
+	"github.com/pion/dtls/v2/pkg/crypto/clientcertificate"
+)
+
+// NewTLSEcdheRsaWithAes128CbcSha256 constructs a TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256 Cipher
+func NewTLSEcdheRsaWithAes128CbcSha256() *AesCbc {
+	return newAesCbc(clientcertificate.RSASign, TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256, false, KeyExchangeAlgorithmEcdhe, true, 16, sha256.New)
+}
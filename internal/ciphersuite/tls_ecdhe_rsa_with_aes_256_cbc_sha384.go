@@ -0,0 +1,12 @@
+package ciphersuite
+
+import (
+	"crypto/sha512"
+
+	"github.com/pion/dtls/v2/pkg/crypto/clientcertificate"
+)
+
+// NewTLSEcdheRsaWithAes256CbcSha384 constructs a TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA384 Cipher
+func NewTLSEcdheRsaWithAes256CbcSha384() *AesCbc {
+	return newAesCbc(clientcertificate.RSASign, TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA384, false, KeyExchangeAlgorithmEcdhe, true, 32, sha512.New384)
+}
@@ -0,0 +1,34 @@
+package ciphersuite
+
+import (
+	"github.com/pion/dtls/v2/pkg/crypto/ciphersuite"
+	"github.com/pion/dtls/v2/pkg/crypto/clientcertificate"
+)
+
+// TLSEcjpakeWithAes128Ccm8 implements the TLS_ECJPAKE_WITH_AES_128_CCM_8
+// CipherSuite, whose key exchange is authenticated by a passphrase
+//
+// https://tools.ietf.org/html/draft-cragie-tls-ecjpake-01
+type TLSEcjpakeWithAes128Ccm8 struct {
+	Aes128Ccm
+}
+
+// NewTLSEcjpakeWithAes128Ccm8 returns the TLS_ECJPAKE_WITH_AES_128_CCM_8 CipherSuite
+func NewTLSEcjpakeWithAes128Ccm8() *TLSEcjpakeWithAes128Ccm8 {
+	return &TLSEcjpakeWithAes128Ccm8{
+		Aes128Ccm: Aes128Ccm{
+			AesCcm: AesCcm{
+				clientCertificateType: clientcertificate.Type(0),
+				id:                    TLS_ECJPAKE_WITH_AES_128_CCM_8,
+				cryptoCCMTagLen:       ciphersuite.CCMTagLength8,
+				keyExchangeAlgorithm:  KeyExchangeAlgorithmEcjpake,
+				ecc:                   true,
+			},
+		},
+	}
+}
+
+// AuthenticationType controls what authentication method is using during the handshake
+func (c *TLSEcjpakeWithAes128Ccm8) AuthenticationType() AuthenticationType {
+	return AuthenticationTypePassword
+}
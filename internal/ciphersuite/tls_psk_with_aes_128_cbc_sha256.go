@@ -14,7 +14,8 @@ import (
 
 // TLSPskWithAes128CbcSha256 implements the TLS_PSK_WITH_AES_128_CBC_SHA256 CipherSuite
 type TLSPskWithAes128CbcSha256 struct {
-	cbc atomic.Value // *cryptoCBC
+	cbc            atomic.Value // *cryptoCBC
+	encryptThenMAC bool
 }
 
 // CertificateType returns what type of certificate this CipherSuite exchanges
@@ -51,6 +52,12 @@ func (c *TLSPskWithAes128CbcSha256) AuthenticationType() AuthenticationType {
 	return AuthenticationTypePreSharedKey
 }
 
+// SetEncryptThenMAC selects Encrypt-then-MAC record protection, as negotiated
+// with the encrypt_then_mac extension. It must be called before Init.
+func (c *TLSPskWithAes128CbcSha256) SetEncryptThenMAC(encryptThenMAC bool) {
+	c.encryptThenMAC = encryptThenMAC
+}
+
 // IsInitialized returns if the CipherSuite has keying material and can
 // encrypt/decrypt packets
 func (c *TLSPskWithAes128CbcSha256) IsInitialized() bool {
@@ -70,15 +77,20 @@ func (c *TLSPskWithAes128CbcSha256) Init(masterSecret, clientRandom, serverRando
 		return err
 	}
 
+	newCBC := ciphersuite.NewCBC
+	if c.encryptThenMAC {
+		newCBC = ciphersuite.NewCBCEncryptThenMAC
+	}
+
 	var cbc *ciphersuite.CBC
 	if isClient {
-		cbc, err = ciphersuite.NewCBC(
+		cbc, err = newCBC(
 			keys.ClientWriteKey, keys.ClientWriteIV, keys.ClientMACKey,
 			keys.ServerWriteKey, keys.ServerWriteIV, keys.ServerMACKey,
 			c.HashFunc(),
 		)
 	} else {
-		cbc, err = ciphersuite.NewCBC(
+		cbc, err = newCBC(
 			keys.ServerWriteKey, keys.ServerWriteIV, keys.ServerMACKey,
 			keys.ClientWriteKey, keys.ClientWriteIV, keys.ClientMACKey,
 			c.HashFunc(),
@@ -108,3 +120,14 @@ func (c *TLSPskWithAes128CbcSha256) Decrypt(raw []byte) ([]byte, error) {
 
 	return cipherSuite.Decrypt(raw)
 }
+
+// DecryptWithHeader decrypts a single TLS RecordLayer whose header has
+// already been parsed, as required for records with a connection ID
+func (c *TLSPskWithAes128CbcSha256) DecryptWithHeader(h recordlayer.Header, raw []byte) ([]byte, error) {
+	cipherSuite, ok := c.cbc.Load().(*ciphersuite.CBC)
+	if !ok {
+		return nil, fmt.Errorf("%w, unable to decrypt", errCipherSuiteNotInit)
+	}
+
+	return cipherSuite.DecryptWithHeader(h, raw)
+}
@@ -0,0 +1,11 @@
+package ciphersuite
+
+import (
+	"github.com/pion/dtls/v2/pkg/crypto/ciphersuite"
+	"github.com/pion/dtls/v2/pkg/crypto/clientcertificate"
+)
+
+// NewTLSPskWithAes256Ccm constructs a TLS_PSK_WITH_AES_256_CCM Cipher
+func NewTLSPskWithAes256Ccm() *Aes256Ccm {
+	return newAes256Ccm(clientcertificate.Type(0), TLS_PSK_WITH_AES_256_CCM, true, ciphersuite.CCMTagLength, KeyExchangeAlgorithmPsk, false)
+}
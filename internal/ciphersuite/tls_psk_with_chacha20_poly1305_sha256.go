@@ -0,0 +1,37 @@
+package ciphersuite
+
+import "github.com/pion/dtls/v2/pkg/crypto/clientcertificate"
+
+// TLSPskWithChacha20Poly1305Sha256 implements the TLS_PSK_WITH_CHACHA20_POLY1305_SHA256 CipherSuite
+type TLSPskWithChacha20Poly1305Sha256 struct {
+	TLSEcdheEcdsaWithChacha20Poly1305Sha256
+}
+
+// CertificateType returns what type of certificate this CipherSuite exchanges
+func (c *TLSPskWithChacha20Poly1305Sha256) CertificateType() clientcertificate.Type {
+	return clientcertificate.Type(0)
+}
+
+// KeyExchangeAlgorithm controls what key exchange algorithm is using during the handshake
+func (c *TLSPskWithChacha20Poly1305Sha256) KeyExchangeAlgorithm() KeyExchangeAlgorithm {
+	return KeyExchangeAlgorithmPsk
+}
+
+// ECC uses Elliptic Curve Cryptography
+func (c *TLSPskWithChacha20Poly1305Sha256) ECC() bool {
+	return false
+}
+
+// ID returns the ID of the CipherSuite
+func (c *TLSPskWithChacha20Poly1305Sha256) ID() ID {
+	return TLS_PSK_WITH_CHACHA20_POLY1305_SHA256
+}
+
+func (c *TLSPskWithChacha20Poly1305Sha256) String() string {
+	return "TLS_PSK_WITH_CHACHA20_POLY1305_SHA256"
+}
+
+// AuthenticationType controls what authentication method is using during the handshake
+func (c *TLSPskWithChacha20Poly1305Sha256) AuthenticationType() AuthenticationType {
+	return AuthenticationTypePreSharedKey
+}
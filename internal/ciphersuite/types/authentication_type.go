@@ -8,4 +8,5 @@ const (
 	AuthenticationTypeCertificate AuthenticationType = iota + 1
 	AuthenticationTypePreSharedKey
 	AuthenticationTypeAnonymous
+	AuthenticationTypePassword
 )
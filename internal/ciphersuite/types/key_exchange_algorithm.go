@@ -9,6 +9,10 @@ const (
 	KeyExchangeAlgorithmNone KeyExchangeAlgorithm = 0
 	KeyExchangeAlgorithmPsk  KeyExchangeAlgorithm = iota << 1
 	KeyExchangeAlgorithmEcdhe
+	KeyExchangeAlgorithmEcjpake KeyExchangeAlgorithm = 1 << 3
+	// KeyExchangeAlgorithmCustom carries the opaque payloads of a key
+	// exchange brought by a custom cipher suite
+	KeyExchangeAlgorithmCustom KeyExchangeAlgorithm = 1 << 4
 )
 
 // Has check if keyExchangeAlgorithm is supported.
@@ -0,0 +1,124 @@
+package udp
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pion/transport/deadline"
+)
+
+// maxBufferSize is the number of bytes of unread datagrams a Conn holds
+// before new datagrams are dropped.
+const maxBufferSize = 1024 * 1024
+
+var (
+	errBufferFull = errors.New("udp: buffer full") //nolint:goerr113
+	errTimeout    = errors.New("udp: i/o timeout") //nolint:goerr113
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return errTimeout.Error() }
+func (timeoutError) Unwrap() error   { return errTimeout }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+type datagram struct {
+	data []byte
+	addr net.Addr
+}
+
+// packetBuffer is a queue of datagrams which, unlike packetio.Buffer,
+// remembers the address each datagram was received from.
+type packetBuffer struct {
+	mu     sync.Mutex
+	queue  []datagram
+	size   int
+	notify chan struct{}
+	closed bool
+
+	readDeadline *deadline.Deadline
+}
+
+func newPacketBuffer() *packetBuffer {
+	return &packetBuffer{
+		notify:       make(chan struct{}, 1),
+		readDeadline: deadline.New(),
+	}
+}
+
+func (b *packetBuffer) write(p []byte, addr net.Addr) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return io.ErrClosedPipe
+	}
+	if b.size+len(p) > maxBufferSize {
+		return errBufferFull
+	}
+
+	b.queue = append(b.queue, datagram{data: append([]byte{}, p...), addr: addr})
+	b.size += len(p)
+
+	select {
+	case b.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// read copies the next datagram into p. Datagrams which do not fit are
+// truncated and io.ErrShortBuffer is returned.
+func (b *packetBuffer) read(p []byte) (int, net.Addr, error) {
+	for {
+		select {
+		case <-b.readDeadline.Done():
+			return 0, nil, timeoutError{}
+		default:
+		}
+
+		b.mu.Lock()
+		if len(b.queue) > 0 {
+			d := b.queue[0]
+			b.queue[0] = datagram{}
+			b.queue = b.queue[1:]
+			b.size -= len(d.data)
+			b.mu.Unlock()
+
+			n := copy(p, d.data)
+			if n < len(d.data) {
+				return n, d.addr, io.ErrShortBuffer
+			}
+			return n, d.addr, nil
+		}
+		if b.closed {
+			b.mu.Unlock()
+			return 0, nil, io.EOF
+		}
+		b.mu.Unlock()
+
+		select {
+		case <-b.readDeadline.Done():
+			return 0, nil, timeoutError{}
+		case <-b.notify:
+		}
+	}
+}
+
+func (b *packetBuffer) close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.closed {
+		b.closed = true
+		close(b.notify)
+	}
+	return nil
+}
+
+func (b *packetBuffer) setReadDeadline(t time.Time) {
+	b.readDeadline.Set(t)
+}
@@ -0,0 +1,456 @@
+// Package udp provides a connection-oriented listener over a UDP PacketConn.
+// It is a copy of github.com/pion/udp v0.1.1, which fixes made upstream no
+// longer reach, extended to route datagrams by an identifier other than the
+// remote address, such as a DTLS Connection ID. Changes other than the
+// routing belong upstream.
+package udp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pion/transport/deadline"
+)
+
+const (
+	receiveMTU           = 8192
+	defaultListenBacklog = 128 // same as Linux default
+
+	// At most this many datagrams from a remote are held while its first
+	// datagram is verified, later ones are dropped
+	maxPendingDatagrams = 16
+)
+
+// Typed errors
+var (
+	ErrClosedListener      = errors.New("udp: listener closed")
+	ErrListenQueueExceeded = errors.New("udp: listen queue exceeded")
+)
+
+// listener augments a connection-oriented Listener over a UDP PacketConn
+type listener struct {
+	pConn *net.UDPConn
+
+	accepting      atomic.Value // bool
+	acceptCh       chan *Conn
+	doneCh         chan struct{}
+	doneOnce       sync.Once
+	acceptFilter   func([]byte) bool
+	acceptVerifier func(net.Addr, []byte) (bool, []byte)
+	readBufferPool *sync.Pool
+
+	datagramRouter       func([]byte) (string, bool)
+	connectionIdentifier func([]byte) (string, bool)
+
+	connLock sync.Mutex
+	conns    map[string]*Conn
+	pending  map[string][][]byte
+	connWG   sync.WaitGroup
+
+	readWG   sync.WaitGroup
+	errClose atomic.Value // error
+}
+
+// Accept waits for and returns the next connection to the listener.
+func (l *listener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.acceptCh:
+		l.connWG.Add(1)
+		return c, nil
+
+	case <-l.doneCh:
+		return nil, ErrClosedListener
+	}
+}
+
+// Close closes the listener.
+// Any blocked Accept operations will be unblocked and return errors.
+func (l *listener) Close() error {
+	var err error
+	l.doneOnce.Do(func() {
+		l.accepting.Store(false)
+		close(l.doneCh)
+
+		l.connLock.Lock()
+		// Close unaccepted connections
+	L_CLOSE:
+		for {
+			select {
+			case c := <-l.acceptCh:
+				close(c.doneCh)
+				l.removeConn(c)
+
+			default:
+				break L_CLOSE
+			}
+		}
+		nConns := len(l.conns)
+		l.connLock.Unlock()
+
+		l.connWG.Done()
+
+		if nConns == 0 {
+			// Wait if this is the final connection
+			l.readWG.Wait()
+			if errClose, ok := l.errClose.Load().(error); ok {
+				err = errClose
+			}
+		} else {
+			err = nil
+		}
+	})
+
+	return err
+}
+
+// Addr returns the listener's network address.
+func (l *listener) Addr() net.Addr {
+	return l.pConn.LocalAddr()
+}
+
+// ListenConfig stores options for listening to an address.
+type ListenConfig struct {
+	// Backlog defines the maximum length of the queue of pending
+	// connections. It is equivalent of the backlog argument of
+	// POSIX listen function.
+	// If a connection request arrives when the queue is full,
+	// the request will be silently discarded, unlike TCP.
+	// Set zero to use default value 128 which is same as Linux default.
+	Backlog int
+
+	// AcceptFilter determines whether the new conn should be made for
+	// the incoming packet. If not set, any packet creates new conn.
+	AcceptFilter func([]byte) bool
+
+	// AcceptVerifier is called for packets from unknown remotes that passed
+	// AcceptFilter. A new conn is only made if it returns true, otherwise
+	// the returned response, if any, is written back to the remote without
+	// keeping any state for it. It is not called while the queue of pending
+	// connections is full, so a new conn is always made if it returns true.
+	// It is called in its own goroutine, so that it may block without
+	// delaying the datagrams of other conns. Later packets from the remote
+	// are held until it returns, and delivered to the new conn.
+	AcceptVerifier func(raddr net.Addr, packet []byte) (accept bool, response []byte)
+
+	// DatagramRouter routes an incoming datagram to a connection by extracting
+	// an identifier from its payload. If no connection is associated with the
+	// identifier, the datagram is routed by remote address.
+	DatagramRouter func([]byte) (string, bool)
+
+	// ConnectionIdentifier extracts an identifier from an outgoing datagram.
+	// If the identifier is not already associated with the connection, it
+	// will be added so that DatagramRouter can find it.
+	ConnectionIdentifier func([]byte) (string, bool)
+}
+
+// Listen creates a new listener based on the ListenConfig.
+func (lc *ListenConfig) Listen(network string, laddr *net.UDPAddr) (net.Listener, error) {
+	if lc.Backlog == 0 {
+		lc.Backlog = defaultListenBacklog
+	}
+
+	conn, err := net.ListenUDP(network, laddr)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &listener{
+		pConn:                conn,
+		acceptCh:             make(chan *Conn, lc.Backlog),
+		conns:                make(map[string]*Conn),
+		pending:              make(map[string][][]byte),
+		doneCh:               make(chan struct{}),
+		acceptFilter:         lc.AcceptFilter,
+		acceptVerifier:       lc.AcceptVerifier,
+		datagramRouter:       lc.DatagramRouter,
+		connectionIdentifier: lc.ConnectionIdentifier,
+		readBufferPool: &sync.Pool{
+			New: func() interface{} {
+				buf := make([]byte, receiveMTU)
+				return &buf
+			},
+		},
+	}
+
+	l.accepting.Store(true)
+	l.connWG.Add(1)
+	l.readWG.Add(2) // wait readLoop and Close execution routine
+
+	go l.readLoop()
+	go func() {
+		l.connWG.Wait()
+		if err := l.pConn.Close(); err != nil {
+			l.errClose.Store(err)
+		}
+		l.readWG.Done()
+	}()
+
+	return l, nil
+}
+
+// Listen creates a new listener using default ListenConfig.
+func Listen(network string, laddr *net.UDPAddr) (net.Listener, error) {
+	return (&ListenConfig{}).Listen(network, laddr)
+}
+
+// readLoop has to tasks:
+// 1. Dispatching incoming packets to the correct Conn.
+//    It can therefore not be ended until all Conns are closed.
+// 2. Creating a new Conn when receiving from a new remote.
+func (l *listener) readLoop() {
+	defer l.readWG.Done()
+
+	for {
+		buf := *(l.readBufferPool.Get().(*[]byte))
+		n, raddr, err := l.pConn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		conn, ok, err := l.getConn(raddr, buf[:n])
+		if err != nil {
+			continue
+		}
+		if ok {
+			_ = conn.buffer.write(buf[:n], raddr)
+		}
+	}
+}
+
+func (l *listener) getConn(raddr net.Addr, buf []byte) (*Conn, bool, error) {
+	l.connLock.Lock()
+	defer l.connLock.Unlock()
+	if l.datagramRouter != nil {
+		if id, ok := l.datagramRouter(buf); ok {
+			if conn, ok := l.conns[id]; ok {
+				return conn, true, nil
+			}
+		}
+	}
+	conn, ok := l.conns[raddr.String()]
+	if !ok {
+		if !l.accepting.Load().(bool) {
+			return nil, false, ErrClosedListener
+		}
+		if l.acceptFilter != nil {
+			if !l.acceptFilter(buf) {
+				return nil, false, nil
+			}
+		}
+		if datagrams, ok := l.pending[raddr.String()]; ok {
+			// The first datagram of the remote is being verified
+			if len(datagrams) < maxPendingDatagrams {
+				l.pending[raddr.String()] = append(datagrams, append([]byte{}, buf...))
+			}
+			return nil, false, nil
+		}
+		// Connections are only queued while holding connLock, and room is
+		// kept for those being verified, so a conn can be queued if there
+		// is room now. The verifier is only called once the conn is sure
+		// to be created, as it may keep state for the accepted conn.
+		if len(l.acceptCh)+len(l.pending) >= cap(l.acceptCh) {
+			return nil, false, ErrListenQueueExceeded
+		}
+		if l.acceptVerifier != nil {
+			l.pending[raddr.String()] = nil
+			l.readWG.Add(1)
+			go l.verify(raddr, append([]byte{}, buf...))
+			return nil, false, nil
+		}
+		conn = l.newConn(raddr)
+		l.acceptCh <- conn
+		l.conns[raddr.String()] = conn
+	}
+	return conn, true, nil
+}
+
+// verify calls acceptVerifier with the first datagram of a remote, and
+// creates its conn if it is accepted. It runs in its own goroutine, without
+// holding connLock, so that the verifier doesn't block the read loop.
+func (l *listener) verify(raddr net.Addr, buf []byte) {
+	defer l.readWG.Done()
+
+	accept, response := l.acceptVerifier(raddr, buf)
+
+	l.connLock.Lock()
+	defer l.connLock.Unlock()
+	datagrams := l.pending[raddr.String()]
+	delete(l.pending, raddr.String())
+	if !accept {
+		if len(response) > 0 {
+			_, _ = l.pConn.WriteTo(response, raddr)
+		}
+		return
+	}
+	if !l.accepting.Load().(bool) {
+		return
+	}
+
+	// Room for the conn was kept in the queue. The datagrams are buffered
+	// before connLock is released, so that they are read in order.
+	conn := l.newConn(raddr)
+	l.acceptCh <- conn
+	l.conns[raddr.String()] = conn
+	for _, datagram := range append([][]byte{buf}, datagrams...) {
+		_ = conn.buffer.write(datagram, raddr)
+	}
+}
+
+// removeConn removes every association of c. connLock must be held.
+func (l *listener) removeConn(c *Conn) {
+	for id, conn := range l.conns {
+		if conn == c {
+			delete(l.conns, id)
+		}
+	}
+}
+
+// Conn augments a connection-oriented connection over a UDP PacketConn
+type Conn struct {
+	listener *listener
+
+	rAddrLock  sync.RWMutex
+	rAddr      net.Addr
+	sourceAddr net.Addr
+
+	buffer *packetBuffer
+
+	doneCh   chan struct{}
+	doneOnce sync.Once
+
+	writeDeadline *deadline.Deadline
+}
+
+func (l *listener) newConn(rAddr net.Addr) *Conn {
+	return &Conn{
+		listener:      l,
+		rAddr:         rAddr,
+		sourceAddr:    rAddr,
+		buffer:        newPacketBuffer(),
+		doneCh:        make(chan struct{}),
+		writeDeadline: deadline.New(),
+	}
+}
+
+// Read reads from c into p
+func (c *Conn) Read(p []byte) (int, error) {
+	n, addr, err := c.buffer.read(p)
+	if addr != nil {
+		c.rAddrLock.Lock()
+		c.sourceAddr = addr
+		c.rAddrLock.Unlock()
+	}
+	return n, err
+}
+
+// Write writes len(p) bytes from p to the DTLS connection
+func (c *Conn) Write(p []byte) (n int, err error) {
+	select {
+	case <-c.writeDeadline.Done():
+		return 0, context.DeadlineExceeded
+	default:
+	}
+	if c.listener.connectionIdentifier != nil {
+		if id, ok := c.listener.connectionIdentifier(p); ok {
+			c.listener.connLock.Lock()
+			if _, exists := c.listener.conns[id]; !exists {
+				c.listener.conns[id] = c
+			}
+			c.listener.connLock.Unlock()
+		}
+	}
+	return c.listener.pConn.WriteTo(p, c.RemoteAddr())
+}
+
+// Close closes the conn and releases any Read calls
+func (c *Conn) Close() error {
+	var err error
+	c.doneOnce.Do(func() {
+		c.listener.connWG.Done()
+		close(c.doneCh)
+		_ = c.buffer.close()
+		c.listener.connLock.Lock()
+		c.listener.removeConn(c)
+		nConns := len(c.listener.conns)
+		c.listener.connLock.Unlock()
+
+		if nConns == 0 && !c.listener.accepting.Load().(bool) {
+			// Wait if this is the final connection
+			c.listener.readWG.Wait()
+			if errClose, ok := c.listener.errClose.Load().(error); ok {
+				err = errClose
+			}
+		} else {
+			err = nil
+		}
+	})
+
+	return err
+}
+
+// LocalAddr implements net.Conn.LocalAddr
+func (c *Conn) LocalAddr() net.Addr {
+	return c.listener.pConn.LocalAddr()
+}
+
+// RemoteAddr implements net.Conn.RemoteAddr
+func (c *Conn) RemoteAddr() net.Addr {
+	c.rAddrLock.RLock()
+	defer c.rAddrLock.RUnlock()
+	return c.rAddr
+}
+
+// SourceAddr returns the address the most recently read datagram was
+// received from. It differs from RemoteAddr when a datagram has been
+// routed to this connection by DatagramRouter from a new address.
+func (c *Conn) SourceAddr() net.Addr {
+	c.rAddrLock.RLock()
+	defer c.rAddrLock.RUnlock()
+	return c.sourceAddr
+}
+
+// SetRemoteAddr changes the address datagrams are written to, and the address
+// by which incoming datagrams are associated with this connection. It should
+// only be called once the peer has been authenticated at the new address.
+func (c *Conn) SetRemoteAddr(addr net.Addr) {
+	c.rAddrLock.Lock()
+	old := c.rAddr
+	c.rAddr = addr
+	c.rAddrLock.Unlock()
+
+	if old.String() == addr.String() {
+		return
+	}
+
+	c.listener.connLock.Lock()
+	defer c.listener.connLock.Unlock()
+	if conn, ok := c.listener.conns[old.String()]; ok && conn == c {
+		delete(c.listener.conns, old.String())
+	}
+	if _, ok := c.listener.conns[addr.String()]; !ok {
+		c.listener.conns[addr.String()] = c
+	}
+}
+
+// SetDeadline implements net.Conn.SetDeadline
+func (c *Conn) SetDeadline(t time.Time) error {
+	c.writeDeadline.Set(t)
+	return c.SetReadDeadline(t)
+}
+
+// SetReadDeadline implements net.Conn.SetDeadline
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	c.buffer.setReadDeadline(t)
+	return nil
+}
+
+// SetWriteDeadline implements net.Conn.SetDeadline
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	c.writeDeadline.Set(t)
+	// Write deadline of underlying connection should not be changed
+	// since the connection can be shared.
+	return nil
+}
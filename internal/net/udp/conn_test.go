@@ -0,0 +1,769 @@
+// +build !js
+
+package udp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+)
+
+var errHandshakeFailed = errors.New("handshake failed")
+
+// Note: doesn't work since closing isn't propagated to the other side
+// func TestNetTest(t *testing.T) {
+//	lim := test.TimeOut(time.Minute*1 + time.Second*10)
+//	defer lim.Stop()
+//
+//	nettest.TestConn(t, func() (c1, c2 net.Conn, stop func(), err error) {
+//		listener, c1, c2, err = pipe()
+//		if err != nil {
+//			return nil, nil, nil, err
+//		}
+//		stop = func() {
+//			c1.Close()
+//			c2.Close()
+//			listener.Close(1 * time.Second)
+//		}
+//		return
+//	})
+//}
+
+func TestStressDuplex(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	// Run the test
+	stressDuplex(t)
+}
+
+func stressDuplex(t *testing.T) {
+	listener, ca, cb, err := pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		err = ca.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = cb.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = listener.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	opt := test.Options{
+		MsgSize:  2048,
+		MsgCount: 1, // Can't rely on UDP message order in CI
+	}
+
+	err = test.StressDuplex(ca, cb, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestListenerCloseTimeout(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	listener, ca, _, err := pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = listener.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Close client after server closes to cleanup
+	err = ca.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestListenerCloseUnaccepted(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	const backlog = 2
+
+	network, addr := getConfig()
+	listener, err := (&ListenConfig{
+		Backlog: backlog,
+	}).Listen(network, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < backlog; i++ {
+		conn, derr := net.DialUDP(network, nil, listener.Addr().(*net.UDPAddr))
+		if derr != nil {
+			t.Error(derr)
+			continue
+		}
+		if _, werr := conn.Write([]byte{byte(i)}); werr != nil {
+			t.Error(werr)
+		}
+		if cerr := conn.Close(); cerr != nil {
+			t.Error(cerr)
+		}
+	}
+
+	time.Sleep(100 * time.Millisecond) // Wait all packets being processed by readLoop
+
+	// Unaccepted connections must be closed by listener.Close()
+	err = listener.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestListenerAcceptFilter(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	testCases := map[string]struct {
+		packet []byte
+		accept bool
+	}{
+		"CreateConn": {
+			packet: []byte{0xAA},
+			accept: true,
+		},
+		"Discarded": {
+			packet: []byte{0x00},
+			accept: false,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			network, addr := getConfig()
+			listener, err := (&ListenConfig{
+				AcceptFilter: func(pkt []byte) bool {
+					return pkt[0] == 0xAA
+				},
+			}).Listen(network, addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var wgAcceptLoop sync.WaitGroup
+			wgAcceptLoop.Add(1)
+			defer func() {
+				cerr := listener.Close()
+				if cerr != nil {
+					t.Fatal(cerr)
+				}
+				wgAcceptLoop.Wait()
+			}()
+
+			conn, derr := net.DialUDP(network, nil, listener.Addr().(*net.UDPAddr))
+			if derr != nil {
+				t.Fatal(derr)
+			}
+			if _, werr := conn.Write(testCase.packet); werr != nil {
+				t.Fatal(werr)
+			}
+			defer func() {
+				if cerr := conn.Close(); cerr != nil {
+					t.Error(cerr)
+				}
+			}()
+
+			chAccepted := make(chan struct{})
+			go func() {
+				defer wgAcceptLoop.Done()
+
+				conn, aerr := listener.Accept()
+				if aerr != nil {
+					if !errors.Is(aerr, ErrClosedListener) {
+						t.Error(aerr)
+					}
+					return
+				}
+				close(chAccepted)
+				if cerr := conn.Close(); cerr != nil {
+					t.Error(cerr)
+				}
+			}()
+
+			var accepted bool
+			select {
+			case <-chAccepted:
+				accepted = true
+			case <-time.After(10 * time.Millisecond):
+			}
+
+			if accepted != testCase.accept {
+				if testCase.accept {
+					t.Error("Packet should create new conn")
+				} else {
+					t.Error("Packet should not create new conn")
+				}
+			}
+		})
+	}
+}
+
+func TestListenerConcurrent(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	const backlog = 2
+
+	network, addr := getConfig()
+	listener, err := (&ListenConfig{
+		Backlog: backlog,
+	}).Listen(network, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < backlog+1; i++ {
+		conn, derr := net.DialUDP(network, nil, listener.Addr().(*net.UDPAddr))
+		if derr != nil {
+			t.Error(derr)
+			continue
+		}
+		if _, werr := conn.Write([]byte{byte(i)}); werr != nil {
+			t.Error(werr)
+		}
+		if cerr := conn.Close(); cerr != nil {
+			t.Error(cerr)
+		}
+	}
+
+	time.Sleep(100 * time.Millisecond) // Wait all packets being processed by readLoop
+
+	for i := 0; i < backlog; i++ {
+		conn, aerr := listener.Accept()
+		if aerr != nil {
+			t.Error(aerr)
+			continue
+		}
+		b := make([]byte, 1)
+		n, rerr := conn.Read(b)
+		if rerr != nil {
+			t.Error(rerr)
+		} else if !bytes.Equal([]byte{byte(i)}, b[:n]) {
+			t.Errorf("Packet from connection %d is wrong, expected: [%d], got: %v", i, i, b[:n])
+		}
+		if err = conn.Close(); err != nil {
+			t.Error(err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if conn, aerr := listener.Accept(); !errors.Is(aerr, ErrClosedListener) {
+			t.Errorf("Connection exceeding backlog limit must be discarded: %v", aerr)
+			if aerr == nil {
+				_ = conn.Close()
+			}
+		}
+	}()
+
+	time.Sleep(100 * time.Millisecond) // Last Accept should be discarded
+	err = listener.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wg.Wait()
+}
+
+func pipe() (net.Listener, net.Conn, *net.UDPConn, error) {
+	// Start listening
+	network, addr := getConfig()
+	listener, err := Listen(network, addr)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to listen: %w", err)
+	}
+
+	// Open a connection
+	var dConn *net.UDPConn
+	dConn, err = net.DialUDP(network, nil, listener.Addr().(*net.UDPAddr))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to dial: %w", err)
+	}
+
+	// Write to the connection to initiate it
+	handshake := "hello"
+	_, err = dConn.Write([]byte(handshake))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to write to dialed Conn: %w", err)
+	}
+
+	// Accept the connection
+	var lConn net.Conn
+	lConn, err = listener.Accept()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to accept Conn: %w", err)
+	}
+
+	buf := make([]byte, len(handshake))
+	n := 0
+	n, err = lConn.Read(buf)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to read handshake: %w", err)
+	}
+
+	result := string(buf[:n])
+	if handshake != result {
+		return nil, nil, nil, fmt.Errorf("%w: %s != %s", errHandshakeFailed, handshake, result)
+	}
+
+	return listener, lConn, dConn, nil
+}
+
+func getConfig() (string, *net.UDPAddr) {
+	return "udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 0}
+}
+
+func TestConnClose(t *testing.T) {
+	lim := test.TimeOut(time.Second * 5)
+	defer lim.Stop()
+
+	t.Run("Close", func(t *testing.T) {
+		// Check for leaking routines
+		report := test.CheckRoutines(t)
+		defer report()
+
+		l, ca, cb, errPipe := pipe()
+		if errPipe != nil {
+			t.Fatal(errPipe)
+		}
+		if err := ca.Close(); err != nil {
+			t.Errorf("Failed to close A side: %v", err)
+		}
+		if err := cb.Close(); err != nil {
+			t.Errorf("Failed to close B side: %v", err)
+		}
+		if err := l.Close(); err != nil {
+			t.Errorf("Failed to close listener: %v", err)
+		}
+	})
+	t.Run("CloseError1", func(t *testing.T) {
+		// Check for leaking routines
+		report := test.CheckRoutines(t)
+		defer report()
+
+		l, ca, cb, errPipe := pipe()
+		if errPipe != nil {
+			t.Fatal(errPipe)
+		}
+		// Close l.pConn to inject error.
+		if err := l.(*listener).pConn.Close(); err != nil {
+			t.Error(err)
+		}
+
+		if err := cb.Close(); err != nil {
+			t.Errorf("Failed to close A side: %v", err)
+		}
+		if err := ca.Close(); err != nil {
+			t.Errorf("Failed to close B side: %v", err)
+		}
+		if err := l.Close(); err == nil {
+			t.Errorf("Error is not propagated to Listener.Close")
+		}
+	})
+	t.Run("CloseError2", func(t *testing.T) {
+		// Check for leaking routines
+		report := test.CheckRoutines(t)
+		defer report()
+
+		l, ca, cb, errPipe := pipe()
+		if errPipe != nil {
+			t.Fatal(errPipe)
+		}
+		// Close l.pConn to inject error.
+		if err := l.(*listener).pConn.Close(); err != nil {
+			t.Error(err)
+		}
+
+		if err := cb.Close(); err != nil {
+			t.Errorf("Failed to close A side: %v", err)
+		}
+		if err := l.Close(); err != nil {
+			t.Errorf("Failed to close listener: %v", err)
+		}
+		if err := ca.Close(); err == nil {
+			t.Errorf("Error is not propagated to Conn.Close")
+		}
+	})
+}
+
+func TestListenerRouting(t *testing.T) {
+	// Datagrams starting with 0xff are routed by the following byte, and
+	// connections are identified by the first byte they write.
+	lc := ListenConfig{
+		DatagramRouter: func(buf []byte) (string, bool) {
+			if len(buf) < 2 || buf[0] != 0xff {
+				return "", false
+			}
+			return string(buf[1:2]), true
+		},
+		ConnectionIdentifier: func(buf []byte) (string, bool) {
+			if len(buf) < 1 {
+				return "", false
+			}
+			return string(buf[:1]), true
+		},
+	}
+	listener, err := lc.Listen("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = listener.Close()
+	}()
+
+	dial := func() *net.UDPConn {
+		c, dErr := net.DialUDP("udp", nil, listener.Addr().(*net.UDPAddr))
+		if dErr != nil {
+			t.Fatal(dErr)
+		}
+		return c
+	}
+	readConn := func(c net.Conn) ([]byte, error) {
+		buf := make([]byte, receiveMTU)
+		if dErr := c.SetReadDeadline(time.Now().Add(time.Second)); dErr != nil {
+			return nil, dErr
+		}
+		n, rErr := c.Read(buf)
+		return buf[:n], rErr
+	}
+
+	first := dial()
+	defer func() {
+		_ = first.Close()
+	}()
+	if _, err = first.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	if _, err = readConn(conn); err != nil {
+		t.Fatal(err)
+	}
+
+	// Associate the identifier 'a' with the accepted connection
+	if _, err = conn.Write([]byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = readConn(first); err != nil {
+		t.Fatal(err)
+	}
+
+	// A datagram carrying the identifier from a new address must reach the
+	// same connection.
+	second := dial()
+	defer func() {
+		_ = second.Close()
+	}()
+	if _, err = second.Write([]byte{0xff, 'a', 0x01}); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := readConn(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(msg, []byte{0xff, 'a', 0x01}) {
+		t.Errorf("unexpected datagram: %x", msg)
+	}
+
+	c, ok := conn.(*Conn)
+	if !ok {
+		t.Fatal("accepted connection is not a *Conn")
+	}
+	if c.SourceAddr().String() != second.LocalAddr().String() {
+		t.Errorf("SourceAddr: got %v, want %v", c.SourceAddr(), second.LocalAddr())
+	}
+	if c.RemoteAddr().String() != first.LocalAddr().String() {
+		t.Errorf("RemoteAddr changed before SetRemoteAddr: %v", c.RemoteAddr())
+	}
+
+	// Once migrated, writes go to the new address
+	c.SetRemoteAddr(c.SourceAddr())
+	if _, err = conn.Write([]byte("b")); err != nil {
+		t.Fatal(err)
+	}
+	if msg, err = readConn(second); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(msg, []byte("b")) {
+		t.Errorf("unexpected datagram: %x", msg)
+	}
+}
+
+func TestListenerAcceptVerifier(t *testing.T) {
+	// Only datagrams carrying a token are accepted, others are answered
+	// with the token and don't create a connection.
+	lc := ListenConfig{
+		AcceptVerifier: func(_ net.Addr, buf []byte) (bool, []byte) {
+			if bytes.Equal(buf, []byte("token")) {
+				return true, nil
+			}
+			return false, []byte("token")
+		},
+	}
+	ln, err := lc.Listen("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = ln.Close()
+	}()
+
+	c, err := net.DialUDP("udp", nil, ln.Addr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = c.Close()
+	}()
+
+	if _, err = c.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, receiveMTU)
+	if err = c.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	n, err := c.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf[:n], []byte("token")) {
+		t.Fatalf("unexpected response: %x", buf[:n])
+	}
+
+	l, ok := ln.(*listener)
+	if !ok {
+		t.Fatal("listener is not a *listener")
+	}
+	l.connLock.Lock()
+	nConns := len(l.conns)
+	l.connLock.Unlock()
+	if nConns != 0 {
+		t.Fatalf("rejected datagram created %d connections", nConns)
+	}
+
+	if _, err = c.Write([]byte("token")); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	n, err = conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf[:n], []byte("token")) {
+		t.Errorf("unexpected datagram: %x", buf[:n])
+	}
+}
+
+func TestListenerAcceptVerifierQueueExceeded(t *testing.T) {
+	// The verifier is not called while no connection can be queued, so any
+	// state it keeps for an accepted datagram belongs to a queued conn.
+	var verified int32
+	lc := ListenConfig{
+		Backlog: 1,
+		AcceptVerifier: func(net.Addr, []byte) (bool, []byte) {
+			atomic.AddInt32(&verified, 1)
+			return true, nil
+		},
+	}
+	ln, err := lc.Listen("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = ln.Close()
+	}()
+
+	l, ok := ln.(*listener)
+	if !ok {
+		t.Fatal("listener is not a *listener")
+	}
+	first := &net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 1}
+	second := &net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 2}
+	if _, _, err := l.getConn(first, []byte("a")); err != nil {
+		t.Fatalf("first conn not verified: %v", err)
+	}
+	// The queue is full while the first conn is verified, and once it is
+	// queued
+	if _, _, err := l.getConn(second, []byte("a")); !errors.Is(err, ErrListenQueueExceeded) {
+		t.Fatalf("expected error: %v, got: %v", ErrListenQueueExceeded, err)
+	}
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	if conn.RemoteAddr().String() != first.String() {
+		t.Errorf("unexpected conn from %s", conn.RemoteAddr())
+	}
+	if v := atomic.LoadInt32(&verified); v != 1 {
+		t.Errorf("verifier called %d times, expected once", v)
+	}
+}
+
+func TestListenerSlowAcceptVerifier(t *testing.T) {
+	// A verifier blocked on a new remote delays neither established conns
+	// nor later datagrams of the remote, which are held until it returns.
+	release := make(chan struct{})
+	lc := ListenConfig{
+		AcceptVerifier: func(_ net.Addr, buf []byte) (bool, []byte) {
+			if bytes.Equal(buf, []byte("slow")) {
+				<-release
+			}
+			return true, nil
+		},
+	}
+	ln, err := lc.Listen("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = ln.Close()
+	}()
+
+	dial := func() *net.UDPConn {
+		c, dErr := net.DialUDP("udp", nil, ln.Addr().(*net.UDPAddr))
+		if dErr != nil {
+			t.Fatal(dErr)
+		}
+		return c
+	}
+	established := dial()
+	defer func() {
+		_ = established.Close()
+	}()
+	if _, err = established.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	buf := make([]byte, receiveMTU)
+	if _, err = conn.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	slow := dial()
+	defer func() {
+		_ = slow.Close()
+	}()
+	for _, msg := range []string{"slow", "a", "b"} {
+		if _, err = slow.Write([]byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The established conn is served while the verifier blocks
+	if _, err = established.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	if err = conn.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf[:n], []byte("ping")) {
+		t.Fatalf("unexpected datagram: %x", buf[:n])
+	}
+	if _, err = conn.Write([]byte("pong")); err != nil {
+		t.Fatal(err)
+	}
+	if err = established.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if n, err = established.Read(buf); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(buf[:n], []byte("pong")) {
+		t.Fatalf("unexpected datagram: %x", buf[:n])
+	}
+
+	close(release)
+	slowConn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = slowConn.Close()
+	}()
+	if err = slowConn.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range []string{"slow", "a", "b"} {
+		if n, err = slowConn.Read(buf); err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(buf[:n], []byte(msg)) {
+			t.Errorf("unexpected datagram: %x, expected %x", buf[:n], msg)
+		}
+	}
+}
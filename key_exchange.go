@@ -0,0 +1,107 @@
+package dtls
+
+import (
+	"context"
+	"net"
+)
+
+// KeyExchange generates and consumes the payloads of the ServerKeyExchange
+// and ClientKeyExchange messages, and derives the premaster secret from them.
+// A new KeyExchange is used for every handshake.
+type KeyExchange interface {
+	// ServerKeyExchange returns the payload of the ServerKeyExchange sent by
+	// the server. A nil payload omits the message.
+	ServerKeyExchange() ([]byte, error)
+
+	// ProcessServerKeyExchange reads the payload of the ServerKeyExchange
+	// received by the client, nil if the message was omitted
+	ProcessServerKeyExchange(payload []byte) error
+
+	// ClientKeyExchange returns the non-nil payload of the ClientKeyExchange
+	// sent by the client, and the premaster secret
+	ClientKeyExchange() (payload, preMasterSecret []byte, err error)
+
+	// ProcessClientKeyExchange reads the payload of the ClientKeyExchange
+	// received by the server, and returns the premaster secret
+	ProcessClientKeyExchange(payload []byte) (preMasterSecret []byte, err error)
+}
+
+// KeyExchangeCipherSuite is a CipherSuite that brings its own KeyExchange,
+// which replaces the ECDHE and PSK key exchanges of the handshake. Its
+// payloads are exchanged as they are, and KeyExchangeAlgorithm is ignored.
+//
+// No certificates are exchanged with a custom key exchange, so the
+// KeyExchange must authenticate the peer itself, and the CipherSuite should
+// report CipherSuiteAuthenticationTypeAnonymous. It can't be combined with
+// certificate authentication.
+type KeyExchangeCipherSuite interface {
+	CipherSuite
+
+	// NewKeyExchange returns the KeyExchange of the handshake described by
+	// info
+	NewKeyExchange(info *KeyExchangeInfo) (KeyExchange, error)
+}
+
+// KeyExchangeInfo describes the handshake a KeyExchange is created for, so it
+// can look up the credentials of the peer
+type KeyExchangeInfo struct {
+	// ClientRandom and ServerRandom are the randoms of the hellos
+	ClientRandom []byte
+	ServerRandom []byte
+
+	// IsClient is true if the KeyExchange runs on the client
+	IsClient bool
+
+	// ServerName is the server name requested by the client in the
+	// server_name extension, empty if none
+	ServerName string
+
+	// Identity is the PSKIdentityHint of the Config: the identity of the
+	// client on the client, the identity hint on the server
+	Identity []byte
+
+	// RemoteAddr is the address of the peer
+	RemoteAddr net.Addr
+
+	ctx context.Context
+}
+
+// Context returns the context of the handshake
+func (i *KeyExchangeInfo) Context() context.Context {
+	return i.ctx
+}
+
+// messageKeyExchangeAlgorithm returns the KeyExchangeAlgorithm the key exchange
+// messages of a CipherSuite are parsed with
+func messageKeyExchangeAlgorithm(c CipherSuite) CipherSuiteKeyExchangeAlgorithm {
+	if _, ok := c.(KeyExchangeCipherSuite); ok {
+		return CipherSuiteKeyExchangeAlgorithmCustom
+	}
+	return c.KeyExchangeAlgorithm()
+}
+
+// newKeyExchange creates the KeyExchange of the negotiated CipherSuite, nil
+// if it uses a built-in key exchange
+func newKeyExchange(ctx context.Context, c flightConn, state *State, cfg *handshakeConfig) (KeyExchange, error) {
+	suite, ok := state.cipherSuite.(KeyExchangeCipherSuite)
+	if !ok {
+		return nil, nil //nolint:nilnil
+	}
+
+	localRandom := state.localRandom.MarshalFixed()
+	remoteRandom := state.remoteRandom.MarshalFixed()
+	info := &KeyExchangeInfo{
+		IsClient:   state.isClient,
+		ServerName: state.serverName,
+		Identity:   cfg.localPSKIdentityHint,
+		RemoteAddr: c.RemoteAddr(),
+		ctx:        ctx,
+	}
+	if state.isClient {
+		info.ClientRandom, info.ServerRandom = localRandom[:], remoteRandom[:]
+		info.ServerName = cfg.serverName
+	} else {
+		info.ClientRandom, info.ServerRandom = remoteRandom[:], localRandom[:]
+	}
+	return suite.NewKeyExchange(info)
+}
@@ -1,11 +1,14 @@
 package dtls
 
 import (
+	"crypto/rand"
 	"net"
+	"sync"
 
+	"github.com/pion/dtls/v2/internal/net/udp"
 	"github.com/pion/dtls/v2/pkg/protocol"
+	"github.com/pion/dtls/v2/pkg/protocol/handshake"
 	"github.com/pion/dtls/v2/pkg/protocol/recordlayer"
-	"github.com/pion/udp"
 )
 
 // Listen creates a DTLS listener
@@ -14,6 +17,19 @@ func Listen(network string, laddr *net.UDPAddr, config *Config) (net.Listener, e
 		return nil, err
 	}
 
+	cookieSecrets := config.CookieSecrets
+	if cookieSecrets == nil {
+		var secret [CookieSecretLength]byte
+		if _, err := rand.Read(secret[:]); err != nil {
+			return nil, err
+		}
+		cookieSecrets = &CookieSecrets{secrets: [][CookieSecretLength]byte{secret}}
+	}
+	l := &listener{
+		config:        config,
+		cookieSecrets: cookieSecrets,
+	}
+
 	lc := udp.ListenConfig{
 		AcceptFilter: func(packet []byte) bool {
 			pkts, err := recordlayer.UnpackDatagram(packet)
@@ -26,15 +42,22 @@ func Listen(network string, laddr *net.UDPAddr, config *Config) (net.Listener, e
 			}
 			return h.ContentType == protocol.ContentTypeHandshake
 		},
+		AcceptVerifier: l.verifyHello,
+	}
+	// If connection ID support is enabled, then they must be supported in
+	// routing.
+	if config.ConnectionIDGenerator != nil {
+		if size := len(config.ConnectionIDGenerator()); size > 0 {
+			lc.DatagramRouter = cidDatagramRouter(size)
+			lc.ConnectionIdentifier = cidConnIdentifier()
+		}
 	}
 	parent, err := lc.Listen(network, laddr)
 	if err != nil {
 		return nil, err
 	}
-	return &listener{
-		config: config,
-		parent: parent,
-	}, nil
+	l.start(parent)
+	return l, nil
 }
 
 // NewListener creates a DTLS listener which accepts connections from an inner Listener.
@@ -43,35 +66,232 @@ func NewListener(inner net.Listener, config *Config) (net.Listener, error) {
 		return nil, err
 	}
 
-	return &listener{
+	l := &listener{
 		config: config,
-		parent: inner,
-	}, nil
+	}
+	l.start(inner)
+	return l, nil
 }
 
-// listener represents a DTLS listener
+// listener represents a DTLS listener. Connections accepted from the parent
+// listener are handshaked in the background, and only established
+// connections are returned by Accept.
 type listener struct {
 	config *Config
 	parent net.Listener
+
+	// cookieSecrets is nil when the parent listener doesn't allow
+	// answering ClientHellos statelessly
+	cookieSecrets *CookieSecrets
+	// acceptedHellos maps remote addresses to the ClientHello the
+	// connection was created for
+	acceptedHellos sync.Map
+
+	// handshakeSlots holds a token for every connection handshaking or
+	// waiting for Accept
+	handshakeSlots chan struct{}
+	handshakes     sync.WaitGroup
+	conns          chan *Conn
+
+	closeOnce sync.Once
+	closed    chan struct{}
+	// acceptDone is closed once the parent listener failed and every
+	// handshake finished, acceptErr is the error of the parent listener
+	acceptDone chan struct{}
+	acceptErr  error
 }
 
-// Accept waits for and returns the next connection to the listener.
+// start accepts connections from the parent listener in the background
+func (l *listener) start(parent net.Listener) {
+	maxHandshakes := l.config.MaxConcurrentHandshakes
+	if maxHandshakes <= 0 {
+		maxHandshakes = defaultMaxConcurrentHandshakes
+	}
+
+	l.parent = parent
+	l.handshakeSlots = make(chan struct{}, maxHandshakes)
+	l.conns = make(chan *Conn)
+	l.closed = make(chan struct{})
+	l.acceptDone = make(chan struct{})
+	go l.acceptLoop()
+}
+
+// Accept waits for and returns the next connection to the listener, once
+// its handshake completed.
 // You have to either close or read on all connection that are created.
 // Connection handshake will timeout using ConnectContextMaker in the Config.
 // If you want to specify the timeout duration, set ConnectContextMaker.
+// Handshakes which fail are reported to OnHandshakeError in the Config.
 func (l *listener) Accept() (net.Conn, error) {
-	c, err := l.parent.Accept()
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.acceptDone:
+		return nil, l.acceptErr
+	}
+}
+
+func (l *listener) acceptLoop() {
+	defer close(l.acceptDone)
+	defer l.handshakes.Wait()
+
+	for {
+		// Leave further clients to the parent listener until a handshake
+		// slot is free
+		select {
+		case l.handshakeSlots <- struct{}{}:
+		case <-l.closed:
+			l.acceptErr = errListenerClosed
+			return
+		}
+
+		c, err := l.parent.Accept()
+		if err != nil {
+			<-l.handshakeSlots
+			select {
+			case <-l.closed:
+				l.acceptErr = errListenerClosed
+			default:
+				l.acceptErr = err
+			}
+			return
+		}
+
+		l.handshakes.Add(1)
+		go l.handshake(c)
+	}
+}
+
+// handshake performs the handshake of a connection accepted from the
+// parent listener, and hands it over to Accept. A failed handshake is
+// reported to OnHandshakeError once it has released its slot and the
+// listener, so that the callback may close the listener.
+func (l *listener) handshake(c net.Conn) {
+	err := l.establish(c)
+	<-l.handshakeSlots
+	l.handshakes.Done()
+
+	if err == nil || l.config.OnHandshakeError == nil {
+		return
+	}
+	select {
+	case <-l.closed:
+	default:
+		l.config.OnHandshakeError(c.RemoteAddr(), err)
+	}
+}
+
+// establish handshakes a connection and queues it for Accept, it returns
+// the error of a failed handshake unless the listener aborted it
+func (l *listener) establish(c net.Conn) error {
+	hello := &acceptedClientHello{}
+	key := c.RemoteAddr().String()
+	if v, ok := l.acceptedHellos.Load(key); ok {
+		l.acceptedHellos.Delete(key)
+		if accepted, ok := v.(*acceptedClientHello); ok {
+			hello = accepted
+		}
+	}
+
+	ctx, cancel := l.config.connectContextMaker()
+	defer cancel()
+
+	// Closing the listener aborts the handshake
+	handshakeDone := make(chan struct{})
+	defer close(handshakeDone)
+	go func() {
+		select {
+		case <-l.closed:
+			cancel()
+		case <-handshakeDone:
+		}
+	}()
+
+	conn, err := createConn(ctx, c, l.config, false, nil, hello)
 	if err != nil {
-		return nil, err
+		// Nobody else can close the connection of a failed handshake
+		_ = c.Close()
+		return err
+	}
+
+	select {
+	case l.conns <- conn:
+	case <-l.closed:
+		_ = conn.Close()
 	}
-	return Server(c, l.config)
+	return nil
+}
+
+// verifyHello is called for datagrams from addresses without a connection.
+// ClientHellos without a valid cookie are answered with a HelloVerifyRequest
+// without keeping any state, a connection is only created once the client
+// proves that it can receive packets at its address. Fragmented ClientHellos
+// are verified from their first fragment, later fragments are dropped unless
+// the cookie exchange is skipped for the address.
+func (l *listener) verifyHello(raddr net.Addr, packet []byte) (bool, []byte) {
+	h, hs, body, ok := parseInitialClientHello(packet)
+	if !ok {
+		return false, nil
+	}
+	hello := &acceptedClientHello{
+		messageSequence: hs.MessageSequence,
+		recordSequence:  h.SequenceNumber,
+	}
+
+	switch {
+	case l.config.SkipHelloVerify != nil && l.config.SkipHelloVerify(raddr):
+	case body == nil:
+		return false, nil
+	default:
+		cookie, params, ok := splitClientHelloCookie(body)
+		if !ok {
+			return false, nil
+		}
+		if len(cookie) == 0 || !l.cookieSecrets.verify(cookie, raddr, params) {
+			// A missing or stale cookie is answered with a fresh one
+			response, err := helloVerifyRequest(h, hs, l.cookieSecrets.generate(raddr, params))
+			if err != nil {
+				return false, nil
+			}
+			return false, response
+		}
+		hello.cookieVerified = true
+	}
+
+	// Fragmented ClientHellos are admitted by the Conn once reassembled
+	if int(hs.Length) == len(body) && l.config.includesAdmission() {
+		clientHello := &handshake.MessageClientHello{}
+		if err := clientHello.Unmarshal(body); err != nil {
+			return false, nil
+		}
+		switch l.config.admitHandshake(newClientHelloInfo(raddr, nil, clientHello)) {
+		case AdmissionAccept:
+			hello.admitted = true
+		case AdmissionReject:
+			response, err := rejectAlert(h)
+			if err != nil {
+				return false, nil
+			}
+			return false, response
+		default:
+			return false, nil
+		}
+	}
+
+	l.acceptedHellos.Store(raddr.String(), hello)
+	return true, nil
 }
 
-// Close closes the listener.
+// Close closes the listener, and aborts the handshakes in progress.
 // Any blocked Accept operations will be unblocked and return errors.
 // Already Accepted connections are not closed.
 func (l *listener) Close() error {
-	return l.parent.Close()
+	l.closeOnce.Do(func() {
+		close(l.closed)
+	})
+	err := l.parent.Close()
+	<-l.acceptDone
+	return err
 }
 
 // Addr returns the listener's network address.
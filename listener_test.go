@@ -0,0 +1,639 @@
+package dtls
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pion/dtls/v2/internal/net/udp"
+	"github.com/pion/dtls/v2/pkg/crypto/selfsign"
+	"github.com/pion/dtls/v2/pkg/protocol"
+	"github.com/pion/dtls/v2/pkg/protocol/alert"
+	"github.com/pion/dtls/v2/pkg/protocol/handshake"
+	"github.com/pion/dtls/v2/pkg/protocol/recordlayer"
+	"github.com/pion/transport/test"
+)
+
+func TestListenerHelloVerify(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	var secret1, secret2 [CookieSecretLength]byte
+	secret1[0], secret2[0] = 1, 2
+
+	for name, skip := range map[string]bool{
+		"Stateless": false,
+		"Skip":      true,
+	} {
+		skip := skip
+		t.Run(name, func(t *testing.T) {
+			secrets, err := NewCookieSecrets(secret1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			serverCfg := &Config{
+				CookieSecrets: secrets,
+				SkipHelloVerify: func(net.Addr) bool {
+					return skip
+				},
+			}
+			ln, err := testListen(serverCfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				_ = ln.Close()
+			}()
+
+			if !skip {
+				// Cookies of a rotated out secret are still valid
+				raw := probeHelloVerify(t, ln.Addr())
+				if len(raw.Cookie) != sha256.Size {
+					t.Fatalf("unexpected cookie length: %d", len(raw.Cookie))
+				}
+				secrets.Rotate(secret2)
+			}
+
+			serverErr := make(chan error, 1)
+			go func() {
+				conn, aErr := ln.Accept()
+				if aErr == nil {
+					aErr = conn.Close()
+				}
+				serverErr <- aErr
+			}()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			client, err := DialWithContext(ctx, "udp", ln.Addr().(*net.UDPAddr), &Config{InsecureSkipVerify: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				_ = client.Close()
+			}()
+			if err := <-serverErr; err != nil {
+				t.Fatal(err)
+			}
+
+			if gotCookie := len(client.state.cookie) > 0; gotCookie == skip {
+				t.Errorf("cookie exchange performed: %v, expected: %v", gotCookie, !skip)
+			}
+		})
+	}
+}
+
+func TestListenerAdmission(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	for listenName, listen := range map[string]func(*Config) (net.Listener, error){
+		"Listen":      testListen,
+		"NewListener": testNewListener,
+	} {
+		listen := listen
+		for name, test := range map[string]struct {
+			decision        AdmissionDecision
+			wantServerError error
+			wantClientError error
+		}{
+			"Accept": {
+				decision: AdmissionAccept,
+			},
+			"Drop": {
+				decision:        AdmissionDrop,
+				wantServerError: errHandshakeDropped,
+				wantClientError: context.DeadlineExceeded,
+			},
+			"Reject": {
+				decision:        AdmissionReject,
+				wantServerError: errHandshakeRejected,
+				wantClientError: &alertError{&alert.Alert{Level: alert.Fatal, Description: alert.AccessDenied}},
+			},
+		} {
+			test := test
+			t.Run(listenName+name, func(t *testing.T) {
+				var admissions int32
+				handshakeErr := make(chan error, 1)
+				ln, err := listen(&Config{
+					AdmitHandshake: func(info *ClientHelloInfo) AdmissionDecision {
+						atomic.AddInt32(&admissions, 1)
+						if info.ServerName != "admission.test" || info.RemoteAddr == nil {
+							return AdmissionReject
+						}
+						return test.decision
+					},
+					OnHandshakeError: func(remoteAddr net.Addr, err error) {
+						handshakeErr <- err
+					},
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer func() {
+					_ = ln.Close()
+				}()
+
+				serverErr := make(chan error, 1)
+				go func() {
+					conn, aErr := ln.Accept()
+					if aErr == nil {
+						aErr = conn.Close()
+					}
+					serverErr <- aErr
+				}()
+
+				timeout := 10 * time.Second
+				if test.decision == AdmissionDrop {
+					timeout = 500 * time.Millisecond
+				}
+				ctx, cancel := context.WithTimeout(context.Background(), timeout)
+				defer cancel()
+				client, err := DialWithContext(ctx, "udp", ln.Addr().(*net.UDPAddr), &Config{
+					ServerName:         "admission.test",
+					InsecureSkipVerify: true,
+				})
+				if test.wantClientError == nil {
+					if err != nil {
+						t.Fatal(err)
+					}
+					_ = client.Close()
+					if err := <-serverErr; err != nil {
+						t.Fatal(err)
+					}
+				} else {
+					if !errors.Is(err, test.wantClientError) {
+						t.Errorf("Client error exp(%v) failed(%v)", test.wantClientError, err)
+					}
+					// The listener returned by Listen keeps no state for
+					// handshakes which aren't admitted
+					if listenName == "NewListener" {
+						if err := <-handshakeErr; !errors.Is(err, test.wantServerError) {
+							t.Errorf("Server error exp(%v) failed(%v)", test.wantServerError, err)
+						}
+					}
+					_ = ln.Close()
+					if err := <-serverErr; !errors.Is(err, errListenerClosed) {
+						t.Errorf("Accept error exp(%v) failed(%v)", errListenerClosed, err)
+					}
+				}
+				select {
+				case err := <-handshakeErr:
+					t.Errorf("Unexpected handshake error(%v)", err)
+				default:
+				}
+
+				if n := atomic.LoadInt32(&admissions); n != 1 {
+					t.Errorf("AdmitHandshake called %d times, expected once", n)
+				}
+			})
+		}
+	}
+}
+
+func TestListenerSlowAdmission(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	// AdmitHandshake blocking on a new client delays no established
+	// connection
+	release := make(chan struct{})
+	ln, err := testListen(&Config{
+		AdmitHandshake: func(info *ClientHelloInfo) AdmissionDecision {
+			if info.ServerName == "slow.test" {
+				<-release
+			}
+			return AdmissionAccept
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = ln.Close()
+	}()
+
+	accepted := make(chan net.Conn, 2)
+	go func() {
+		for {
+			conn, aErr := ln.Accept()
+			if aErr != nil {
+				return
+			}
+			accepted <- conn
+		}
+	}()
+
+	dial := func(serverName string) (*Conn, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return DialWithContext(ctx, "udp", ln.Addr().(*net.UDPAddr), &Config{
+			ServerName:         serverName,
+			InsecureSkipVerify: true,
+		})
+	}
+	client, err := dial("fast.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = client.Close()
+	}()
+	server := <-accepted
+	defer func() {
+		_ = server.Close()
+	}()
+
+	slowErr := make(chan error, 1)
+	go func() {
+		slow, dErr := dial("slow.test")
+		if dErr == nil {
+			dErr = slow.Close()
+		}
+		slowErr <- dErr
+	}()
+	// Let the ClientHello of the slow client reach AdmitHandshake
+	time.Sleep(100 * time.Millisecond)
+
+	buf := make([]byte, 32)
+	for _, c := range []struct {
+		from, to net.Conn
+	}{{client, server}, {server, client}} {
+		if _, err = c.from.Write([]byte("ping")); err != nil {
+			t.Fatal(err)
+		}
+		if err = c.to.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+			t.Fatal(err)
+		}
+		if n, rErr := c.to.Read(buf); rErr != nil {
+			t.Fatalf("established connection blocked: %v", rErr)
+		} else if string(buf[:n]) != "ping" {
+			t.Fatalf("unexpected data: %q", buf[:n])
+		}
+	}
+
+	close(release)
+	if err = <-slowErr; err != nil {
+		t.Fatal(err)
+	}
+	_ = (<-accepted).Close()
+}
+
+func TestListenerCloseOnHandshakeError(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	listener := make(chan net.Listener, 1)
+	closed := make(chan error, 1)
+	ln, err := testNewListener(&Config{
+		AdmitHandshake: func(*ClientHelloInfo) AdmissionDecision {
+			return AdmissionReject
+		},
+		// Closing the listener from the callback must not wait for the
+		// handshake reporting the error
+		OnHandshakeError: func(net.Addr, error) {
+			closed <- (<-listener).Close()
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener <- ln
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err = DialWithContext(ctx, "udp", ln.Addr().(*net.UDPAddr), &Config{InsecureSkipVerify: true}); err == nil {
+		t.Fatal("Client handshake succeeded, expected a rejection")
+	}
+	if err := <-closed; err != nil {
+		t.Error(err)
+	}
+	if _, err := ln.Accept(); !errors.Is(err, errListenerClosed) {
+		t.Errorf("Accept error exp(%v) failed(%v)", errListenerClosed, err)
+	}
+}
+
+func TestListenerFragmentedClientHello(t *testing.T) {
+	// The first fragment of a ClientHello which doesn't fit a datagram, up
+	// to the session_id and the cookie
+	fragmentedHello := func(messageSequence uint16, cookie []byte) []byte {
+		fragment := make([]byte, 64)
+		fragment[0], fragment[1] = 0xfe, 0xfd
+		fragment[2] = 0x07 // random
+		fragment[35] = byte(len(cookie))
+		fragment = append(append(fragment[:36], cookie...), fragment[36:]...)
+		hs, err := (&handshake.Header{
+			Type:            handshake.TypeClientHello,
+			Length:          1024,
+			MessageSequence: messageSequence,
+			FragmentLength:  uint32(len(fragment)),
+		}).Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		h, err := (&recordlayer.Header{
+			ContentType: protocol.ContentTypeHandshake,
+			ContentLen:  uint16(len(hs) + len(fragment)),
+			Version:     protocol.Version1_2,
+		}).Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return append(append(h, hs...), fragment...)
+	}
+
+	secrets, err := NewCookieSecrets([CookieSecretLength]byte{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raddr := &net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 5684}
+	newListener := func(skip bool) *listener {
+		return &listener{
+			config: &Config{
+				SkipHelloVerify: func(net.Addr) bool {
+					return skip
+				},
+				AdmitHandshake: func(*ClientHelloInfo) AdmissionDecision {
+					return AdmissionReject
+				},
+			},
+			cookieSecrets: secrets,
+		}
+	}
+
+	// The first fragment of a ClientHello is answered statelessly with a
+	// HelloVerifyRequest
+	l := newListener(false)
+	accept, response := l.verifyHello(raddr, fragmentedHello(0, nil))
+	if accept || len(response) == 0 {
+		t.Fatalf("accept(%v) response(%x), expected a HelloVerifyRequest", accept, response)
+	}
+	if _, stored := l.acceptedHellos.Load(raddr.String()); stored {
+		t.Fatal("ClientHello stored without a cookie")
+	}
+	r := &recordlayer.RecordLayer{}
+	if err = r.Unmarshal(response); err != nil {
+		t.Fatal(err)
+	}
+	hvr, ok := r.Content.(*handshake.Handshake).Message.(*handshake.MessageHelloVerifyRequest)
+	if !ok {
+		t.Fatalf("response %T, expected a HelloVerifyRequest", r.Content.(*handshake.Handshake).Message)
+	}
+
+	// Its cookie verifies the first fragment of the next ClientHello, which
+	// is admitted by the Conn once reassembled
+	accept, response = l.verifyHello(raddr, fragmentedHello(1, hvr.Cookie))
+	if !accept || len(response) != 0 {
+		t.Fatalf("accept(%v) response(%x), expected accept", accept, response)
+	}
+	v, stored := l.acceptedHellos.Load(raddr.String())
+	if !stored {
+		t.Fatal("ClientHello not stored")
+	}
+	if hello := v.(*acceptedClientHello); !hello.cookieVerified || hello.admitted || hello.messageSequence != 1 {
+		t.Errorf("ClientHello stored as %+v", hello)
+	}
+
+	// Later fragments are dropped, unless the cookie exchange is skipped
+	later := fragmentedHello(0, nil)
+	later[recordlayer.HeaderSize+8] = 0x40 // fragment_offset
+	for _, skip := range []bool{false, true} {
+		l := newListener(skip)
+		accept, response := l.verifyHello(raddr, later)
+		if accept != skip || len(response) != 0 {
+			t.Errorf("skip(%v): accept(%v) response(%x), expected accept(%v)", skip, accept, response, skip)
+		}
+	}
+}
+
+func TestListenerConcurrentHandshakes(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	for name, test := range map[string]struct {
+		maxHandshakes int
+		accepted      bool
+	}{
+		"Concurrent": {
+			accepted: true,
+		},
+		"Limited": {
+			maxHandshakes: 1,
+		},
+	} {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			handshakeErr := make(chan error, 1)
+			ln, err := testListen(&Config{
+				SkipHelloVerify: func(net.Addr) bool {
+					return true
+				},
+				MaxConcurrentHandshakes: test.maxHandshakes,
+				OnHandshakeError: func(remoteAddr net.Addr, err error) {
+					handshakeErr <- err
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				_ = ln.Close()
+			}()
+
+			// A peer which stops after its ClientHello keeps its handshake
+			// in progress
+			stalled, err := net.DialUDP("udp", nil, ln.Addr().(*net.UDPAddr))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				_ = stalled.Close()
+			}()
+			if err = sendClientHello(nil, stalled, 0, nil); err != nil {
+				t.Fatal(err)
+			}
+			// New clients are verified concurrently, the handshake is in
+			// progress once the server answered
+			if err = stalled.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+				t.Fatal(err)
+			}
+			if _, err = stalled.Read(make([]byte, 8192)); err != nil {
+				t.Fatal(err)
+			}
+
+			serverErr := make(chan error, 1)
+			go func() {
+				conn, aErr := ln.Accept()
+				if aErr == nil {
+					aErr = conn.Close()
+				}
+				serverErr <- aErr
+			}()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+			defer cancel()
+			client, err := DialWithContext(ctx, "udp", ln.Addr().(*net.UDPAddr), &Config{InsecureSkipVerify: true})
+			if accepted := err == nil; accepted != test.accepted {
+				t.Fatalf("Client accepted(%v), expected(%v): %v", accepted, test.accepted, err)
+			}
+			if test.accepted {
+				_ = client.Close()
+				if err := <-serverErr; err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// Closing the listener aborts the handshakes in progress,
+			// without reporting them
+			_ = ln.Close()
+			if !test.accepted {
+				if err := <-serverErr; !errors.Is(err, errListenerClosed) {
+					t.Errorf("Accept error exp(%v) failed(%v)", errListenerClosed, err)
+				}
+			}
+			select {
+			case err := <-handshakeErr:
+				t.Errorf("Unexpected handshake error(%v)", err)
+			default:
+			}
+		})
+	}
+}
+
+func TestListenerHandshakeRateLimiter(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	ln, err := testListen(&Config{
+		HandshakeRateLimiter: &HandshakeRateLimiter{
+			AddressRate: 0.001,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = ln.Close()
+	}()
+
+	go func() {
+		for {
+			conn, aErr := ln.Accept()
+			if aErr != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+
+	// Only the first handshake from the address is admitted
+	for i, admitted := range []bool{true, false} {
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		client, err := DialWithContext(ctx, "udp", ln.Addr().(*net.UDPAddr), &Config{InsecureSkipVerify: true})
+		cancel()
+		if admitted != (err == nil) {
+			t.Fatalf("Handshake %d: admitted(%v), got error(%v)", i, admitted, err)
+		}
+		if err == nil {
+			_ = client.Close()
+		}
+	}
+}
+
+func testNewListener(cfg *Config) (net.Listener, error) {
+	cert, err := selfsign.GenerateSelfSigned()
+	if err != nil {
+		return nil, err
+	}
+	cfg.Certificates = []tls.Certificate{cert}
+	inner, err := udp.Listen("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		return nil, err
+	}
+	return NewListener(inner, cfg)
+}
+
+func testListen(cfg *Config) (net.Listener, error) {
+	cert, err := selfsign.GenerateSelfSigned()
+	if err != nil {
+		return nil, err
+	}
+	cfg.Certificates = []tls.Certificate{cert}
+	return Listen("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)}, cfg)
+}
+
+// probeHelloVerify sends a ClientHello without a cookie and returns the
+// HelloVerifyRequest answering it.
+func probeHelloVerify(t *testing.T, addr net.Addr) *handshake.MessageHelloVerifyRequest {
+	conn, err := net.DialUDP("udp", nil, addr.(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	if err = sendClientHello(nil, conn, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 1500)
+	if err = conn.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &recordlayer.RecordLayer{}
+	if err = r.Unmarshal(buf[:n]); err != nil {
+		t.Fatal(err)
+	}
+	if r.Header.ContentType != protocol.ContentTypeHandshake {
+		t.Fatalf("unexpected content type: %v", r.Header.ContentType)
+	}
+	h, ok := r.Content.(*handshake.Handshake)
+	if !ok {
+		t.Fatalf("unexpected content: %T", r.Content)
+	}
+	hvr, ok := h.Message.(*handshake.MessageHelloVerifyRequest)
+	if !ok {
+		t.Fatalf("unexpected message: %T", h.Message)
+	}
+	return hvr
+}
@@ -0,0 +1,82 @@
+package dtls
+
+import "container/list"
+
+// lruMap is a map holding at most size entries, which evicts its least
+// recently used evictable entry to make room for a new one. It is not safe
+// for concurrent use.
+type lruMap struct {
+	size    int
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type lruEntry struct {
+	key   string
+	value interface{}
+}
+
+func newLRUMap(size int) *lruMap {
+	return &lruMap{
+		size:    size,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+// get returns the value of key, and marks it as the most recently used
+func (m *lruMap) get(key string) (interface{}, bool) {
+	e, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	m.order.MoveToFront(e)
+	return e.Value.(*lruEntry).value, true //nolint:forcetypeassert
+}
+
+// peek returns the value of key, without marking it as used
+func (m *lruMap) peek(key string) (interface{}, bool) {
+	e, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	return e.Value.(*lruEntry).value, true //nolint:forcetypeassert
+}
+
+// put sets the value of key as the most recently used. If the map is full,
+// the least recently used entry whose value is evictable is evicted, any
+// entry if evictable is nil. put returns false without setting key if no
+// entry can be evicted.
+func (m *lruMap) put(key string, value interface{}, evictable func(interface{}) bool) bool {
+	if e, ok := m.entries[key]; ok {
+		e.Value.(*lruEntry).value = value //nolint:forcetypeassert
+		m.order.MoveToFront(e)
+		return true
+	}
+	if m.order.Len() >= m.size {
+		e := m.order.Back()
+		for ; e != nil; e = e.Prev() {
+			if evictable == nil || evictable(e.Value.(*lruEntry).value) { //nolint:forcetypeassert
+				break
+			}
+		}
+		if e == nil {
+			return false
+		}
+		m.order.Remove(e)
+		delete(m.entries, e.Value.(*lruEntry).key) //nolint:forcetypeassert
+	}
+	m.entries[key] = m.order.PushFront(&lruEntry{key: key, value: value})
+	return true
+}
+
+func (m *lruMap) remove(key string) {
+	if e, ok := m.entries[key]; ok {
+		m.order.Remove(e)
+		delete(m.entries, key)
+	}
+}
+
+func (m *lruMap) len() int {
+	return m.order.Len()
+}
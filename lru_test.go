@@ -0,0 +1,52 @@
+package dtls
+
+import "testing"
+
+func TestLRUMap(t *testing.T) {
+	m := newLRUMap(2)
+	m.put("a", 1, nil)
+	m.put("b", 2, nil)
+
+	// Getting a makes b the least recently used entry, which is evicted
+	if v, ok := m.get("a"); !ok || v != 1 {
+		t.Fatalf("get(a): %v, %v", v, ok)
+	}
+	m.put("c", 3, nil)
+	if _, ok := m.peek("b"); ok {
+		t.Error("least recently used entry not evicted")
+	}
+
+	// Peeking doesn't mark a as used
+	if v, ok := m.peek("a"); !ok || v != 1 {
+		t.Fatalf("peek(a): %v, %v", v, ok)
+	}
+	m.put("c", 4, nil)
+	m.put("d", 5, nil)
+	if _, ok := m.peek("a"); ok {
+		t.Error("peeked entry must not be marked as used")
+	}
+	if v, ok := m.peek("c"); !ok || v != 4 {
+		t.Errorf("peek(c): %v, %v", v, ok)
+	}
+
+	m.remove("c")
+	if _, ok := m.get("c"); ok || m.len() != 1 {
+		t.Errorf("removed entry still present, len %d", m.len())
+	}
+
+	// Only evictable entries are evicted, the least recently used first
+	odd := func(v interface{}) bool { return v.(int)%2 == 1 } //nolint:forcetypeassert
+	m.put("e", 6, odd)
+	if !m.put("f", 7, odd) {
+		t.Fatal("evictable entry d not evicted")
+	}
+	if _, ok := m.peek("d"); ok {
+		t.Error("evictable entry d still present")
+	}
+	if m.put("g", 8, func(v interface{}) bool { return v.(int)%2 == 0 && v.(int) != 6 }) { //nolint:forcetypeassert
+		t.Error("entry added without an evictable entry")
+	}
+	if _, ok := m.peek("g"); ok || m.len() != 2 {
+		t.Errorf("entry added without an evictable entry, len %d", m.len())
+	}
+}
@@ -0,0 +1,35 @@
+package dtls
+
+import (
+	"crypto/x509"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// VerifyOCSPStaple is a Config.VerifyOCSPResponse policy that fails closed.
+// It requires the server to staple an OCSP response for its certificate,
+// signed by the issuer of the certificate or a responder delegated by it,
+// that is current and has the status good.
+func VerifyOCSPStaple(ocspResponse []byte, verifiedChains [][]*x509.Certificate) error {
+	if len(ocspResponse) == 0 {
+		return errNoOCSPResponse
+	}
+	if len(verifiedChains) == 0 || len(verifiedChains[0]) < 2 {
+		return errNoOCSPIssuer
+	}
+
+	resp, err := ocsp.ParseResponseForCert(ocspResponse, verifiedChains[0][0], verifiedChains[0][1])
+	if err != nil {
+		return err
+	}
+	if resp.Status != ocsp.Good {
+		return errOCSPResponseNotGood
+	}
+
+	now := time.Now()
+	if now.Before(resp.ThisUpdate) || (!resp.NextUpdate.IsZero() && now.After(resp.NextUpdate)) {
+		return errOCSPResponseExpired
+	}
+	return nil
+}
@@ -0,0 +1,279 @@
+package dtls
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/pion/dtls/v2/internal/net/dpipe"
+	dtlselliptic "github.com/pion/dtls/v2/pkg/crypto/elliptic"
+	"github.com/pion/dtls/v2/pkg/crypto/hash"
+	"github.com/pion/dtls/v2/pkg/crypto/signature"
+	"github.com/pion/dtls/v2/pkg/protocol"
+	"github.com/pion/dtls/v2/pkg/protocol/extension"
+	"github.com/pion/dtls/v2/pkg/protocol/handshake"
+	"github.com/pion/dtls/v2/pkg/protocol/recordlayer"
+	"github.com/pion/transport/test"
+	"golang.org/x/crypto/ocsp"
+)
+
+// createTestCertificates creates a CA, and a leaf certificate for localhost
+// signed by it
+func createTestCertificates(t *testing.T) (*x509.Certificate, *ecdsa.PrivateKey, tls.Certificate) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTemplate, ca, &leafKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(leafDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ca, caKey, tls.Certificate{
+		Certificate: [][]byte{leafDER, caDER},
+		PrivateKey:  leafKey,
+		Leaf:        leaf,
+	}
+}
+
+func TestOCSPStapling(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	for name, tt := range map[string]struct {
+		status       int
+		noStaple     bool
+		verify       func([]byte, [][]*x509.Certificate) error
+		wantResponse bool
+		wantErr      bool
+	}{
+		"Stapled": {
+			status:       ocsp.Good,
+			verify:       VerifyOCSPStaple,
+			wantResponse: true,
+		},
+		"NotRequested": {
+			status: ocsp.Good,
+		},
+		"Missing": {
+			noStaple: true,
+			verify:   VerifyOCSPStaple,
+			wantErr:  true,
+		},
+		"Revoked": {
+			status:  ocsp.Revoked,
+			verify:  VerifyOCSPStaple,
+			wantErr: true,
+		},
+	} {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			caCert, caKey, serverCert := createTestCertificates(t)
+			roots := x509.NewCertPool()
+			roots.AddCert(caCert)
+			if !tt.noStaple {
+				staple, err := ocsp.CreateResponse(caCert, caCert, ocsp.Response{
+					Status:       tt.status,
+					SerialNumber: serverCert.Leaf.SerialNumber,
+					ThisUpdate:   time.Now().Add(-time.Minute),
+					NextUpdate:   time.Now().Add(time.Hour),
+					RevokedAt:    time.Now().Add(-time.Minute),
+				}, caKey)
+				if err != nil {
+					t.Fatal(err)
+				}
+				serverCert.OCSPStaple = staple
+			}
+			cipherSuites := []CipherSuiteID{TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}
+
+			ca, cb := dpipe.Pipe()
+			type result struct {
+				c   *Conn
+				err error
+			}
+			c := make(chan result)
+
+			go func() {
+				client, err := ClientWithContext(context.Background(), ca, &Config{
+					CipherSuites:       cipherSuites,
+					RootCAs:            roots,
+					ServerName:         "localhost",
+					VerifyOCSPResponse: tt.verify,
+				})
+				c <- result{client, err}
+			}()
+
+			server, err := testServer(context.Background(), cb, &Config{
+				CipherSuites: cipherSuites,
+				Certificates: []tls.Certificate{serverCert},
+			}, false)
+			res := <-c
+			defer func() {
+				if err == nil {
+					_ = server.Close()
+				}
+				if res.err == nil {
+					_ = res.c.Close()
+				}
+			}()
+
+			if tt.wantErr {
+				if !errors.Is(res.err, errInvalidOCSPResponse) {
+					t.Errorf("Client error expected: \"%v\", got: \"%v\"", errInvalidOCSPResponse, res.err)
+				}
+				if err == nil {
+					t.Error("Server error expected")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Server failed(%v)", err)
+			}
+			if res.err != nil {
+				t.Fatalf("Client failed(%v)", res.err)
+			}
+
+			if tt.wantResponse != bytes.Equal(res.c.state.ocspResponse, serverCert.OCSPStaple) {
+				t.Errorf("Client got OCSP response %x, expected stapled %t", res.c.state.ocspResponse, tt.wantResponse)
+			}
+		})
+	}
+}
+
+func TestOCSPUnacknowledgedCertificateStatus(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	_, _, serverCert := createTestCertificates(t)
+	cipherSuiteID := uint16(TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256)
+
+	// A server stapling a CertificateStatus although its ServerHello didn't
+	// acknowledge the status_request
+	messages := []handshake.Message{
+		&handshake.MessageServerHello{
+			Version:           protocol.Version1_2,
+			CipherSuiteID:     &cipherSuiteID,
+			CompressionMethod: defaultCompressionMethods()[0],
+		},
+		&handshake.MessageCertificate{
+			Certificate: serverCert.Certificate,
+		},
+		&handshake.MessageCertificateStatus{
+			StatusType: extension.StatusTypeOCSP,
+			Response:   []byte{0x01},
+		},
+		&handshake.MessageServerKeyExchange{
+			EllipticCurveType:  dtlselliptic.CurveTypeNamedCurve,
+			NamedCurve:         dtlselliptic.X25519,
+			PublicKey:          make([]byte, 32),
+			HashAlgorithm:      hash.SHA256,
+			SignatureAlgorithm: signature.ECDSA,
+			Signature:          []byte{0x01},
+		},
+		&handshake.MessageServerHelloDone{},
+	}
+
+	ca, cb := dpipe.Pipe()
+	defer func() {
+		_ = ca.Close()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	clientErr := make(chan error, 1)
+	go func() {
+		client, err := ClientWithContext(ctx, cb, &Config{
+			CipherSuites:       []CipherSuiteID{TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+			InsecureSkipVerify: true,
+			VerifyOCSPResponse: VerifyOCSPStaple,
+		})
+		if err == nil {
+			_ = client.Close()
+		}
+		clientErr <- err
+	}()
+
+	// ClientHello
+	if _, err := ca.Read(make([]byte, 8192)); err != nil {
+		t.Fatal(err)
+	}
+	for i, msg := range messages {
+		packet, err := (&recordlayer.RecordLayer{
+			Header: recordlayer.Header{
+				Version:        protocol.Version1_2,
+				SequenceNumber: uint64(i),
+			},
+			Content: &handshake.Handshake{
+				Header: handshake.Header{
+					MessageSequence: uint16(i),
+				},
+				Message: msg,
+			},
+		}).Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ca.Write(packet); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := <-clientErr; !errors.Is(err, errUnexpectedCertificateStatus) {
+		t.Errorf("Client error expected: \"%v\", got: \"%v\"", errUnexpectedCertificateStatus, err)
+	}
+}
@@ -25,6 +25,7 @@ type CBC struct {
 	writeCBC, readCBC cbcMode
 	writeMac, readMac []byte
 	h                 prf.HashFunc
+	encryptThenMAC    bool
 }
 
 // NewCBC creates a DTLS CBC Cipher
@@ -59,20 +60,34 @@ func NewCBC(localKey, localWriteIV, localMac, remoteKey, remoteWriteIV, remoteMa
 	}, nil
 }
 
+// NewCBCEncryptThenMAC creates a DTLS CBC Cipher that calculates the MAC over
+// the encrypted record, as negotiated with the encrypt_then_mac extension
+// https://tools.ietf.org/html/rfc7366
+func NewCBCEncryptThenMAC(localKey, localWriteIV, localMac, remoteKey, remoteWriteIV, remoteMac []byte, h prf.HashFunc) (*CBC, error) {
+	c, err := NewCBC(localKey, localWriteIV, localMac, remoteKey, remoteWriteIV, remoteMac, h)
+	if err != nil {
+		return nil, err
+	}
+	c.encryptThenMAC = true
+	return c, nil
+}
+
 // Encrypt encrypt a DTLS RecordLayer message
 func (c *CBC) Encrypt(pkt *recordlayer.RecordLayer, raw []byte) ([]byte, error) {
-	payload := raw[recordlayer.HeaderSize:]
-	raw = raw[:recordlayer.HeaderSize]
+	hdrLen := pkt.Header.Size()
+	payload := raw[hdrLen:]
+	raw = raw[:hdrLen]
 	blockSize := c.writeCBC.BlockSize()
 
-	// Generate + Append MAC
+	// Generate + Append MAC, unless it is calculated over the ciphertext
 	h := pkt.Header
-
-	MAC, err := c.hmac(h.Epoch, h.SequenceNumber, h.ContentType, h.Version, payload, c.writeMac, c.h)
-	if err != nil {
-		return nil, err
+	if !c.encryptThenMAC {
+		MAC, err := c.mac(&h, payload, c.writeMac)
+		if err != nil {
+			return nil, err
+		}
+		payload = append(payload, MAC...)
 	}
-	payload = append(payload, MAC...)
 
 	// Generate + Append padding
 	padding := make([]byte, blockSize-len(payload)%blockSize)
@@ -93,29 +108,49 @@ func (c *CBC) Encrypt(pkt *recordlayer.RecordLayer, raw []byte) ([]byte, error)
 	c.writeCBC.CryptBlocks(payload, payload)
 	payload = append(iv, payload...)
 
+	// With Encrypt-then-MAC the MAC covers the IV and ciphertext
+	if c.encryptThenMAC {
+		MAC, err := c.mac(&h, payload, c.writeMac)
+		if err != nil {
+			return nil, err
+		}
+		payload = append(payload, MAC...)
+	}
+
 	// Prepend unencrypte header with encrypted payload
 	raw = append(raw, payload...)
 
 	// Update recordLayer size to include IV+MAC+Padding
-	binary.BigEndian.PutUint16(raw[recordlayer.HeaderSize-2:], uint16(len(raw)-recordlayer.HeaderSize))
+	binary.BigEndian.PutUint16(raw[hdrLen-2:], uint16(len(raw)-hdrLen))
 
 	return raw, nil
 }
 
 // Decrypt decrypts a DTLS RecordLayer message
 func (c *CBC) Decrypt(in []byte) ([]byte, error) {
-	body := in[recordlayer.HeaderSize:]
+	var h recordlayer.Header
+	if err := h.Unmarshal(in); err != nil {
+		return nil, err
+	}
+	return c.DecryptWithHeader(h, in)
+}
+
+// DecryptWithHeader decrypts a DTLS RecordLayer message whose header has
+// already been parsed. The connection ID of a record can't be parsed from
+// the record alone, so records with a connection ID must be decrypted with
+// DecryptWithHeader.
+func (c *CBC) DecryptWithHeader(h recordlayer.Header, in []byte) ([]byte, error) {
+	hdrLen := h.Size()
+	body := in[hdrLen:]
 	blockSize := c.readCBC.BlockSize()
 	mac := c.h()
 
-	var h recordlayer.Header
-	err := h.Unmarshal(in)
 	switch {
-	case err != nil:
-		return nil, err
 	case h.ContentType == protocol.ContentTypeChangeCipherSpec:
 		// Nothing to encrypt with ChangeCipherSpec
 		return in, nil
+	case c.encryptThenMAC:
+		return c.decryptEncryptThenMAC(h, in)
 	case len(body)%blockSize != 0 || len(body) < blockSize+util.Max(mac.Size()+1, blockSize):
 		return nil, errNotEnoughRoomForNonce
 	}
@@ -142,14 +177,57 @@ func (c *CBC) Decrypt(in []byte) ([]byte, error) {
 	dataEnd := len(body) - macSize - paddingLen
 
 	expectedMAC := body[dataEnd : dataEnd+macSize]
-	actualMAC, err := c.hmac(h.Epoch, h.SequenceNumber, h.ContentType, h.Version, body[:dataEnd], c.readMac, c.h)
+	actualMAC, err := c.mac(&h, body[:dataEnd], c.readMac)
 
 	// Compute Local MAC and compare
 	if err != nil || !hmac.Equal(actualMAC, expectedMAC) {
 		return nil, errInvalidMAC
 	}
 
-	return append(in[:recordlayer.HeaderSize], body[:dataEnd]...), nil
+	return append(in[:hdrLen], body[:dataEnd]...), nil
+}
+
+// decryptEncryptThenMAC decrypts a record protected with Encrypt-then-MAC. The
+// MAC is checked before decrypting, so the padding of a forged record is never
+// examined.
+// https://tools.ietf.org/html/rfc7366#section-3
+func (c *CBC) decryptEncryptThenMAC(h recordlayer.Header, in []byte) ([]byte, error) {
+	hdrLen := h.Size()
+	body := in[hdrLen:]
+	blockSize := c.readCBC.BlockSize()
+	macSize := c.h().Size()
+
+	// IV + at least one block of ciphertext + MAC
+	dataEnd := len(body) - macSize
+	if dataEnd < 2*blockSize || dataEnd%blockSize != 0 {
+		return nil, errNotEnoughRoomForNonce
+	}
+
+	actualMAC, err := c.mac(&h, body[:dataEnd], c.readMac)
+	if err != nil || !hmac.Equal(actualMAC, body[dataEnd:]) {
+		return nil, errInvalidMAC
+	}
+
+	// Set + remove per record IV
+	c.readCBC.SetIV(body[:blockSize])
+	body = body[blockSize:dataEnd]
+
+	// Decrypt
+	c.readCBC.CryptBlocks(body, body)
+
+	paddingLen, paddingGood := examinePadding(body)
+	if paddingGood != 255 {
+		return nil, errInvalidMAC
+	}
+
+	return append(in[:hdrLen], body[:len(body)-paddingLen]...), nil
+}
+
+func (c *CBC) mac(h *recordlayer.Header, payload []byte, key []byte) ([]byte, error) {
+	if h.ContentType == protocol.ContentTypeConnectionID {
+		return c.hmacCID(h, payload, key, c.h)
+	}
+	return c.hmac(h.Epoch, h.SequenceNumber, h.ContentType, h.Version, payload, key, c.h)
 }
 
 func (c *CBC) hmac(epoch uint16, sequenceNumber uint64, contentType protocol.ContentType, protocolVersion protocol.Version, payload []byte, key []byte, hf func() hash.Hash) ([]byte, error) {
@@ -172,3 +250,20 @@ func (c *CBC) hmac(epoch uint16, sequenceNumber uint64, contentType protocol.Con
 
 	return h.Sum(nil), nil
 }
+
+// hmacCID calculates the MAC of a tls12_cid record according to
+// https://datatracker.ietf.org/doc/html/rfc9146#section-5.1
+func (c *CBC) hmacCID(header *recordlayer.Header, payload []byte, key []byte, hf func() hash.Hash) ([]byte, error) {
+	h := hmac.New(hf, key)
+
+	msg := append(generateCIDPrefix(header), header.ConnectionID...)
+	msg = append(msg, byte(len(payload)>>8), byte(len(payload)))
+
+	if _, err := h.Write(msg); err != nil {
+		return nil, err
+	} else if _, err := h.Write(payload); err != nil {
+		return nil, err
+	}
+
+	return h.Sum(nil), nil
+}
@@ -0,0 +1,70 @@
+package ciphersuite
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/pion/dtls/v2/pkg/protocol"
+	"github.com/pion/dtls/v2/pkg/protocol/recordlayer"
+)
+
+func TestCBCEncryptThenMAC(t *testing.T) {
+	key, iv, mac := make([]byte, 16), make([]byte, 16), make([]byte, 32)
+	newCBC := func(encryptThenMAC bool) *CBC {
+		constructor := NewCBC
+		if encryptThenMAC {
+			constructor = NewCBCEncryptThenMAC
+		}
+		c, err := constructor(key, iv, mac, key, iv, mac, sha256.New)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	data := []byte("encrypt-then-mac")
+	encrypt := func(c *CBC) []byte {
+		pkt := &recordlayer.RecordLayer{
+			Header: recordlayer.Header{
+				Version: protocol.Version1_2,
+				Epoch:   1,
+			},
+			Content: &protocol.ApplicationData{Data: data},
+		}
+		raw, err := pkt.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		encrypted, err := c.Encrypt(pkt, raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return encrypted
+	}
+	decrypt := func(c *CBC, in []byte) ([]byte, error) {
+		return c.Decrypt(append([]byte{}, in...))
+	}
+
+	encrypted := encrypt(newCBC(true))
+	decrypted, err := decrypt(newCBC(true), encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decrypted[recordlayer.HeaderSize:], data) {
+		t.Errorf("Decrypted %q, expected %q", decrypted[recordlayer.HeaderSize:], data)
+	}
+
+	if _, err := decrypt(newCBC(false), encrypted); err == nil {
+		t.Error("Expected MAC-then-encrypt to reject an Encrypt-then-MAC record")
+	}
+	if _, err := decrypt(newCBC(true), encrypt(newCBC(false))); err == nil {
+		t.Error("Expected Encrypt-then-MAC to reject a MAC-then-encrypt record")
+	}
+
+	// The MAC covers the ciphertext
+	encrypted[recordlayer.HeaderSize] ^= 0x01
+	if _, err := decrypt(newCBC(true), encrypted); err == nil {
+		t.Error("Expected a modified ciphertext to be rejected")
+	}
+}
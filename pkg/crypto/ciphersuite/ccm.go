@@ -59,46 +59,66 @@ func NewCCM(tagLen CCMTagLen, localKey, localWriteIV, remoteKey, remoteWriteIV [
 
 // Encrypt encrypt a DTLS RecordLayer message
 func (c *CCM) Encrypt(pkt *recordlayer.RecordLayer, raw []byte) ([]byte, error) {
-	payload := raw[recordlayer.HeaderSize:]
-	raw = raw[:recordlayer.HeaderSize]
+	hdrLen := pkt.Header.Size()
+	payload := raw[hdrLen:]
+	raw = raw[:hdrLen]
 
 	nonce := append(append([]byte{}, c.localWriteIV[:4]...), make([]byte, 8)...)
 	if _, err := rand.Read(nonce[4:]); err != nil {
 		return nil, err
 	}
 
-	additionalData := generateAEADAdditionalData(&pkt.Header, len(payload))
+	var additionalData []byte
+	if pkt.Header.ContentType == protocol.ContentTypeConnectionID {
+		additionalData = generateAEADAdditionalDataCID(&pkt.Header, len(payload))
+	} else {
+		additionalData = generateAEADAdditionalData(&pkt.Header, len(payload))
+	}
 	encryptedPayload := c.localCCM.Seal(nil, nonce, payload, additionalData)
 
 	encryptedPayload = append(nonce[4:], encryptedPayload...)
 	raw = append(raw, encryptedPayload...)
 
 	// Update recordLayer size to include explicit nonce
-	binary.BigEndian.PutUint16(raw[recordlayer.HeaderSize-2:], uint16(len(raw)-recordlayer.HeaderSize))
+	binary.BigEndian.PutUint16(raw[hdrLen-2:], uint16(len(raw)-hdrLen))
 	return raw, nil
 }
 
 // Decrypt decrypts a DTLS RecordLayer message
 func (c *CCM) Decrypt(in []byte) ([]byte, error) {
 	var h recordlayer.Header
-	err := h.Unmarshal(in)
-	switch {
-	case err != nil:
+	if err := h.Unmarshal(in); err != nil {
 		return nil, err
+	}
+	return c.DecryptWithHeader(h, in)
+}
+
+// DecryptWithHeader decrypts a DTLS RecordLayer message whose header has
+// already been parsed. The connection ID of a record can't be parsed from
+// the record alone, so records with a connection ID must be decrypted with
+// DecryptWithHeader.
+func (c *CCM) DecryptWithHeader(h recordlayer.Header, in []byte) ([]byte, error) {
+	hdrLen := h.Size()
+	switch {
 	case h.ContentType == protocol.ContentTypeChangeCipherSpec:
 		// Nothing to encrypt with ChangeCipherSpec
 		return in, nil
-	case len(in) <= (8 + recordlayer.HeaderSize):
+	case len(in) <= (8 + hdrLen):
 		return nil, errNotEnoughRoomForNonce
 	}
 
-	nonce := append(append([]byte{}, c.remoteWriteIV[:4]...), in[recordlayer.HeaderSize:recordlayer.HeaderSize+8]...)
-	out := in[recordlayer.HeaderSize+8:]
+	nonce := append(append([]byte{}, c.remoteWriteIV[:4]...), in[hdrLen:hdrLen+8]...)
+	out := in[hdrLen+8:]
 
-	additionalData := generateAEADAdditionalData(&h, len(out)-int(c.tagLen))
-	out, err = c.remoteCCM.Open(out[:0], nonce, out, additionalData)
+	var additionalData []byte
+	if h.ContentType == protocol.ContentTypeConnectionID {
+		additionalData = generateAEADAdditionalDataCID(&h, len(out)-int(c.tagLen))
+	} else {
+		additionalData = generateAEADAdditionalData(&h, len(out)-int(c.tagLen))
+	}
+	out, err := c.remoteCCM.Open(out[:0], nonce, out, additionalData)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", errDecryptPacket, err)
 	}
-	return append(in[:recordlayer.HeaderSize], out...), nil
+	return append(in[:hdrLen], out...), nil
 }
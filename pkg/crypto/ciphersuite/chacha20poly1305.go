@@ -0,0 +1,111 @@
+package ciphersuite
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/pion/dtls/v2/pkg/protocol"
+	"github.com/pion/dtls/v2/pkg/protocol/recordlayer"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// ChaCha20Poly1305 Provides an API to Encrypt/Decrypt DTLS 1.2 Packets
+// https://tools.ietf.org/html/rfc7905
+type ChaCha20Poly1305 struct {
+	localAEAD, remoteAEAD       cipher.AEAD
+	localWriteIV, remoteWriteIV []byte
+}
+
+// NewChaCha20Poly1305 creates a DTLS ChaCha20-Poly1305 Cipher
+func NewChaCha20Poly1305(localKey, localWriteIV, remoteKey, remoteWriteIV []byte) (*ChaCha20Poly1305, error) {
+	localAEAD, err := chacha20poly1305.New(localKey)
+	if err != nil {
+		return nil, err
+	}
+
+	remoteAEAD, err := chacha20poly1305.New(remoteKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChaCha20Poly1305{
+		localAEAD:     localAEAD,
+		localWriteIV:  localWriteIV,
+		remoteAEAD:    remoteAEAD,
+		remoteWriteIV: remoteWriteIV,
+	}, nil
+}
+
+// The nonce is the write IV XORed with the epoch and sequence number, which
+// are padded on the left to its length. There is no explicit nonce sent in
+// the record. RFC 7905 Section 2
+func chaCha20Poly1305Nonce(writeIV []byte, h *recordlayer.Header) []byte {
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	binary.BigEndian.PutUint64(nonce[4:], h.SequenceNumber)
+	binary.BigEndian.PutUint16(nonce[4:], h.Epoch)
+	for i := range nonce {
+		nonce[i] ^= writeIV[i]
+	}
+	return nonce
+}
+
+// Encrypt encrypt a DTLS RecordLayer message
+func (c *ChaCha20Poly1305) Encrypt(pkt *recordlayer.RecordLayer, raw []byte) ([]byte, error) {
+	hdrLen := pkt.Header.Size()
+	payload := raw[hdrLen:]
+	raw = raw[:hdrLen]
+
+	var additionalData []byte
+	if pkt.Header.ContentType == protocol.ContentTypeConnectionID {
+		additionalData = generateAEADAdditionalDataCID(&pkt.Header, len(payload))
+	} else {
+		additionalData = generateAEADAdditionalData(&pkt.Header, len(payload))
+	}
+
+	r := make([]byte, len(raw), len(raw)+len(payload)+c.localAEAD.Overhead())
+	copy(r, raw)
+	r = c.localAEAD.Seal(r, chaCha20Poly1305Nonce(c.localWriteIV, &pkt.Header), payload, additionalData)
+
+	// Update recordLayer size to include the authentication tag
+	binary.BigEndian.PutUint16(r[hdrLen-2:], uint16(len(r)-hdrLen))
+	return r, nil
+}
+
+// Decrypt decrypts a DTLS RecordLayer message
+func (c *ChaCha20Poly1305) Decrypt(in []byte) ([]byte, error) {
+	var h recordlayer.Header
+	if err := h.Unmarshal(in); err != nil {
+		return nil, err
+	}
+	return c.DecryptWithHeader(h, in)
+}
+
+// DecryptWithHeader decrypts a DTLS RecordLayer message whose header has
+// already been parsed. The connection ID of a record can't be parsed from
+// the record alone, so records with a connection ID must be decrypted with
+// DecryptWithHeader.
+func (c *ChaCha20Poly1305) DecryptWithHeader(h recordlayer.Header, in []byte) ([]byte, error) {
+	hdrLen := h.Size()
+	switch {
+	case h.ContentType == protocol.ContentTypeChangeCipherSpec:
+		// Nothing to encrypt with ChangeCipherSpec
+		return in, nil
+	case len(in) < hdrLen+c.remoteAEAD.Overhead():
+		return nil, errNotEnoughRoomForTag
+	}
+
+	out := in[hdrLen:]
+
+	var additionalData []byte
+	if h.ContentType == protocol.ContentTypeConnectionID {
+		additionalData = generateAEADAdditionalDataCID(&h, len(out)-c.remoteAEAD.Overhead())
+	} else {
+		additionalData = generateAEADAdditionalData(&h, len(out)-c.remoteAEAD.Overhead())
+	}
+	out, err := c.remoteAEAD.Open(out[:0], chaCha20Poly1305Nonce(c.remoteWriteIV, &h), out, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errDecryptPacket, err)
+	}
+	return append(in[:hdrLen], out...), nil
+}
@@ -0,0 +1,66 @@
+package ciphersuite
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/pion/dtls/v2/pkg/protocol"
+	"github.com/pion/dtls/v2/pkg/protocol/recordlayer"
+)
+
+func TestChaCha20Poly1305(t *testing.T) {
+	key, iv := make([]byte, 32), make([]byte, 12)
+	for i := range iv {
+		iv[i] = byte(i)
+	}
+	c, err := NewChaCha20Poly1305(key, iv, key, iv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte("chacha20-poly1305")
+	pkt := &recordlayer.RecordLayer{
+		Header: recordlayer.Header{
+			Version:        protocol.Version1_2,
+			Epoch:          1,
+			SequenceNumber: 2,
+		},
+		Content: &protocol.ApplicationData{Data: data},
+	}
+	raw, err := pkt.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := c.Encrypt(pkt, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// No explicit nonce is sent, only the tag is added
+	if len(encrypted) != len(raw)+16 {
+		t.Errorf("Encrypted length %d, expected %d", len(encrypted), len(raw)+16)
+	}
+
+	decrypt := func(in []byte) ([]byte, error) {
+		return c.Decrypt(append([]byte{}, in...))
+	}
+
+	decrypted, err := decrypt(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decrypted[recordlayer.HeaderSize:], data) {
+		t.Errorf("Decrypted %q, expected %q", decrypted[recordlayer.HeaderSize:], data)
+	}
+
+	// The sequence number is part of the nonce
+	replayed := append([]byte{}, encrypted...)
+	replayed[recordlayer.HeaderSize-3] ^= 0x01
+	if _, err := decrypt(replayed); !errors.Is(err, errDecryptPacket) {
+		t.Errorf("Expected error: %v, got: %v", errDecryptPacket, err)
+	}
+
+	if _, err := decrypt(encrypted[:recordlayer.HeaderSize+8]); !errors.Is(err, errNotEnoughRoomForTag) {
+		t.Errorf("Expected error: %v, got: %v", errNotEnoughRoomForTag, err)
+	}
+}
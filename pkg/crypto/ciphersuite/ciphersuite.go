@@ -5,12 +5,14 @@ import (
 	"encoding/binary"
 	"errors"
 
+	"github.com/pion/dtls/v2/internal/util"
 	"github.com/pion/dtls/v2/pkg/protocol"
 	"github.com/pion/dtls/v2/pkg/protocol/recordlayer"
 )
 
 var (
 	errNotEnoughRoomForNonce = &protocol.InternalError{Err: errors.New("buffer not long enough to contain nonce")} //nolint:goerr113
+	errNotEnoughRoomForTag   = &protocol.InternalError{Err: errors.New("buffer not long enough to contain tag")}   //nolint:goerr113
 	errDecryptPacket         = &protocol.TemporaryError{Err: errors.New("failed to decrypt packet")}               //nolint:goerr113
 	errInvalidMAC            = &protocol.TemporaryError{Err: errors.New("invalid mac")}                            //nolint:goerr113
 	errFailedToCast          = &protocol.FatalError{Err: errors.New("failed to cast")}                             //nolint:goerr113
@@ -30,6 +32,29 @@ func generateAEADAdditionalData(h *recordlayer.Header, payloadLen int) []byte {
 	return additionalData[:]
 }
 
+// generateAEADAdditionalDataCID generates additional data for AEAD ciphers
+// according to https://datatracker.ietf.org/doc/html/rfc9146#name-aead-ciphers
+func generateAEADAdditionalDataCID(h *recordlayer.Header, payloadLen int) []byte {
+	return append(append(generateCIDPrefix(h), h.ConnectionID...), byte(payloadLen>>8), byte(payloadLen))
+}
+
+// generateCIDPrefix generates the fields common to the MAC and AEAD additional
+// data of a tls12_cid record, up to but not including the connection ID itself.
+func generateCIDPrefix(h *recordlayer.Header) []byte {
+	var prefix [21]byte
+	// seq_num_placeholder
+	copy(prefix[:], []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	prefix[8] = byte(protocol.ContentTypeConnectionID)
+	prefix[9] = byte(len(h.ConnectionID))
+	prefix[10] = byte(protocol.ContentTypeConnectionID)
+	prefix[11] = h.Version.Major
+	prefix[12] = h.Version.Minor
+	binary.BigEndian.PutUint16(prefix[13:], h.Epoch)
+	util.PutBigEndianUint48(prefix[15:], h.SequenceNumber)
+
+	return prefix[:]
+}
+
 // examinePadding returns, in constant time, the length of the padding to remove
 // from the end of payload. It also returns a byte which is equal to 255 if the
 // padding was valid and 0 otherwise. See RFC 2246, Section 6.2.3.2.
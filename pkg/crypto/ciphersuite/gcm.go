@@ -52,8 +52,9 @@ func NewGCM(localKey, localWriteIV, remoteKey, remoteWriteIV []byte) (*GCM, erro
 
 // Encrypt encrypt a DTLS RecordLayer message
 func (g *GCM) Encrypt(pkt *recordlayer.RecordLayer, raw []byte) ([]byte, error) {
-	payload := raw[recordlayer.HeaderSize:]
-	raw = raw[:recordlayer.HeaderSize]
+	hdrLen := pkt.Header.Size()
+	payload := raw[hdrLen:]
+	raw = raw[:hdrLen]
 
 	nonce := make([]byte, gcmNonceLength)
 	copy(nonce, g.localWriteIV[:4])
@@ -61,7 +62,12 @@ func (g *GCM) Encrypt(pkt *recordlayer.RecordLayer, raw []byte) ([]byte, error)
 		return nil, err
 	}
 
-	additionalData := generateAEADAdditionalData(&pkt.Header, len(payload))
+	var additionalData []byte
+	if pkt.Header.ContentType == protocol.ContentTypeConnectionID {
+		additionalData = generateAEADAdditionalDataCID(&pkt.Header, len(payload))
+	} else {
+		additionalData = generateAEADAdditionalData(&pkt.Header, len(payload))
+	}
 	encryptedPayload := g.localGCM.Seal(nil, nonce, payload, additionalData)
 	r := make([]byte, len(raw)+len(nonce[4:])+len(encryptedPayload))
 	copy(r, raw)
@@ -69,32 +75,46 @@ func (g *GCM) Encrypt(pkt *recordlayer.RecordLayer, raw []byte) ([]byte, error)
 	copy(r[len(raw)+len(nonce[4:]):], encryptedPayload)
 
 	// Update recordLayer size to include explicit nonce
-	binary.BigEndian.PutUint16(r[recordlayer.HeaderSize-2:], uint16(len(r)-recordlayer.HeaderSize))
+	binary.BigEndian.PutUint16(r[hdrLen-2:], uint16(len(r)-hdrLen))
 	return r, nil
 }
 
 // Decrypt decrypts a DTLS RecordLayer message
 func (g *GCM) Decrypt(in []byte) ([]byte, error) {
 	var h recordlayer.Header
-	err := h.Unmarshal(in)
-	switch {
-	case err != nil:
+	if err := h.Unmarshal(in); err != nil {
 		return nil, err
+	}
+	return g.DecryptWithHeader(h, in)
+}
+
+// DecryptWithHeader decrypts a DTLS RecordLayer message whose header has
+// already been parsed. The connection ID of a record can't be parsed from
+// the record alone, so records with a connection ID must be decrypted with
+// DecryptWithHeader.
+func (g *GCM) DecryptWithHeader(h recordlayer.Header, in []byte) ([]byte, error) {
+	hdrLen := h.Size()
+	switch {
 	case h.ContentType == protocol.ContentTypeChangeCipherSpec:
 		// Nothing to encrypt with ChangeCipherSpec
 		return in, nil
-	case len(in) <= (8 + recordlayer.HeaderSize):
+	case len(in) <= (8 + hdrLen):
 		return nil, errNotEnoughRoomForNonce
 	}
 
 	nonce := make([]byte, 0, gcmNonceLength)
-	nonce = append(append(nonce, g.remoteWriteIV[:4]...), in[recordlayer.HeaderSize:recordlayer.HeaderSize+8]...)
-	out := in[recordlayer.HeaderSize+8:]
+	nonce = append(append(nonce, g.remoteWriteIV[:4]...), in[hdrLen:hdrLen+8]...)
+	out := in[hdrLen+8:]
 
-	additionalData := generateAEADAdditionalData(&h, len(out)-gcmTagLength)
-	out, err = g.remoteGCM.Open(out[:0], nonce, out, additionalData)
+	var additionalData []byte
+	if h.ContentType == protocol.ContentTypeConnectionID {
+		additionalData = generateAEADAdditionalDataCID(&h, len(out)-gcmTagLength)
+	} else {
+		additionalData = generateAEADAdditionalData(&h, len(out)-gcmTagLength)
+	}
+	out, err := g.remoteGCM.Open(out[:0], nonce, out, additionalData)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", errDecryptPacket, err)
 	}
-	return append(in[:recordlayer.HeaderSize], out...), nil
+	return append(in[:hdrLen], out...), nil
 }
@@ -0,0 +1,395 @@
+// Package ecjpake implements the EC J-PAKE password authenticated key
+// exchange on secp256r1 with SHA-256, as used by the
+// TLS_ECJPAKE_WITH_AES_128_CCM_8 cipher suite for Thread commissioning.
+//
+// https://tools.ietf.org/html/draft-cragie-tls-ecjpake-01
+// https://tools.ietf.org/html/rfc8236
+package ecjpake
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"filippo.io/bigmod"
+	"github.com/pion/dtls/v2/pkg/protocol"
+)
+
+var (
+	errInvalidPassphrase = &protocol.FatalError{Err: errors.New("ecjpake: passphrase must not be zero")}                  //nolint:goerr113
+	errBufferTooSmall    = &protocol.FatalError{Err: errors.New("ecjpake: buffer is too small")}                          //nolint:goerr113
+	errLengthMismatch    = &protocol.FatalError{Err: errors.New("ecjpake: data length and declared length do not match")} //nolint:goerr113
+	errInvalidPoint      = &protocol.FatalError{Err: errors.New("ecjpake: invalid point")}                                //nolint:goerr113
+	errInvalidProof      = &protocol.FatalError{Err: errors.New("ecjpake: zero-knowledge proof verification failed")}     //nolint:goerr113
+	errNoPeerRoundOne    = &protocol.FatalError{Err: errors.New("ecjpake: round one of the peer has not been read")}      //nolint:goerr113
+	errNoPeerRoundTwo    = &protocol.FatalError{Err: errors.New("ecjpake: round two of the peer has not been read")}      //nolint:goerr113
+)
+
+// Role is the side of the exchange, whose name identifies the prover in
+// the zero-knowledge proofs
+type Role int
+
+// Role enums
+const (
+	Client Role = iota
+	Server
+)
+
+func (r Role) id() []byte {
+	if r == Server {
+		return []byte("server")
+	}
+	return []byte("client")
+}
+
+func (r Role) peer() Role {
+	if r == Server {
+		return Client
+	}
+	return Server
+}
+
+// order is the order n of the secp256r1 base point. The secret scalars
+// (the passphrase s, the private keys and the proof nonces) are kept as
+// bigmod.Nat, whose arithmetic modulo n is constant time, and are only
+// multiplied with points as fixed-length scalars by crypto/elliptic, which
+// is constant time for P-256. The points and the proofs of the peer are
+// public, and are verified with math/big.
+var order = func() *bigmod.Modulus {
+	n, err := bigmod.NewModulusFromBig(elliptic.P256().Params().N)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}()
+
+type point struct {
+	x, y *big.Int
+}
+
+// Context holds the state of one side of an EC J-PAKE exchange. The
+// mine/peer naming follows the unified description of the exchange, where
+// both sides run the same steps.
+type Context struct {
+	role Role
+	s    *bigmod.Nat
+
+	xm1, xm2 *bigmod.Nat
+	xM1, xM2 point
+	xP1, xP2 point
+	xP       point
+
+	roundOne []byte
+	roundTwo []byte
+}
+
+// New creates a Context for the given role, and generates the keys of
+// round one
+func New(role Role, passphrase []byte) (*Context, error) {
+	xm1, err := randomScalar()
+	if err != nil {
+		return nil, err
+	}
+	xm2, err := randomScalar()
+	if err != nil {
+		return nil, err
+	}
+	return newContext(role, passphrase, xm1, xm2)
+}
+
+func newContext(role Role, passphrase []byte, xm1, xm2 *bigmod.Nat) (*Context, error) {
+	// s is the passphrase as a big-endian integer modulo n
+	s := bigmod.NewNat().ExpandFor(order)
+	radix, err := bigmod.NewNat().SetBytes([]byte{0x01, 0x00}, order)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range passphrase {
+		digit, err := bigmod.NewNat().SetBytes([]byte{b}, order)
+		if err != nil {
+			return nil, err
+		}
+		s.Mul(radix, order).Add(digit, order)
+	}
+	if s.IsZero() == 1 {
+		return nil, errInvalidPassphrase
+	}
+
+	params := elliptic.P256().Params()
+	generator := point{params.Gx, params.Gy}
+	c := &Context{
+		role: role,
+		s:    s,
+		xm1:  xm1,
+		xm2:  xm2,
+		xM1:  scalarMult(generator, xm1.Bytes(order)),
+		xM2:  scalarMult(generator, xm2.Bytes(order)),
+	}
+
+	for _, k := range []struct {
+		x *bigmod.Nat
+		X point
+	}{{c.xm1, c.xM1}, {c.xm2, c.xM2}} {
+		if c.roundOne, err = appendKeyKP(c.roundOne, generator, k.x, k.X, role.id()); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// RoundOne returns the ECJPAKEKeyKPPairList of this side, as sent in the
+// ecjpake_key_kp_pair extension. It is generated once, so retransmitted
+// hellos carry the same keys.
+func (c *Context) RoundOne() []byte {
+	return append([]byte{}, c.roundOne...)
+}
+
+// ReadRoundOne reads the ECJPAKEKeyKPPairList of the peer, and verifies
+// its zero-knowledge proofs
+func (c *Context) ReadRoundOne(data []byte) error {
+	params := elliptic.P256().Params()
+	generator := point{params.Gx, params.Gy}
+
+	xP1, rest, err := readKeyKP(data, generator, c.role.peer().id())
+	if err != nil {
+		return err
+	}
+	xP2, rest, err := readKeyKP(rest, generator, c.role.peer().id())
+	if err != nil {
+		return err
+	}
+	if len(rest) != 0 {
+		return errLengthMismatch
+	}
+
+	c.xP1, c.xP2 = xP1, xP2
+	c.roundTwo = nil
+	return nil
+}
+
+// RoundTwo returns the ECJPAKEKeyKP of this side, as sent in the
+// ServerKeyExchange or ClientKeyExchange. It is generated once, after
+// the round one of the peer has been read.
+func (c *Context) RoundTwo() ([]byte, error) {
+	if c.roundTwo != nil {
+		return append([]byte{}, c.roundTwo...), nil
+	}
+	if c.xP1.x == nil {
+		return nil, errNoPeerRoundOne
+	}
+
+	// G = Xm1 + Xp1 + Xp2, xm = xm2 * s, Xm = xm * G
+	generator, err := add(c.xM1, c.xP1, c.xP2)
+	if err != nil {
+		return nil, err
+	}
+	xm := mul(c.xm2, c.s)
+	xM := scalarMult(generator, xm.Bytes(order))
+
+	if c.roundTwo, err = appendKeyKP(nil, generator, xm, xM, c.role.id()); err != nil {
+		return nil, err
+	}
+	return append([]byte{}, c.roundTwo...), nil
+}
+
+// ReadRoundTwo reads the ECJPAKEKeyKP of the peer, and verifies its
+// zero-knowledge proof
+func (c *Context) ReadRoundTwo(data []byte) error {
+	if c.xP1.x == nil {
+		return errNoPeerRoundOne
+	}
+
+	// The peer used G = Xp1 + Xm1 + Xm2
+	generator, err := add(c.xP1, c.xM1, c.xM2)
+	if err != nil {
+		return err
+	}
+	xP, rest, err := readKeyKP(data, generator, c.role.peer().id())
+	if err != nil {
+		return err
+	}
+	if len(rest) != 0 {
+		return errLengthMismatch
+	}
+
+	c.xP = xP
+	return nil
+}
+
+// PreMasterSecret derives the premaster secret SHA-256(K.X), where
+// K = (Xp - Xp2 * xm2 * s) * xm2
+func (c *Context) PreMasterSecret() ([]byte, error) {
+	if c.xP.x == nil {
+		return nil, errNoPeerRoundTwo
+	}
+
+	curve := elliptic.P256()
+	t := bigmod.NewNat().ExpandFor(order).Sub(mul(c.xm2, c.s), order)
+
+	k, err := add(c.xP, scalarMult(c.xP2, t.Bytes(order)))
+	if err != nil {
+		return nil, err
+	}
+	k = scalarMult(k, c.xm2.Bytes(order))
+	if k.x.Sign() == 0 && k.y.Sign() == 0 {
+		return nil, errInvalidPoint
+	}
+
+	x := k.x.Bytes()
+	kx := make([]byte, (curve.Params().BitSize+7)/8)
+	copy(kx[len(kx)-len(x):], x)
+	secret := sha256.Sum256(kx)
+	return secret[:], nil
+}
+
+// randomScalar returns a random scalar in [1, n-1]
+func randomScalar() (*bigmod.Nat, error) {
+	b := make([]byte, order.Size())
+	for {
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		k, err := bigmod.NewNat().SetBytes(b, order)
+		if err == nil && k.IsZero() == 0 {
+			return k, nil
+		}
+	}
+}
+
+// mul returns a * b mod n
+func mul(a, b *bigmod.Nat) *bigmod.Nat {
+	return bigmod.NewNat().ExpandFor(order).Add(a, order).Mul(b, order)
+}
+
+// appendKeyKP appends the ECJPAKEKeyKP of X = x * g, with a Schnorr proof
+// of the knowledge of x
+//
+//	struct {
+//	    ECPoint X;
+//	    ECSchnorrZKP zkp;
+//	} ECJPAKEKeyKP;
+//
+//	struct {
+//	    ECPoint V;
+//	    opaque r<1..2^8-1>;
+//	} ECSchnorrZKP;
+func appendKeyKP(out []byte, g point, x *bigmod.Nat, xPublic point, id []byte) ([]byte, error) {
+	v, err := randomScalar()
+	if err != nil {
+		return nil, err
+	}
+	vPublic := scalarMult(g, v.Bytes(order))
+
+	// r = v - x * h mod n
+	hBytes := make([]byte, order.Size())
+	proofHash(g, vPublic, xPublic, id).FillBytes(hBytes)
+	h, err := bigmod.NewNat().SetBytes(hBytes, order)
+	if err != nil {
+		return nil, err
+	}
+	r := v.Sub(mul(x, h), order)
+
+	// r is public, it is sent without leading zeros
+	rBytes := new(big.Int).SetBytes(r.Bytes(order)).Bytes()
+	out = appendPoint(out, xPublic)
+	out = appendPoint(out, vPublic)
+	out = append(out, byte(len(rBytes)))
+	return append(out, rBytes...), nil
+}
+
+// readKeyKP reads an ECJPAKEKeyKP, verifies that V = r * g + h * X, and
+// returns X with the remaining data
+func readKeyKP(data []byte, g point, id []byte) (point, []byte, error) {
+	xPublic, data, err := readPoint(data)
+	if err != nil {
+		return point{}, nil, err
+	}
+	vPublic, data, err := readPoint(data)
+	if err != nil {
+		return point{}, nil, err
+	}
+	if len(data) < 1 {
+		return point{}, nil, errBufferTooSmall
+	}
+	rLength := int(data[0])
+	if len(data) < 1+rLength {
+		return point{}, nil, errBufferTooSmall
+	}
+	r := new(big.Int).SetBytes(data[1 : 1+rLength])
+	data = data[1+rLength:]
+
+	h := proofHash(g, vPublic, xPublic, id)
+	expected, err := add(scalarMult(g, r.Bytes()), scalarMult(xPublic, h.Bytes()))
+	if err != nil || expected.x.Cmp(vPublic.x) != 0 || expected.y.Cmp(vPublic.y) != 0 {
+		return point{}, nil, errInvalidProof
+	}
+	return xPublic, data, nil
+}
+
+// proofHash returns SHA-256(G || V || X || ID) mod n, where each point
+// and the ID is prefixed with its four byte length
+func proofHash(g, vPublic, xPublic point, id []byte) *big.Int {
+	curve := elliptic.P256()
+	var data []byte
+	for _, field := range [][]byte{
+		elliptic.Marshal(curve, g.x, g.y),
+		elliptic.Marshal(curve, vPublic.x, vPublic.y),
+		elliptic.Marshal(curve, xPublic.x, xPublic.y),
+		id,
+	} {
+		data = append(data, 0x00, 0x00, 0x00, 0x00)
+		binary.BigEndian.PutUint32(data[len(data)-4:], uint32(len(field)))
+		data = append(data, field...)
+	}
+
+	digest := sha256.Sum256(data)
+	h := new(big.Int).SetBytes(digest[:])
+	return h.Mod(h, curve.Params().N)
+}
+
+func appendPoint(out []byte, p point) []byte {
+	raw := elliptic.Marshal(elliptic.P256(), p.x, p.y)
+	out = append(out, byte(len(raw)))
+	return append(out, raw...)
+}
+
+// readPoint reads an uncompressed ECPoint, which must be on the curve
+func readPoint(data []byte) (point, []byte, error) {
+	if len(data) < 1 {
+		return point{}, nil, errBufferTooSmall
+	}
+	length := int(data[0])
+	if len(data) < 1+length {
+		return point{}, nil, errBufferTooSmall
+	}
+	x, y := elliptic.Unmarshal(elliptic.P256(), data[1:1+length])
+	if x == nil {
+		return point{}, nil, errInvalidPoint
+	}
+	return point{x, y}, data[1+length:], nil
+}
+
+// add returns the sum of points, which must not be the point at infinity
+func add(points ...point) (point, error) {
+	curve := elliptic.P256()
+	sum := points[0]
+	for _, p := range points[1:] {
+		x, y := curve.Add(sum.x, sum.y, p.x, p.y)
+		sum = point{x, y}
+	}
+	if sum.x.Sign() == 0 && sum.y.Sign() == 0 {
+		return point{}, errInvalidPoint
+	}
+	return sum, nil
+}
+
+// scalarMult returns k * p. It is constant time for secret scalars of the
+// length of n.
+func scalarMult(p point, k []byte) point {
+	x, y := elliptic.P256().ScalarMult(p.x, p.y, k)
+	return point{x, y}
+}
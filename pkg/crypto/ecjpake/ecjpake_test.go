@@ -0,0 +1,233 @@
+package ecjpake
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"filippo.io/bigmod"
+)
+
+func exchange(t *testing.T, clientPassphrase, serverPassphrase []byte) (client, server *Context) {
+	var err error
+	if client, err = New(Client, clientPassphrase); err != nil {
+		t.Fatal(err)
+	}
+	if server, err = New(Server, serverPassphrase); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = server.ReadRoundOne(client.RoundOne()); err != nil {
+		t.Fatal(err)
+	}
+	if err = client.ReadRoundOne(server.RoundOne()); err != nil {
+		t.Fatal(err)
+	}
+
+	serverRoundTwo, err := server.RoundTwo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = client.ReadRoundTwo(serverRoundTwo); err != nil {
+		t.Fatal(err)
+	}
+	clientRoundTwo, err := client.RoundTwo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = server.ReadRoundTwo(clientRoundTwo); err != nil {
+		t.Fatal(err)
+	}
+	return client, server
+}
+
+func TestExchange(t *testing.T) {
+	for name, tt := range map[string]struct {
+		clientPassphrase, serverPassphrase []byte
+		wantEqual                          bool
+	}{
+		"SamePassphrase": {
+			clientPassphrase: []byte("threadjpaketest"),
+			serverPassphrase: []byte("threadjpaketest"),
+			wantEqual:        true,
+		},
+		"DifferentPassphrase": {
+			clientPassphrase: []byte("threadjpaketest"),
+			serverPassphrase: []byte("threadjpaketesT"),
+			wantEqual:        false,
+		},
+	} {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			client, server := exchange(t, tt.clientPassphrase, tt.serverPassphrase)
+
+			clientSecret, err := client.PreMasterSecret()
+			if err != nil {
+				t.Fatal(err)
+			}
+			serverSecret, err := server.PreMasterSecret()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(clientSecret) != 32 {
+				t.Errorf("Premaster secret length %d, expected 32", len(clientSecret))
+			}
+			if bytes.Equal(clientSecret, serverSecret) != tt.wantEqual {
+				t.Errorf("Premaster secrets client(% 02x) server(% 02x), expected equal: %v", clientSecret, serverSecret, tt.wantEqual)
+			}
+		})
+	}
+}
+
+// The private keys and premaster secret of the EC J-PAKE self test of
+// mbedTLS, library/ecjpake.c
+func TestPreMasterSecretVector(t *testing.T) {
+	scalar := func(first, last byte) *bigmod.Nat {
+		b := make([]byte, 32)
+		for i := range b {
+			b[i] = first + byte(i)
+		}
+		b[31] = last
+		k, err := bigmod.NewNat().SetBytes(b, order)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+	passphrase := []byte("threadjpaketest")
+	expected := []byte{
+		0xf3, 0xd4, 0x7f, 0x59, 0x98, 0x44, 0xdb, 0x92, 0xa5, 0x69, 0xbb, 0xe7, 0x98, 0x1e, 0x39, 0xd9,
+		0x31, 0xfd, 0x74, 0x3b, 0xf2, 0x2e, 0x98, 0xf9, 0xb4, 0x38, 0xf7, 0x19, 0xd3, 0xc4, 0xf3, 0x51,
+	}
+
+	client, err := newContext(Client, passphrase, scalar(0x01, 0x21), scalar(0x61, 0x81))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := newContext(Server, passphrase, scalar(0x61, 0x81), scalar(0xc1, 0xe1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = server.ReadRoundOne(client.RoundOne()); err != nil {
+		t.Fatal(err)
+	}
+	if err = client.ReadRoundOne(server.RoundOne()); err != nil {
+		t.Fatal(err)
+	}
+	serverRoundTwo, err := server.RoundTwo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = client.ReadRoundTwo(serverRoundTwo); err != nil {
+		t.Fatal(err)
+	}
+	clientRoundTwo, err := client.RoundTwo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = server.ReadRoundTwo(clientRoundTwo); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, c := range map[string]*Context{"client": client, "server": server} {
+		secret, err := c.PreMasterSecret()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(secret, expected) {
+			t.Errorf("Premaster secret of the %s % 02x, expected % 02x", name, secret, expected)
+		}
+	}
+}
+
+func TestRoundsAreCached(t *testing.T) {
+	client, _ := exchange(t, []byte("threadjpaketest"), []byte("threadjpaketest"))
+
+	if !bytes.Equal(client.RoundOne(), client.RoundOne()) {
+		t.Error("Round one changed")
+	}
+	first, err := client.RoundTwo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := client.RoundTwo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Error("Round two changed")
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	passphrase := []byte("threadjpaketest")
+	client, err := New(Client, passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherClient, err := New(Client, passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := New(Server, passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roundOne := server.RoundOne()
+	tamperedProof := append([]byte{}, roundOne...)
+	tamperedProof[133] ^= 0x01 // First byte of r, after the points X and V
+	offCurve := append([]byte{}, roundOne...)
+	offCurve[10] ^= 0x01
+
+	for name, tt := range map[string]struct {
+		data    []byte
+		wantErr error
+	}{
+		"Empty":         {nil, errBufferTooSmall},
+		"Truncated":     {roundOne[:len(roundOne)-1], errBufferTooSmall},
+		"TrailingData":  {append(append([]byte{}, roundOne...), 0x00), errLengthMismatch},
+		"TamperedProof": {tamperedProof, errInvalidProof},
+		"OffCurve":      {offCurve, errInvalidPoint},
+		// The proofs of a client name the client, so they don't verify
+		// as proofs of the server
+		"WrongRole": {otherClient.RoundOne(), errInvalidProof},
+	} {
+		if err := client.ReadRoundOne(tt.data); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: expected error %v, got %v", name, tt.wantErr, err)
+		}
+	}
+
+	if _, err := client.RoundTwo(); !errors.Is(err, errNoPeerRoundOne) {
+		t.Errorf("Expected error %v, got %v", errNoPeerRoundOne, err)
+	}
+	if err := client.ReadRoundTwo(nil); !errors.Is(err, errNoPeerRoundOne) {
+		t.Errorf("Expected error %v, got %v", errNoPeerRoundOne, err)
+	}
+	if _, err := client.PreMasterSecret(); !errors.Is(err, errNoPeerRoundTwo) {
+		t.Errorf("Expected error %v, got %v", errNoPeerRoundTwo, err)
+	}
+
+	// A round two must prove knowledge for the generator of this exchange
+	if err := client.ReadRoundOne(roundOne); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.ReadRoundOne(otherClient.RoundOne()); err != nil {
+		t.Fatal(err)
+	}
+	roundTwo, err := server.RoundTwo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.ReadRoundTwo(roundTwo); !errors.Is(err, errInvalidProof) {
+		t.Errorf("Expected error %v, got %v", errInvalidProof, err)
+	}
+}
+
+func TestInvalidPassphrase(t *testing.T) {
+	for _, passphrase := range [][]byte{nil, {0x00, 0x00}} {
+		if _, err := New(Client, passphrase); !errors.Is(err, errInvalidPassphrase) {
+			t.Errorf("Expected error %v, got %v", errInvalidPassphrase, err)
+		}
+	}
+}
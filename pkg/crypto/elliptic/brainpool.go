@@ -0,0 +1,316 @@
+package elliptic
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"math/big"
+	"sync"
+
+	"filippo.io/bigmod"
+)
+
+// brainpoolCurve is one of the Brainpool curves of RFC 5639, a short
+// Weierstrass curve y² = x³ + ax + b of prime order. Field elements use the
+// constant time modular arithmetic of filippo.io/bigmod, and points are added
+// with the complete formulas of Renes, Costello and Batina, which have no
+// exceptional cases. Scalar multiplications therefore take the same time
+// whatever the scalar, for scalars of the same length.
+// https://eprint.iacr.org/2015/1060
+type brainpoolCurve struct {
+	params *elliptic.CurveParams
+	p, n   *bigmod.Modulus
+	a, b   *bigmod.Nat
+	// b3 is 3b, as used by the addition formulas
+	b3 *bigmod.Nat
+	// pMinus2 inverts a field element by Fermat's little theorem
+	pMinus2 []byte
+	g       *brainpoolPoint
+}
+
+// brainpoolPoint is a point in projective coordinates (X:Y:Z), representing
+// the affine point (X/Z, Y/Z). The point at infinity is (0:1:0).
+type brainpoolPoint struct {
+	x, y, z *bigmod.Nat
+}
+
+//nolint:gochecknoglobals
+var (
+	brainpoolOnce                    sync.Once
+	brainpoolP256r1, brainpoolP384r1 *brainpoolCurve
+)
+
+func initBrainpool() {
+	brainpoolP256r1 = newBrainpoolCurve("brainpoolP256r1", 256,
+		"a9fb57dba1eea9bc3e660a909d838d726e3bf623d52620282013481d1f6e5377",
+		"7d5a0975fc2c3057eef67530417affe7fb8055c126dc5c6ce94a4b44f330b5d9",
+		"26dc5c6ce94a4b44f330b5d9bbd77cbf958416295cf7e1ce6bccdc18ff8c07b6",
+		"8bd2aeb9cb7e57cb2c4b482ffc81b7afb9de27e1e3bd23c23a4453bd9ace3262",
+		"547ef835c3dac4fd97f8461a14611dc9c27745132ded8e545c1d54c72f046997",
+		"a9fb57dba1eea9bc3e660a909d838d718c397aa3b561a6f7901e0e82974856a7",
+	)
+	brainpoolP384r1 = newBrainpoolCurve("brainpoolP384r1", 384,
+		"8cb91e82a3386d280f5d6f7e50e641df152f7109ed5456b412b1da197fb71123acd3a729901d1a71874700133107ec53",
+		"7bc382c63d8c150c3c72080ace05afa0c2bea28e4fb22787139165efba91f90f8aa5814a503ad4eb04a8c7dd22ce2826",
+		"04a8c7dd22ce28268b39b55416f0447c2fb77de107dcd2a62e880ea53eeb62d57cb4390295dbc9943ab78696fa504c11",
+		"1d1c64f068cf45ffa2a63a81b7c13f6b8847a3e77ef14fe3db7fcafe0cbd10e8e826e03436d646aaef87b2e247d4af1e",
+		"8abe1d7520f9c2a45cb1eb8e95cfd55262b70b29feec5864e19c054ff99129280e4646217791811142820341263c5315",
+		"8cb91e82a3386d280f5d6f7e50e641df152f7109ed5456b31f166e6cac0425a7cf3ab6af6b7fc3103b883202e9046565",
+	)
+}
+
+func newBrainpoolCurve(name string, bitSize int, p, a, b, gx, gy, n string) *brainpoolCurve {
+	fromHex := func(s string) *big.Int {
+		i, _ := new(big.Int).SetString(s, 16)
+		return i
+	}
+	params := &elliptic.CurveParams{
+		Name:    name,
+		BitSize: bitSize,
+		P:       fromHex(p),
+		N:       fromHex(n),
+		B:       fromHex(b),
+		Gx:      fromHex(gx),
+		Gy:      fromHex(gy),
+	}
+
+	c := &brainpoolCurve{params: params}
+	var err error
+	if c.p, err = bigmod.NewModulusFromBig(params.P); err != nil {
+		panic(err)
+	}
+	if c.n, err = bigmod.NewModulusFromBig(params.N); err != nil {
+		panic(err)
+	}
+	c.a = c.mustElement(fromHex(a))
+	c.b = c.mustElement(params.B)
+	c.b3 = c.set(c.b).Add(c.b, c.p).Add(c.b, c.p)
+	c.pMinus2 = new(big.Int).Sub(params.P, big.NewInt(2)).Bytes()
+	c.g = &brainpoolPoint{c.mustElement(params.Gx), c.mustElement(params.Gy), c.mustElement(big.NewInt(1))}
+	return c
+}
+
+func brainpoolP256r1Curve() *brainpoolCurve {
+	brainpoolOnce.Do(initBrainpool)
+	return brainpoolP256r1
+}
+
+func brainpoolP384r1Curve() *brainpoolCurve {
+	brainpoolOnce.Do(initBrainpool)
+	return brainpoolP384r1
+}
+
+func (c *brainpoolCurve) mustElement(v *big.Int) *bigmod.Nat {
+	e, err := bigmod.NewNat().SetBytes(v.Bytes(), c.p)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// set returns a copy of the field element x
+func (c *brainpoolCurve) set(x *bigmod.Nat) *bigmod.Nat {
+	return bigmod.NewNat().ExpandFor(c.p).Add(x, c.p)
+}
+
+func (c *brainpoolCurve) infinity() *brainpoolPoint {
+	zero := bigmod.NewNat().ExpandFor(c.p)
+	return &brainpoolPoint{zero, c.mustElement(big.NewInt(1)), c.set(zero)}
+}
+
+// add returns p + q with Algorithm 1 of Renes, Costello and Batina, which
+// is also valid for p = q and for the point at infinity
+func (c *brainpoolCurve) add(p, q *brainpoolPoint) *brainpoolPoint {
+	mul := func(x, y *bigmod.Nat) *bigmod.Nat { return c.set(x).Mul(y, c.p) }
+	add := func(x, y *bigmod.Nat) *bigmod.Nat { return c.set(x).Add(y, c.p) }
+	sub := func(x, y *bigmod.Nat) *bigmod.Nat { return c.set(x).Sub(y, c.p) }
+
+	t0 := mul(p.x, q.x)
+	t1 := mul(p.y, q.y)
+	t2 := mul(p.z, q.z)
+	t3 := sub(mul(add(p.x, p.y), add(q.x, q.y)), add(t0, t1))
+	t4 := sub(mul(add(p.x, p.z), add(q.x, q.z)), add(t0, t2))
+	t5 := sub(mul(add(p.y, p.z), add(q.y, q.z)), add(t1, t2))
+	z3 := add(mul(c.b3, t2), mul(c.a, t4))
+	x3 := sub(t1, z3)
+	z3 = add(t1, z3)
+	y3 := mul(x3, z3)
+	t1 = add(add(t0, t0), t0)
+	t2 = mul(c.a, t2)
+	t4 = mul(c.b3, t4)
+	t1 = add(t1, t2)
+	t2 = mul(c.a, sub(t0, t2))
+	t4 = add(t4, t2)
+	y3 = add(y3, mul(t1, t4))
+	x3 = sub(mul(t3, x3), mul(t5, t4))
+	z3 = add(mul(t5, z3), mul(t3, t1))
+	return &brainpoolPoint{x3, y3, z3}
+}
+
+// scalarMult returns k*p. A doubling and an addition are computed for
+// every bit of k, and the sum is kept or discarded in constant time.
+func (c *brainpoolCurve) scalarMult(p *brainpoolPoint, k []byte) *brainpoolPoint {
+	q := c.infinity()
+	for _, b := range k {
+		for i := 7; i >= 0; i-- {
+			q = c.add(q, q)
+			c.assign(q, c.add(q, p), (b>>uint(i))&1)
+		}
+	}
+	return q
+}
+
+// assign sets p to q if bit is 1, and leaves it unchanged if bit is 0,
+// computing p + bit*(q - p) for every coordinate
+func (c *brainpoolCurve) assign(p, q *brainpoolPoint, bit byte) {
+	on, _ := bigmod.NewNat().SetBytes([]byte{bit}, c.p)
+	for _, coordinates := range [][2]*bigmod.Nat{{p.x, q.x}, {p.y, q.y}, {p.z, q.z}} {
+		diff := c.set(coordinates[1]).Sub(coordinates[0], c.p).Mul(on, c.p)
+		coordinates[0].Add(diff, c.p)
+	}
+}
+
+// affine returns the affine coordinates of p, or false for the point at
+// infinity
+func (c *brainpoolCurve) affine(p *brainpoolPoint) ([]byte, []byte, bool) {
+	if p.z.IsZero() == 1 {
+		return nil, nil, false
+	}
+	zInv := bigmod.NewNat().Exp(p.z, c.pMinus2, c.p)
+	x := c.set(p.x).Mul(zInv, c.p)
+	y := c.set(p.y).Mul(zInv, c.p)
+	return x.Bytes(c.p), y.Bytes(c.p), true
+}
+
+// marshal encodes p as an uncompressed point
+func (c *brainpoolCurve) marshal(p *brainpoolPoint) ([]byte, error) {
+	x, y, ok := c.affine(p)
+	if !ok {
+		return nil, errPointAtInfinity
+	}
+	return append(append([]byte{4}, x...), y...), nil
+}
+
+// unmarshal decodes an uncompressed point, which must be on the curve
+func (c *brainpoolCurve) unmarshal(data []byte) (*brainpoolPoint, error) {
+	size := c.p.Size()
+	if len(data) != 1+2*size || data[0] != 4 {
+		return nil, errInvalidPointLength
+	}
+	x, err := bigmod.NewNat().SetBytes(data[1:1+size], c.p)
+	if err != nil {
+		return nil, errPointNotOnCurve
+	}
+	y, err := bigmod.NewNat().SetBytes(data[1+size:], c.p)
+	if err != nil {
+		return nil, errPointNotOnCurve
+	}
+
+	// y² = x³ + ax + b
+	y2 := c.set(y).Mul(y, c.p)
+	rhs := c.set(x).Mul(x, c.p)
+	rhs.Add(c.a, c.p).Mul(x, c.p).Add(c.b, c.p)
+	if y2.Equal(rhs) != 1 {
+		return nil, errPointNotOnCurve
+	}
+	return &brainpoolPoint{x, y, c.mustElement(big.NewInt(1))}, nil
+}
+
+// generateKeypair generates a private key in [1, n-1], by rejecting the
+// random values out of that range
+func (c *brainpoolCurve) generateKeypair(nc Curve) (*Keypair, error) {
+	privateKey := make([]byte, c.n.Size())
+	for {
+		if _, err := rand.Read(privateKey); err != nil {
+			return nil, err
+		}
+		if k, err := bigmod.NewNat().SetBytes(privateKey, c.n); err == nil && k.IsZero() == 0 {
+			break
+		}
+	}
+
+	publicKey, err := c.marshal(c.scalarMult(c.g, privateKey))
+	if err != nil {
+		return nil, err
+	}
+	return &Keypair{nc, publicKey, privateKey}, nil
+}
+
+// sharedSecret returns the x-coordinate of the product of the private key
+// and the public key of the peer. RFC 8422 Section 5.10
+func (c *brainpoolCurve) sharedSecret(privateKey, publicKey []byte) ([]byte, error) {
+	if len(privateKey) != c.n.Size() {
+		return nil, errInvalidScalarLength
+	}
+	p, err := c.unmarshal(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	x, _, ok := c.affine(c.scalarMult(p, privateKey))
+	if !ok {
+		return nil, errPointAtInfinity
+	}
+	return x, nil
+}
+
+// The elliptic.Curve methods let crypto/ecdsa verify signatures of
+// Brainpool keys. Like the crypto/elliptic curves, they panic on points
+// which are not on the curve.
+
+func (c *brainpoolCurve) Params() *elliptic.CurveParams {
+	return c.params
+}
+
+func (c *brainpoolCurve) IsOnCurve(x, y *big.Int) bool {
+	_, err := c.fromAffine(x, y)
+	return err == nil
+}
+
+func (c *brainpoolCurve) Add(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	return c.toAffine(c.add(c.mustFromAffine(x1, y1), c.mustFromAffine(x2, y2)))
+}
+
+func (c *brainpoolCurve) Double(x1, y1 *big.Int) (*big.Int, *big.Int) {
+	p := c.mustFromAffine(x1, y1)
+	return c.toAffine(c.add(p, p))
+}
+
+func (c *brainpoolCurve) ScalarMult(x1, y1 *big.Int, k []byte) (*big.Int, *big.Int) {
+	return c.toAffine(c.scalarMult(c.mustFromAffine(x1, y1), k))
+}
+
+func (c *brainpoolCurve) ScalarBaseMult(k []byte) (*big.Int, *big.Int) {
+	return c.toAffine(c.scalarMult(c.g, k))
+}
+
+// fromAffine converts affine coordinates, with (0, 0) standing for the point
+// at infinity as in crypto/elliptic
+func (c *brainpoolCurve) fromAffine(x, y *big.Int) (*brainpoolPoint, error) {
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return c.infinity(), nil
+	}
+	size := c.p.Size()
+	if x.Sign() < 0 || y.Sign() < 0 || x.BitLen() > 8*size || y.BitLen() > 8*size {
+		return nil, errPointNotOnCurve
+	}
+	data := make([]byte, 1+2*size)
+	data[0] = 4
+	x.FillBytes(data[1 : 1+size])
+	y.FillBytes(data[1+size:])
+	return c.unmarshal(data)
+}
+
+func (c *brainpoolCurve) mustFromAffine(x, y *big.Int) *brainpoolPoint {
+	p, err := c.fromAffine(x, y)
+	if err != nil {
+		panic("elliptic: " + c.params.Name + " operation on an invalid point")
+	}
+	return p
+}
+
+func (c *brainpoolCurve) toAffine(p *brainpoolPoint) (*big.Int, *big.Int) {
+	x, y, ok := c.affine(p)
+	if !ok {
+		return new(big.Int), new(big.Int)
+	}
+	return new(big.Int).SetBytes(x), new(big.Int).SetBytes(y)
+}
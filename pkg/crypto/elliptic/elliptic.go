@@ -9,7 +9,14 @@ import (
 	"golang.org/x/crypto/curve25519"
 )
 
-var errInvalidNamedCurve = errors.New("invalid named curve")
+var (
+	errInvalidNamedCurve   = errors.New("invalid named curve")
+	errInvalidScalarLength = errors.New("invalid scalar length")
+	errInvalidPointLength  = errors.New("invalid point length")
+	errPointNotOnCurve     = errors.New("point is not on the curve")
+	errPointAtInfinity     = errors.New("point is the point at infinity")
+	errLowOrderPoint       = errors.New("point is of low order")
+)
 
 // CurvePointFormat is used to represent the IANA registered curve points
 //
@@ -52,17 +59,60 @@ type Curve uint16
 
 // Curve enums
 const (
-	P256   Curve = 0x0017
-	P384   Curve = 0x0018
-	X25519 Curve = 0x001d
+	P256            Curve = 0x0017
+	P384            Curve = 0x0018
+	P521            Curve = 0x0019
+	BrainpoolP256r1 Curve = 0x001a
+	BrainpoolP384r1 Curve = 0x001b
+	X25519          Curve = 0x001d
+	X448            Curve = 0x001e
+
+	// X25519MLKEM768Experimental is the X25519MLKEM768 hybrid group of
+	// draft-kwiatkowski-tls-ecdhe-mlkem with key shares carried in the
+	// ServerKeyExchange and ClientKeyExchange. The draft only defines the
+	// group for TLS 1.3, so this DTLS 1.2 variant is specific to pion and
+	// uses a codepoint of the private use range of RFC 8422 rather than the
+	// 0x11ec of the draft. It only interoperates with pion/dtls.
+	X25519MLKEM768Experimental Curve = 0xfe11
 )
 
 // Curves returns all curves we implement
 func Curves() map[Curve]bool {
 	return map[Curve]bool{
-		X25519: true,
-		P256:   true,
-		P384:   true,
+		X25519:                     true,
+		X448:                       true,
+		P256:                       true,
+		P384:                       true,
+		P521:                       true,
+		BrainpoolP256r1:            true,
+		BrainpoolP384r1:            true,
+		X25519MLKEM768Experimental: x25519MLKEM768Supported,
+	}
+}
+
+// Hybrid returns if c is a hybrid post-quantum group. The key shares of the
+// server and the client differ, and do not fit the one byte length prefix
+// of an ECPoint.
+func (c Curve) Hybrid() bool {
+	return c == X25519MLKEM768Experimental
+}
+
+// EllipticCurve returns the crypto/elliptic implementation of a Weierstrass
+// curve. It returns nil for X25519, X448 and the hybrid groups.
+func (c Curve) EllipticCurve() elliptic.Curve {
+	switch c {
+	case P256:
+		return elliptic.P256()
+	case P384:
+		return elliptic.P384()
+	case P521:
+		return elliptic.P521()
+	case BrainpoolP256r1:
+		return brainpoolP256r1Curve()
+	case BrainpoolP384r1:
+		return brainpoolP384r1Curve()
+	default:
+		return nil
 	}
 }
 
@@ -80,10 +130,53 @@ func GenerateKeypair(c Curve) (*Keypair, error) {
 
 		curve25519.ScalarBaseMult(&public, &private)
 		return &Keypair{X25519, public[:], private[:]}, nil
-	case P256:
-		return ellipticCurveKeypair(P256, elliptic.P256(), elliptic.P256())
-	case P384:
-		return ellipticCurveKeypair(P384, elliptic.P384(), elliptic.P384())
+	case X448:
+		return generateX448Keypair()
+	case P256, P384, P521:
+		return ellipticCurveKeypair(c, c.EllipticCurve(), c.EllipticCurve())
+	case BrainpoolP256r1:
+		return brainpoolP256r1Curve().generateKeypair(c)
+	case BrainpoolP384r1:
+		return brainpoolP384r1Curve().generateKeypair(c)
+	case X25519MLKEM768Experimental:
+		return generateX25519MLKEM768Keypair()
+	default:
+		return nil, errInvalidNamedCurve
+	}
+}
+
+// SharedSecret returns the ECDH shared secret of a private key and the
+// public key of the peer on X448 or a Brainpool curve
+func SharedSecret(c Curve, privateKey, peerPublicKey []byte) ([]byte, error) {
+	switch c {
+	case X448:
+		return x448SharedSecret(privateKey, peerPublicKey)
+	case BrainpoolP256r1:
+		return brainpoolP256r1Curve().sharedSecret(privateKey, peerPublicKey)
+	case BrainpoolP384r1:
+		return brainpoolP384r1Curve().sharedSecret(privateKey, peerPublicKey)
+	default:
+		return nil, errInvalidNamedCurve
+	}
+}
+
+// Encapsulate generates the key share of the client for the key share of
+// the server of a hybrid group, and returns it with the shared secret
+func Encapsulate(c Curve, peerPublicKey []byte) (*Keypair, []byte, error) {
+	switch c {
+	case X25519MLKEM768Experimental:
+		return x25519MLKEM768Encapsulate(peerPublicKey)
+	default:
+		return nil, nil, errInvalidNamedCurve
+	}
+}
+
+// Decapsulate returns the shared secret of a hybrid group from the private
+// key of the server and the key share of the client
+func Decapsulate(c Curve, privateKey, peerPublicKey []byte) ([]byte, error) {
+	switch c {
+	case X25519MLKEM768Experimental:
+		return x25519MLKEM768Decapsulate(privateKey, peerPublicKey)
 	default:
 		return nil, errInvalidNamedCurve
 	}
@@ -0,0 +1,176 @@
+package elliptic
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestX448(t *testing.T) {
+	basepoint := make([]byte, 56)
+	basepoint[0] = 5
+
+	// https://tools.ietf.org/html/rfc7748#section-5.2
+	// https://tools.ietf.org/html/rfc7748#section-6.2
+	for name, test := range map[string]struct {
+		scalar, point, expected string
+	}{
+		"Vector1": {
+			scalar:   "3d262fddf9ec8e88495266fea19a34d28882acef045104d0d1aae121700a779c984c24f8cdd78fbff44943eba368f54b29259a4f1c600ad3",
+			point:    "06fce640fa3487bfda5f6cf2d5263f8aad88334cbd07437f020f08f9814dc031ddbdc38c19c6da2583fa5429db94ada18aa7a7fb4ef8a086",
+			expected: "ce3e4ff95a60dc6697da1db1d85e6afbdf79b50a2412d7546d5f239fe14fbaadeb445fc66a01b0779d98223961111e21766282f73dd96b6f",
+		},
+		"AlicePublicKey": {
+			scalar:   "9a8f4925d1519f5775cf46b04b5800d4ee9ee8bae8bc5565d498c28dd9c9baf574a9419744897391006382a6f127ab1d9ac2d8c0a598726b",
+			point:    hex.EncodeToString(basepoint),
+			expected: "9b08f7cc31b7e3e67d22d5aea121074a273bd2b83de09c63faa73d2c22c5d9bbc836647241d953d40c5b12da88120d53177f80e532c41fa0",
+		},
+		"SharedSecret": {
+			scalar:   "9a8f4925d1519f5775cf46b04b5800d4ee9ee8bae8bc5565d498c28dd9c9baf574a9419744897391006382a6f127ab1d9ac2d8c0a598726b",
+			point:    "3eb7a829b0cd20f5bcfc0b599b6feccf6da4627107bdb0d4f345b43027d8b972fc3e34fb4232a13ca706dcb57aec3dae07bdc1c67bf33609",
+			expected: "07fff4181ac6cc95ec1c16a94a0f74d12da232ce40a77552281d282bb60c0b56fd2464c335543936521c24403085d59a449a5037514a879d",
+		},
+	} {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			out, err := SharedSecret(X448, mustDecodeHex(t, test.scalar), mustDecodeHex(t, test.point))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expected := mustDecodeHex(t, test.expected); !bytes.Equal(expected, out) {
+				t.Errorf("X448 exp: %x actual: %x", expected, out)
+			}
+		})
+	}
+
+	t.Run("LowOrderPoint", func(t *testing.T) {
+		if _, err := SharedSecret(X448, basepoint, make([]byte, 56)); !errors.Is(err, errLowOrderPoint) {
+			t.Errorf("Expected error: %v, got: %v", errLowOrderPoint, err)
+		}
+	})
+
+	t.Run("InvalidLength", func(t *testing.T) {
+		if _, err := SharedSecret(X448, make([]byte, 32), basepoint); !errors.Is(err, errInvalidScalarLength) {
+			t.Errorf("Expected error: %v, got: %v", errInvalidScalarLength, err)
+		}
+		if _, err := SharedSecret(X448, basepoint, make([]byte, 32)); !errors.Is(err, errInvalidPointLength) {
+			t.Errorf("Expected error: %v, got: %v", errInvalidPointLength, err)
+		}
+	})
+}
+
+func TestBrainpoolCurves(t *testing.T) {
+	for _, c := range []Curve{BrainpoolP256r1, BrainpoolP384r1} {
+		curve := c.EllipticCurve()
+		t.Run(curve.Params().Name, func(t *testing.T) {
+			params := curve.Params()
+			if !curve.IsOnCurve(params.Gx, params.Gy) {
+				t.Fatal("Generator is not on the curve")
+			}
+			if curve.IsOnCurve(params.Gx, new(big.Int).Add(params.Gy, big.NewInt(1))) {
+				t.Error("Point off the curve reported on the curve")
+			}
+
+			// The generator has order N
+			if x, y := curve.ScalarBaseMult(params.N.Bytes()); x.Sign() != 0 || y.Sign() != 0 {
+				t.Error("N*G is not the point at infinity")
+			}
+			nMinus1 := new(big.Int).Sub(params.N, big.NewInt(1))
+			if x, y := curve.ScalarBaseMult(nMinus1.Bytes()); x.Cmp(params.Gx) != 0 || y.Cmp(new(big.Int).Sub(params.P, params.Gy)) != 0 {
+				t.Error("(N-1)*G is not -G")
+			}
+
+			x2, y2 := curve.Double(params.Gx, params.Gy)
+			if x, y := curve.Add(params.Gx, params.Gy, params.Gx, params.Gy); x.Cmp(x2) != 0 || y.Cmp(y2) != 0 {
+				t.Error("G+G does not match 2*G")
+			}
+			x3, y3 := curve.Add(x2, y2, params.Gx, params.Gy)
+			if !curve.IsOnCurve(x3, y3) {
+				t.Error("2*G+G is not on the curve")
+			}
+			if x, y := curve.ScalarBaseMult(big.NewInt(3).Bytes()); x.Cmp(x3) != 0 || y.Cmp(y3) != 0 {
+				t.Error("3*G does not match 2*G+G")
+			}
+			if x, y := curve.Add(x3, y3, new(big.Int), new(big.Int)); x.Cmp(x3) != 0 || y.Cmp(y3) != 0 {
+				t.Error("3*G plus the point at infinity does not match 3*G")
+			}
+		})
+	}
+}
+
+func TestBrainpoolSharedSecret(t *testing.T) {
+	// Keys and shared secrets computed by OpenSSL
+	for name, test := range map[string]struct {
+		curve                                        Curve
+		privateKey, publicKey, peerPublicKey, secret string
+	}{
+		"BrainpoolP256r1": {
+			curve:         BrainpoolP256r1,
+			privateKey:    "8b3c2175d6e06aa132c9348acef58d390aaa9e65e61fef7371002f49bb64889d",
+			publicKey:     "040f76aa5bb9711dcdd578c60a74ca13288cbf77fcfdeb3a39fc5180acee93374a0e34abb752d673376395b26e1e74ddfd821ec9825fc8e4215de8b0e28fe1099e",
+			peerPublicKey: "0459d9f3d8169c5273c6ec129263e06eb37e2999cb4852494fffefbbbab14a34f27bc7461c7193437a93786151da08ef2b41f74db38c57b7b520f8d02a4f3e9487",
+			secret:        "7ed8f974163a1c87fe0566a70537e1ccfa47d3560798e46c8ef7aed72a61e020",
+		},
+		"BrainpoolP384r1": {
+			curve:         BrainpoolP384r1,
+			privateKey:    "6d016f77aec9fb05799c806129e7114edd7190dc15903cf8c8f98c86d1547ebea2faee12b37fe4b6e4df7730f854057f",
+			publicKey:     "0450a32b102bd0874d67c1f9ab737b93a76777b07b6bd7058605545531a2182dbdd8a57397b82b1d4e78a048708706ac4f2499e412ad86de2b05037c7059f625d2cae3b033339007628de94e46ebcd453f42bb5e5479563527df9c789690c24c8d",
+			peerPublicKey: "04217e40882a1b58e7371570040190712209272cd99d11f9a36a25f1af816766420c38c413e4b8af73a75dd1b5a4b7e0a00f67384e0b7e73f556de9eb090a46006476c971aaf0866ada507805301de99cf16fb597ceee8ea6869c4c8ed9dea47c0",
+			secret:        "4fac4534cd84c07c136b6b100053016c4db1926d8e59272516428996ae64cd34956605af137fb993dbff0578c6648ea0",
+		},
+	} {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			curve := test.curve.EllipticCurve()
+			x, y := curve.ScalarBaseMult(mustDecodeHex(t, test.privateKey))
+			publicKey := append(append([]byte{4}, x.FillBytes(make([]byte, len(test.privateKey)/2))...), y.FillBytes(make([]byte, len(test.privateKey)/2))...)
+			if expected := mustDecodeHex(t, test.publicKey); !bytes.Equal(expected, publicKey) {
+				t.Errorf("Public key exp: %x actual: %x", expected, publicKey)
+			}
+
+			secret, err := SharedSecret(test.curve, mustDecodeHex(t, test.privateKey), mustDecodeHex(t, test.peerPublicKey))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expected := mustDecodeHex(t, test.secret); !bytes.Equal(expected, secret) {
+				t.Errorf("Shared secret exp: %x actual: %x", expected, secret)
+			}
+
+			invalid := mustDecodeHex(t, test.peerPublicKey)
+			invalid[len(invalid)-1] ^= 1
+			if _, err := SharedSecret(test.curve, mustDecodeHex(t, test.privateKey), invalid); !errors.Is(err, errPointNotOnCurve) {
+				t.Errorf("Expected error: %v, got: %v", errPointNotOnCurve, err)
+			}
+		})
+	}
+}
+
+func TestGenerateKeypair(t *testing.T) {
+	for c, supported := range Curves() {
+		if !supported {
+			continue
+		}
+		keypair, err := GenerateKeypair(c)
+		if err != nil {
+			t.Fatalf("Curve %#04x: %v", uint16(c), err)
+		}
+		if keypair.Curve != c || len(keypair.PublicKey) == 0 || len(keypair.PrivateKey) == 0 {
+			t.Errorf("Curve %#04x: invalid keypair", uint16(c))
+		}
+	}
+
+	if _, err := GenerateKeypair(Curve(0)); !errors.Is(err, errInvalidNamedCurve) {
+		t.Errorf("Expected error: %v, got: %v", errInvalidNamedCurve, err)
+	}
+}
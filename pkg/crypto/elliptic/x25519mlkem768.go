@@ -0,0 +1,103 @@
+//go:build go1.24
+// +build go1.24
+
+package elliptic
+
+import (
+	"crypto/mlkem"
+	"crypto/rand"
+
+	"golang.org/x/crypto/curve25519"
+)
+
+const x25519MLKEM768Supported = true
+
+// The X25519MLKEM768Experimental key shares and shared secret are laid out
+// as in draft-kwiatkowski-tls-ecdhe-mlkem. The server sends the ML-KEM-768
+// encapsulation key and its X25519 public key in the ServerKeyExchange, and
+// the client answers with the ML-KEM-768 ciphertext and its X25519 public
+// key in the ClientKeyExchange. The draft only defines the group for TLS
+// 1.3, carrying the key shares in those messages with a two byte length is
+// specific to pion.
+//
+// https://datatracker.ietf.org/doc/draft-kwiatkowski-tls-ecdhe-mlkem/
+const (
+	x25519MLKEM768SeedSize = mlkem.SeedSize
+	x25519KeySize          = curve25519.ScalarSize
+)
+
+func generateX25519MLKEM768Keypair() (*Keypair, error) {
+	decapsulationKey, err := mlkem.GenerateKey768()
+	if err != nil {
+		return nil, err
+	}
+
+	x25519Private := make([]byte, x25519KeySize)
+	if _, err = rand.Read(x25519Private); err != nil {
+		return nil, err
+	}
+	x25519Public, err := curve25519.X25519(x25519Private, curve25519.Basepoint)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Keypair{
+		Curve:      X25519MLKEM768Experimental,
+		PublicKey:  append(decapsulationKey.EncapsulationKey().Bytes(), x25519Public...),
+		PrivateKey: append(decapsulationKey.Bytes(), x25519Private...),
+	}, nil
+}
+
+func x25519MLKEM768Encapsulate(peerPublicKey []byte) (*Keypair, []byte, error) {
+	if len(peerPublicKey) != mlkem.EncapsulationKeySize768+x25519KeySize {
+		return nil, nil, errInvalidPointLength
+	}
+	encapsulationKey, err := mlkem.NewEncapsulationKey768(peerPublicKey[:mlkem.EncapsulationKeySize768])
+	if err != nil {
+		return nil, nil, err
+	}
+	mlkemSecret, ciphertext := encapsulationKey.Encapsulate()
+
+	x25519Private := make([]byte, x25519KeySize)
+	if _, err = rand.Read(x25519Private); err != nil {
+		return nil, nil, err
+	}
+	x25519Public, err := curve25519.X25519(x25519Private, curve25519.Basepoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	x25519Secret, err := curve25519.X25519(x25519Private, peerPublicKey[mlkem.EncapsulationKeySize768:])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &Keypair{
+		Curve:      X25519MLKEM768Experimental,
+		PublicKey:  append(ciphertext, x25519Public...),
+		PrivateKey: x25519Private,
+	}, append(mlkemSecret, x25519Secret...), nil
+}
+
+func x25519MLKEM768Decapsulate(privateKey, peerPublicKey []byte) ([]byte, error) {
+	if len(privateKey) != x25519MLKEM768SeedSize+x25519KeySize {
+		return nil, errInvalidScalarLength
+	}
+	if len(peerPublicKey) != mlkem.CiphertextSize768+x25519KeySize {
+		return nil, errInvalidPointLength
+	}
+
+	decapsulationKey, err := mlkem.NewDecapsulationKey768(privateKey[:x25519MLKEM768SeedSize])
+	if err != nil {
+		return nil, err
+	}
+	mlkemSecret, err := decapsulationKey.Decapsulate(peerPublicKey[:mlkem.CiphertextSize768])
+	if err != nil {
+		return nil, err
+	}
+	x25519Secret, err := curve25519.X25519(privateKey[x25519MLKEM768SeedSize:], peerPublicKey[mlkem.CiphertextSize768:])
+	if err != nil {
+		return nil, err
+	}
+
+	return append(mlkemSecret, x25519Secret...), nil
+}
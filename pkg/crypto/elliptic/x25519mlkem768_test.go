@@ -0,0 +1,59 @@
+//go:build go1.24
+// +build go1.24
+
+package elliptic
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestX25519MLKEM768(t *testing.T) {
+	server, err := GenerateKeypair(X25519MLKEM768Experimental)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(server.PublicKey) != 1216 {
+		t.Fatalf("Server key share length %d, expected 1216", len(server.PublicKey))
+	}
+
+	client, clientSecret, err := Encapsulate(X25519MLKEM768Experimental, server.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(client.PublicKey) != 1120 {
+		t.Fatalf("Client key share length %d, expected 1120", len(client.PublicKey))
+	}
+
+	serverSecret, err := Decapsulate(X25519MLKEM768Experimental, server.PrivateKey, client.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(serverSecret) != 64 || !bytes.Equal(clientSecret, serverSecret) {
+		t.Errorf("Shared secret mismatch % 02x != % 02x", clientSecret, serverSecret)
+	}
+
+	// A ciphertext for another key decapsulates to an unrelated secret
+	other, err := GenerateKeypair(X25519MLKEM768Experimental)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherSecret, err := Decapsulate(X25519MLKEM768Experimental, other.PrivateKey, client.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(otherSecret, clientSecret) {
+		t.Error("Shared secret of another key matched")
+	}
+
+	if _, _, err := Encapsulate(X25519MLKEM768Experimental, server.PublicKey[:100]); !errors.Is(err, errInvalidPointLength) {
+		t.Errorf("Expected error: %v, got: %v", errInvalidPointLength, err)
+	}
+	if _, err := Decapsulate(X25519MLKEM768Experimental, server.PrivateKey, client.PublicKey[:100]); !errors.Is(err, errInvalidPointLength) {
+		t.Errorf("Expected error: %v, got: %v", errInvalidPointLength, err)
+	}
+	if _, _, err := Encapsulate(X25519, server.PublicKey); !errors.Is(err, errInvalidNamedCurve) {
+		t.Errorf("Expected error: %v, got: %v", errInvalidNamedCurve, err)
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !go1.24
+// +build !go1.24
+
+package elliptic
+
+// ML-KEM is only available from the standard library of Go 1.24 on
+const x25519MLKEM768Supported = false
+
+func generateX25519MLKEM768Keypair() (*Keypair, error) {
+	return nil, errInvalidNamedCurve
+}
+
+func x25519MLKEM768Encapsulate([]byte) (*Keypair, []byte, error) {
+	return nil, nil, errInvalidNamedCurve
+}
+
+func x25519MLKEM768Decapsulate([]byte, []byte) ([]byte, error) {
+	return nil, errInvalidNamedCurve
+}
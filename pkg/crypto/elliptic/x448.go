@@ -0,0 +1,38 @@
+package elliptic
+
+import (
+	"crypto/rand"
+
+	"github.com/cloudflare/circl/dh/x448"
+)
+
+// X448 is implemented by github.com/cloudflare/circl
+// https://tools.ietf.org/html/rfc7748#section-5
+func generateX448Keypair() (*Keypair, error) {
+	var public, private x448.Key
+	if _, err := rand.Read(private[:]); err != nil {
+		return nil, err
+	}
+
+	x448.KeyGen(&public, &private)
+	return &Keypair{X448, public[:], private[:]}, nil
+}
+
+func x448SharedSecret(privateKey, publicKey []byte) ([]byte, error) {
+	if len(privateKey) != x448.Size {
+		return nil, errInvalidScalarLength
+	}
+	if len(publicKey) != x448.Size {
+		return nil, errInvalidPointLength
+	}
+
+	var shared, private, public x448.Key
+	copy(private[:], privateKey)
+	copy(public[:], publicKey)
+	// The shared secret of a low order point is all zeros
+	// https://tools.ietf.org/html/rfc7748#section-6.2
+	if !x448.Shared(&shared, &private, &public) {
+		return nil, errLowOrderPoint
+	}
+	return shared[:], nil
+}
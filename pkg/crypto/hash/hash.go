@@ -25,6 +25,11 @@ const (
 	Ed25519 Algorithm = 8
 )
 
+// Intrinsic is the hash algorithm of the signature algorithms that specify
+// their own hash, like Ed25519 and RSASSA-PSS
+// https://tools.ietf.org/html/rfc8422#section-5.1.3
+const Intrinsic = Ed25519
+
 // String makes hashAlgorithm printable
 func (a Algorithm) String() string {
 	switch a {
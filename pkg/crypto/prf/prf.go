@@ -82,6 +82,12 @@ func EcdhePSKPreMasterSecret(psk, publicKey, privateKey []byte, curve elliptic.C
 	if err != nil {
 		return nil, err
 	}
+	return EcdhePSKPreMasterSecretFromSharedSecret(psk, preMasterSecret), nil
+}
+
+// EcdhePSKPreMasterSecretFromSharedSecret implements TLS 1.2 Premaster Secret
+// generation given a psk and the shared secret of the key exchange
+func EcdhePSKPreMasterSecretFromSharedSecret(psk, preMasterSecret []byte) []byte {
 	out := make([]byte, 2+len(preMasterSecret)+2+len(psk))
 
 	// write preMasterSecret length
@@ -99,7 +105,7 @@ func EcdhePSKPreMasterSecret(psk, publicKey, privateKey []byte, curve elliptic.C
 
 	// write psk
 	copy(out[offset:], psk)
-	return out, nil
+	return out
 }
 
 // PreMasterSecret implements TLS 1.2 Premaster Secret generation given a keypair and a curve
@@ -107,10 +113,12 @@ func PreMasterSecret(publicKey, privateKey []byte, curve elliptic.Curve) ([]byte
 	switch curve {
 	case elliptic.X25519:
 		return curve25519.X25519(privateKey, publicKey)
-	case elliptic.P256:
-		return ellipticCurvePreMasterSecret(publicKey, privateKey, ellipticStdlib.P256(), ellipticStdlib.P256())
-	case elliptic.P384:
-		return ellipticCurvePreMasterSecret(publicKey, privateKey, ellipticStdlib.P384(), ellipticStdlib.P384())
+	case elliptic.X448, elliptic.BrainpoolP256r1, elliptic.BrainpoolP384r1:
+		return elliptic.SharedSecret(curve, privateKey, publicKey)
+	case elliptic.X25519MLKEM768Experimental:
+		return elliptic.Decapsulate(curve, privateKey, publicKey)
+	case elliptic.P256, elliptic.P384, elliptic.P521:
+		return ellipticCurvePreMasterSecret(publicKey, privateKey, curve.EllipticCurve(), curve.EllipticCurve())
 	default:
 		return nil, errInvalidNamedCurve
 	}
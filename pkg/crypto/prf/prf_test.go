@@ -22,6 +22,49 @@ func TestPreMasterSecret(t *testing.T) {
 	}
 }
 
+func TestPreMasterSecretCurves(t *testing.T) {
+	for curve, supported := range elliptic.Curves() {
+		if !supported || curve.Hybrid() {
+			continue
+		}
+		local, err := elliptic.GenerateKeypair(curve)
+		if err != nil {
+			t.Fatal(err)
+		}
+		remote, err := elliptic.GenerateKeypair(curve)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		localSecret, err := PreMasterSecret(remote.PublicKey, local.PrivateKey, curve)
+		if err != nil {
+			t.Fatalf("Curve %#04x: %v", uint16(curve), err)
+		}
+		remoteSecret, err := PreMasterSecret(local.PublicKey, remote.PrivateKey, curve)
+		if err != nil {
+			t.Fatalf("Curve %#04x: %v", uint16(curve), err)
+		}
+		if !bytes.Equal(localSecret, remoteSecret) {
+			t.Errorf("Curve %#04x: PremasterSecret mismatch % 02x != % 02x", uint16(curve), localSecret, remoteSecret)
+		}
+	}
+
+	// brainpoolP256r1 shared secret computed by OpenSSL
+	privateKey := []byte{0x8b, 0x3c, 0x21, 0x75, 0xd6, 0xe0, 0x6a, 0xa1, 0x32, 0xc9, 0x34, 0x8a, 0xce, 0xf5, 0x8d, 0x39, 0x0a, 0xaa, 0x9e, 0x65, 0xe6, 0x1f, 0xef, 0x73, 0x71, 0x00, 0x2f, 0x49, 0xbb, 0x64, 0x88, 0x9d}
+	publicKey := []byte{
+		0x04, 0x59, 0xd9, 0xf3, 0xd8, 0x16, 0x9c, 0x52, 0x73, 0xc6, 0xec, 0x12, 0x92, 0x63, 0xe0, 0x6e, 0xb3, 0x7e, 0x29, 0x99, 0xcb, 0x48, 0x52, 0x49, 0x4f, 0xff, 0xef, 0xbb, 0xba, 0xb1, 0x4a, 0x34, 0xf2,
+		0x7b, 0xc7, 0x46, 0x1c, 0x71, 0x93, 0x43, 0x7a, 0x93, 0x78, 0x61, 0x51, 0xda, 0x08, 0xef, 0x2b, 0x41, 0xf7, 0x4d, 0xb3, 0x8c, 0x57, 0xb7, 0xb5, 0x20, 0xf8, 0xd0, 0x2a, 0x4f, 0x3e, 0x94, 0x87,
+	}
+	expectedPreMasterSecret := []byte{0x7e, 0xd8, 0xf9, 0x74, 0x16, 0x3a, 0x1c, 0x87, 0xfe, 0x05, 0x66, 0xa7, 0x05, 0x37, 0xe1, 0xcc, 0xfa, 0x47, 0xd3, 0x56, 0x07, 0x98, 0xe4, 0x6c, 0x8e, 0xf7, 0xae, 0xd7, 0x2a, 0x61, 0xe0, 0x20}
+
+	preMasterSecret, err := PreMasterSecret(publicKey, privateKey, elliptic.BrainpoolP256r1)
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(expectedPreMasterSecret, preMasterSecret) {
+		t.Fatalf("PremasterSecret exp: % 02x actual: % 02x", expectedPreMasterSecret, preMasterSecret)
+	}
+}
+
 func TestMasterSecret(t *testing.T) {
 	preMasterSecret := []byte{0xdf, 0x4a, 0x29, 0x1b, 0xaa, 0x1e, 0xb7, 0xcf, 0xa6, 0x93, 0x4b, 0x29, 0xb4, 0x74, 0xba, 0xad, 0x26, 0x97, 0xe2, 0x9f, 0x1f, 0x92, 0x0d, 0xcc, 0x77, 0xc8, 0xa0, 0xa0, 0x88, 0x44, 0x76, 0x24}
 	clientRandom := []byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f}
@@ -11,6 +11,17 @@ const (
 	RSA       Algorithm = 1
 	ECDSA     Algorithm = 3
 	Ed25519   Algorithm = 7
+
+	// RSASSA-PSS with a rsaEncryption public key, and with an RSASSA-PSS
+	// public key. The hash is part of the signature algorithm, the hash
+	// algorithm is always Intrinsic.
+	// https://tools.ietf.org/html/rfc8446#section-4.2.3
+	RSA_PSS_RSAE_SHA256 Algorithm = 4  //nolint:revive,stylecheck
+	RSA_PSS_RSAE_SHA384 Algorithm = 5  //nolint:revive,stylecheck
+	RSA_PSS_RSAE_SHA512 Algorithm = 6  //nolint:revive,stylecheck
+	RSA_PSS_PSS_SHA256  Algorithm = 9  //nolint:revive,stylecheck
+	RSA_PSS_PSS_SHA384  Algorithm = 10 //nolint:revive,stylecheck
+	RSA_PSS_PSS_SHA512  Algorithm = 11 //nolint:revive,stylecheck
 )
 
 // Algorithms returns all implemented Signature Algorithms
@@ -20,5 +31,40 @@ func Algorithms() map[Algorithm]struct{} {
 		RSA:       {},
 		ECDSA:     {},
 		Ed25519:   {},
+
+		RSA_PSS_RSAE_SHA256: {},
+		RSA_PSS_RSAE_SHA384: {},
+		RSA_PSS_RSAE_SHA512: {},
+		RSA_PSS_PSS_SHA256:  {},
+		RSA_PSS_PSS_SHA384:  {},
+		RSA_PSS_PSS_SHA512:  {},
+	}
+}
+
+// IsRSAPSS returns if the Algorithm is RSASSA-PSS with either type of
+// public key
+func (a Algorithm) IsRSAPSS() bool {
+	return a.IsRSAPSSRSAE() || a.IsRSAPSSPSS()
+}
+
+// IsRSAPSSRSAE returns if the Algorithm is RSASSA-PSS with a rsaEncryption
+// public key
+func (a Algorithm) IsRSAPSSRSAE() bool {
+	switch a {
+	case RSA_PSS_RSAE_SHA256, RSA_PSS_RSAE_SHA384, RSA_PSS_RSAE_SHA512:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsRSAPSSPSS returns if the Algorithm is RSASSA-PSS with an RSASSA-PSS
+// public key
+func (a Algorithm) IsRSAPSSPSS() bool {
+	switch a {
+	case RSA_PSS_PSS_SHA256, RSA_PSS_PSS_SHA384, RSA_PSS_PSS_SHA512:
+		return true
+	default:
+		return false
 	}
 }
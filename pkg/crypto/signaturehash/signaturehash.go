@@ -28,6 +28,12 @@ func Algorithms() []Algorithm {
 		{hash.SHA256, signature.ECDSA},
 		{hash.SHA384, signature.ECDSA},
 		{hash.SHA512, signature.ECDSA},
+		{hash.Intrinsic, signature.RSA_PSS_RSAE_SHA256},
+		{hash.Intrinsic, signature.RSA_PSS_RSAE_SHA384},
+		{hash.Intrinsic, signature.RSA_PSS_RSAE_SHA512},
+		{hash.Intrinsic, signature.RSA_PSS_PSS_SHA256},
+		{hash.Intrinsic, signature.RSA_PSS_PSS_SHA384},
+		{hash.Intrinsic, signature.RSA_PSS_PSS_SHA512},
 		{hash.SHA256, signature.RSA},
 		{hash.SHA384, signature.RSA},
 		{hash.SHA512, signature.RSA},
@@ -46,14 +52,21 @@ func SelectSignatureScheme(sigs []Algorithm, privateKey crypto.PrivateKey) (Algo
 }
 
 // isCompatible checks that given private key is compatible with the signature scheme.
+// Any crypto.Signer is supported, like keys held by a hardware token.
 func (a *Algorithm) isCompatible(privateKey crypto.PrivateKey) bool {
-	switch privateKey.(type) {
-	case ed25519.PrivateKey:
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return false
+	}
+	switch signer.Public().(type) {
+	case ed25519.PublicKey:
 		return a.Signature == signature.Ed25519
-	case *ecdsa.PrivateKey:
+	case *ecdsa.PublicKey:
 		return a.Signature == signature.ECDSA
-	case *rsa.PrivateKey:
-		return a.Signature == signature.RSA
+	case *rsa.PublicKey:
+		// Whether an RSASSA-PSS scheme suits the public key depends on the
+		// certificate, which the caller has to check
+		return a.Signature == signature.RSA || a.Signature.IsRSAPSS()
 	default:
 		return false
 	}
@@ -76,6 +89,9 @@ func ParseSignatureSchemes(sigs []tls.SignatureScheme, insecureHashes bool) ([]A
 		if _, ok := hash.Algorithms()[h]; !ok || (ok && h == hash.None) {
 			return nil, fmt.Errorf("SignatureScheme %04x: %w", ss, errInvalidHashAlgorithm)
 		}
+		if sig.IsRSAPSS() && h != hash.Intrinsic {
+			return nil, fmt.Errorf("SignatureScheme %04x: %w", ss, errInvalidHashAlgorithm)
+		}
 		if h.Insecure() && !insecureHashes {
 			continue
 		}
@@ -91,3 +107,27 @@ func ParseSignatureSchemes(sigs []tls.SignatureScheme, insecureHashes bool) ([]A
 
 	return out, nil
 }
+
+// CryptoHash returns the hash the signed content is digested with. The
+// RSASSA-PSS signature algorithms specify their own hash.
+func (a *Algorithm) CryptoHash() crypto.Hash {
+	return a.hashAlgorithm().CryptoHash()
+}
+
+// Digest performs the digest of the signed content
+func (a *Algorithm) Digest(b []byte) []byte {
+	return a.hashAlgorithm().Digest(b)
+}
+
+func (a *Algorithm) hashAlgorithm() hash.Algorithm {
+	switch a.Signature {
+	case signature.RSA_PSS_RSAE_SHA256, signature.RSA_PSS_PSS_SHA256:
+		return hash.SHA256
+	case signature.RSA_PSS_RSAE_SHA384, signature.RSA_PSS_PSS_SHA384:
+		return hash.SHA384
+	case signature.RSA_PSS_RSAE_SHA512, signature.RSA_PSS_PSS_SHA512:
+		return hash.SHA512
+	default:
+		return a.Hash
+	}
+}
@@ -1,6 +1,7 @@
 package signaturehash
 
 import (
+	"crypto"
 	"crypto/tls"
 	"errors"
 	"reflect"
@@ -78,6 +79,34 @@ func TestParseSignatureSchemes(t *testing.T) {
 			insecureHashes: true,
 			err:            nil,
 		},
+		"TranslateRSAPSS": {
+			input: []tls.SignatureScheme{
+				tls.PSSWithSHA256,
+				tls.PSSWithSHA384,
+				tls.PSSWithSHA512,
+				0x0809, // rsa_pss_pss_sha256
+				0x080a, // rsa_pss_pss_sha384
+				0x080b, // rsa_pss_pss_sha512
+			},
+			expected: []Algorithm{
+				{hash.Intrinsic, signature.RSA_PSS_RSAE_SHA256},
+				{hash.Intrinsic, signature.RSA_PSS_RSAE_SHA384},
+				{hash.Intrinsic, signature.RSA_PSS_RSAE_SHA512},
+				{hash.Intrinsic, signature.RSA_PSS_PSS_SHA256},
+				{hash.Intrinsic, signature.RSA_PSS_PSS_SHA384},
+				{hash.Intrinsic, signature.RSA_PSS_PSS_SHA512},
+			},
+			insecureHashes: false,
+			err:            nil,
+		},
+		"InvalidRSAPSSHashAlgorithm": {
+			input: []tls.SignatureScheme{
+				0x0404, // Invalid: RSASSA-PSS with SHA-256 instead of intrinsic
+			},
+			expected:       nil,
+			insecureHashes: false,
+			err:            errInvalidHashAlgorithm,
+		},
 		"OnlyInsecureHashAlgorithm": {
 			input: []tls.SignatureScheme{
 				tls.ECDSAWithSHA1, // Insecure
@@ -100,3 +129,21 @@ func TestParseSignatureSchemes(t *testing.T) {
 		})
 	}
 }
+
+func TestAlgorithmCryptoHash(t *testing.T) {
+	for _, test := range []struct {
+		Algorithm Algorithm
+		Hash      crypto.Hash
+	}{
+		{Algorithm{hash.SHA384, signature.ECDSA}, crypto.SHA384},
+		{Algorithm{hash.SHA256, signature.RSA}, crypto.SHA256},
+		{Algorithm{hash.Intrinsic, signature.RSA_PSS_RSAE_SHA256}, crypto.SHA256},
+		{Algorithm{hash.Intrinsic, signature.RSA_PSS_RSAE_SHA512}, crypto.SHA512},
+		{Algorithm{hash.Intrinsic, signature.RSA_PSS_PSS_SHA384}, crypto.SHA384},
+		{Algorithm{hash.Intrinsic, signature.Ed25519}, crypto.Hash(0)},
+	} {
+		if h := test.Algorithm.CryptoHash(); h != test.Hash {
+			t.Errorf("%+v: expected hash %v, got %v", test.Algorithm, test.Hash, h)
+		}
+	}
+}
@@ -35,32 +35,34 @@ type Description byte
 
 // Description enums
 const (
-	CloseNotify            Description = 0
-	UnexpectedMessage      Description = 10
-	BadRecordMac           Description = 20
-	DecryptionFailed       Description = 21
-	RecordOverflow         Description = 22
-	DecompressionFailure   Description = 30
-	HandshakeFailure       Description = 40
-	NoCertificate          Description = 41
-	BadCertificate         Description = 42
-	UnsupportedCertificate Description = 43
-	CertificateRevoked     Description = 44
-	CertificateExpired     Description = 45
-	CertificateUnknown     Description = 46
-	IllegalParameter       Description = 47
-	UnknownCA              Description = 48
-	AccessDenied           Description = 49
-	DecodeError            Description = 50
-	DecryptError           Description = 51
-	ExportRestriction      Description = 60
-	ProtocolVersion        Description = 70
-	InsufficientSecurity   Description = 71
-	InternalError          Description = 80
-	UserCanceled           Description = 90
-	NoRenegotiation        Description = 100
-	UnsupportedExtension   Description = 110
-	NoApplicationProtocol  Description = 120
+	CloseNotify                  Description = 0
+	UnexpectedMessage            Description = 10
+	BadRecordMac                 Description = 20
+	DecryptionFailed             Description = 21
+	RecordOverflow               Description = 22
+	DecompressionFailure         Description = 30
+	HandshakeFailure             Description = 40
+	NoCertificate                Description = 41
+	BadCertificate               Description = 42
+	UnsupportedCertificate       Description = 43
+	CertificateRevoked           Description = 44
+	CertificateExpired           Description = 45
+	CertificateUnknown           Description = 46
+	IllegalParameter             Description = 47
+	UnknownCA                    Description = 48
+	AccessDenied                 Description = 49
+	DecodeError                  Description = 50
+	DecryptError                 Description = 51
+	ExportRestriction            Description = 60
+	ProtocolVersion              Description = 70
+	InsufficientSecurity         Description = 71
+	InternalError                Description = 80
+	UserCanceled                 Description = 90
+	NoRenegotiation              Description = 100
+	UnsupportedExtension         Description = 110
+	BadCertificateStatusResponse Description = 113
+	UnknownPSKIdentity           Description = 115
+	NoApplicationProtocol        Description = 120
 )
 
 func (d Description) String() string {
@@ -115,6 +117,10 @@ func (d Description) String() string {
 		return "NoRenegotiation"
 	case UnsupportedExtension:
 		return "UnsupportedExtension"
+	case BadCertificateStatusResponse:
+		return "BadCertificateStatusResponse"
+	case UnknownPSKIdentity:
+		return "UnknownPSKIdentity"
 	case NoApplicationProtocol:
 		return "NoApplicationProtocol"
 	default:
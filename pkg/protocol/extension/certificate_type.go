@@ -0,0 +1,123 @@
+package extension
+
+import (
+	"encoding/binary"
+)
+
+const (
+	certificateTypeHeaderSize = 4
+)
+
+// CertificateType is a type of certificate from the TLS Certificate Types
+// registry
+//
+// https://www.iana.org/assignments/tls-extensiontype-values/tls-extensiontype-values.xhtml#tls-extensiontype-values-3
+type CertificateType uint8
+
+// CertificateType enums
+const (
+	CertificateTypeX509         CertificateType = 0
+	CertificateTypeRawPublicKey CertificateType = 2
+)
+
+// ClientCertificateType allows a Client/Server to negotiate the type
+// of certificate the client authenticates with
+//
+// https://tools.ietf.org/html/rfc7250#section-3
+type ClientCertificateType struct {
+	CertificateTypes []CertificateType
+
+	// Selected is set for the extension in the ServerHello, which carries
+	// the single type selected by the server
+	Selected bool
+}
+
+// TypeValue returns the extension TypeValue
+func (c ClientCertificateType) TypeValue() TypeValue {
+	return ClientCertificateTypeTypeValue
+}
+
+// Marshal encodes the extension
+func (c *ClientCertificateType) Marshal() ([]byte, error) {
+	return marshalCertificateTypes(c.TypeValue(), c.CertificateTypes, c.Selected)
+}
+
+// Unmarshal populates the extension from encoded data
+func (c *ClientCertificateType) Unmarshal(data []byte) (err error) {
+	c.CertificateTypes, c.Selected, err = unmarshalCertificateTypes(c.TypeValue(), data)
+	return err
+}
+
+// ServerCertificateType allows a Client/Server to negotiate the type
+// of certificate the server authenticates with
+//
+// https://tools.ietf.org/html/rfc7250#section-3
+type ServerCertificateType struct {
+	CertificateTypes []CertificateType
+
+	// Selected is set for the extension in the ServerHello, which carries
+	// the single type selected by the server
+	Selected bool
+}
+
+// TypeValue returns the extension TypeValue
+func (s ServerCertificateType) TypeValue() TypeValue {
+	return ServerCertificateTypeTypeValue
+}
+
+// Marshal encodes the extension
+func (s *ServerCertificateType) Marshal() ([]byte, error) {
+	return marshalCertificateTypes(s.TypeValue(), s.CertificateTypes, s.Selected)
+}
+
+// Unmarshal populates the extension from encoded data
+func (s *ServerCertificateType) Unmarshal(data []byte) (err error) {
+	s.CertificateTypes, s.Selected, err = unmarshalCertificateTypes(s.TypeValue(), data)
+	return err
+}
+
+// The ClientHello carries a list of types, the ServerHello a single type
+// without a length prefix
+func marshalCertificateTypes(typeValue TypeValue, certificateTypes []CertificateType, selected bool) ([]byte, error) {
+	if len(certificateTypes) == 0 || len(certificateTypes) > 255 || (selected && len(certificateTypes) != 1) {
+		return nil, errInvalidCertificateTypeFormat
+	}
+
+	out := make([]byte, certificateTypeHeaderSize)
+	binary.BigEndian.PutUint16(out, uint16(typeValue))
+	if !selected {
+		out = append(out, byte(len(certificateTypes)))
+	}
+	for _, v := range certificateTypes {
+		out = append(out, byte(v))
+	}
+	binary.BigEndian.PutUint16(out[2:], uint16(len(out)-certificateTypeHeaderSize))
+	return out, nil
+}
+
+func unmarshalCertificateTypes(typeValue TypeValue, data []byte) ([]CertificateType, bool, error) {
+	if len(data) <= certificateTypeHeaderSize {
+		return nil, false, errBufferTooSmall
+	} else if TypeValue(binary.BigEndian.Uint16(data)) != typeValue {
+		return nil, false, errInvalidExtensionType
+	}
+
+	extensionLength := int(binary.BigEndian.Uint16(data[2:]))
+	if certificateTypeHeaderSize+extensionLength > len(data) {
+		return nil, false, errLengthMismatch
+	}
+	body := data[certificateTypeHeaderSize : certificateTypeHeaderSize+extensionLength]
+
+	switch {
+	case len(body) == 1:
+		return []CertificateType{CertificateType(body[0])}, true, nil
+	case len(body) < 2 || int(body[0]) != len(body)-1:
+		return nil, false, errInvalidCertificateTypeFormat
+	}
+
+	certificateTypes := []CertificateType{}
+	for _, v := range body[1:] {
+		certificateTypes = append(certificateTypes, CertificateType(v))
+	}
+	return certificateTypes, false, nil
+}
@@ -0,0 +1,57 @@
+package extension
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCertificateType(t *testing.T) {
+	for _, test := range []struct {
+		Name      string
+		Extension Extension
+		Raw       []byte
+	}{
+		{
+			Name: "ClientHello",
+			Extension: &ClientCertificateType{
+				CertificateTypes: []CertificateType{CertificateTypeRawPublicKey, CertificateTypeX509},
+			},
+			Raw: []byte{0x00, 0x13, 0x00, 0x03, 0x02, 0x02, 0x00},
+		},
+		{
+			Name: "ServerHello",
+			Extension: &ServerCertificateType{
+				CertificateTypes: []CertificateType{CertificateTypeRawPublicKey},
+				Selected:         true,
+			},
+			Raw: []byte{0x00, 0x14, 0x00, 0x01, 0x02},
+		},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			raw, err := test.Extension.Marshal()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(raw, test.Raw) {
+				t.Errorf("certificateType marshal: got %#v, want %#v", raw, test.Raw)
+			}
+
+			extensions, err := Unmarshal(append([]byte{0x00, byte(len(raw))}, raw...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(extensions, []Extension{test.Extension}) {
+				t.Errorf("certificateType unmarshal: got %#v, want %#v", extensions, test.Extension)
+			}
+		})
+	}
+
+	if _, err := (&ServerCertificateType{Selected: true}).Marshal(); !errors.Is(err, errInvalidCertificateTypeFormat) {
+		t.Errorf("expected error: %v, got: %v", errInvalidCertificateTypeFormat, err)
+	}
+	if err := (&ClientCertificateType{}).Unmarshal([]byte{0x00, 0x13, 0x00, 0x02, 0x02, 0x00}); !errors.Is(err, errInvalidCertificateTypeFormat) {
+		t.Errorf("expected error: %v, got: %v", errInvalidCertificateTypeFormat, err)
+	}
+}
@@ -0,0 +1,49 @@
+package extension
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConnectionID(t *testing.T) {
+	for _, test := range []struct {
+		Name string
+		Raw  []byte
+		CID  []byte
+	}{
+		{
+			Name: "Empty",
+			Raw:  []byte{0x00, 0x36, 0x00, 0x01, 0x00},
+			CID:  []byte{},
+		},
+		{
+			Name: "Populated",
+			Raw:  []byte{0x00, 0x36, 0x00, 0x05, 0x04, 0x01, 0x02, 0x03, 0x04},
+			CID:  []byte{0x01, 0x02, 0x03, 0x04},
+		},
+	} {
+		extension := ConnectionID{CID: test.CID}
+		raw, err := extension.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(raw, test.Raw) {
+			t.Errorf("%s extensionConnectionID marshal: got %#v, want %#v", test.Name, raw, test.Raw)
+		}
+
+		newExtension := ConnectionID{}
+		if err := newExtension.Unmarshal(raw); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(newExtension, extension) {
+			t.Errorf("%s extensionConnectionID unmarshal: got %#v, want %#v", test.Name, newExtension, extension)
+		}
+	}
+}
+
+func TestConnectionIDInvalid(t *testing.T) {
+	extension := ConnectionID{}
+	if err := extension.Unmarshal([]byte{0x00, 0x36, 0x00, 0x02, 0x04, 0x01}); err == nil {
+		t.Error("expected error for truncated connection ID")
+	}
+}
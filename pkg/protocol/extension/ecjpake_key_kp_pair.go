@@ -0,0 +1,47 @@
+package extension
+
+import (
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// ECJPAKEKeyKPPair carries the ECJPAKEKeyKPPairList of the first round of
+// the EC J-PAKE key exchange, sent by the client in its ClientHello and by
+// the server in its ServerHello. The list is kept encoded, it is read and
+// verified by the key exchange.
+//
+// https://tools.ietf.org/html/draft-cragie-tls-ecjpake-01#section-8.2
+type ECJPAKEKeyKPPair struct {
+	KeyKPPairList []byte
+}
+
+// TypeValue returns the extension TypeValue
+func (e ECJPAKEKeyKPPair) TypeValue() TypeValue {
+	return ECJPAKEKeyKPPairTypeValue
+}
+
+// Marshal encodes the extension
+func (e *ECJPAKEKeyKPPair) Marshal() ([]byte, error) {
+	var b cryptobyte.Builder
+	b.AddUint16(uint16(e.TypeValue()))
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddBytes(e.KeyKPPairList)
+	})
+	return b.Bytes()
+}
+
+// Unmarshal populates the extension from encoded data
+func (e *ECJPAKEKeyKPPair) Unmarshal(data []byte) error {
+	val := cryptobyte.String(data)
+	var extension uint16
+	val.ReadUint16(&extension)
+	if TypeValue(extension) != e.TypeValue() {
+		return errInvalidExtensionType
+	}
+
+	var list cryptobyte.String
+	if !val.ReadUint16LengthPrefixed(&list) || len(list) == 0 {
+		return errLengthMismatch
+	}
+	e.KeyKPPairList = append([]byte{}, list...)
+	return nil
+}
@@ -0,0 +1,32 @@
+package extension
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestECJPAKEKeyKPPair(t *testing.T) {
+	rawExtension := []byte{0x01, 0x00, 0x00, 0x03, 0x41, 0x04, 0x01}
+	parsedExtension := &ECJPAKEKeyKPPair{KeyKPPairList: []byte{0x41, 0x04, 0x01}}
+
+	raw, err := parsedExtension.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(raw, rawExtension) {
+		t.Errorf("ECJPAKEKeyKPPair marshal: got %#v, want %#v", raw, rawExtension)
+	}
+
+	extensions, err := Unmarshal(append([]byte{0x00, byte(len(rawExtension))}, rawExtension...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(extensions, []Extension{parsedExtension}) {
+		t.Errorf("ECJPAKEKeyKPPair unmarshal: got %#v, want %#v", extensions, parsedExtension)
+	}
+
+	if err := (&ECJPAKEKeyKPPair{}).Unmarshal([]byte{0x01, 0x00, 0x00, 0x00}); !errors.Is(err, errLengthMismatch) {
+		t.Errorf("Expected error: %v, got: %v", errLengthMismatch, err)
+	}
+}
@@ -0,0 +1,46 @@
+package extension
+
+import "encoding/binary"
+
+const (
+	encryptThenMACHeaderSize = 4
+)
+
+// EncryptThenMAC defines a TLS extension that negotiates calculating the MAC
+// of CBC cipher suites over the ciphertext instead of the plaintext.
+//
+// https://tools.ietf.org/html/rfc7366
+type EncryptThenMAC struct {
+	Supported bool
+}
+
+// TypeValue returns the extension TypeValue
+func (e EncryptThenMAC) TypeValue() TypeValue {
+	return EncryptThenMACTypeValue
+}
+
+// Marshal encodes the extension
+func (e *EncryptThenMAC) Marshal() ([]byte, error) {
+	if !e.Supported {
+		return []byte{}, nil
+	}
+
+	out := make([]byte, encryptThenMACHeaderSize)
+
+	binary.BigEndian.PutUint16(out, uint16(e.TypeValue()))
+	binary.BigEndian.PutUint16(out[2:], uint16(0)) // length
+	return out, nil
+}
+
+// Unmarshal populates the extension from encoded data
+func (e *EncryptThenMAC) Unmarshal(data []byte) error {
+	if len(data) < encryptThenMACHeaderSize {
+		return errBufferTooSmall
+	} else if TypeValue(binary.BigEndian.Uint16(data)) != e.TypeValue() {
+		return errInvalidExtensionType
+	}
+
+	e.Supported = true
+
+	return nil
+}
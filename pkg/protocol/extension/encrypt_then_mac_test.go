@@ -0,0 +1,26 @@
+package extension
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEncryptThenMAC(t *testing.T) {
+	extension := EncryptThenMAC{Supported: true}
+	raw, err := extension.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []byte{0x00, 0x16, 0x00, 0x00}
+	if !reflect.DeepEqual(raw, expect) {
+		t.Errorf("extensionEncryptThenMAC marshal: got %#v, want %#v", raw, expect)
+	}
+
+	newExtension := EncryptThenMAC{}
+	if err := newExtension.Unmarshal(raw); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(newExtension, extension) {
+		t.Errorf("extensionEncryptThenMAC unmarshal: got %#v, want %#v", newExtension, extension)
+	}
+}
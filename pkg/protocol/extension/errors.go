@@ -8,10 +8,15 @@ import (
 
 var (
 	// ErrALPNInvalidFormat is raised when the ALPN format is invalid
-	ErrALPNInvalidFormat    = &protocol.FatalError{Err: errors.New("invalid alpn format")}                             //nolint:goerr113
-	errALPNNoAppProto       = &protocol.FatalError{Err: errors.New("no application protocol")}                         //nolint:goerr113
-	errBufferTooSmall       = &protocol.TemporaryError{Err: errors.New("buffer is too small")}                         //nolint:goerr113
-	errInvalidExtensionType = &protocol.FatalError{Err: errors.New("invalid extension type")}                          //nolint:goerr113
-	errInvalidSNIFormat     = &protocol.FatalError{Err: errors.New("invalid server name format")}                      //nolint:goerr113
-	errLengthMismatch       = &protocol.InternalError{Err: errors.New("data length and declared length do not match")} //nolint:goerr113
+	ErrALPNInvalidFormat              = &protocol.FatalError{Err: errors.New("invalid alpn format")}                             //nolint:goerr113
+	errALPNNoAppProto                 = &protocol.FatalError{Err: errors.New("no application protocol")}                         //nolint:goerr113
+	errBufferTooSmall                 = &protocol.TemporaryError{Err: errors.New("buffer is too small")}                         //nolint:goerr113
+	errInvalidExtensionType           = &protocol.FatalError{Err: errors.New("invalid extension type")}                          //nolint:goerr113
+	errInvalidCIDFormat               = &protocol.FatalError{Err: errors.New("invalid connection id format")}                    //nolint:goerr113
+	errInvalidCertificateTypeFormat   = &protocol.FatalError{Err: errors.New("invalid certificate type format")}                 //nolint:goerr113
+	errInvalidMaxFragmentLengthFormat = &protocol.FatalError{Err: errors.New("invalid max fragment length format")}              //nolint:goerr113
+	errInvalidRecordSizeLimitFormat   = &protocol.FatalError{Err: errors.New("invalid record size limit format")}                //nolint:goerr113
+	errInvalidStatusRequestFormat     = &protocol.FatalError{Err: errors.New("invalid status request format")}                   //nolint:goerr113
+	errInvalidSNIFormat               = &protocol.FatalError{Err: errors.New("invalid server name format")}                      //nolint:goerr113
+	errLengthMismatch                 = &protocol.InternalError{Err: errors.New("data length and declared length do not match")} //nolint:goerr113
 )
@@ -11,12 +11,21 @@ type TypeValue uint16
 // TypeValue constants
 const (
 	ServerNameTypeValue                   TypeValue = 0
+	MaxFragmentLengthTypeValue            TypeValue = 1
+	StatusRequestTypeValue                TypeValue = 5
 	SupportedEllipticCurvesTypeValue      TypeValue = 10
 	SupportedPointFormatsTypeValue        TypeValue = 11
 	SupportedSignatureAlgorithmsTypeValue TypeValue = 13
 	UseSRTPTypeValue                      TypeValue = 14
 	ALPNTypeValue                         TypeValue = 16
+	ClientCertificateTypeTypeValue        TypeValue = 19
+	ServerCertificateTypeTypeValue        TypeValue = 20
+	EncryptThenMACTypeValue               TypeValue = 22
 	UseExtendedMasterSecretTypeValue      TypeValue = 23
+	RecordSizeLimitTypeValue              TypeValue = 28
+	SessionTicketTypeValue                TypeValue = 35
+	ConnectionIDTypeValue                 TypeValue = 54
+	ECJPAKEKeyKPPairTypeValue             TypeValue = 256
 	RenegotiationInfoTypeValue            TypeValue = 65281
 )
 
@@ -59,14 +68,34 @@ func Unmarshal(buf []byte) ([]Extension, error) {
 		switch TypeValue(binary.BigEndian.Uint16(buf[offset:])) {
 		case ServerNameTypeValue:
 			err = unmarshalAndAppend(buf[offset:], &ServerName{})
+		case MaxFragmentLengthTypeValue:
+			err = unmarshalAndAppend(buf[offset:], &MaxFragmentLength{})
+		case StatusRequestTypeValue:
+			err = unmarshalAndAppend(buf[offset:], &StatusRequest{})
 		case SupportedEllipticCurvesTypeValue:
 			err = unmarshalAndAppend(buf[offset:], &SupportedEllipticCurves{})
+		case SupportedSignatureAlgorithmsTypeValue:
+			err = unmarshalAndAppend(buf[offset:], &SupportedSignatureAlgorithms{})
 		case UseSRTPTypeValue:
 			err = unmarshalAndAppend(buf[offset:], &UseSRTP{})
 		case ALPNTypeValue:
 			err = unmarshalAndAppend(buf[offset:], &ALPN{})
+		case ClientCertificateTypeTypeValue:
+			err = unmarshalAndAppend(buf[offset:], &ClientCertificateType{})
+		case ServerCertificateTypeTypeValue:
+			err = unmarshalAndAppend(buf[offset:], &ServerCertificateType{})
+		case EncryptThenMACTypeValue:
+			err = unmarshalAndAppend(buf[offset:], &EncryptThenMAC{})
 		case UseExtendedMasterSecretTypeValue:
 			err = unmarshalAndAppend(buf[offset:], &UseExtendedMasterSecret{})
+		case RecordSizeLimitTypeValue:
+			err = unmarshalAndAppend(buf[offset:], &RecordSizeLimit{})
+		case SessionTicketTypeValue:
+			err = unmarshalAndAppend(buf[offset:], &SessionTicket{})
+		case ConnectionIDTypeValue:
+			err = unmarshalAndAppend(buf[offset:], &ConnectionID{})
+		case ECJPAKEKeyKPPairTypeValue:
+			err = unmarshalAndAppend(buf[offset:], &ECJPAKEKeyKPPair{})
 		case RenegotiationInfoTypeValue:
 			err = unmarshalAndAppend(buf[offset:], &RenegotiationInfo{})
 		default:
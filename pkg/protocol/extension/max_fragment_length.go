@@ -0,0 +1,71 @@
+package extension
+
+import (
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// FragmentLength is a maximum record plaintext length a client can request
+// with the max_fragment_length extension
+type FragmentLength uint8
+
+// FragmentLength enums
+const (
+	FragmentLength512  FragmentLength = 1
+	FragmentLength1024 FragmentLength = 2
+	FragmentLength2048 FragmentLength = 3
+	FragmentLength4096 FragmentLength = 4
+)
+
+// Size returns the length in bytes, or 0 if the value is not a valid
+// FragmentLength
+func (f FragmentLength) Size() int {
+	if f < FragmentLength512 || f > FragmentLength4096 {
+		return 0
+	}
+	return 1 << (8 + f)
+}
+
+// MaxFragmentLength is a TLS extension that allows a client to negotiate a
+// smaller maximum record plaintext length. The server echoes the requested
+// value to accept it.
+//
+// https://tools.ietf.org/html/rfc6066#section-4
+type MaxFragmentLength struct {
+	Length FragmentLength
+}
+
+// TypeValue returns the extension TypeValue
+func (m MaxFragmentLength) TypeValue() TypeValue {
+	return MaxFragmentLengthTypeValue
+}
+
+// Marshal encodes the extension
+func (m *MaxFragmentLength) Marshal() ([]byte, error) {
+	var b cryptobyte.Builder
+	b.AddUint16(uint16(m.TypeValue()))
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddUint8(uint8(m.Length))
+	})
+	return b.Bytes()
+}
+
+// Unmarshal populates the extension from encoded data. Values outside of
+// the FragmentLength enums are decoded as is, so they can be answered with
+// an illegal_parameter alert.
+func (m *MaxFragmentLength) Unmarshal(data []byte) error {
+	val := cryptobyte.String(data)
+	var extension uint16
+	val.ReadUint16(&extension)
+	if TypeValue(extension) != m.TypeValue() {
+		return errInvalidExtensionType
+	}
+
+	var extData cryptobyte.String
+	var length uint8
+	if !val.ReadUint16LengthPrefixed(&extData) ||
+		!extData.ReadUint8(&length) || !extData.Empty() {
+		return errInvalidMaxFragmentLengthFormat
+	}
+	m.Length = FragmentLength(length)
+	return nil
+}
@@ -0,0 +1,44 @@
+package extension
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMaxFragmentLength(t *testing.T) {
+	extension := MaxFragmentLength{Length: FragmentLength1024}
+	raw, err := extension.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []byte{0x00, 0x01, 0x00, 0x01, 0x02}
+	if !reflect.DeepEqual(raw, expect) {
+		t.Errorf("extensionMaxFragmentLength marshal: got %#v, want %#v", raw, expect)
+	}
+
+	newExtension := MaxFragmentLength{}
+	if err := newExtension.Unmarshal(raw); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(newExtension, extension) {
+		t.Errorf("extensionMaxFragmentLength unmarshal: got %#v, want %#v", newExtension, extension)
+	}
+
+	if err := newExtension.Unmarshal([]byte{0x00, 0x01, 0x00, 0x02, 0x02, 0x00}); !errors.Is(err, errInvalidMaxFragmentLengthFormat) {
+		t.Errorf("expected error: %v, got: %v", errInvalidMaxFragmentLengthFormat, err)
+	}
+
+	for length, size := range map[FragmentLength]int{
+		FragmentLength512:  512,
+		FragmentLength1024: 1024,
+		FragmentLength2048: 2048,
+		FragmentLength4096: 4096,
+		0:                  0,
+		5:                  0,
+	} {
+		if got := length.Size(); got != size {
+			t.Errorf("FragmentLength(%d).Size(): got %d, want %d", length, got, size)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package extension
+
+import (
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// RecordSizeLimit is a TLS extension that advertises the largest record
+// plaintext an endpoint is willing to receive.
+//
+// https://tools.ietf.org/html/rfc8449
+type RecordSizeLimit struct {
+	RecordSizeLimit uint16
+}
+
+// TypeValue returns the extension TypeValue
+func (r RecordSizeLimit) TypeValue() TypeValue {
+	return RecordSizeLimitTypeValue
+}
+
+// Marshal encodes the extension
+func (r *RecordSizeLimit) Marshal() ([]byte, error) {
+	var b cryptobyte.Builder
+	b.AddUint16(uint16(r.TypeValue()))
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddUint16(r.RecordSizeLimit)
+	})
+	return b.Bytes()
+}
+
+// Unmarshal populates the extension from encoded data
+func (r *RecordSizeLimit) Unmarshal(data []byte) error {
+	val := cryptobyte.String(data)
+	var extension uint16
+	val.ReadUint16(&extension)
+	if TypeValue(extension) != r.TypeValue() {
+		return errInvalidExtensionType
+	}
+
+	var extData cryptobyte.String
+	if !val.ReadUint16LengthPrefixed(&extData) ||
+		!extData.ReadUint16(&r.RecordSizeLimit) || !extData.Empty() {
+		return errInvalidRecordSizeLimitFormat
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package extension
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestRecordSizeLimit(t *testing.T) {
+	extension := RecordSizeLimit{RecordSizeLimit: 512}
+	raw, err := extension.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []byte{0x00, 0x1c, 0x00, 0x02, 0x02, 0x00}
+	if !reflect.DeepEqual(raw, expect) {
+		t.Errorf("extensionRecordSizeLimit marshal: got %#v, want %#v", raw, expect)
+	}
+
+	newExtension := RecordSizeLimit{}
+	if err := newExtension.Unmarshal(raw); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(newExtension, extension) {
+		t.Errorf("extensionRecordSizeLimit unmarshal: got %#v, want %#v", newExtension, extension)
+	}
+
+	if err := newExtension.Unmarshal([]byte{0x00, 0x1c, 0x00, 0x01, 0x02}); !errors.Is(err, errInvalidRecordSizeLimitFormat) {
+		t.Errorf("expected error: %v, got: %v", errInvalidRecordSizeLimitFormat, err)
+	}
+}
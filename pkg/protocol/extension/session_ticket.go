@@ -0,0 +1,47 @@
+package extension
+
+import (
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// SessionTicket is a TLS extension used to request and present session
+// tickets for stateless session resumption. A client sends an empty ticket
+// to request one, a server sends an empty extension to signal that it will
+// issue a NewSessionTicket.
+//
+// https://tools.ietf.org/html/rfc5077#section-3.2
+type SessionTicket struct {
+	Ticket []byte
+}
+
+// TypeValue returns the extension TypeValue
+func (s SessionTicket) TypeValue() TypeValue {
+	return SessionTicketTypeValue
+}
+
+// Marshal encodes the extension
+func (s *SessionTicket) Marshal() ([]byte, error) {
+	var b cryptobyte.Builder
+	b.AddUint16(uint16(s.TypeValue()))
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddBytes(s.Ticket)
+	})
+	return b.Bytes()
+}
+
+// Unmarshal populates the extension from encoded data
+func (s *SessionTicket) Unmarshal(data []byte) error {
+	val := cryptobyte.String(data)
+	var extension uint16
+	val.ReadUint16(&extension)
+	if TypeValue(extension) != s.TypeValue() {
+		return errInvalidExtensionType
+	}
+
+	var ticket cryptobyte.String
+	if !val.ReadUint16LengthPrefixed(&ticket) {
+		return errLengthMismatch
+	}
+	s.Ticket = append([]byte{}, ticket...)
+	return nil
+}
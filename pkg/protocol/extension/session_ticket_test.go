@@ -0,0 +1,49 @@
+package extension
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSessionTicket(t *testing.T) {
+	for _, test := range []struct {
+		Name   string
+		Raw    []byte
+		Ticket []byte
+	}{
+		{
+			Name:   "Empty",
+			Raw:    []byte{0x00, 0x23, 0x00, 0x00},
+			Ticket: []byte{},
+		},
+		{
+			Name:   "Populated",
+			Raw:    []byte{0x00, 0x23, 0x00, 0x03, 0x01, 0x02, 0x03},
+			Ticket: []byte{0x01, 0x02, 0x03},
+		},
+	} {
+		extension := SessionTicket{Ticket: test.Ticket}
+		raw, err := extension.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(raw, test.Raw) {
+			t.Errorf("%s extensionSessionTicket marshal: got %#v, want %#v", test.Name, raw, test.Raw)
+		}
+
+		newExtension := SessionTicket{}
+		if err := newExtension.Unmarshal(raw); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(newExtension, extension) {
+			t.Errorf("%s extensionSessionTicket unmarshal: got %#v, want %#v", test.Name, newExtension, extension)
+		}
+	}
+}
+
+func TestSessionTicketInvalid(t *testing.T) {
+	extension := SessionTicket{}
+	if err := extension.Unmarshal([]byte{0x00, 0x23, 0x00, 0x04, 0x01}); err == nil {
+		t.Error("expected error for truncated session ticket")
+	}
+}
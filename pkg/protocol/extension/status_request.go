@@ -0,0 +1,103 @@
+package extension
+
+import (
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// StatusType is the type of certificate status a client requests
+type StatusType uint8
+
+// StatusType enums
+const (
+	StatusTypeOCSP StatusType = 1
+)
+
+// StatusRequest is a TLS extension that allows a client to request the
+// server to staple the status of its certificate, such as an OCSP response,
+// to the handshake
+//
+// https://tools.ietf.org/html/rfc6066#section-8
+type StatusRequest struct {
+	// StatusType is zero for the extension in the ServerHello, which is
+	// empty
+	StatusType StatusType
+
+	// ResponderIDList and RequestExtensions are DER encoded as described
+	// for an OCSPStatusRequest
+	ResponderIDList   [][]byte
+	RequestExtensions []byte
+}
+
+// TypeValue returns the extension TypeValue
+func (s StatusRequest) TypeValue() TypeValue {
+	return StatusRequestTypeValue
+}
+
+// Marshal encodes the extension
+func (s *StatusRequest) Marshal() ([]byte, error) {
+	var b cryptobyte.Builder
+	b.AddUint16(uint16(s.TypeValue()))
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		if s.StatusType == 0 {
+			return
+		}
+		b.AddUint8(uint8(s.StatusType))
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			for _, id := range s.ResponderIDList {
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+					b.AddBytes(id)
+				})
+			}
+		})
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddBytes(s.RequestExtensions)
+		})
+	})
+	return b.Bytes()
+}
+
+// Unmarshal populates the extension from encoded data. The request of
+// status types other than OCSP is not decoded.
+func (s *StatusRequest) Unmarshal(data []byte) error {
+	val := cryptobyte.String(data)
+	var extension uint16
+	val.ReadUint16(&extension)
+	if TypeValue(extension) != s.TypeValue() {
+		return errInvalidExtensionType
+	}
+
+	var extData cryptobyte.String
+	if !val.ReadUint16LengthPrefixed(&extData) {
+		return errInvalidStatusRequestFormat
+	}
+	*s = StatusRequest{}
+	if extData.Empty() {
+		return nil
+	}
+
+	var statusType uint8
+	if !extData.ReadUint8(&statusType) {
+		return errInvalidStatusRequestFormat
+	}
+	s.StatusType = StatusType(statusType)
+	if s.StatusType != StatusTypeOCSP {
+		return nil
+	}
+
+	var responderIDList, requestExtensions cryptobyte.String
+	if !extData.ReadUint16LengthPrefixed(&responderIDList) ||
+		!extData.ReadUint16LengthPrefixed(&requestExtensions) || !extData.Empty() {
+		return errInvalidStatusRequestFormat
+	}
+	for !responderIDList.Empty() {
+		var id cryptobyte.String
+		if !responderIDList.ReadUint16LengthPrefixed(&id) || id.Empty() {
+			return errInvalidStatusRequestFormat
+		}
+		s.ResponderIDList = append(s.ResponderIDList, append([]byte{}, id...))
+	}
+	if !requestExtensions.Empty() {
+		s.RequestExtensions = append([]byte{}, requestExtensions...)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package extension
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStatusRequest(t *testing.T) {
+	for _, test := range []struct {
+		Name      string
+		Extension StatusRequest
+		Raw       []byte
+	}{
+		{
+			Name:      "ClientHello",
+			Extension: StatusRequest{StatusType: StatusTypeOCSP},
+			Raw:       []byte{0x00, 0x05, 0x00, 0x05, 0x01, 0x00, 0x00, 0x00, 0x00},
+		},
+		{
+			Name: "ResponderIDs",
+			Extension: StatusRequest{
+				StatusType:        StatusTypeOCSP,
+				ResponderIDList:   [][]byte{{0x01, 0x02}},
+				RequestExtensions: []byte{0x03},
+			},
+			Raw: []byte{0x00, 0x05, 0x00, 0x0a, 0x01, 0x00, 0x04, 0x00, 0x02, 0x01, 0x02, 0x00, 0x01, 0x03},
+		},
+		{
+			Name:      "ServerHello",
+			Extension: StatusRequest{},
+			Raw:       []byte{0x00, 0x05, 0x00, 0x00},
+		},
+	} {
+		raw, err := test.Extension.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(raw, test.Raw) {
+			t.Errorf("%s extensionStatusRequest marshal: got %#v, want %#v", test.Name, raw, test.Raw)
+		}
+
+		newExtension := StatusRequest{}
+		if err := newExtension.Unmarshal(raw); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(newExtension, test.Extension) {
+			t.Errorf("%s extensionStatusRequest unmarshal: got %#v, want %#v", test.Name, newExtension, test.Extension)
+		}
+	}
+
+	if err := (&StatusRequest{}).Unmarshal([]byte{0x00, 0x05, 0x00, 0x03, 0x01, 0x00, 0x00}); err == nil {
+		t.Error("expected error for truncated status request")
+	}
+}
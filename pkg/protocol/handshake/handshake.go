@@ -4,7 +4,9 @@ package handshake
 import (
 	"github.com/pion/dtls/v2/internal/ciphersuite/types"
 	"github.com/pion/dtls/v2/internal/util"
+	"github.com/pion/dtls/v2/pkg/crypto/elliptic"
 	"github.com/pion/dtls/v2/pkg/protocol"
+	"github.com/pion/dtls/v2/pkg/protocol/extension"
 )
 
 // Type is the unique identifier for each handshake message
@@ -17,6 +19,7 @@ const (
 	TypeClientHello        Type = 1
 	TypeServerHello        Type = 2
 	TypeHelloVerifyRequest Type = 3
+	TypeNewSessionTicket   Type = 4
 	TypeCertificate        Type = 11
 	TypeServerKeyExchange  Type = 12
 	TypeCertificateRequest Type = 13
@@ -24,6 +27,7 @@ const (
 	TypeCertificateVerify  Type = 15
 	TypeClientKeyExchange  Type = 16
 	TypeFinished           Type = 20
+	TypeCertificateStatus  Type = 22
 )
 
 // String returns the string representation of this type
@@ -37,6 +41,8 @@ func (t Type) String() string {
 		return "ServerHello"
 	case TypeHelloVerifyRequest:
 		return "HelloVerifyRequest"
+	case TypeNewSessionTicket:
+		return "NewSessionTicket"
 	case TypeCertificate:
 		return "TypeCertificate"
 	case TypeServerKeyExchange:
@@ -51,6 +57,8 @@ func (t Type) String() string {
 		return "ClientKeyExchange"
 	case TypeFinished:
 		return "Finished"
+	case TypeCertificateStatus:
+		return "CertificateStatus"
 	}
 	return ""
 }
@@ -73,6 +81,8 @@ type Handshake struct {
 	Message Message
 
 	KeyExchangeAlgorithm types.KeyExchangeAlgorithm
+	CertificateType      extension.CertificateType
+	NamedCurve           elliptic.Curve
 }
 
 // ContentType returns what kind of content this message is carying
@@ -124,10 +134,12 @@ func (h *Handshake) Unmarshal(data []byte) error {
 		h.Message = &MessageClientHello{}
 	case TypeHelloVerifyRequest:
 		h.Message = &MessageHelloVerifyRequest{}
+	case TypeNewSessionTicket:
+		h.Message = &MessageNewSessionTicket{}
 	case TypeServerHello:
 		h.Message = &MessageServerHello{}
 	case TypeCertificate:
-		h.Message = &MessageCertificate{}
+		h.Message = &MessageCertificate{CertificateType: h.CertificateType}
 	case TypeServerKeyExchange:
 		h.Message = &MessageServerKeyExchange{KeyExchangeAlgorithm: h.KeyExchangeAlgorithm}
 	case TypeCertificateRequest:
@@ -135,11 +147,13 @@ func (h *Handshake) Unmarshal(data []byte) error {
 	case TypeServerHelloDone:
 		h.Message = &MessageServerHelloDone{}
 	case TypeClientKeyExchange:
-		h.Message = &MessageClientKeyExchange{KeyExchangeAlgorithm: h.KeyExchangeAlgorithm}
+		h.Message = &MessageClientKeyExchange{KeyExchangeAlgorithm: h.KeyExchangeAlgorithm, NamedCurve: h.NamedCurve}
 	case TypeFinished:
 		h.Message = &MessageFinished{}
 	case TypeCertificateVerify:
 		h.Message = &MessageCertificateVerify{}
+	case TypeCertificateStatus:
+		h.Message = &MessageCertificateStatus{}
 	default:
 		return errNotImplemented
 	}
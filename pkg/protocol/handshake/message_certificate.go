@@ -2,6 +2,7 @@ package handshake
 
 import (
 	"github.com/pion/dtls/v2/internal/util"
+	"github.com/pion/dtls/v2/pkg/protocol/extension"
 )
 
 // MessageCertificate is a DTLS Handshake Message
@@ -10,6 +11,13 @@ import (
 // https://tools.ietf.org/html/rfc5246#section-7.4.2
 type MessageCertificate struct {
 	Certificate [][]byte
+
+	// CertificateType is the negotiated type of the certificate. A raw
+	// public key is sent as a single DER encoded SubjectPublicKeyInfo
+	// instead of a certificate chain.
+	//
+	// https://tools.ietf.org/html/rfc7250#section-3
+	CertificateType extension.CertificateType
 }
 
 // Type returns the Handshake Type
@@ -25,6 +33,14 @@ const (
 func (m *MessageCertificate) Marshal() ([]byte, error) {
 	out := make([]byte, handshakeMessageCertificateLengthFieldSize)
 
+	if m.CertificateType == extension.CertificateTypeRawPublicKey {
+		if len(m.Certificate) != 1 || len(m.Certificate[0]) == 0 {
+			return nil, errInvalidRawPublicKey
+		}
+		util.PutBigEndianUint24(out, uint32(len(m.Certificate[0])))
+		return append(out, m.Certificate[0]...), nil
+	}
+
 	for _, r := range m.Certificate {
 		// Certificate Length
 		out = append(out, make([]byte, handshakeMessageCertificateLengthFieldSize)...)
@@ -49,6 +65,14 @@ func (m *MessageCertificate) Unmarshal(data []byte) error {
 		return errLengthMismatch
 	}
 
+	if m.CertificateType == extension.CertificateTypeRawPublicKey {
+		if len(data) == handshakeMessageCertificateLengthFieldSize {
+			return errInvalidRawPublicKey
+		}
+		m.Certificate = [][]byte{append([]byte{}, data[handshakeMessageCertificateLengthFieldSize:]...)}
+		return nil
+	}
+
 	offset := handshakeMessageCertificateLengthFieldSize
 	for offset < len(data) {
 		certificateLen := int(util.BigEndianUint24(data[offset:]))
@@ -21,6 +21,9 @@ https://tools.ietf.org/html/rfc5246#section-7.4.4
 type MessageCertificateRequest struct {
 	CertificateTypes        []clientcertificate.Type
 	SignatureHashAlgorithms []signaturehash.Algorithm
+	// CertificateAuthorities are the DER-encoded distinguished names of
+	// the certificate authorities the server accepts
+	CertificateAuthorities [][]byte
 }
 
 const (
@@ -47,6 +50,13 @@ func (m *MessageCertificateRequest) Marshal() ([]byte, error) {
 	}
 
 	out = append(out, []byte{0x00, 0x00}...) // Distinguished Names Length
+	distinguishedNamesOffset := len(out)
+	for _, ca := range m.CertificateAuthorities {
+		out = append(out, []byte{0x00, 0x00}...)
+		binary.BigEndian.PutUint16(out[len(out)-2:], uint16(len(ca)))
+		out = append(out, ca...)
+	}
+	binary.BigEndian.PutUint16(out[distinguishedNamesOffset-2:], uint16(len(out)-distinguishedNamesOffset))
 	return out, nil
 }
 
@@ -95,6 +105,31 @@ func (m *MessageCertificateRequest) Unmarshal(data []byte) error {
 		}
 		m.SignatureHashAlgorithms = append(m.SignatureHashAlgorithms, signaturehash.Algorithm{Signature: s, Hash: h})
 	}
+	offset += signatureHashAlgorithmsLength
+
+	if len(data) < offset+2 {
+		return errBufferTooSmall
+	}
+	distinguishedNamesLength := int(binary.BigEndian.Uint16(data[offset:]))
+	offset += 2
+
+	if (offset + distinguishedNamesLength) > len(data) {
+		return errBufferTooSmall
+	}
+
+	for end := offset + distinguishedNamesLength; offset < end; {
+		if end < offset+2 {
+			return errLengthMismatch
+		}
+		distinguishedNameLength := int(binary.BigEndian.Uint16(data[offset:]))
+		offset += 2
+
+		if end < offset+distinguishedNameLength {
+			return errLengthMismatch
+		}
+		m.CertificateAuthorities = append(m.CertificateAuthorities, append([]byte{}, data[offset:offset+distinguishedNameLength]...))
+		offset += distinguishedNameLength
+	}
 
 	return nil
 }
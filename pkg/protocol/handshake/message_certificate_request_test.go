@@ -1,6 +1,7 @@
 package handshake
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 
@@ -44,3 +45,42 @@ func TestHandshakeMessageCertificateRequest(t *testing.T) {
 		t.Errorf("parsedCertificateRequest marshal: got %#v, want %#v", raw, rawCertificateRequest)
 	}
 }
+
+func TestHandshakeMessageCertificateRequestAuthorities(t *testing.T) {
+	rawCertificateRequest := []byte{
+		0x01, 0x40, 0x00, 0x02, 0x04, 0x03, 0x00, 0x0b, 0x00, 0x03,
+		0x30, 0x01, 0x02, 0x00, 0x04, 0x30, 0x02, 0x03, 0x04,
+	}
+	parsedCertificateRequest := &MessageCertificateRequest{
+		CertificateTypes: []clientcertificate.Type{
+			clientcertificate.ECDSASign,
+		},
+		SignatureHashAlgorithms: []signaturehash.Algorithm{
+			{Hash: hash.SHA256, Signature: signature.ECDSA},
+		},
+		CertificateAuthorities: [][]byte{
+			{0x30, 0x01, 0x02},
+			{0x30, 0x02, 0x03, 0x04},
+		},
+	}
+
+	c := &MessageCertificateRequest{}
+	if err := c.Unmarshal(rawCertificateRequest); err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(c, parsedCertificateRequest) {
+		t.Errorf("parsedCertificateRequest unmarshal: got %#v, want %#v", c, parsedCertificateRequest)
+	}
+
+	raw, err := c.Marshal()
+	if err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(raw, rawCertificateRequest) {
+		t.Errorf("parsedCertificateRequest marshal: got %#v, want %#v", raw, rawCertificateRequest)
+	}
+
+	// A distinguished name overflowing the list is rejected
+	rawCertificateRequest[7] = 0x04
+	if err := (&MessageCertificateRequest{}).Unmarshal(rawCertificateRequest[:12]); !errors.Is(err, errLengthMismatch) {
+		t.Errorf("Unmarshal: got %v, want %v", err, errLengthMismatch)
+	}
+}
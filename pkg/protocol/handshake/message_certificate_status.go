@@ -0,0 +1,64 @@
+package handshake
+
+import (
+	"github.com/pion/dtls/v2/internal/util"
+	"github.com/pion/dtls/v2/pkg/protocol/extension"
+)
+
+const (
+	certificateStatusResponseLengthFieldSize = 3
+	certificateStatusMinSize                 = 1 + certificateStatusResponseLengthFieldSize
+)
+
+// MessageCertificateStatus is sent by the server immediately after its
+// Certificate to staple the status of the certificate, if the client asked
+// for it with the status_request extension.
+//
+// https://tools.ietf.org/html/rfc6066#section-8
+type MessageCertificateStatus struct {
+	StatusType extension.StatusType
+
+	// Response is a DER encoded OCSPResponse for StatusTypeOCSP
+	Response []byte
+}
+
+// Type returns the Handshake Type
+func (m MessageCertificateStatus) Type() Type {
+	return TypeCertificateStatus
+}
+
+// Marshal encodes the Handshake
+func (m *MessageCertificateStatus) Marshal() ([]byte, error) {
+	if m.StatusType != extension.StatusTypeOCSP {
+		return nil, errInvalidCertificateStatusType
+	} else if len(m.Response) == 0 || len(m.Response) > 0xffffff {
+		return nil, errInvalidCertificateStatus
+	}
+
+	out := make([]byte, certificateStatusMinSize+len(m.Response))
+	out[0] = byte(m.StatusType)
+	util.PutBigEndianUint24(out[1:], uint32(len(m.Response)))
+	copy(out[certificateStatusMinSize:], m.Response)
+	return out, nil
+}
+
+// Unmarshal populates the message from encoded data
+func (m *MessageCertificateStatus) Unmarshal(data []byte) error {
+	if len(data) < certificateStatusMinSize {
+		return errBufferTooSmall
+	}
+
+	m.StatusType = extension.StatusType(data[0])
+	if m.StatusType != extension.StatusTypeOCSP {
+		return errInvalidCertificateStatusType
+	}
+
+	responseLength := int(util.BigEndianUint24(data[1:]))
+	if len(data) != certificateStatusMinSize+responseLength {
+		return errLengthMismatch
+	} else if responseLength == 0 {
+		return errInvalidCertificateStatus
+	}
+	m.Response = append([]byte{}, data[certificateStatusMinSize:]...)
+	return nil
+}
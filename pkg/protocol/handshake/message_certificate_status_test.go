@@ -0,0 +1,43 @@
+package handshake
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/pion/dtls/v2/pkg/protocol/extension"
+)
+
+func TestHandshakeMessageCertificateStatus(t *testing.T) {
+	rawCertificateStatus := []byte{
+		0x01, 0x00, 0x00, 0x04, 0x30, 0x02, 0x0a, 0x00,
+	}
+	parsedCertificateStatus := &MessageCertificateStatus{
+		StatusType: extension.StatusTypeOCSP,
+		Response:   []byte{0x30, 0x02, 0x0a, 0x00},
+	}
+
+	m := &MessageCertificateStatus{}
+	if err := m.Unmarshal(rawCertificateStatus); err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(m, parsedCertificateStatus) {
+		t.Errorf("handshakeMessageCertificateStatus unmarshal: got %#v, want %#v", m, parsedCertificateStatus)
+	}
+
+	raw, err := m.Marshal()
+	if err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(raw, rawCertificateStatus) {
+		t.Errorf("handshakeMessageCertificateStatus marshal: got %#v, want %#v", raw, rawCertificateStatus)
+	}
+
+	if err := (&MessageCertificateStatus{}).Unmarshal(rawCertificateStatus[:6]); !errors.Is(err, errLengthMismatch) {
+		t.Errorf("Expected error: %v, got: %v", errLengthMismatch, err)
+	}
+	if err := (&MessageCertificateStatus{}).Unmarshal([]byte{0x02, 0x00, 0x00, 0x01, 0x00}); !errors.Is(err, errInvalidCertificateStatusType) {
+		t.Errorf("Expected error: %v, got: %v", errInvalidCertificateStatusType, err)
+	}
+	if _, err := (&MessageCertificateStatus{StatusType: extension.StatusTypeOCSP}).Marshal(); !errors.Is(err, errInvalidCertificateStatus) {
+		t.Errorf("Expected error: %v, got: %v", errInvalidCertificateStatus, err)
+	}
+}
@@ -2,8 +2,11 @@ package handshake
 
 import (
 	"crypto/x509"
+	"errors"
 	"reflect"
 	"testing"
+
+	"github.com/pion/dtls/v2/pkg/protocol/extension"
 )
 
 func TestHandshakeMessageCertificate(t *testing.T) {
@@ -97,3 +100,33 @@ func TestEmptyHandshakeMessageCertificate(t *testing.T) {
 		t.Errorf("handshakeMessageCertificate unmarshal: got %#v, want %#v", c, expectedCertificate)
 	}
 }
+
+func TestRawPublicKeyHandshakeMessageCertificate(t *testing.T) {
+	rawCertificate := []byte{
+		0x00, 0x00, 0x04, 0x30, 0x02, 0x05, 0x00,
+	}
+
+	expectedCertificate := &MessageCertificate{
+		Certificate:     [][]byte{{0x30, 0x02, 0x05, 0x00}},
+		CertificateType: extension.CertificateTypeRawPublicKey,
+	}
+
+	c := &MessageCertificate{CertificateType: extension.CertificateTypeRawPublicKey}
+	if err := c.Unmarshal(rawCertificate); err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(c, expectedCertificate) {
+		t.Errorf("handshakeMessageCertificate unmarshal: got %#v, want %#v", c, expectedCertificate)
+	}
+
+	raw, err := c.Marshal()
+	if err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(raw, rawCertificate) {
+		t.Errorf("handshakeMessageCertificate marshal: got %#v, want %#v", raw, rawCertificate)
+	}
+
+	c = &MessageCertificate{CertificateType: extension.CertificateTypeRawPublicKey}
+	if _, err := c.Marshal(); !errors.Is(err, errInvalidRawPublicKey) {
+		t.Errorf("expected error: %v, got: %v", errInvalidRawPublicKey, err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/binary"
 
 	"github.com/pion/dtls/v2/internal/ciphersuite/types"
+	"github.com/pion/dtls/v2/pkg/crypto/elliptic"
 )
 
 // MessageClientKeyExchange is a DTLS Handshake Message
@@ -17,6 +18,16 @@ type MessageClientKeyExchange struct {
 	IdentityHint []byte
 	PublicKey    []byte
 
+	// NamedCurve is only needed for hybrid groups, whose key shares have
+	// a two byte length
+	NamedCurve elliptic.Curve
+
+	// ECJPAKEKeyKP is the encoded round two of the EC J-PAKE key exchange
+	ECJPAKEKeyKP []byte
+
+	// KeyExchangeData is the opaque payload of a custom key exchange
+	KeyExchangeData []byte
+
 	// for unmarshaling
 	KeyExchangeAlgorithm types.KeyExchangeAlgorithm
 }
@@ -28,6 +39,12 @@ func (m MessageClientKeyExchange) Type() Type {
 
 // Marshal encodes the Handshake
 func (m *MessageClientKeyExchange) Marshal() (out []byte, err error) {
+	if m.ECJPAKEKeyKP != nil {
+		return append(out, m.ECJPAKEKeyKP...), nil
+	}
+	if m.KeyExchangeData != nil {
+		return append(out, m.KeyExchangeData...), nil
+	}
 	if m.IdentityHint == nil && m.PublicKey == nil {
 		return nil, errInvalidClientKeyExchange
 	}
@@ -38,8 +55,7 @@ func (m *MessageClientKeyExchange) Marshal() (out []byte, err error) {
 	}
 
 	if m.PublicKey != nil {
-		out = append(out, byte(len(m.PublicKey)))
-		out = append(out, m.PublicKey...)
+		out = appendKeyShare(out, m.NamedCurve, m.PublicKey)
 	}
 
 	return out, nil
@@ -48,10 +64,16 @@ func (m *MessageClientKeyExchange) Marshal() (out []byte, err error) {
 // Unmarshal populates the message from encoded data
 func (m *MessageClientKeyExchange) Unmarshal(data []byte) error {
 	switch {
+	case m.KeyExchangeAlgorithm == types.KeyExchangeAlgorithmCustom:
+		m.KeyExchangeData = append([]byte{}, data...)
+		return nil
 	case len(data) < 2:
 		return errBufferTooSmall
 	case m.KeyExchangeAlgorithm == types.KeyExchangeAlgorithmNone:
 		return errCipherSuiteUnset
+	case m.KeyExchangeAlgorithm == types.KeyExchangeAlgorithmEcjpake:
+		m.ECJPAKEKeyKP = append([]byte{}, data...)
+		return nil
 	}
 
 	offset := 0
@@ -66,6 +88,21 @@ func (m *MessageClientKeyExchange) Unmarshal(data []byte) error {
 	}
 
 	if m.KeyExchangeAlgorithm.Has(types.KeyExchangeAlgorithmEcdhe) {
+		if m.NamedCurve.Hybrid() {
+			if len(data) < offset+2 {
+				return errBufferTooSmall
+			}
+			publicKeyLength := int(binary.BigEndian.Uint16(data[offset:]))
+			if publicKeyLength > len(data)-2-offset {
+				return errBufferTooSmall
+			}
+			m.PublicKey = append([]byte{}, data[offset+2:offset+2+publicKeyLength]...)
+			return nil
+		}
+
+		if len(data) <= offset {
+			return errBufferTooSmall
+		}
 		publicKeyLength := int(data[offset])
 		if publicKeyLength > len(data)-1-offset {
 			return errBufferTooSmall
@@ -76,3 +113,15 @@ func (m *MessageClientKeyExchange) Unmarshal(data []byte) error {
 
 	return nil
 }
+
+// appendKeyShare appends an ECPoint, or the key share of a hybrid group
+// which does not fit the one byte length of an ECPoint
+func appendKeyShare(out []byte, curve elliptic.Curve, publicKey []byte) []byte {
+	if curve.Hybrid() {
+		out = append(out, 0x00, 0x00)
+		binary.BigEndian.PutUint16(out[len(out)-2:], uint16(len(publicKey)))
+	} else {
+		out = append(out, byte(len(publicKey)))
+	}
+	return append(out, publicKey...)
+}
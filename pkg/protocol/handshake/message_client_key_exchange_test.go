@@ -1,10 +1,12 @@
 package handshake
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 
 	"github.com/pion/dtls/v2/internal/ciphersuite/types"
+	"github.com/pion/dtls/v2/pkg/crypto/elliptic"
 )
 
 func TestHandshakeMessageClientKeyExchange(t *testing.T) {
@@ -34,3 +36,92 @@ func TestHandshakeMessageClientKeyExchange(t *testing.T) {
 		t.Errorf("handshakeMessageClientKeyExchange marshal: got %#v, want %#v", raw, rawClientKeyExchange)
 	}
 }
+
+func TestHandshakeMessageClientKeyExchangeHybrid(t *testing.T) {
+	publicKey := make([]byte, 1120)
+	for i := range publicKey {
+		publicKey[i] = byte(i)
+	}
+	rawClientKeyExchange := append([]byte{0x04, 0x60}, publicKey...)
+	parsedClientKeyExchange := &MessageClientKeyExchange{
+		PublicKey:            publicKey,
+		NamedCurve:           elliptic.X25519MLKEM768Experimental,
+		KeyExchangeAlgorithm: types.KeyExchangeAlgorithmEcdhe,
+	}
+
+	c := &MessageClientKeyExchange{
+		NamedCurve:           elliptic.X25519MLKEM768Experimental,
+		KeyExchangeAlgorithm: types.KeyExchangeAlgorithmEcdhe,
+	}
+	if err := c.Unmarshal(rawClientKeyExchange); err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(c, parsedClientKeyExchange) {
+		t.Errorf("handshakeMessageClientKeyExchange unmarshal: got %#v, want %#v", c, parsedClientKeyExchange)
+	}
+
+	raw, err := c.Marshal()
+	if err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(raw, rawClientKeyExchange) {
+		t.Errorf("handshakeMessageClientKeyExchange marshal: got %#v, want %#v", raw, rawClientKeyExchange)
+	}
+
+	c = &MessageClientKeyExchange{
+		NamedCurve:           elliptic.X25519MLKEM768Experimental,
+		KeyExchangeAlgorithm: types.KeyExchangeAlgorithmEcdhe,
+	}
+	if err := c.Unmarshal(rawClientKeyExchange[:100]); !errors.Is(err, errBufferTooSmall) {
+		t.Errorf("Expected error: %v, got: %v", errBufferTooSmall, err)
+	}
+}
+
+func TestHandshakeMessageClientKeyExchangeECJPAKE(t *testing.T) {
+	// The ECJPAKEKeyKP is sent without a length of its own
+	rawClientKeyExchange := []byte{0x41, 0x04, 0x01, 0x02, 0x41, 0x04, 0x03, 0x04, 0x01, 0x05}
+	parsedClientKeyExchange := &MessageClientKeyExchange{
+		ECJPAKEKeyKP:         rawClientKeyExchange,
+		KeyExchangeAlgorithm: types.KeyExchangeAlgorithmEcjpake,
+	}
+
+	c := &MessageClientKeyExchange{
+		KeyExchangeAlgorithm: types.KeyExchangeAlgorithmEcjpake,
+	}
+	if err := c.Unmarshal(rawClientKeyExchange); err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(c, parsedClientKeyExchange) {
+		t.Errorf("handshakeMessageClientKeyExchange unmarshal: got %#v, want %#v", c, parsedClientKeyExchange)
+	}
+
+	raw, err := c.Marshal()
+	if err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(raw, rawClientKeyExchange) {
+		t.Errorf("handshakeMessageClientKeyExchange marshal: got %#v, want %#v", raw, rawClientKeyExchange)
+	}
+}
+
+func TestHandshakeMessageClientKeyExchangeCustom(t *testing.T) {
+	// The payload of a custom key exchange is opaque, and may be shorter
+	// than any built-in key exchange
+	rawClientKeyExchange := []byte{0x01}
+	parsedClientKeyExchange := &MessageClientKeyExchange{
+		KeyExchangeData:      rawClientKeyExchange,
+		KeyExchangeAlgorithm: types.KeyExchangeAlgorithmCustom,
+	}
+
+	c := &MessageClientKeyExchange{
+		KeyExchangeAlgorithm: types.KeyExchangeAlgorithmCustom,
+	}
+	if err := c.Unmarshal(rawClientKeyExchange); err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(c, parsedClientKeyExchange) {
+		t.Errorf("handshakeMessageClientKeyExchange unmarshal: got %#v, want %#v", c, parsedClientKeyExchange)
+	}
+
+	raw, err := c.Marshal()
+	if err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(raw, rawClientKeyExchange) {
+		t.Errorf("handshakeMessageClientKeyExchange marshal: got %#v, want %#v", raw, rawClientKeyExchange)
+	}
+}
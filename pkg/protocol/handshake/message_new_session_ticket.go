@@ -0,0 +1,56 @@
+package handshake
+
+import (
+	"encoding/binary"
+)
+
+const (
+	newSessionTicketLifetimeSize = 4
+	newSessionTicketMinSize      = newSessionTicketLifetimeSize + 2
+)
+
+// MessageNewSessionTicket is sent by the server during the handshake to
+// hand the client an opaque ticket, which can be used to resume the session
+// without any state kept on the server. An empty Ticket means the server
+// decided not to issue a ticket after all.
+//
+// https://tools.ietf.org/html/rfc5077#section-3.3
+type MessageNewSessionTicket struct {
+	// TicketLifetimeHint is the number of seconds the ticket should be
+	// stored for, zero means unspecified
+	TicketLifetimeHint uint32
+	Ticket             []byte
+}
+
+// Type returns the Handshake Type
+func (m MessageNewSessionTicket) Type() Type {
+	return TypeNewSessionTicket
+}
+
+// Marshal encodes the Handshake
+func (m *MessageNewSessionTicket) Marshal() ([]byte, error) {
+	if len(m.Ticket) > 0xffff {
+		return nil, errTicketTooLong
+	}
+
+	out := make([]byte, newSessionTicketMinSize+len(m.Ticket))
+	binary.BigEndian.PutUint32(out, m.TicketLifetimeHint)
+	binary.BigEndian.PutUint16(out[newSessionTicketLifetimeSize:], uint16(len(m.Ticket)))
+	copy(out[newSessionTicketMinSize:], m.Ticket)
+	return out, nil
+}
+
+// Unmarshal populates the message from encoded data
+func (m *MessageNewSessionTicket) Unmarshal(data []byte) error {
+	if len(data) < newSessionTicketMinSize {
+		return errBufferTooSmall
+	}
+
+	m.TicketLifetimeHint = binary.BigEndian.Uint32(data)
+	ticketLength := int(binary.BigEndian.Uint16(data[newSessionTicketLifetimeSize:]))
+	if len(data) != newSessionTicketMinSize+ticketLength {
+		return errLengthMismatch
+	}
+	m.Ticket = append([]byte{}, data[newSessionTicketMinSize:]...)
+	return nil
+}
@@ -0,0 +1,35 @@
+package handshake
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestHandshakeMessageNewSessionTicket(t *testing.T) {
+	rawNewSessionTicket := []byte{
+		0x00, 0x01, 0x51, 0x80, 0x00, 0x04, 0xde, 0xad, 0xbe, 0xef,
+	}
+	parsedNewSessionTicket := &MessageNewSessionTicket{
+		TicketLifetimeHint: 86400,
+		Ticket:             []byte{0xde, 0xad, 0xbe, 0xef},
+	}
+
+	m := &MessageNewSessionTicket{}
+	if err := m.Unmarshal(rawNewSessionTicket); err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(m, parsedNewSessionTicket) {
+		t.Errorf("handshakeMessageNewSessionTicket unmarshal: got %#v, want %#v", m, parsedNewSessionTicket)
+	}
+
+	raw, err := m.Marshal()
+	if err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(raw, rawNewSessionTicket) {
+		t.Errorf("handshakeMessageNewSessionTicket marshal: got %#v, want %#v", raw, rawNewSessionTicket)
+	}
+
+	if err := (&MessageNewSessionTicket{}).Unmarshal(rawNewSessionTicket[:8]); !errors.Is(err, errLengthMismatch) {
+		t.Errorf("Expected error: %v, got: %v", errLengthMismatch, err)
+	}
+}
@@ -20,6 +20,13 @@ type MessageServerKeyExchange struct {
 	SignatureAlgorithm signature.Algorithm
 	Signature          []byte
 
+	// ECJPAKEKeyKP is the encoded round two of the EC J-PAKE key exchange,
+	// which follows the curve instead of a public key and signature
+	ECJPAKEKeyKP []byte
+
+	// KeyExchangeData is the opaque payload of a custom key exchange
+	KeyExchangeData []byte
+
 	// for unmarshaling
 	KeyExchangeAlgorithm types.KeyExchangeAlgorithm
 }
@@ -31,20 +38,26 @@ func (m MessageServerKeyExchange) Type() Type {
 
 // Marshal encodes the Handshake
 func (m *MessageServerKeyExchange) Marshal() ([]byte, error) {
+	if m.KeyExchangeData != nil {
+		return append([]byte{}, m.KeyExchangeData...), nil
+	}
+
 	var out []byte
 	if m.IdentityHint != nil {
 		out = append([]byte{0x00, 0x00}, m.IdentityHint...)
 		binary.BigEndian.PutUint16(out, uint16(len(out)-2))
 	}
 
-	if m.EllipticCurveType == 0 || len(m.PublicKey) == 0 {
+	if m.EllipticCurveType == 0 || (len(m.PublicKey) == 0 && len(m.ECJPAKEKeyKP) == 0) {
 		return out, nil
 	}
 	out = append(out, byte(m.EllipticCurveType), 0x00, 0x00)
 	binary.BigEndian.PutUint16(out[len(out)-2:], uint16(m.NamedCurve))
+	if len(m.ECJPAKEKeyKP) > 0 {
+		return append(out, m.ECJPAKEKeyKP...), nil
+	}
 
-	out = append(out, byte(len(m.PublicKey)))
-	out = append(out, m.PublicKey...)
+	out = appendKeyShare(out, m.NamedCurve, m.PublicKey)
 	switch {
 	case m.HashAlgorithm != hash.None && len(m.Signature) == 0:
 		return nil, errInvalidHashAlgorithm
@@ -66,6 +79,9 @@ func (m *MessageServerKeyExchange) Marshal() ([]byte, error) {
 // Unmarshal populates the message from encoded data
 func (m *MessageServerKeyExchange) Unmarshal(data []byte) error {
 	switch {
+	case m.KeyExchangeAlgorithm == types.KeyExchangeAlgorithmCustom:
+		m.KeyExchangeData = append([]byte{}, data...)
+		return nil
 	case len(data) < 2:
 		return errBufferTooSmall
 	case m.KeyExchangeAlgorithm == types.KeyExchangeAlgorithmNone:
@@ -84,7 +100,7 @@ func (m *MessageServerKeyExchange) Unmarshal(data []byte) error {
 		return errLengthMismatch
 	}
 
-	if !m.KeyExchangeAlgorithm.Has(types.KeyExchangeAlgorithmEcdhe) {
+	if !m.KeyExchangeAlgorithm.Has(types.KeyExchangeAlgorithmEcdhe) && m.KeyExchangeAlgorithm != types.KeyExchangeAlgorithmEcjpake {
 		return errLengthMismatch
 	}
 
@@ -101,16 +117,29 @@ func (m *MessageServerKeyExchange) Unmarshal(data []byte) error {
 	if _, ok := elliptic.Curves()[m.NamedCurve]; !ok {
 		return errInvalidNamedCurve
 	}
+	if m.KeyExchangeAlgorithm == types.KeyExchangeAlgorithmEcjpake {
+		if len(data) < 4 {
+			return errBufferTooSmall
+		}
+		m.ECJPAKEKeyKP = append([]byte{}, data[3:]...)
+		return nil
+	}
 	if len(data) < 4 {
 		return errBufferTooSmall
 	}
 
-	publicKeyLength := int(data[3])
-	offset := 4 + publicKeyLength
-	if len(data) < offset {
+	publicKeyLength, offset := int(data[3]), 4
+	if m.NamedCurve.Hybrid() {
+		if len(data) < 5 {
+			return errBufferTooSmall
+		}
+		publicKeyLength, offset = int(binary.BigEndian.Uint16(data[3:])), 5
+	}
+	if len(data) < offset+publicKeyLength {
 		return errBufferTooSmall
 	}
-	m.PublicKey = append([]byte{}, data[4:offset]...)
+	m.PublicKey = append([]byte{}, data[offset:offset+publicKeyLength]...)
+	offset += publicKeyLength
 
 	// Anon connection doesn't contains hashAlgorithm, signatureAlgorithm, signature
 	if len(data) == offset {
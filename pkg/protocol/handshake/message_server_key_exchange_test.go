@@ -73,4 +73,73 @@ func TestHandshakeMessageServerKeyExchange(t *testing.T) {
 
 		test(rawServerKeyExchange, parsedServerKeyExchange)
 	})
+
+	t.Run("Hybrid", func(t *testing.T) {
+		publicKey := make([]byte, 1216)
+		for i := range publicKey {
+			publicKey[i] = byte(i)
+		}
+		rawServerKeyExchange := append([]byte{0x03, 0xfe, 0x11, 0x04, 0xc0}, publicKey...)
+		parsedServerKeyExchange := &MessageServerKeyExchange{
+			EllipticCurveType:    elliptic.CurveTypeNamedCurve,
+			NamedCurve:           elliptic.X25519MLKEM768Experimental,
+			PublicKey:            publicKey,
+			HashAlgorithm:        hash.None,
+			SignatureAlgorithm:   signature.Anonymous,
+			KeyExchangeAlgorithm: types.KeyExchangeAlgorithmEcdhe,
+		}
+
+		test(rawServerKeyExchange, parsedServerKeyExchange)
+	})
+}
+
+func TestHandshakeMessageServerKeyExchangeECJPAKE(t *testing.T) {
+	// ECParameters for secp256r1, followed by the ECJPAKEKeyKP
+	rawServerKeyExchange := []byte{0x03, 0x00, 0x17, 0x41, 0x04, 0x01, 0x02, 0x41, 0x04, 0x03, 0x04, 0x01, 0x05}
+	parsedServerKeyExchange := &MessageServerKeyExchange{
+		EllipticCurveType:    elliptic.CurveTypeNamedCurve,
+		NamedCurve:           elliptic.P256,
+		ECJPAKEKeyKP:         rawServerKeyExchange[3:],
+		KeyExchangeAlgorithm: types.KeyExchangeAlgorithmEcjpake,
+	}
+
+	c := &MessageServerKeyExchange{
+		KeyExchangeAlgorithm: types.KeyExchangeAlgorithmEcjpake,
+	}
+	if err := c.Unmarshal(rawServerKeyExchange); err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(c, parsedServerKeyExchange) {
+		t.Errorf("handshakeMessageServerKeyExchange unmarshal: got %#v, want %#v", c, parsedServerKeyExchange)
+	}
+
+	raw, err := c.Marshal()
+	if err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(raw, rawServerKeyExchange) {
+		t.Errorf("handshakeMessageServerKeyExchange marshal: got %#v, want %#v", raw, rawServerKeyExchange)
+	}
+}
+
+func TestHandshakeMessageServerKeyExchangeCustom(t *testing.T) {
+	rawServerKeyExchange := []byte{0x03, 0x00, 0x17, 0x01}
+	parsedServerKeyExchange := &MessageServerKeyExchange{
+		KeyExchangeData:      rawServerKeyExchange,
+		KeyExchangeAlgorithm: types.KeyExchangeAlgorithmCustom,
+	}
+
+	c := &MessageServerKeyExchange{
+		KeyExchangeAlgorithm: types.KeyExchangeAlgorithmCustom,
+	}
+	if err := c.Unmarshal(rawServerKeyExchange); err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(c, parsedServerKeyExchange) {
+		t.Errorf("handshakeMessageServerKeyExchange unmarshal: got %#v, want %#v", c, parsedServerKeyExchange)
+	}
+
+	raw, err := c.Marshal()
+	if err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(raw, rawServerKeyExchange) {
+		t.Errorf("handshakeMessageServerKeyExchange marshal: got %#v, want %#v", raw, rawServerKeyExchange)
+	}
 }
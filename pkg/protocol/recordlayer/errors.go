@@ -13,4 +13,5 @@ var (
 	errSequenceNumberOverflow     = &protocol.InternalError{Err: errors.New("sequence number overflow")}                        //nolint:goerr113
 	errUnsupportedProtocolVersion = &protocol.FatalError{Err: errors.New("unsupported protocol version")}                       //nolint:goerr113
 	errInvalidContentType         = &protocol.TemporaryError{Err: errors.New("invalid content type")}                           //nolint:goerr113
+	errNoContentType              = &protocol.TemporaryError{Err: errors.New("inner plaintext has no content type")}            //nolint:goerr113
 )
@@ -14,36 +14,65 @@ type Header struct {
 	Version        protocol.Version
 	Epoch          uint16
 	SequenceNumber uint64 // uint48 in spec
+
+	// Optional Fields
+	ConnectionID []byte
 }
 
 // RecordLayer enums
 const (
+	// HeaderSize is the size of a record header without a connection ID
 	HeaderSize        = 13
 	MaxSequenceNumber = 0x0000FFFFFFFFFFFF
 )
 
+// Size returns the total size of the header, including the connection ID
+// when the record is of type tls12_cid.
+func (h *Header) Size() int {
+	if h.ContentType == protocol.ContentTypeConnectionID {
+		return HeaderSize + len(h.ConnectionID)
+	}
+	return HeaderSize
+}
+
 // Marshal encodes a TLS RecordLayer Header to binary
 func (h *Header) Marshal() ([]byte, error) {
 	if h.SequenceNumber > MaxSequenceNumber {
 		return nil, errSequenceNumberOverflow
 	}
 
-	out := make([]byte, HeaderSize)
+	hs := h.Size()
+	out := make([]byte, hs)
 	out[0] = byte(h.ContentType)
 	out[1] = h.Version.Major
 	out[2] = h.Version.Minor
 	binary.BigEndian.PutUint16(out[3:], h.Epoch)
 	util.PutBigEndianUint48(out[5:], h.SequenceNumber)
-	binary.BigEndian.PutUint16(out[HeaderSize-2:], h.ContentLen)
+	if h.ContentType == protocol.ContentTypeConnectionID {
+		copy(out[11:], h.ConnectionID)
+	}
+	binary.BigEndian.PutUint16(out[hs-2:], h.ContentLen)
 	return out, nil
 }
 
-// Unmarshal populates a TLS RecordLayer Header from binary
+// Unmarshal populates a TLS RecordLayer Header from binary.
+// The connection ID of a tls12_cid record is not self-describing, so
+// ConnectionID must be set to a slice of the expected length before calling
+// Unmarshal on such a record.
 func (h *Header) Unmarshal(data []byte) error {
 	if len(data) < HeaderSize {
 		return errBufferTooSmall
 	}
 	h.ContentType = protocol.ContentType(data[0])
+	if h.ContentType == protocol.ContentTypeConnectionID {
+		// A tls12_cid record is only valid if we are expecting a connection ID
+		if len(h.ConnectionID) == 0 {
+			return errInvalidContentType
+		}
+		if len(data) < h.Size() {
+			return errBufferTooSmall
+		}
+	}
 	h.Version.Major = data[1]
 	h.Version.Minor = data[2]
 	h.Epoch = binary.BigEndian.Uint16(data[3:])
@@ -57,5 +86,9 @@ func (h *Header) Unmarshal(data []byte) error {
 		return errUnsupportedProtocolVersion
 	}
 
+	if h.ContentType == protocol.ContentTypeConnectionID {
+		h.ConnectionID = append(h.ConnectionID[:0], data[11:11+len(h.ConnectionID)]...)
+	}
+
 	return nil
 }
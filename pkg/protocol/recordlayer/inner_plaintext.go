@@ -0,0 +1,40 @@
+package recordlayer
+
+import (
+	"github.com/pion/dtls/v2/pkg/protocol"
+)
+
+// InnerPlaintext implements DTLSInnerPlaintext, the payload of a tls12_cid
+// record before encryption. The real content type is carried inside the
+// encrypted payload, optionally followed by zero padding.
+//
+// https://datatracker.ietf.org/doc/html/rfc9146#section-4
+type InnerPlaintext struct {
+	Content  []byte
+	RealType protocol.ContentType
+	Zeros    uint
+}
+
+// Marshal encodes a DTLS InnerPlaintext to binary
+func (p *InnerPlaintext) Marshal() ([]byte, error) {
+	out := make([]byte, len(p.Content)+1+int(p.Zeros))
+	copy(out, p.Content)
+	out[len(p.Content)] = byte(p.RealType)
+	return out, nil
+}
+
+// Unmarshal populates a DTLS InnerPlaintext from binary
+func (p *InnerPlaintext) Unmarshal(data []byte) error {
+	// Process in reverse
+	i := len(data) - 1
+	for i >= 0 && data[i] == 0 {
+		i--
+	}
+	if i < 0 {
+		return errNoContentType
+	}
+	p.RealType = protocol.ContentType(data[i])
+	p.Content = append([]byte{}, data[:i]...)
+	p.Zeros = uint(len(data) - i - 1)
+	return nil
+}
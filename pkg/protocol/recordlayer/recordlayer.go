@@ -79,14 +79,24 @@ func (r *RecordLayer) Unmarshal(data []byte) error {
 // separate records.
 // https://tools.ietf.org/html/rfc6347#section-4.2.3
 func UnpackDatagram(buf []byte) ([][]byte, error) {
+	return ContentAwareUnpackDatagram(buf, 0)
+}
+
+// ContentAwareUnpackDatagram is the same as UnpackDatagram but considers the
+// presence of a connection ID of length cidLength on tls12_cid records.
+func ContentAwareUnpackDatagram(buf []byte, cidLength int) ([][]byte, error) {
 	out := [][]byte{}
 
 	for offset := 0; len(buf) != offset; {
-		if len(buf)-offset <= HeaderSize {
+		headerSize := HeaderSize
+		if protocol.ContentType(buf[offset]) == protocol.ContentTypeConnectionID {
+			headerSize += cidLength
+		}
+		if len(buf)-offset <= headerSize {
 			return nil, errInvalidPacketLength
 		}
 
-		pktLen := (HeaderSize + int(binary.BigEndian.Uint16(buf[offset+11:])))
+		pktLen := (headerSize + int(binary.BigEndian.Uint16(buf[offset+headerSize-2:])))
 		if offset+pktLen > len(buf) {
 			return nil, errInvalidPacketLength
 		}
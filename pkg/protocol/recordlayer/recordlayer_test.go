@@ -90,3 +90,118 @@ func TestRecordLayerRoundTrip(t *testing.T) {
 		}
 	}
 }
+
+func TestContentAwareUDPDecode(t *testing.T) {
+	for _, test := range []struct {
+		Name      string
+		Data      []byte
+		CIDLength int
+		Want      [][]byte
+		WantError error
+	}{
+		{
+			Name: "Connection ID and Change Cipher Spec",
+			Data: []byte{
+				0x19, 0xfe, 0xfd, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0xaa, 0xbb, 0x00, 0x02, 0x01, 0x02,
+				0x14, 0xfe, 0xff, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x12, 0x00, 0x01, 0x01,
+			},
+			CIDLength: 2,
+			Want: [][]byte{
+				{0x19, 0xfe, 0xfd, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0xaa, 0xbb, 0x00, 0x02, 0x01, 0x02},
+				{0x14, 0xfe, 0xff, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x12, 0x00, 0x01, 0x01},
+			},
+		},
+		{
+			Name:      "Connection ID declared invalid length",
+			Data:      []byte{0x19, 0xfe, 0xfd, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0xaa, 0xbb, 0x00, 0xff, 0x01},
+			CIDLength: 2,
+			WantError: errInvalidPacketLength,
+		},
+	} {
+		dtlsPkts, err := ContentAwareUnpackDatagram(test.Data, test.CIDLength)
+		if !errors.Is(err, test.WantError) {
+			t.Errorf("Unexpected Error %q: exp: %v got: %v", test.Name, test.WantError, err)
+		} else if !reflect.DeepEqual(test.Want, dtlsPkts) {
+			t.Errorf("%q UDP decode: got %q, want %q", test.Name, dtlsPkts, test.Want)
+		}
+	}
+}
+
+func TestHeaderConnectionID(t *testing.T) {
+	raw := []byte{0x19, 0xfe, 0xfd, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x05, 0xaa, 0xbb, 0xcc, 0x00, 0x10}
+	want := Header{
+		ContentType:    protocol.ContentTypeConnectionID,
+		ContentLen:     16,
+		Version:        protocol.Version1_2,
+		Epoch:          1,
+		SequenceNumber: 5,
+		ConnectionID:   []byte{0xaa, 0xbb, 0xcc},
+	}
+
+	out, err := want.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(raw, out) {
+		t.Errorf("header.marshal: got % 02x, want % 02x", out, raw)
+	}
+
+	h := Header{}
+	if err := h.Unmarshal(raw); !errors.Is(err, errInvalidContentType) {
+		t.Errorf("Unexpected Error without connection ID: exp: %v got: %v", errInvalidContentType, err)
+	}
+
+	h = Header{ConnectionID: make([]byte, 3)}
+	if err := h.Unmarshal(raw); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(h.ConnectionID, want.ConnectionID) || h.Epoch != want.Epoch || h.SequenceNumber != want.SequenceNumber {
+		t.Errorf("header.unmarshal: got %#v, want %#v", h, want)
+	}
+	if h.Size() != len(raw) {
+		t.Errorf("header.size: got %d, want %d", h.Size(), len(raw))
+	}
+}
+
+func TestInnerPlaintextRoundTrip(t *testing.T) {
+	for _, test := range []struct {
+		Name string
+		Data []byte
+		Want *InnerPlaintext
+	}{
+		{
+			Name: "No padding",
+			Data: []byte{0x01, 0x02, 0x17},
+			Want: &InnerPlaintext{
+				Content:  []byte{0x01, 0x02},
+				RealType: protocol.ContentTypeApplicationData,
+			},
+		},
+		{
+			Name: "Padding",
+			Data: []byte{0x01, 0x00, 0x15, 0x00, 0x00, 0x00},
+			Want: &InnerPlaintext{
+				Content:  []byte{0x01, 0x00},
+				RealType: protocol.ContentTypeAlert,
+				Zeros:    3,
+			},
+		},
+	} {
+		p := &InnerPlaintext{}
+		if err := p.Unmarshal(test.Data); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(test.Want, p) {
+			t.Errorf("%q innerPlaintext.unmarshal: got %#v, want %#v", test.Name, p, test.Want)
+		}
+
+		data, err := p.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(test.Data, data) {
+			t.Errorf("%q innerPlaintext.marshal: got % 02x, want % 02x", test.Name, data, test.Data)
+		}
+	}
+
+	if err := (&InnerPlaintext{}).Unmarshal([]byte{0x00, 0x00}); !errors.Is(err, errNoContentType) {
+		t.Errorf("Unexpected Error: exp: %v got: %v", errNoContentType, err)
+	}
+}
@@ -0,0 +1,99 @@
+package dtls
+
+import (
+	"context"
+	"errors"
+
+	"github.com/pion/dtls/v2/pkg/protocol/alert"
+)
+
+// usesPSK reports whether PSK cipher suites are enabled
+func (c *handshakeConfig) usesPSK() bool {
+	return c.localPSKCallback != nil || c.localGetPSK != nil || c.localGetClientPSK != nil
+}
+
+// serverPSK returns the pre-shared key of the identity sent by a client
+func serverPSK(ctx context.Context, state *State, cfg *handshakeConfig, identity []byte) ([]byte, *alert.Alert, error) {
+	remoteAddr := state.clientHelloInfo.RemoteAddr
+	if cfg.pskAuthTracker != nil {
+		if err := cfg.pskAuthTracker.check(identity, remoteAddr); err != nil {
+			return nil, &alert.Alert{Level: alert.Fatal, Description: alert.AccessDenied}, err
+		}
+	}
+
+	var psk []byte
+	var err error
+	if cfg.localGetPSK != nil {
+		psk, err = cfg.localGetPSK(ctx, identity, state.clientHelloInfo)
+	} else {
+		psk, err = cfg.localPSKCallback(identity)
+	}
+	if err != nil {
+		if cfg.pskAuthTracker != nil && errors.Is(err, ErrUnknownPSKIdentity) {
+			cfg.pskAuthTracker.fail(identity, remoteAddr)
+		}
+		return nil, pskAlert(err), err
+	}
+	state.pskAuthPending = cfg.pskAuthTracker != nil
+	return psk, nil, nil
+}
+
+// pskIdentityResumable reports whether a session established with the PSK
+// identity of a client can be resumed: the identity must not be locked out by
+// the PSKAuthTracker, and GetPSK or PSKCallback must still know it. A refused
+// identity is not reported to the PSKAuthTracker, as the client falls back to
+// a full handshake.
+func pskIdentityResumable(ctx context.Context, state *State, cfg *handshakeConfig, identity []byte) bool {
+	if cfg.pskAuthTracker != nil && cfg.pskAuthTracker.check(identity, state.clientHelloInfo.RemoteAddr) != nil {
+		return false
+	}
+
+	var err error
+	switch {
+	case cfg.localGetPSK != nil:
+		_, err = cfg.localGetPSK(ctx, identity, state.clientHelloInfo)
+	case cfg.localPSKCallback != nil:
+		_, err = cfg.localPSKCallback(identity)
+	default:
+		return false
+	}
+	return err == nil
+}
+
+// reportPSKAuth reports the outcome of a server handshake to the
+// PSKAuthTracker, once the pre-shared key of the identity of the client
+// was looked up
+func reportPSKAuth(state *State, cfg *handshakeConfig, success bool) {
+	if !state.pskAuthPending {
+		return
+	}
+	state.pskAuthPending = false
+	if success {
+		cfg.pskAuthTracker.succeed(state.IdentityHint, state.clientHelloInfo.RemoteAddr)
+	} else {
+		cfg.pskAuthTracker.fail(state.IdentityHint, state.clientHelloInfo.RemoteAddr)
+	}
+}
+
+// clientPSK returns the identity a client sends for the identity hint of
+// the server, and its pre-shared key
+func clientPSK(ctx context.Context, cfg *handshakeConfig, identityHint []byte) ([]byte, []byte, *alert.Alert, error) {
+	identity, psk := cfg.localPSKIdentityHint, []byte(nil)
+	var err error
+	if cfg.localGetClientPSK != nil {
+		identity, psk, err = cfg.localGetClientPSK(ctx, identityHint)
+	} else {
+		psk, err = cfg.localPSKCallback(identityHint)
+	}
+	if err != nil {
+		return nil, nil, pskAlert(err), err
+	}
+	return identity, psk, nil, nil
+}
+
+func pskAlert(err error) *alert.Alert {
+	if errors.Is(err, ErrUnknownPSKIdentity) {
+		return &alert.Alert{Level: alert.Fatal, Description: alert.UnknownPSKIdentity}
+	}
+	return &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}
+}
@@ -0,0 +1,260 @@
+package dtls
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPSKAuthMaxFailures = 5
+	defaultPSKAuthBackoff     = time.Second
+	defaultPSKAuthMaxBackoff  = time.Hour
+
+	// At most this many identities and subnets are tracked each, the least
+	// recently failed ones which aren't locked out are forgotten first
+	pskAuthTrackerMaxEntries = 4096
+)
+
+// PSKAuthEventType is the type of a PSKAuthEvent
+type PSKAuthEventType int
+
+// PSKAuthEventType enums
+const (
+	// PSKAuthFailed is reported for an unknown identity, or a handshake
+	// failing after the pre-shared key of the identity was looked up
+	PSKAuthFailed PSKAuthEventType = iota + 1
+	// PSKAuthLockedOut is reported when an identity or a subnet gets
+	// locked out
+	PSKAuthLockedOut
+	// PSKAuthRejected is reported for a handshake rejected by a lockout
+	PSKAuthRejected
+)
+
+func (t PSKAuthEventType) String() string {
+	switch t {
+	case PSKAuthFailed:
+		return "PSKAuthFailed"
+	case PSKAuthLockedOut:
+		return "PSKAuthLockedOut"
+	case PSKAuthRejected:
+		return "PSKAuthRejected"
+	default:
+		return "Invalid PSKAuthEventType"
+	}
+}
+
+// PSKAuthEvent is reported by a PSKAuthTracker
+type PSKAuthEvent struct {
+	Type       PSKAuthEventType
+	Identity   []byte
+	RemoteAddr net.Addr
+
+	// IdentityFailures and AddressFailures are the consecutive failures of
+	// the identity and of the subnet of the address of the client
+	IdentityFailures int
+	AddressFailures  int
+
+	// LockedUntil is the end of the lockout, zero if none
+	LockedUntil time.Time
+}
+
+// PSKAuthTracker counts the failed PSK authentications of a server per
+// identity and per subnet of the source address, and locks them out with
+// exponential backoff after MaxFailures consecutive failures. A handshake
+// fails if the identity is unknown, or if the Finished of the client can't
+// be decrypted or verified with the pre-shared key of the identity. A
+// successful handshake resets the count of its identity and subnet. Note
+// that anyone may lock out an identity by failing with it.
+//
+// The failures of at most 4096 identities and subnets are tracked each. To
+// make room, the least recently failed ones which aren't locked out are
+// forgotten. Lockouts are never forgotten before they end: while 4096
+// identities or subnets are locked out, the failures of other ones aren't
+// tracked.
+//
+// A PSKAuthTracker is safe for concurrent use, and may be shared by the
+// Configs of several servers.
+type PSKAuthTracker struct {
+	// MaxFailures is the number of consecutive failures from which an
+	// identity or subnet is locked out, 5 if zero
+	MaxFailures int
+
+	// Backoff is the lockout after MaxFailures failures, doubled by every
+	// further failure up to MaxBackoff. 1 second and 1 hour if zero.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	// IPv4SubnetPrefix and IPv6SubnetPrefix are the prefix lengths of the
+	// subnets the source addresses are tracked by, 24 and 64 if zero
+	IPv4SubnetPrefix int
+	IPv6SubnetPrefix int
+
+	// OnEvent is called for every PSKAuthEvent, if non-nil. It must not
+	// block, as it is called by the handshake.
+	OnEvent func(PSKAuthEvent)
+
+	mu         sync.Mutex
+	identities *lruMap
+	addresses  *lruMap
+	now        func() time.Time
+}
+
+type pskAuthFailures struct {
+	count       int
+	lockedUntil time.Time
+}
+
+// check rejects an authentication from a locked out identity or address
+func (t *PSKAuthTracker) check(identity []byte, remoteAddr net.Addr) error {
+	t.mu.Lock()
+	now := t.timeNow()
+	identityFailures := t.peekFailures(t.identities, string(identity))
+	addressFailures := t.peekFailures(t.addresses, t.subnetKey(remoteAddr))
+
+	event := PSKAuthEvent{
+		Type:             PSKAuthRejected,
+		Identity:         identity,
+		RemoteAddr:       remoteAddr,
+		IdentityFailures: identityFailures.failures(),
+		AddressFailures:  addressFailures.failures(),
+	}
+	for _, f := range []*pskAuthFailures{identityFailures, addressFailures} {
+		if f != nil && f.lockedUntil.After(now) && f.lockedUntil.After(event.LockedUntil) {
+			event.LockedUntil = f.lockedUntil
+		}
+	}
+	t.mu.Unlock()
+
+	if event.LockedUntil.IsZero() {
+		return nil
+	}
+	t.report(event)
+	return errPSKAuthLockedOut
+}
+
+// fail counts a failed authentication, and locks out the identity and the
+// subnet which reached MaxFailures
+func (t *PSKAuthTracker) fail(identity []byte, remoteAddr net.Addr) {
+	t.mu.Lock()
+	now := t.timeNow()
+	if t.identities == nil {
+		t.identities = newLRUMap(pskAuthTrackerMaxEntries)
+		t.addresses = newLRUMap(pskAuthTrackerMaxEntries)
+	}
+
+	identityFailures := t.addFailure(t.identities, string(identity), now)
+	addressFailures := t.addFailure(t.addresses, t.subnetKey(remoteAddr), now)
+	event := PSKAuthEvent{
+		Type:             PSKAuthFailed,
+		Identity:         identity,
+		RemoteAddr:       remoteAddr,
+		IdentityFailures: identityFailures.count,
+		AddressFailures:  addressFailures.count,
+	}
+	for _, f := range []*pskAuthFailures{identityFailures, addressFailures} {
+		if f.lockedUntil.After(event.LockedUntil) {
+			event.LockedUntil = f.lockedUntil
+		}
+	}
+	t.mu.Unlock()
+
+	t.report(event)
+	if event.LockedUntil.After(now) {
+		event.Type = PSKAuthLockedOut
+		t.report(event)
+	}
+}
+
+// succeed resets the failures of the identity and the subnet
+func (t *PSKAuthTracker) succeed(identity []byte, remoteAddr net.Addr) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.identities != nil {
+		t.identities.remove(string(identity))
+		t.addresses.remove(t.subnetKey(remoteAddr))
+	}
+}
+
+// peekFailures returns the failures of a key, nil if none
+func (t *PSKAuthTracker) peekFailures(failures *lruMap, key string) *pskAuthFailures {
+	if failures == nil {
+		return nil
+	}
+	if f, ok := failures.peek(key); ok {
+		return f.(*pskAuthFailures) //nolint:forcetypeassert
+	}
+	return nil
+}
+
+// addFailure counts a failure of a key. The failure is not tracked if the
+// key is new, and every tracked key is locked out.
+func (t *PSKAuthTracker) addFailure(failures *lruMap, key string, now time.Time) *pskAuthFailures {
+	f := t.peekFailures(failures, key)
+	if f == nil {
+		f = &pskAuthFailures{}
+	}
+	failures.put(key, f, func(v interface{}) bool {
+		return !v.(*pskAuthFailures).lockedUntil.After(now) //nolint:forcetypeassert
+	})
+	f.count++
+
+	maxFailures := t.MaxFailures
+	if maxFailures <= 0 {
+		maxFailures = defaultPSKAuthMaxFailures
+	}
+	if f.count >= maxFailures {
+		f.lockedUntil = now.Add(t.backoff(f.count - maxFailures))
+	}
+	return f
+}
+
+// backoff returns the lockout after the given number of failures beyond
+// MaxFailures
+func (t *PSKAuthTracker) backoff(exceeded int) time.Duration {
+	backoff, maxBackoff := t.Backoff, t.MaxBackoff
+	if backoff <= 0 {
+		backoff = defaultPSKAuthBackoff
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = defaultPSKAuthMaxBackoff
+	}
+	for i := 0; i < exceeded && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		return maxBackoff
+	}
+	return backoff
+}
+
+func (t *PSKAuthTracker) report(event PSKAuthEvent) {
+	if t.OnEvent != nil {
+		t.OnEvent(event)
+	}
+}
+
+func (t *PSKAuthTracker) timeNow() time.Time {
+	if t.now != nil {
+		return t.now()
+	}
+	return time.Now()
+}
+
+func (f *pskAuthFailures) failures() int {
+	if f == nil {
+		return 0
+	}
+	return f.count
+}
+
+// subnetKey identifies a source address by its subnet, as a client may use
+// any port and address of it. Addresses which aren't IP addresses are
+// identified by their string representation.
+func (t *PSKAuthTracker) subnetKey(remoteAddr net.Addr) string {
+	addressKey, subnetKey := addressKeys(remoteAddr, t.IPv4SubnetPrefix, t.IPv6SubnetPrefix)
+	if subnetKey == "" {
+		return addressKey
+	}
+	return subnetKey
+}
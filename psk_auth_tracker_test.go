@@ -0,0 +1,126 @@
+package dtls
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestPSKAuthTracker(t *testing.T) {
+	now := time.Unix(1000, 0)
+	var events []PSKAuthEventType
+	tracker := &PSKAuthTracker{
+		MaxFailures: 2,
+		Backoff:     time.Second,
+		MaxBackoff:  4 * time.Second,
+		OnEvent: func(e PSKAuthEvent) {
+			events = append(events, e.Type)
+		},
+		now: func() time.Time { return now },
+	}
+
+	addr := &net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 5684}
+	otherPort := &net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 5685}
+	sameSubnet := &net.UDPAddr{IP: net.IPv4(192, 0, 2, 2), Port: 5684}
+	otherAddr := &net.UDPAddr{IP: net.IPv4(198, 51, 100, 1), Port: 5684}
+	identity := []byte("identity")
+
+	assertLocked := func(identity []byte, addr net.Addr, locked bool) {
+		t.Helper()
+		if err := tracker.check(identity, addr); locked != errors.Is(err, errPSKAuthLockedOut) {
+			t.Fatalf("check(%s, %s) at %s: locked(%v), got error(%v)", identity, addr, now, locked, err)
+		}
+	}
+
+	tracker.fail(identity, addr)
+	assertLocked(identity, addr, false)
+
+	// The second failure locks out the identity and the subnet of the
+	// address, whatever the port
+	tracker.fail(identity, addr)
+	assertLocked(identity, otherAddr, true)
+	assertLocked([]byte("other"), otherPort, true)
+	assertLocked([]byte("other"), sameSubnet, true)
+	assertLocked([]byte("other"), otherAddr, false)
+
+	now = now.Add(time.Second)
+	assertLocked(identity, addr, false)
+
+	// Further failures double the lockout, up to MaxBackoff
+	for _, lockout := range []time.Duration{2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		tracker.fail(identity, addr)
+		now = now.Add(lockout - time.Millisecond)
+		assertLocked(identity, addr, true)
+		now = now.Add(time.Millisecond)
+		assertLocked(identity, addr, false)
+	}
+
+	// A success resets the failures
+	tracker.succeed(identity, addr)
+	tracker.fail(identity, addr)
+	assertLocked(identity, addr, false)
+
+	// The least recently failed identities and subnets are forgotten
+	for i := 0; i < pskAuthTrackerMaxEntries; i++ {
+		tracker.fail([]byte{byte(i >> 8), byte(i)}, &net.UDPAddr{IP: net.IPv4(10, byte(i>>8), byte(i), 1), Port: 5684})
+	}
+	if n := tracker.identities.len() + tracker.addresses.len(); n != 2*pskAuthTrackerMaxEntries {
+		t.Errorf("tracked %d entries, expected %d", n, 2*pskAuthTrackerMaxEntries)
+	}
+	if tracker.peekFailures(tracker.identities, string(identity)) != nil {
+		t.Error("least recently failed identity not forgotten")
+	}
+	events = events[:len(events)-pskAuthTrackerMaxEntries]
+
+	expected := []PSKAuthEventType{
+		PSKAuthFailed,
+		PSKAuthFailed, PSKAuthLockedOut, PSKAuthRejected, PSKAuthRejected, PSKAuthRejected,
+		PSKAuthFailed, PSKAuthLockedOut, PSKAuthRejected,
+		PSKAuthFailed, PSKAuthLockedOut, PSKAuthRejected,
+		PSKAuthFailed, PSKAuthLockedOut, PSKAuthRejected,
+		PSKAuthFailed,
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("Events: expected(%v) actual(%v)", expected, events)
+	}
+	for i := range expected {
+		if events[i] != expected[i] {
+			t.Fatalf("Events: expected(%v) actual(%v)", expected, events)
+		}
+	}
+}
+
+func TestPSKAuthTrackerFlood(t *testing.T) {
+	now := time.Unix(1000, 0)
+	tracker := &PSKAuthTracker{
+		MaxFailures: 1,
+		now:         func() time.Time { return now },
+	}
+
+	addr := &net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 5684}
+	identity := []byte("identity")
+	tracker.fail(identity, addr)
+
+	// Failures of new identities and subnets don't evict lockouts, even
+	// once the tracker is full of them
+	for i := 0; i < 2*pskAuthTrackerMaxEntries; i++ {
+		tracker.fail([]byte{byte(i >> 8), byte(i)}, &net.UDPAddr{IP: net.IPv4(10, byte(i>>8), byte(i), 1), Port: 5684})
+	}
+	if n := tracker.identities.len(); n != pskAuthTrackerMaxEntries {
+		t.Errorf("tracked %d identities, expected %d", n, pskAuthTrackerMaxEntries)
+	}
+	if err := tracker.check(identity, &net.UDPAddr{IP: net.IPv4(198, 51, 100, 1), Port: 5684}); !errors.Is(err, errPSKAuthLockedOut) {
+		t.Errorf("flooded identity not locked out, got error(%v)", err)
+	}
+	if err := tracker.check([]byte("other"), addr); !errors.Is(err, errPSKAuthLockedOut) {
+		t.Errorf("flooded subnet not locked out, got error(%v)", err)
+	}
+
+	// Once the lockouts end, their entries make room for new failures
+	now = now.Add(defaultPSKAuthBackoff)
+	tracker.fail([]byte("new"), addr)
+	if err := tracker.check([]byte("new"), &net.UDPAddr{IP: net.IPv4(198, 51, 100, 1), Port: 5684}); !errors.Is(err, errPSKAuthLockedOut) {
+		t.Errorf("new identity not tracked, got error(%v)", err)
+	}
+}
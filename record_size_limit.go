@@ -0,0 +1,108 @@
+package dtls
+
+import (
+	"github.com/pion/dtls/v2/pkg/protocol/alert"
+	"github.com/pion/dtls/v2/pkg/protocol/extension"
+)
+
+// FragmentLength is a maximum record plaintext length a client can request
+// https://tools.ietf.org/html/rfc6066#section-4
+type FragmentLength = extension.FragmentLength
+
+// FragmentLength enums
+const (
+	FragmentLength512  FragmentLength = extension.FragmentLength512
+	FragmentLength1024 FragmentLength = extension.FragmentLength1024
+	FragmentLength2048 FragmentLength = extension.FragmentLength2048
+	FragmentLength4096 FragmentLength = extension.FragmentLength4096
+)
+
+const (
+	// RFC 8449 Section 4
+	minRecordSizeLimit = 64
+	// The largest record plaintext of DTLS 1.2
+	maxRecordSizeLimit = 1 << 14
+)
+
+// clientRecordSizeExtensions returns the extensions a client limits the size
+// of the records it receives with
+func clientRecordSizeExtensions(cfg *handshakeConfig) []extension.Extension {
+	extensions := []extension.Extension{}
+	if cfg.recordSizeLimit > 0 {
+		extensions = append(extensions, &extension.RecordSizeLimit{RecordSizeLimit: uint16(cfg.recordSizeLimit)})
+	}
+	if cfg.maxFragmentLength != 0 {
+		extensions = append(extensions, &extension.MaxFragmentLength{Length: cfg.maxFragmentLength})
+	}
+	return extensions
+}
+
+// negotiateRecordSize applies the record_size_limit or max_fragment_length
+// extension offered in the ClientHello, nil if absent. A server that supports
+// record_size_limit ignores max_fragment_length. RFC 8449 Section 5
+func negotiateRecordSize(state *State, cfg *handshakeConfig, recordSizeLimit *extension.RecordSizeLimit, maxFragmentLength *extension.MaxFragmentLength) (*alert.Alert, error) {
+	state.setLocalRecordSizeLimit(0)
+	state.remoteRecordSizeLimit = 0
+	state.recordSizeExtension = nil
+
+	switch {
+	case recordSizeLimit != nil:
+		if recordSizeLimit.RecordSizeLimit < minRecordSizeLimit {
+			return &alert.Alert{Level: alert.Fatal, Description: alert.IllegalParameter}, errInvalidRecordSizeExtension
+		}
+		state.remoteRecordSizeLimit = minInt(int(recordSizeLimit.RecordSizeLimit), maxRecordSizeLimit)
+
+		// Answer even without a limit of our own, so the client knows its
+		// limit is honored
+		limit := maxRecordSizeLimit
+		if cfg.recordSizeLimit > 0 {
+			limit = cfg.recordSizeLimit
+			state.setLocalRecordSizeLimit(limit)
+		}
+		state.recordSizeExtension = &extension.RecordSizeLimit{RecordSizeLimit: uint16(limit)}
+	case maxFragmentLength != nil:
+		size := maxFragmentLength.Length.Size()
+		if size == 0 {
+			return &alert.Alert{Level: alert.Fatal, Description: alert.IllegalParameter}, errInvalidRecordSizeExtension
+		}
+		state.setLocalRecordSizeLimit(size)
+		state.remoteRecordSizeLimit = size
+		state.recordSizeExtension = &extension.MaxFragmentLength{Length: maxFragmentLength.Length}
+	}
+	return nil, nil //nolint:nilnil
+}
+
+// handleServerRecordSize applies the record_size_limit or max_fragment_length
+// extension a server answered the ClientHello with, nil if absent
+func handleServerRecordSize(state *State, cfg *handshakeConfig, recordSizeLimit *extension.RecordSizeLimit, maxFragmentLength *extension.MaxFragmentLength) (*alert.Alert, error) {
+	state.setLocalRecordSizeLimit(0)
+	state.remoteRecordSizeLimit = 0
+
+	switch {
+	case (recordSizeLimit != nil && cfg.recordSizeLimit == 0) || (maxFragmentLength != nil && cfg.maxFragmentLength == 0):
+		return &alert.Alert{Level: alert.Fatal, Description: alert.UnsupportedExtension}, errInvalidRecordSizeExtension
+	case recordSizeLimit != nil && maxFragmentLength != nil:
+		// RFC 8449 Section 5
+		return &alert.Alert{Level: alert.Fatal, Description: alert.IllegalParameter}, errInvalidRecordSizeExtension
+	case recordSizeLimit != nil:
+		if recordSizeLimit.RecordSizeLimit < minRecordSizeLimit {
+			return &alert.Alert{Level: alert.Fatal, Description: alert.IllegalParameter}, errInvalidRecordSizeExtension
+		}
+		state.setLocalRecordSizeLimit(cfg.recordSizeLimit)
+		state.remoteRecordSizeLimit = minInt(int(recordSizeLimit.RecordSizeLimit), maxRecordSizeLimit)
+	case maxFragmentLength != nil:
+		if maxFragmentLength.Length != cfg.maxFragmentLength {
+			return &alert.Alert{Level: alert.Fatal, Description: alert.IllegalParameter}, errInvalidRecordSizeExtension
+		}
+		state.setLocalRecordSizeLimit(cfg.maxFragmentLength.Size())
+		state.remoteRecordSizeLimit = cfg.maxFragmentLength.Size()
+	}
+	return nil, nil //nolint:nilnil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -10,7 +10,7 @@ func Resume(state *State, conn net.Conn, config *Config) (*Conn, error) {
 	if err := state.initCipherSuite(); err != nil {
 		return nil, err
 	}
-	c, err := createConn(context.Background(), conn, config, state.isClient, state)
+	c, err := createConn(context.Background(), conn, config, state.isClient, state, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,132 @@
+package dtls
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+)
+
+// RevocationChecker checks whether a certificate of the chains a peer
+// certificate was verified with has been revoked. Implementations may
+// consult any source, such as CRLs or an OCSP responder.
+type RevocationChecker interface {
+	// CheckRevocation returns a non-nil error if a certificate of the
+	// verified chains has been revoked, or its status can't be determined
+	// and the checker fails closed
+	CheckRevocation(verifiedChains [][]*x509.Certificate) error
+}
+
+// CRLRevocationChecker is a RevocationChecker consulting CRLs loaded from
+// local files. The files are loaded again once they are older than the
+// reload interval, so CRLs can be replaced on disk while running. A CRL is
+// only used between its ThisUpdate and NextUpdate times.
+type CRLRevocationChecker struct {
+	paths          []string
+	reloadInterval time.Duration
+
+	mu       sync.Mutex
+	crls     []*x509.RevocationList
+	loadedAt time.Time
+}
+
+// NewCRLRevocationChecker creates a CRLRevocationChecker with the DER or PEM
+// encoded CRLs of the given files. A reloadInterval of zero disables
+// reloading, except by calling Reload.
+func NewCRLRevocationChecker(paths []string, reloadInterval time.Duration) (*CRLRevocationChecker, error) {
+	c := &CRLRevocationChecker{
+		paths:          paths,
+		reloadInterval: reloadInterval,
+	}
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Reload loads the CRLs from their files. If a file can't be loaded, the
+// previously loaded CRLs are kept and the error is returned. Either way,
+// the next reload is due after the reload interval.
+func (c *CRLRevocationChecker) Reload() error {
+	crls, err := loadCRLs(c.paths)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loadedAt = time.Now()
+	if err != nil {
+		return err
+	}
+	c.crls = crls
+	return nil
+}
+
+func loadCRLs(paths []string) ([]*x509.RevocationList, error) {
+	crls := make([]*x509.RevocationList, 0, len(paths))
+	for _, path := range paths {
+		raw, err := ioutil.ReadFile(path) //nolint:gosec
+		if err != nil {
+			return nil, err
+		}
+		if block, _ := pem.Decode(raw); block != nil && block.Type == "X509 CRL" {
+			raw = block.Bytes
+		}
+		crl, err := x509.ParseRevocationList(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		crls = append(crls, crl)
+	}
+	return crls, nil
+}
+
+// CheckRevocation implements RevocationChecker. A certificate is revoked if
+// it is listed in a CRL signed by its issuer in the chain. It fails closed
+// if a CRL of the issuer is not current. CRLs due to be reloaded are
+// reloaded by a single check, while the others keep using the loaded CRLs.
+// A failed reload keeps the loaded CRLs, until they are no longer current.
+func (c *CRLRevocationChecker) CheckRevocation(verifiedChains [][]*x509.Certificate) error {
+	c.mu.Lock()
+	reload := c.reloadInterval > 0 && time.Since(c.loadedAt) >= c.reloadInterval
+	if reload {
+		c.loadedAt = time.Now()
+	}
+	c.mu.Unlock()
+
+	if reload {
+		_ = c.Reload()
+	}
+
+	c.mu.Lock()
+	crls := c.crls
+	c.mu.Unlock()
+
+	now := time.Now()
+	for _, chain := range verifiedChains {
+		for i := 0; i+1 < len(chain); i++ {
+			if err := checkCRLs(crls, chain[i], chain[i+1], now); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func checkCRLs(crls []*x509.RevocationList, cert, issuer *x509.Certificate, now time.Time) error {
+	for _, crl := range crls {
+		// The signature identifies the CRLs issued for the certificate
+		if crl.CheckSignatureFrom(issuer) != nil {
+			continue
+		}
+		if now.Before(crl.ThisUpdate) || (!crl.NextUpdate.IsZero() && now.After(crl.NextUpdate)) {
+			return fmt.Errorf("%w: issuer %s", errCRLNotCurrent, issuer.Subject)
+		}
+		for _, revoked := range crl.RevokedCertificateEntries {
+			if revoked.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+				return fmt.Errorf("%w: serial number %s", errCertificateListed, cert.SerialNumber)
+			}
+		}
+	}
+	return nil
+}
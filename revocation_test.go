@@ -0,0 +1,202 @@
+package dtls
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pion/dtls/v2/internal/net/dpipe"
+	"github.com/pion/dtls/v2/pkg/protocol/alert"
+	"github.com/pion/transport/test"
+)
+
+func writeTestCRL(t *testing.T, path string, ca *x509.Certificate, caKey *ecdsa.PrivateKey, nextUpdate time.Time, revoked ...*big.Int) {
+	revokedCerts := []x509.RevocationListEntry{}
+	for _, serial := range revoked {
+		revokedCerts = append(revokedCerts, x509.RevocationListEntry{SerialNumber: serial, RevocationTime: time.Now()})
+	}
+	crl, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		RevokedCertificateEntries: revokedCerts,
+		Number:                    big.NewInt(time.Now().UnixNano()),
+		ThisUpdate:                nextUpdate.Add(-2 * time.Hour),
+		NextUpdate:                nextUpdate,
+	}, ca, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, crl, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCRLRevocationChecker(t *testing.T) {
+	dir, err := ioutil.TempDir("", "crl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	path := filepath.Join(dir, "ca.crl")
+
+	ca, caKey, cert := createTestCertificates(t)
+	otherCA, otherCAKey, _ := createTestCertificates(t)
+	chains := [][]*x509.Certificate{{cert.Leaf, ca}}
+
+	if _, err = NewCRLRevocationChecker([]string{path}, 0); err == nil {
+		t.Fatal("Expected error loading a missing CRL")
+	}
+
+	// A CRL of another CA doesn't revoke the certificate
+	writeTestCRL(t, path, otherCA, otherCAKey, time.Now().Add(time.Hour), cert.Leaf.SerialNumber)
+	checker, err := NewCRLRevocationChecker([]string{path}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = checker.CheckRevocation(chains); err != nil {
+		t.Errorf("Certificate revoked by a CRL of another CA: %v", err)
+	}
+
+	writeTestCRL(t, path, ca, caKey, time.Now().Add(time.Hour), cert.Leaf.SerialNumber)
+	if err = checker.CheckRevocation(chains); err != nil {
+		t.Errorf("CRL reloaded without a reload interval: %v", err)
+	}
+	if err = checker.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if err = checker.CheckRevocation(chains); !errors.Is(err, errCertificateListed) {
+		t.Errorf("Expected error: %v, got: %v", errCertificateListed, err)
+	}
+
+	// With a reload interval, the updated CRL is picked up by the check
+	checker, err = NewCRLRevocationChecker([]string{path}, time.Nanosecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestCRL(t, path, ca, caKey, time.Now().Add(time.Hour), big.NewInt(42))
+	if err = checker.CheckRevocation(chains); err != nil {
+		t.Errorf("Certificate revoked after reload: %v", err)
+	}
+
+	// The loaded CRLs are kept if they can't be reloaded
+	if err = os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err = checker.Reload(); err == nil {
+		t.Error("Expected error reloading a missing CRL")
+	}
+	if err = checker.CheckRevocation(chains); err != nil {
+		t.Errorf("Loaded CRLs not kept after a failed reload: %v", err)
+	}
+
+	// Fail closed if a CRL of the issuer is not current
+	writeTestCRL(t, path, ca, caKey, time.Now().Add(-time.Minute))
+	if err = checker.CheckRevocation(chains); !errors.Is(err, errCRLNotCurrent) {
+		t.Errorf("Expected error: %v, got: %v", errCRLNotCurrent, err)
+	}
+	writeTestCRL(t, path, ca, caKey, time.Now().Add(3*time.Hour))
+	if err = checker.CheckRevocation(chains); !errors.Is(err, errCRLNotCurrent) {
+		t.Errorf("Expected error: %v, got: %v", errCRLNotCurrent, err)
+	}
+}
+
+type revocationCheckerFunc func([][]*x509.Certificate) error
+
+func (f revocationCheckerFunc) CheckRevocation(verifiedChains [][]*x509.Certificate) error {
+	return f(verifiedChains)
+}
+
+func TestRevocationChecker(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	errRevoked := errors.New("revoked") //nolint:goerr113
+	revoked := revocationCheckerFunc(func([][]*x509.Certificate) error { return errRevoked })
+	notRevoked := revocationCheckerFunc(func([][]*x509.Certificate) error { return nil })
+
+	for name, tt := range map[string]struct {
+		clientChecker, serverChecker RevocationChecker
+		wantClientErr, wantServerErr error
+	}{
+		"NotRevoked": {
+			clientChecker: notRevoked,
+			serverChecker: notRevoked,
+		},
+		"ServerRevoked": {
+			clientChecker: revoked,
+			serverChecker: notRevoked,
+			wantClientErr: errCertificateRevoked,
+			wantServerErr: &alertError{&alert.Alert{Level: alert.Fatal, Description: alert.CertificateRevoked}},
+		},
+		"ClientRevoked": {
+			clientChecker: notRevoked,
+			serverChecker: revoked,
+			wantClientErr: &alertError{&alert.Alert{Level: alert.Fatal, Description: alert.CertificateRevoked}},
+			wantServerErr: errCertificateRevoked,
+		},
+	} {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			caCert, _, cert := createTestCertificates(t)
+			roots := x509.NewCertPool()
+			roots.AddCert(caCert)
+			cipherSuites := []CipherSuiteID{TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}
+
+			ca, cb := dpipe.Pipe()
+			type result struct {
+				c   *Conn
+				err error
+			}
+			c := make(chan result)
+
+			go func() {
+				client, err := ClientWithContext(context.Background(), ca, &Config{
+					CipherSuites:      cipherSuites,
+					Certificates:      []tls.Certificate{cert},
+					RootCAs:           roots,
+					ServerName:        "localhost",
+					RevocationChecker: tt.clientChecker,
+				})
+				c <- result{client, err}
+			}()
+
+			server, err := ServerWithContext(context.Background(), cb, &Config{
+				CipherSuites:      cipherSuites,
+				Certificates:      []tls.Certificate{cert},
+				ClientAuth:        RequireAndVerifyClientCert,
+				ClientCAs:         roots,
+				RevocationChecker: tt.serverChecker,
+			})
+			res := <-c
+			defer func() {
+				if err == nil {
+					_ = server.Close()
+				}
+				if res.err == nil {
+					_ = res.c.Close()
+				}
+			}()
+
+			if !errors.Is(res.err, tt.wantClientErr) {
+				t.Errorf("Client error expected: \"%v\", got: \"%v\"", tt.wantClientErr, res.err)
+			}
+			if !errors.Is(err, tt.wantServerErr) {
+				t.Errorf("Server error expected: \"%v\", got: \"%v\"", tt.wantServerErr, err)
+			}
+		})
+	}
+}
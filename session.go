@@ -6,6 +6,9 @@ type Session struct {
 	ID []byte
 	// Secret store session master secret
 	Secret []byte
+	// Ticket store the session ticket issued by the server, if any.
+	// Only used by clients.
+	Ticket []byte
 }
 
 // SessionStore defines methods needed for session resumption.
@@ -0,0 +1,294 @@
+package dtls
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/pion/dtls/v2/pkg/protocol/handshake"
+	"golang.org/x/crypto/cryptobyte"
+)
+
+const (
+	// SessionTicketKeyLength is the length of a session ticket key
+	SessionTicketKeyLength = 32
+
+	// defaultSessionTicketLifetime is how long a session can be resumed
+	// with tickets if Config.SessionTicketLifetime is zero
+	defaultSessionTicketLifetime = 24 * time.Hour
+	// maxSessionTicketKeys limits how many previous keys are kept by Rotate
+	maxSessionTicketKeys = 8
+
+	sessionTicketKeyNameLength = 16
+	sessionTicketNonceLength   = 12
+)
+
+// SessionTicketKeys is the set of keys a server uses to protect session
+// tickets (RFC 5077). New tickets are encrypted with the first key, while
+// tickets encrypted with any key of the set are accepted. A single
+// SessionTicketKeys can be shared by the Config of several servers so that
+// any of them can resume a session without a shared SessionStore.
+// SessionTicketKeys is safe for concurrent use.
+type SessionTicketKeys struct {
+	mu   sync.RWMutex
+	keys []sessionTicketKey
+}
+
+type sessionTicketKey struct {
+	name [sessionTicketKeyNameLength]byte
+	aead cipher.AEAD
+}
+
+// sessionTicketState is the session state sealed into a ticket
+type sessionTicketState struct {
+	cipherSuiteID        CipherSuiteID
+	extendedMasterSecret bool
+	createdAt            time.Time
+	masterSecret         []byte
+	// identity is the PSK identity of the client, if any
+	identity []byte
+	// serverName is the server name indicated by the client, if any
+	serverName string
+}
+
+// NewSessionTicketKeys creates a key set from one or more keys, the first of
+// which is used to encrypt new tickets. Keys should be generated with a
+// cryptographically secure random number generator.
+func NewSessionTicketKeys(keys ...[SessionTicketKeyLength]byte) (*SessionTicketKeys, error) {
+	k := &SessionTicketKeys{}
+	if err := k.SetKeys(keys...); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// SetKeys replaces all keys of the set. Tickets encrypted with a key that is
+// no longer part of the set can not be used for resumption anymore.
+func (k *SessionTicketKeys) SetKeys(keys ...[SessionTicketKeyLength]byte) error {
+	if len(keys) == 0 {
+		return errNoSessionTicketKeys
+	}
+
+	ticketKeys := make([]sessionTicketKey, 0, len(keys))
+	for _, key := range keys {
+		ticketKey, err := newSessionTicketKey(key)
+		if err != nil {
+			return err
+		}
+		ticketKeys = append(ticketKeys, ticketKey)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys = ticketKeys
+	return nil
+}
+
+// Rotate makes key the key used to encrypt new tickets. Previous keys are
+// still accepted for decryption, up to a limit of the most recent ones.
+func (k *SessionTicketKeys) Rotate(key [SessionTicketKeyLength]byte) error {
+	ticketKey, err := newSessionTicketKey(key)
+	if err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys = append([]sessionTicketKey{ticketKey}, k.keys...)
+	if len(k.keys) > maxSessionTicketKeys {
+		k.keys = k.keys[:maxSessionTicketKeys]
+	}
+	return nil
+}
+
+// The key name and the AES-256-GCM key are derived from the configured key
+// so keys can be distributed as a single random value.
+func newSessionTicketKey(key [SessionTicketKeyLength]byte) (sessionTicketKey, error) {
+	hashed := sha512.Sum512(key[:])
+
+	block, err := aes.NewCipher(hashed[sessionTicketKeyNameLength : sessionTicketKeyNameLength+32])
+	if err != nil {
+		return sessionTicketKey{}, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return sessionTicketKey{}, err
+	}
+
+	ticketKey := sessionTicketKey{aead: aead}
+	copy(ticketKey.name[:], hashed[:sessionTicketKeyNameLength])
+	return ticketKey, nil
+}
+
+// encrypt seals the session state into a ticket of the form
+// key_name | nonce | ciphertext
+func (k *SessionTicketKeys) encrypt(s *sessionTicketState) ([]byte, error) {
+	k.mu.RLock()
+	if len(k.keys) == 0 {
+		k.mu.RUnlock()
+		return nil, errNoSessionTicketKeys
+	}
+	key := k.keys[0]
+	k.mu.RUnlock()
+
+	plaintext, err := s.marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, sessionTicketKeyNameLength+sessionTicketNonceLength, sessionTicketKeyNameLength+sessionTicketNonceLength+len(plaintext)+key.aead.Overhead())
+	copy(out, key.name[:])
+	nonce := out[sessionTicketKeyNameLength:]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return key.aead.Seal(out, nonce, plaintext, key.name[:]), nil
+}
+
+// decrypt opens a ticket, reporting false if it was not issued with one of
+// the keys of the set or has been tampered with
+func (k *SessionTicketKeys) decrypt(ticket []byte) (*sessionTicketState, bool) {
+	if len(ticket) < sessionTicketKeyNameLength+sessionTicketNonceLength {
+		return nil, false
+	}
+	name := ticket[:sessionTicketKeyNameLength]
+	nonce := ticket[sessionTicketKeyNameLength : sessionTicketKeyNameLength+sessionTicketNonceLength]
+	ciphertext := ticket[sessionTicketKeyNameLength+sessionTicketNonceLength:]
+
+	k.mu.RLock()
+	var aead cipher.AEAD
+	for _, key := range k.keys {
+		if subtle.ConstantTimeCompare(name, key.name[:]) == 1 {
+			aead = key.aead
+			break
+		}
+	}
+	k.mu.RUnlock()
+	if aead == nil {
+		return nil, false
+	}
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, name)
+	if err != nil {
+		return nil, false
+	}
+
+	s := &sessionTicketState{}
+	if !s.unmarshal(plaintext) {
+		return nil, false
+	}
+	return s, true
+}
+
+func (s *sessionTicketState) marshal() ([]byte, error) {
+	var b cryptobyte.Builder
+	b.AddUint16(uint16(s.cipherSuiteID))
+	if s.extendedMasterSecret {
+		b.AddUint8(1)
+	} else {
+		b.AddUint8(0)
+	}
+	createdAt := make([]byte, 8)
+	binary.BigEndian.PutUint64(createdAt, uint64(s.createdAt.Unix()))
+	b.AddBytes(createdAt)
+	b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddBytes(s.masterSecret)
+	})
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddBytes(s.identity)
+	})
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddBytes([]byte(s.serverName))
+	})
+	return b.Bytes()
+}
+
+func (s *sessionTicketState) unmarshal(data []byte) bool {
+	val := cryptobyte.String(data)
+	var cipherSuiteID uint16
+	var extendedMasterSecret uint8
+	var createdAt []byte
+	var masterSecret, identity, serverName cryptobyte.String
+	if !val.ReadUint16(&cipherSuiteID) ||
+		!val.ReadUint8(&extendedMasterSecret) ||
+		!val.ReadBytes(&createdAt, 8) ||
+		!val.ReadUint8LengthPrefixed(&masterSecret) ||
+		!val.ReadUint16LengthPrefixed(&identity) ||
+		!val.ReadUint16LengthPrefixed(&serverName) ||
+		!val.Empty() {
+		return false
+	}
+
+	s.cipherSuiteID = CipherSuiteID(cipherSuiteID)
+	s.extendedMasterSecret = extendedMasterSecret == 1
+	s.createdAt = time.Unix(int64(binary.BigEndian.Uint64(createdAt)), 0)
+	s.masterSecret = append([]byte{}, masterSecret...)
+	if len(identity) > 0 {
+		s.identity = append([]byte{}, identity...)
+	}
+	s.serverName = string(serverName)
+	return true
+}
+
+// resumable reports if the session of a ticket can be resumed with the
+// parameters negotiated in the current ClientHello, within the lifetime of
+// the session.
+// A session must not be resumed with a different Extended Master Secret
+// state, RFC 7627 Section 5.3, nor for a different server name, RFC 6066
+// Section 3.
+func (s *sessionTicketState) resumable(state *State, now time.Time, lifetime time.Duration) bool {
+	return state.cipherSuite != nil &&
+		s.cipherSuiteID == state.cipherSuite.ID() &&
+		s.extendedMasterSecret == state.extendedMasterSecret &&
+		s.serverName == state.serverName &&
+		now.Sub(s.createdAt) < lifetime
+}
+
+// newSessionTicketHandshake builds the NewSessionTicket a server sends for
+// the current session. The ticket is cached so retransmitted flights carry
+// the same ticket. Sessions authenticated with a client certificate get an
+// empty ticket, as the certificate is not part of the ticket.
+// A ticket issued on resumption keeps the creation time of the session, so
+// resuming doesn't extend its lifetime.
+func newSessionTicketHandshake(state *State, cfg *handshakeConfig) (*handshake.Handshake, error) {
+	createdAt := state.sessionCreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	lifetime := cfg.sessionTicketLifetime - time.Since(createdAt)
+
+	if state.localSessionTicket == nil {
+		ticket := []byte{}
+		if state.PeerCertificates == nil && lifetime > 0 {
+			var err error
+			ticket, err = cfg.sessionTicketKeys.encrypt(&sessionTicketState{
+				cipherSuiteID:        state.cipherSuite.ID(),
+				extendedMasterSecret: state.extendedMasterSecret,
+				createdAt:            createdAt,
+				masterSecret:         state.masterSecret,
+				identity:             state.IdentityHint,
+				serverName:           state.serverName,
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		state.localSessionTicket = ticket
+	}
+
+	var lifetimeHint uint32
+	if len(state.localSessionTicket) > 0 && lifetime > 0 {
+		lifetimeHint = uint32(lifetime / time.Second)
+	}
+	return &handshake.Handshake{
+		Message: &handshake.MessageNewSessionTicket{
+			TicketLifetimeHint: lifetimeHint,
+			Ticket:             state.localSessionTicket,
+		},
+	}, nil
+}
@@ -0,0 +1,225 @@
+package dtls
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pion/dtls/v2/pkg/protocol/handshake"
+	"github.com/pion/logging"
+)
+
+func TestSessionTicketKeys(t *testing.T) {
+	key1 := [SessionTicketKeyLength]byte{1}
+	key2 := [SessionTicketKeyLength]byte{2}
+
+	if _, err := NewSessionTicketKeys(); !errors.Is(err, errNoSessionTicketKeys) {
+		t.Fatalf("Expected error: %v, got: %v", errNoSessionTicketKeys, err)
+	}
+
+	keys, err := NewSessionTicketKeys(key1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &sessionTicketState{
+		cipherSuiteID:        TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+		extendedMasterSecret: true,
+		createdAt:            time.Unix(1<<33, 0),
+		masterSecret:         []byte{0x01, 0x02, 0x03},
+		identity:             []byte("client"),
+		serverName:           "example.com",
+	}
+	ticket, err := keys.encrypt(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decrypted, ok := keys.decrypt(ticket)
+	if !ok {
+		t.Fatal("failed to decrypt ticket")
+	}
+	if decrypted.cipherSuiteID != s.cipherSuiteID ||
+		decrypted.extendedMasterSecret != s.extendedMasterSecret ||
+		!decrypted.createdAt.Equal(s.createdAt) ||
+		!bytes.Equal(decrypted.masterSecret, s.masterSecret) ||
+		!bytes.Equal(decrypted.identity, s.identity) ||
+		decrypted.serverName != s.serverName {
+		t.Errorf("ticket state mismatch: got %#v, want %#v", decrypted, s)
+	}
+
+	tampered := append([]byte{}, ticket...)
+	tampered[len(tampered)-1] ^= 0xff
+	if _, ok := keys.decrypt(tampered); ok {
+		t.Error("tampered ticket must not decrypt")
+	}
+
+	// Rotated out keys are still accepted, new tickets use the new key
+	if err := keys.Rotate(key2); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := keys.decrypt(ticket); !ok {
+		t.Error("ticket of a rotated key must decrypt")
+	}
+	newTicket, err := keys.encrypt(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(newTicket[:sessionTicketKeyNameLength], ticket[:sessionTicketKeyNameLength]) {
+		t.Error("new ticket must use the rotated key")
+	}
+
+	// Replacing the keys invalidates older tickets
+	if err := keys.SetKeys(key2); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := keys.decrypt(ticket); ok {
+		t.Error("ticket of a removed key must not decrypt")
+	}
+	if _, ok := keys.decrypt(newTicket); !ok {
+		t.Error("ticket of a current key must decrypt")
+	}
+}
+
+func TestNewSessionTicketHandshakeLifetime(t *testing.T) {
+	keys, err := NewSessionTicketKeys([SessionTicketKeyLength]byte{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &handshakeConfig{
+		sessionTicketKeys:     keys,
+		sessionTicketLifetime: time.Hour,
+	}
+
+	for _, test := range []struct {
+		name      string
+		createdAt time.Duration
+		issued    bool
+	}{
+		{"NewSession", 0, true},
+		{"ResumedSession", 30 * time.Minute, true},
+		{"ExpiredSession", 2 * time.Hour, false},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			state := &State{
+				cipherSuite:  cipherSuiteForID(TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, nil),
+				masterSecret: []byte{0x01, 0x02, 0x03},
+			}
+			if test.createdAt != 0 {
+				state.sessionCreatedAt = time.Now().Add(-test.createdAt)
+			}
+
+			h, err := newSessionTicketHandshake(state, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			msg, ok := h.Message.(*handshake.MessageNewSessionTicket)
+			if !ok {
+				t.Fatalf("unexpected message: %T", h.Message)
+			}
+			if !test.issued {
+				if len(msg.Ticket) != 0 || msg.TicketLifetimeHint != 0 {
+					t.Fatalf("expired session must get an empty ticket: %#v", msg)
+				}
+				return
+			}
+
+			s, ok := keys.decrypt(msg.Ticket)
+			if !ok {
+				t.Fatal("failed to decrypt ticket")
+			}
+			// A re-issued ticket keeps the creation time of the session
+			if !state.sessionCreatedAt.IsZero() && s.createdAt.Unix() != state.sessionCreatedAt.Unix() {
+				t.Errorf("createdAt: expected(%v) actual(%v)", state.sessionCreatedAt, s.createdAt)
+			}
+			remaining := uint32((cfg.sessionTicketLifetime - test.createdAt) / time.Second)
+			if msg.TicketLifetimeHint > remaining || msg.TicketLifetimeHint+1 < remaining {
+				t.Errorf("TicketLifetimeHint: expected(%d) actual(%d)", remaining, msg.TicketLifetimeHint)
+			}
+			if !s.resumable(state, time.Now(), cfg.sessionTicketLifetime) {
+				t.Error("session must be resumable within its lifetime")
+			}
+			if s.resumable(state, s.createdAt.Add(cfg.sessionTicketLifetime), cfg.sessionTicketLifetime) {
+				t.Error("session must not be resumable after its lifetime")
+			}
+		})
+	}
+}
+
+func TestHandleHelloResumeTicket(t *testing.T) {
+	keys, err := NewSessionTicketKeys([SessionTicketKeyLength]byte{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionID := []byte{0x01, 0x02, 0x03}
+	masterSecret := make([]byte, 48)
+
+	for _, test := range []struct {
+		name       string
+		ticket     sessionTicketState
+		serverName string
+		resumed    bool
+	}{
+		{
+			name:       "SameServerName",
+			ticket:     sessionTicketState{serverName: "example.com"},
+			serverName: "example.com",
+			resumed:    true,
+		},
+		{
+			name:       "DifferentServerName",
+			ticket:     sessionTicketState{serverName: "example.com"},
+			serverName: "example.org",
+		},
+		{
+			name:    "KnownIdentity",
+			ticket:  sessionTicketState{identity: []byte("known")},
+			resumed: true,
+		},
+		{
+			name:   "RevokedIdentity",
+			ticket: sessionTicketState{identity: []byte("revoked")},
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			cfg := &handshakeConfig{
+				sessionTicketKeys:     keys,
+				sessionTicketLifetime: time.Hour,
+				localPSKCallback: func(identity []byte) ([]byte, error) {
+					if string(identity) != "known" {
+						return nil, ErrUnknownPSKIdentity
+					}
+					return []byte{0xAB, 0xC1, 0x23}, nil
+				},
+				log: logging.NewDefaultLoggerFactory().NewLogger("dtls"),
+			}
+			state := &State{
+				cipherSuite:     cipherSuiteForID(TLS_PSK_WITH_AES_128_GCM_SHA256, nil),
+				serverName:      test.serverName,
+				clientHelloInfo: &ClientHelloInfo{},
+			}
+
+			test.ticket.cipherSuiteID = TLS_PSK_WITH_AES_128_GCM_SHA256
+			test.ticket.createdAt = time.Now()
+			test.ticket.masterSecret = masterSecret
+			ticket, err := keys.encrypt(&test.ticket)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			next, _, err := handleHelloResume(context.Background(), sessionID, ticket, state, cfg, flight2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resumed := next == flight4b; resumed != test.resumed {
+				t.Fatalf("resumed: expected(%v) actual(%v)", test.resumed, resumed)
+			}
+			if test.resumed && !bytes.Equal(state.IdentityHint, test.ticket.identity) {
+				t.Errorf("IdentityHint: expected(%v) actual(%v)", test.ticket.identity, state.IdentityHint)
+			}
+		})
+	}
+}
@@ -2,11 +2,17 @@ package dtls
 
 import (
 	"bytes"
+	"crypto/tls"
 	"encoding/gob"
 	"sync/atomic"
+	"time"
 
+	"github.com/pion/dtls/v2/pkg/crypto/clientcertificate"
+	"github.com/pion/dtls/v2/pkg/crypto/ecjpake"
 	"github.com/pion/dtls/v2/pkg/crypto/elliptic"
 	"github.com/pion/dtls/v2/pkg/crypto/prf"
+	"github.com/pion/dtls/v2/pkg/crypto/signaturehash"
+	"github.com/pion/dtls/v2/pkg/protocol/extension"
 	"github.com/pion/dtls/v2/pkg/protocol/handshake"
 	"github.com/pion/transport/replaydetector"
 )
@@ -28,6 +34,7 @@ type State struct {
 
 	preMasterSecret      []byte
 	extendedMasterSecret bool
+	encryptThenMAC       bool
 
 	namedCurve                 elliptic.Curve
 	localKeypair               *elliptic.Keypair
@@ -35,16 +42,100 @@ type State struct {
 	handshakeSendSequence      int
 	handshakeRecvSequence      int
 	serverName                 string
-	remoteRequestedCertificate bool   // Did we get a CertificateRequest
-	localCertificatesVerify    []byte // cache CertificateVerify
-	localVerifyData            []byte // cached VerifyData
-	localKeySignature          []byte // cached keySignature
-	peerCertificatesVerified   bool
+	remoteRequestedCertificate bool // Did we get a CertificateRequest
+
+	// remoteCertificateTypes and remoteCertificateAuthorities are the types
+	// of certificate and the authorities accepted by the CertificateRequest
+	remoteCertificateTypes       []clientcertificate.Type
+	remoteCertificateAuthorities [][]byte
+
+	localCertificatesVerify  []byte // cache CertificateVerify
+	localVerifyData          []byte // cached VerifyData
+	localKeySignature        []byte // cached keySignature
+	peerCertificatesVerified bool
+
+	// clientHelloInfo describes the ClientHello received by a server
+	clientHelloInfo *ClientHelloInfo
+	// localPSKIdentity is the PSK identity sent by a client
+	localPSKIdentity []byte
+	// pskAuthPending is set once a server looked up the pre-shared key of
+	// the client, until the outcome of the handshake is reported to the
+	// PSKAuthTracker
+	pskAuthPending bool
+	// handshakeDecryptFailed is set with atomic operations by the read loop
+	// when an encrypted handshake record fails to decrypt, which means the
+	// keys of the peer differ
+	handshakeDecryptFailed int32
+
+	// localCertificate is the certificate a server selected for the
+	// ClientHello, nil if none
+	localCertificate *tls.Certificate
 
 	replayDetector []replaydetector.ReplayDetector
 
+	// sessionTicket is the ticket offered by a client in its ClientHello
+	sessionTicket []byte
+	// useSessionTicket is set when the ServerHello carries the session_ticket
+	// extension, meaning the server sends a NewSessionTicket
+	useSessionTicket bool
+	// localSessionTicket caches the ticket issued by a server
+	localSessionTicket []byte
+	// sessionCreatedAt is the creation time of a session a server resumed
+	// from a ticket, zero for a new session
+	sessionCreatedAt time.Time
+
 	peerSupportedProtocols []string
 	NegotiatedProtocol     string
+
+	// Certificate types negotiated with the client_certificate_type and
+	// server_certificate_type extensions (RFC 7250)
+	localCertificateType, remoteCertificateType CertificateType
+	// certificateTypeExtensions are the extensions a server answers
+	// the offered certificate types with
+	certificateTypeExtensions []extension.Extension
+
+	// localRecordSizeLimit is the largest record plaintext we accept, and
+	// remoteRecordSizeLimit the largest the peer accepts, as negotiated with
+	// the record_size_limit or max_fragment_length extension. Zero if none.
+	localRecordSizeLimit  atomic.Value
+	remoteRecordSizeLimit int
+	// recordSizeExtension is the extension a server answers the offered
+	// record size limit with
+	recordSizeExtension extension.Extension
+
+	// remoteRequestedCertificateStatus is set when the ClientHello carries
+	// the status_request extension for OCSP, certificateStatusNegotiated
+	// when the ServerHello acknowledges it, and ocspResponse holds the
+	// response stapled by the server, nil if none
+	remoteRequestedCertificateStatus bool
+	certificateStatusNegotiated      bool
+	ocspResponse                     []byte
+
+	// remoteSignatureSchemes are the signature schemes the peer advertised
+	// in the signature_algorithms extension or the CertificateRequest
+	remoteSignatureSchemes []signaturehash.Algorithm
+
+	// ecjpake holds the EC J-PAKE exchange while an EC J-PAKE cipher suite
+	// is offered or negotiated
+	ecjpake *ecjpake.Context
+
+	// keyExchange is the KeyExchange of a KeyExchangeCipherSuite, nil if
+	// the negotiated CipherSuite uses a built-in key exchange
+	keyExchange KeyExchange
+
+	// Connection Identifiers must be negotiated afresh on session resumption.
+	// https://datatracker.ietf.org/doc/html/rfc9146#name-the-connection_id-extension
+
+	// localConnectionID is the locally generated connection ID that is expected
+	// to be received from the remote endpoint.
+	// For a server, this is the connection ID sent in ServerHello.
+	// For a client, this is the connection ID sent in the ClientHello.
+	localConnectionID atomic.Value
+	// remoteConnectionID is the connection ID that the remote endpoint
+	// specifies should be sent.
+	// For a server, this is the connection ID received in the ClientHello.
+	// For a client, this is the connection ID received in the ServerHello.
+	remoteConnectionID []byte
 }
 
 type serializedState struct {
@@ -60,6 +151,9 @@ type serializedState struct {
 	IdentityHint          []byte
 	SessionID             []byte
 	IsClient              bool
+	LocalConnectionID     []byte
+	RemoteConnectionID    []byte
+	EncryptThenMAC        bool
 }
 
 func (s *State) clone() *State {
@@ -89,6 +183,9 @@ func (s *State) serialize() *serializedState {
 		IdentityHint:          s.IdentityHint,
 		SessionID:             s.SessionID,
 		IsClient:              s.isClient,
+		LocalConnectionID:     s.getLocalConnectionID(),
+		RemoteConnectionID:    s.remoteConnectionID,
+		EncryptThenMAC:        s.encryptThenMAC,
 	}
 }
 
@@ -118,6 +215,7 @@ func (s *State) deserialize(serialized serializedState) {
 
 	// Set cipher suite
 	s.cipherSuite = cipherSuiteForID(CipherSuiteID(serialized.CipherSuiteID), nil)
+	s.setEncryptThenMAC(serialized.EncryptThenMAC)
 
 	atomic.StoreUint64(&s.localSequenceNumber[epoch], serialized.SequenceNumber)
 	s.srtpProtectionProfile = SRTPProtectionProfile(serialized.SRTPProtectionProfile)
@@ -126,6 +224,19 @@ func (s *State) deserialize(serialized serializedState) {
 	s.PeerCertificates = serialized.PeerCertificates
 	s.IdentityHint = serialized.IdentityHint
 	s.SessionID = serialized.SessionID
+
+	// Set connection IDs
+	s.setLocalConnectionID(serialized.LocalConnectionID)
+	s.remoteConnectionID = serialized.RemoteConnectionID
+}
+
+// setEncryptThenMAC records if Encrypt-then-MAC was negotiated, and applies
+// it to the cipher suite before it is initialized
+func (s *State) setEncryptThenMAC(v bool) {
+	s.encryptThenMAC = v
+	if c, ok := s.cipherSuite.(encryptThenMACCipherSuite); ok {
+		c.SetEncryptThenMAC(v)
+	}
 }
 
 func (s *State) initCipherSuite() error {
@@ -213,3 +324,25 @@ func (s *State) getLocalEpoch() uint16 {
 	}
 	return 0
 }
+
+func (s *State) getLocalConnectionID() []byte {
+	if localConnectionID, ok := s.localConnectionID.Load().([]byte); ok {
+		return localConnectionID
+	}
+	return nil
+}
+
+func (s *State) setLocalConnectionID(v []byte) {
+	s.localConnectionID.Store(v)
+}
+
+func (s *State) getLocalRecordSizeLimit() int {
+	if limit, ok := s.localRecordSizeLimit.Load().(int); ok {
+		return limit
+	}
+	return 0
+}
+
+func (s *State) setLocalRecordSizeLimit(v int) {
+	s.localRecordSizeLimit.Store(v)
+}
@@ -1,5 +1,7 @@
 package dtls
 
+import "github.com/pion/dtls/v2/pkg/crypto/elliptic"
+
 func findMatchingSRTPProfile(a, b []SRTPProtectionProfile) (SRTPProtectionProfile, bool) {
 	for _, aProfile := range a {
 		for _, bProfile := range b {
@@ -11,6 +13,25 @@ func findMatchingSRTPProfile(a, b []SRTPProtectionProfile) (SRTPProtectionProfil
 	return 0, false
 }
 
+// An empty list of curve preferences means X25519, P-256 and P-384
+func defaultCurvePreferences(curves []elliptic.Curve) []elliptic.Curve {
+	if len(curves) == 0 {
+		return []elliptic.Curve{elliptic.X25519, elliptic.P256, elliptic.P384}
+	}
+	return curves
+}
+
+func findMatchingEllipticCurve(a, b []elliptic.Curve) (elliptic.Curve, bool) {
+	for _, aCurve := range a {
+		for _, bCurve := range b {
+			if aCurve == bCurve {
+				return aCurve, true
+			}
+		}
+	}
+	return 0, false
+}
+
 func findMatchingCipherSuite(a, b []CipherSuite) (CipherSuite, bool) {
 	for _, aSuite := range a {
 		for _, bSuite := range b {